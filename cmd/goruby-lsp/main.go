@@ -2,12 +2,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/jarredhawkins/goruby-lsp/internal/cache"
 	"github.com/jarredhawkins/goruby-lsp/internal/index"
 	"github.com/jarredhawkins/goruby-lsp/internal/lsp"
 	"github.com/jarredhawkins/goruby-lsp/internal/parser"
@@ -15,17 +23,52 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "cache":
+			os.Exit(runCacheCommand(os.Args[2:]))
+		case "graph":
+			os.Exit(runGraphCommand(os.Args[2:]))
+		case "namespaces":
+			os.Exit(runNamespacesCommand(os.Args[2:]))
+		}
+	}
+
 	var (
-		rootPath string
-		logFile  string
-		debug    bool
+		rootPath  string
+		logFile   string
+		debug     bool
+		debugAddr string
+		readOnly  bool
+		cacheMax  int64
+		limits    = parser.DefaultLimits
+
+		verifyInterval time.Duration
+		verifySample   int
+		watchMode      string
+		extraRoots     string
+		indexSlice     time.Duration
 	)
 
 	flag.StringVar(&rootPath, "root", "", "Root path of the Ruby project (defaults to current directory)")
 	flag.StringVar(&logFile, "log", "", "Log file path (defaults to stderr)")
 	flag.BoolVar(&debug, "debug", false, "Enable debug logging")
+	flag.StringVar(&debugAddr, "debug-addr", "", "Serve index status as JSON at http://<addr>/debug/index (e.g., localhost:6060)")
+	flag.IntVar(&limits.MaxLineLength, "max-line-length", limits.MaxLineLength, "Skip lines longer than this many bytes (0 disables)")
+	flag.DurationVar(&limits.FileBudget, "parse-budget", limits.FileBudget, "Stop parsing a file after this long (0 disables)")
+	flag.BoolVar(&readOnly, "read-only", false, "Never write to the workspace or run project code (for untrusted repositories)")
+	flag.Int64Var(&cacheMax, "cache-max-size", cache.DefaultMaxSize, "Evict the least recently used workspace caches above this many bytes in total")
+	flag.DurationVar(&verifyInterval, "verify-interval", 10*time.Second, "Recheck a sample of indexed files against the disk this often, repairing missed watcher events (0 disables)")
+	flag.IntVar(&verifySample, "verify-sample", 50, "Files rechecked per verification pass")
+	flag.StringVar(&extraRoots, "extra-roots", "", "Comma-separated directories to index besides the root, relative to it (e.g., vendor/gems/nokogiri/ext); they aren't watched")
+	flag.DurationVar(&indexSlice, "index-slice", 0, "Index in slices of this long, pausing between them so requests aren't starved on slow machines (0 disables)")
+	flag.StringVar(&watchMode, "watcher", "server", "Who watches files for changes: server (fsnotify) or client (registered with the editor, for remote and container setups; falls back to server)")
 	flag.Parse()
 
+	if watchMode != "server" && watchMode != "client" {
+		log.Fatalf("invalid -watcher %q: expected server or client", watchMode)
+	}
+
 	// Default to current directory
 	if rootPath == "" {
 		var err error
@@ -36,6 +79,10 @@ func main() {
 	}
 
 	// Setup logging
+	if readOnly && insideDir(logFile, rootPath) {
+		log.Printf("read-only mode: not writing the log to %s inside the workspace", logFile)
+		logFile = ""
+	}
 	if logFile != "" {
 		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 		if err != nil {
@@ -64,41 +111,248 @@ func main() {
 		cancel()
 	}()
 
+	go evictCaches(rootPath, cacheMax)
+
 	// Initialize parser registry with default matchers
 	registry := parser.NewRegistry()
 	parser.RegisterDefaults(registry)
 
-	// Create and build the index
+	// Create the index
 	idx := index.New(rootPath, registry)
-	if err := idx.Build(ctx); err != nil {
-		log.Fatalf("failed to build index: %v", err)
+	idx.SetScanLimits(limits)
+	idx.SetBuildSlice(indexSlice)
+	if extraRoots != "" {
+		idx.SetExtraRoots(strings.Split(extraRoots, ","))
+	}
+	if debugAddr != "" {
+		go serveDebug(debugAddr, idx)
 	}
 
-	// Start file watcher
-	w, err := watcher.New(rootPath, func(changed, removed []string) {
-		for _, path := range removed {
-			idx.RemoveFile(path)
+	// Start LSP server on stdio
+	server := lsp.NewServer(idx)
+	server.SetReadOnly(readOnly)
+
+	// Build the index once the client is connected, so the editor can show
+	// its progress. Then start the file watcher, unless the client is asked
+	// to watch instead.
+	// Failures are shown to the user rather than ending the session: the
+	// server keeps answering from what it could index.
+	if watchMode == "client" {
+		server.SetClientWatching(func() {
+			if err := startWatcher(ctx, rootPath, idx, server.WatcherError); err != nil {
+				log.Printf("failed to start watcher: %v", err)
+				server.WatcherError(err, true)
+			}
+		})
+	}
+	server.BuildIndexOnInitialized(func(err error) {
+		if err != nil {
+			log.Printf("failed to build index: %v", err)
+			return
 		}
-		for _, path := range changed {
-			if err := idx.UpdateFile(path); err != nil {
-				log.Printf("failed to update file %s: %v", path, err)
+		go idx.RunVerifier(ctx, verifyInterval, verifySample)
+		if watchMode == "server" {
+			if err := startWatcher(ctx, rootPath, idx, server.WatcherError); err != nil {
+				log.Printf("failed to start watcher: %v", err)
+				server.WatcherError(err, true)
 			}
 		}
 	})
-	if err != nil {
-		log.Fatalf("failed to create watcher: %v", err)
+
+	if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("LSP server error: %v", err)
 	}
-	defer w.Close()
 
+	log.Println("ruby-lsp shutdown complete")
+}
+
+// startWatcher watches rootPath with fsnotify, applying changes to idx,
+// until ctx is done. onError is told of errors once watching has started.
+func startWatcher(ctx context.Context, rootPath string, idx *index.Index, onError watcher.ErrorHandler) error {
+	w, err := watcher.New(rootPath, idx.ApplyBatch)
+	if err != nil {
+		return err
+	}
+	w.OnError(onError)
 	if err := w.Start(); err != nil {
-		log.Fatalf("failed to start watcher: %v", err)
+		w.Close()
+		return err
 	}
+	go func() {
+		<-ctx.Done()
+		w.Close()
+	}()
+	return nil
+}
 
-	// Start LSP server on stdio
-	server := lsp.NewServer(idx)
-	if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil {
-		log.Fatalf("LSP server error: %v", err)
+// evictCaches keeps the caches of all workspaces under maxSize bytes,
+// sparing the cache of the workspace at rootPath
+func evictCaches(rootPath string, maxSize int64) {
+	base, err := cache.Base()
+	if err != nil {
+		log.Printf("no cache directory: %v", err)
+		return
 	}
+	current, err := cache.Dir(base, rootPath)
+	if err != nil {
+		log.Printf("no cache directory: %v", err)
+		return
+	}
+	removed, err := cache.Evict(base, maxSize, current)
+	if err != nil {
+		log.Printf("cache eviction failed: %v", err)
+	}
+	for _, dir := range removed {
+		log.Printf("evicted cache %s", dir)
+	}
+}
 
-	log.Println("ruby-lsp shutdown complete")
+// runCacheCommand handles `goruby-lsp cache <clean|dir>` and returns the
+// exit code
+func runCacheCommand(args []string) int {
+	usage := "usage: goruby-lsp cache clean | goruby-lsp cache dir [root]"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	base, err := cache.Base()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "no cache directory: %v\n", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "clean":
+		if err := cache.Clean(base); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to clean %s: %v\n", base, err)
+			return 1
+		}
+		fmt.Printf("removed %s\n", base)
+	case "dir":
+		root := "."
+		if len(args) > 1 {
+			root = args[1]
+		}
+		dir, err := cache.Dir(base, root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		fmt.Println(dir)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	return 0
+}
+
+// runGraphCommand handles `goruby-lsp graph [-format dot|json] [root]`:
+// it prints the project's model association graph. Returns the exit code.
+func runGraphCommand(args []string) int {
+	return runReportCommand("graph", args, func(idx *index.Index) (interface{}, string) {
+		graph := idx.AssociationGraph()
+		return graph, graph.DOT()
+	})
+}
+
+// runNamespacesCommand handles `goruby-lsp namespaces [-format dot|json]
+// [root]`: it prints which top-level namespaces reference which. Returns
+// the exit code.
+func runNamespacesCommand(args []string) int {
+	return runReportCommand("namespaces", args, func(idx *index.Index) (interface{}, string) {
+		report := idx.NamespaceDependencies()
+		return report, report.DOT()
+	})
+}
+
+// runReportCommand indexes the project named in args and prints the report
+// build returns, as DOT or JSON depending on the -format flag. Returns the
+// exit code.
+func runReportCommand(name string, args []string, build func(*index.Index) (interface{}, string)) int {
+	usage := fmt.Sprintf("usage: goruby-lsp %s [-format dot|json] [root]", name)
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	format := flags.String("format", "dot", "Output format: dot or json")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "dot" && *format != "json" {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	root := "."
+	if flags.NArg() > 0 {
+		root = flags.Arg(0)
+	}
+	idx, err := buildIndex(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build index: %v\n", err)
+		return 1
+	}
+
+	report, dot := build(idx)
+	if *format == "dot" {
+		fmt.Print(dot)
+		return 0
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// buildIndex indexes the project at root with the default matchers, for
+// subcommands that report on it. Progress logs are dropped so they don't
+// mix with the report.
+func buildIndex(root string) (*index.Index, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	registry := parser.NewRegistry()
+	parser.RegisterDefaults(registry)
+	idx := index.New(root, registry)
+	return idx, idx.Build(context.Background())
+}
+
+// serveDebug exposes the index status, including build timing, over HTTP
+func serveDebug(addr string, idx *index.Index) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/index", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(idx.Status()); err != nil {
+			log.Printf("failed to write debug status: %v", err)
+		}
+	})
+
+	log.Printf("debug endpoint listening on http://%s/debug/index", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("debug endpoint error: %v", err)
+	}
+}
+
+// insideDir reports whether path is dir or somewhere below it
+func insideDir(path, dir string) bool {
+	if path == "" {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, abs)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
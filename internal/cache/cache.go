@@ -0,0 +1,126 @@
+// Package cache locates the on-disk caches of indexed workspaces. Caches
+// live under the user's cache directory ($XDG_CACHE_HOME/goruby-lsp on
+// Linux), one subdirectory per workspace, so project directories are never
+// written to.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultMaxSize is the total size of all workspace caches above which the
+// least recently used ones are evicted
+const DefaultMaxSize int64 = 1 << 30
+
+// Base returns the directory holding every workspace's cache
+func Base() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goruby-lsp"), nil
+}
+
+// Dir returns the cache directory of the workspace at root, named by a
+// hash of its absolute path. The directory isn't created.
+func Dir(base, root string) (string, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(base, hex.EncodeToString(sum[:8])), nil
+}
+
+// Entry is one workspace's cache
+type Entry struct {
+	Path     string
+	Size     int64
+	LastUsed time.Time // Latest modification of any file in it
+}
+
+// Entries lists the workspace caches under base, least recently used first
+func Entries(base string) ([]Entry, error) {
+	dirs, err := os.ReadDir(base)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		entry := Entry{Path: filepath.Join(base, d.Name())}
+		err := filepath.WalkDir(entry.Path, func(path string, de os.DirEntry, err error) error {
+			if err != nil {
+				return nil // Skip what can't be read
+			}
+			info, err := de.Info()
+			if err != nil {
+				return nil
+			}
+			if !de.IsDir() {
+				entry.Size += info.Size()
+			}
+			if info.ModTime().After(entry.LastUsed) {
+				entry.LastUsed = info.ModTime()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+// Evict removes the least recently used workspace caches under base until
+// their total size is at most maxSize, never removing keep (the current
+// workspace's cache). It returns the removed directories.
+func Evict(base string, maxSize int64, keep string) ([]string, error) {
+	entries, err := Entries(base)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+
+	var removed []string
+	for _, e := range entries {
+		if total <= maxSize {
+			break
+		}
+		if e.Path == keep {
+			continue
+		}
+		if err := os.RemoveAll(e.Path); err != nil {
+			return removed, fmt.Errorf("evicting %s: %w", e.Path, err)
+		}
+		total -= e.Size
+		removed = append(removed, e.Path)
+	}
+	return removed, nil
+}
+
+// Clean removes every workspace cache under base
+func Clean(base string) error {
+	return os.RemoveAll(base)
+}
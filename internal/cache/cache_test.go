@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDirIsPerWorkspace(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	base, err := Base()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(base, os.Getenv("XDG_CACHE_HOME")) {
+		t.Errorf("expected the cache under XDG_CACHE_HOME, got %s", base)
+	}
+
+	a, _ := Dir(base, "/src/shop")
+	b, _ := Dir(base, "/src/blog")
+	again, _ := Dir(base, "/src/shop/")
+	if a == b || a != again || filepath.Dir(a) != base {
+		t.Errorf("unexpected cache dirs %s, %s, %s", a, b, again)
+	}
+}
+
+func TestEvictLeastRecentlyUsed(t *testing.T) {
+	base := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"old", "current", "recent"} {
+		dir := filepath.Join(base, name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		file := filepath.Join(dir, "index.bin")
+		if err := os.WriteFile(file, make([]byte, 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+		used := now.Add(time.Duration(i-3) * time.Hour)
+		os.Chtimes(file, used, used)
+		os.Chtimes(dir, used, used)
+	}
+
+	// The current workspace is kept even though it's older than recent
+	removed, err := Evict(base, 150, filepath.Join(base, "current"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 || filepath.Base(removed[0]) != "old" || filepath.Base(removed[1]) != "recent" {
+		t.Errorf("expected old then recent evicted, got %v", removed)
+	}
+	if _, err := os.Stat(filepath.Join(base, "current")); err != nil {
+		t.Errorf("expected the current cache kept: %v", err)
+	}
+
+	if err := Clean(base); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := Entries(base); len(entries) != 0 {
+		t.Errorf("expected no caches after clean, got %v", entries)
+	}
+}
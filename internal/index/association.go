@@ -0,0 +1,303 @@
+package index
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/parser"
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// maxThroughDepth bounds through: chain walks, guarding against cycles
+const maxThroughDepth = 8
+
+// FindAssociationsTargeting returns relation symbols whose target class is
+// className, matching qualified and unqualified spellings of the name
+func (idx *Index) FindAssociationsTargeting(className string) []*Symbol {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var result []*Symbol
+	for _, syms := range idx.byFile {
+		for _, sym := range syms {
+			if sym.Kind == types.KindRelation && targetMatches(idx.relationTargetLocked(sym), className) {
+				result = append(result, sym)
+			}
+		}
+	}
+	return sortSymbols(result)
+}
+
+// FindInverseAssociation returns the association on rel's target class that
+// mirrors rel: the one named by its inverse_of option, or otherwise one that
+// points back at the class declaring rel, preferring one whose own
+// inverse_of names rel. Returns nil if none is indexed.
+func (idx *Index) FindInverseAssociation(rel *Symbol) *Symbol {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	owner := strings.Join(rel.Scope, "::")
+	inverseOf := rel.Options["inverse_of"]
+
+	// Resolve the target to the full names of its class definitions
+	targets := make(map[string]bool)
+	for _, fullName := range idx.classFullNamesLocked(idx.relationTargetLocked(rel)) {
+		targets[fullName] = true
+	}
+
+	var candidates []*Symbol
+	for _, syms := range idx.byFile {
+		for _, sym := range syms {
+			if sym != rel && sym.Kind == types.KindRelation && targets[strings.Join(sym.Scope, "::")] {
+				candidates = append(candidates, sym)
+			}
+		}
+	}
+
+	// Candidates are sorted so that among several associations pointing
+	// back, one declaring inverse_of: rel wins, and otherwise the first
+	var pointsBack *Symbol
+	for _, sym := range sortSymbols(candidates) {
+		if inverseOf != "" {
+			if sym.Name == inverseOf {
+				return sym
+			}
+			continue
+		}
+		if !targetMatches(sym.TargetName, owner) {
+			continue
+		}
+		if sym.Options["inverse_of"] == rel.Name {
+			return sym
+		}
+		if pointsBack == nil {
+			pointsBack = sym
+		}
+	}
+	return pointsBack
+}
+
+// targetMatches reports whether a TargetName refers to name. Unqualified
+// names match any namespace ("Invoice" matches "Billing::Invoice"), while two
+// qualified names must agree exactly.
+func targetMatches(target, name string) bool {
+	target = strings.TrimPrefix(target, "::")
+	name = strings.TrimPrefix(name, "::")
+	if target == name {
+		return true
+	}
+	if strings.Contains(target, "::") && strings.Contains(name, "::") {
+		return false
+	}
+	return lastSegment(target) == lastSegment(name)
+}
+
+// lastSegment returns the final component of a namespaced name
+func lastSegment(name string) string {
+	if i := strings.LastIndex(name, "::"); i >= 0 {
+		return name[i+2:]
+	}
+	return name
+}
+
+// FindAssociationColumn resolves an association option value at the given
+// line (foreign_key: :owner_id, counter_cache: :items_count) to the column
+// or attribute it names on the model that owns it. Returns nil if name is
+// not such an option value.
+func (idx *Index) FindAssociationColumn(name, filePath string, line int) []*Symbol {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	// The relation spanning the line, so options on continuation lines of
+	// multi-line relations resolve too
+	var rel *Symbol
+	var option string
+	for _, sym := range idx.byFile[filePath] {
+		if sym.Kind != types.KindRelation || sym.Line > line || max(sym.Line, sym.EndLine) < line {
+			continue
+		}
+		for _, key := range []string{"foreign_key", "counter_cache"} {
+			if sym.Options[key] == name {
+				rel, option = sym, key
+			}
+		}
+	}
+	if rel == nil {
+		return nil
+	}
+
+	// belongs_to keeps its foreign key locally; has_one/has_many keep it on
+	// the target. counter_cache always lives on the belongs_to target.
+	model := strings.Join(rel.Scope, "::")
+	if option == "counter_cache" || rel.RelationType != "belongs_to" {
+		target := idx.relationTargetLocked(rel)
+		if model = idx.resolveTypeLocked(target, rel.Scope); model == "" {
+			model = strings.TrimPrefix(target, "::")
+		}
+	}
+
+	// Attributes are defined on the model itself, while schema columns are
+	// scoped by table name, which leaves out the model's namespace (orders
+	// for Shop::Order) or folds it in (shop_orders)
+	for _, candidate := range []string{model, lastSegment(model), strings.ReplaceAll(model, "::", "")} {
+		if results := idx.findDefinitionsLocked(candidate + "#" + name); len(results) > 0 {
+			return results
+		}
+	}
+	return nil
+}
+
+// RelationTarget returns the class a relation points at, inferred from its
+// name or options (following through: chains), and the full name of that
+// class's indexed definition, or "" when nothing indexed defines it
+func (idx *Index) RelationTarget(rel *Symbol) (name, fullName string) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	name = idx.relationTargetLocked(rel)
+	return name, idx.resolveTypeLocked(name, rel.Scope)
+}
+
+// ClosestClass returns the full name of the indexed class whose name is
+// closest to the class a relation infers, for a target nothing defines
+// (has_many :criteria infers Criteria, but the class is Criterion). Names
+// within a third of the inferred name's length in edits are considered,
+// preferring the fewest edits, then a class the relation's scope resolves
+// to, then the first full name. Returns "" when none is close enough.
+func (idx *Index) ClosestClass(rel *Symbol) string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	target := lastSegment(idx.relationTargetLocked(rel))
+	if target == "" {
+		return ""
+	}
+	best := max(1, len(target)/3)
+	var candidates []string
+	for name := range idx.shortNames {
+		if name == target || name[0] < 'A' || name[0] > 'Z' {
+			continue
+		}
+		distance := editDistance(strings.ToLower(name), strings.ToLower(target))
+		if distance > best {
+			continue
+		}
+		var classes []string
+		for _, fullName := range idx.fullNamesLocked(name) {
+			if sym := idx.typeSymbolLocked(fullName); sym != nil && sym.Kind == types.KindClass {
+				classes = append(classes, fullName)
+			}
+		}
+		if len(classes) == 0 {
+			continue
+		}
+		if distance < best {
+			best, candidates = distance, nil
+		}
+		candidates = append(candidates, classes...)
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.Strings(candidates)
+	for _, fullName := range candidates {
+		if idx.resolveTypeLocked(lastSegment(fullName), rel.Scope) == fullName {
+			return fullName
+		}
+	}
+	return candidates[0]
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// relationTargetLocked returns the class a relation resolves to. For
+// through: associations it walks the chain to the final class instead of
+// trusting the name-based guess. Caller must hold at least a read lock.
+func (idx *Index) relationTargetLocked(rel *Symbol) string {
+	return idx.walkThroughLocked(rel, 0)
+}
+
+func (idx *Index) walkThroughLocked(rel *Symbol, depth int) string {
+	through := rel.Options["through"]
+	if through == "" || rel.Options["class_name"] != "" || depth >= maxThroughDepth {
+		return rel.TargetName
+	}
+
+	// The join association declared on the same class
+	join := idx.relationInLocked(strings.Join(rel.Scope, "::"), through)
+	if join == nil {
+		return rel.TargetName
+	}
+	joinClass := idx.walkThroughLocked(join, depth+1)
+
+	// The source association on the join model: source: when given,
+	// otherwise the association's own name in plural or singular form
+	candidates := []string{rel.Name, parser.SingularName(rel.Name)}
+	if source := rel.Options["source"]; source != "" {
+		candidates = []string{source}
+	}
+	for _, name := range candidates {
+		if source := idx.relationInLocked(joinClass, name); source != nil {
+			return idx.walkThroughLocked(source, depth+1)
+		}
+	}
+
+	return rel.TargetName
+}
+
+// relationInLocked finds the relation called name declared by className.
+// Caller must hold at least a read lock.
+func (idx *Index) relationInLocked(className, name string) *Symbol {
+	for _, fullName := range idx.classFullNamesLocked(className) {
+		for _, sym := range idx.symbols[fullName+"::"+name] {
+			if sym.Kind == types.KindRelation {
+				return sym
+			}
+		}
+	}
+	return nil
+}
+
+// classFullNamesLocked resolves a possibly unqualified class name to the full
+// names of matching indexed classes, or the name itself if none are indexed.
+// Caller must hold at least a read lock.
+func (idx *Index) classFullNamesLocked(name string) []string {
+	name = strings.TrimPrefix(name, "::")
+	if _, ok := idx.symbols[name]; ok {
+		return []string{name}
+	}
+
+	var names []string
+	for _, fullName := range idx.fullNamesLocked(lastSegment(name)) {
+		for _, sym := range idx.symbols[fullName] {
+			if sym.Kind == types.KindClass && targetMatches(fullName, name) {
+				names = append(names, fullName)
+				break
+			}
+		}
+	}
+	if len(names) == 0 {
+		return []string{name}
+	}
+	return names
+}
@@ -0,0 +1,280 @@
+package index
+
+import (
+	"testing"
+)
+
+func TestFindAssociationsTargeting(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/invoice.rb", `module Billing
+  class Invoice
+  end
+end`)
+	idx.addContent("/test/account.rb", `class Account
+  has_many :invoices, class_name: 'Billing::Invoice'
+end`)
+	idx.addContent("/test/payment.rb", `module Billing
+  class Payment
+    belongs_to :invoice
+  end
+end`)
+	idx.addContent("/test/other.rb", `module Other
+  class Thing
+    belongs_to :invoice, class_name: 'Other::Invoice'
+  end
+end`)
+
+	rels := idx.FindAssociationsTargeting("Billing::Invoice")
+	if len(rels) != 2 {
+		t.Fatalf("expected 2 associations targeting Billing::Invoice, got %d: %+v", len(rels), rels)
+	}
+	for _, rel := range rels {
+		if rel.FullName == "Other::Thing::invoice" {
+			t.Errorf("did not expect association targeting Other::Invoice")
+		}
+	}
+}
+
+func TestFindInverseAssociation(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/order.rb", `class Order
+  has_many :items, class_name: 'LineItem', inverse_of: :parent_order
+  has_many :line_items
+end`)
+	idx.addContent("/test/line_item.rb", `class LineItem
+  belongs_to :parent_order, class_name: 'Order'
+  belongs_to :order
+end`)
+
+	var explicit, implicit *Symbol
+	for _, rel := range idx.FindAssociationsTargeting("LineItem") {
+		switch rel.Name {
+		case "items":
+			explicit = rel
+		case "line_items":
+			implicit = rel
+		}
+	}
+	if explicit == nil || implicit == nil {
+		t.Fatal("expected both Order associations to target LineItem")
+	}
+
+	if inv := idx.FindInverseAssociation(explicit); inv == nil || inv.Name != "parent_order" {
+		t.Errorf("expected inverse_of to select parent_order, got %+v", inv)
+	}
+
+	// Without inverse_of, the first association pointing back at Order wins
+	if inv := idx.FindInverseAssociation(implicit); inv == nil || inv.Name != "parent_order" {
+		t.Errorf("expected the first association back to Order, got %+v", inv)
+	}
+
+	// unless a later one names it as its inverse
+	idx.UpdateContent("/test/line_item.rb", []byte(`class LineItem
+  belongs_to :parent_order, class_name: 'Order'
+  belongs_to :order, inverse_of: :line_items
+end`), 0)
+	if inv := idx.FindInverseAssociation(implicit); inv == nil || inv.Name != "order" {
+		t.Errorf("expected the association declaring inverse_of: :line_items, got %+v", inv)
+	}
+}
+
+func TestTargetMatches(t *testing.T) {
+	tests := []struct {
+		target, name string
+		want         bool
+	}{
+		{"Invoice", "Invoice", true},
+		{"Billing::Invoice", "Invoice", true},
+		{"Invoice", "Billing::Invoice", true},
+		{"::Billing::Invoice", "Billing::Invoice", true},
+		{"Billing::Invoice", "Other::Invoice", false},
+		{"Invoice", "InvoiceItem", false},
+	}
+	for _, tt := range tests {
+		if got := targetMatches(tt.target, tt.name); got != tt.want {
+			t.Errorf("targetMatches(%q, %q) = %v, want %v", tt.target, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFindAssociationColumn(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/db/schema.rb", `ActiveRecord::Schema.define do
+  create_table "orders" do |t|
+    t.integer "items_count"
+    t.integer "buyer_id"
+  end
+
+  create_table "items" do |t|
+    t.integer "order_ref"
+    t.integer "owner_id"
+  end
+end`)
+	idx.addContent("/test/item.rb", `class Item
+  belongs_to :order, counter_cache: :items_count
+  belongs_to :owner, class_name: 'User', foreign_key: :owner_id
+end`)
+	idx.addContent("/test/order.rb", `class Order
+  has_many(
+    :items,
+    foreign_key: "order_ref",
+  )
+  def buyer_id
+  end
+end`)
+
+	tests := []struct {
+		name     string
+		file     string
+		line     int
+		wantFull string
+	}{
+		{"items_count", "/test/item.rb", 2, "Order#items_count"},
+		{"owner_id", "/test/item.rb", 3, "Item#owner_id"},
+		{"order_ref", "/test/order.rb", 4, "Item#order_ref"},
+	}
+
+	for _, tt := range tests {
+		results := idx.FindAssociationColumn(tt.name, tt.file, tt.line)
+		if len(results) != 1 || results[0].FullName != tt.wantFull {
+			t.Errorf("%s: expected %s, got %+v", tt.name, tt.wantFull, results)
+		}
+	}
+
+	// Outside an association option the lookup doesn't apply
+	if results := idx.FindAssociationColumn("buyer_id", "/test/order.rb", 6); results != nil {
+		t.Errorf("expected nil outside association options, got %+v", results)
+	}
+}
+
+func TestFindAssociationColumnInNamespace(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/db/schema.rb", `ActiveRecord::Schema.define do
+  create_table "shop_items" do |t|
+    t.integer "owner_ref"
+  end
+end`)
+	idx.addContent("/test/shop/item.rb", `module Shop
+  class Item
+    belongs_to :order, counter_cache: :line_count
+    belongs_to :owner, class_name: "User", foreign_key: :owner_ref
+  end
+end`)
+	idx.addContent("/test/shop/order.rb", `module Shop
+  class Order
+    def line_count
+    end
+  end
+end`)
+
+	tests := []struct {
+		name     string
+		line     int
+		wantFull string
+	}{
+		{"line_count", 3, "Shop::Order#line_count"}, // Target resolved in Shop
+		{"owner_ref", 4, "ShopItem#owner_ref"},      // Column of the shop_items table
+	}
+	for _, tt := range tests {
+		results := idx.FindAssociationColumn(tt.name, "/test/shop/item.rb", tt.line)
+		if len(results) != 1 || results[0].FullName != tt.wantFull {
+			t.Errorf("%s: expected %s, got %+v", tt.name, tt.wantFull, results)
+		}
+	}
+}
+
+func TestFindDefinitions_ThroughChain(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/models.rb", `class Store
+  has_many :orders
+  has_many :buyers, through: :orders, source: :customer
+  has_many :addresses, through: :buyers
+  has_one :flagship_manager, through: :flagship
+  has_one :flagship, class_name: 'Branch'
+end
+
+class Order
+  belongs_to :customer, class_name: 'Shop::Client'
+end
+
+class Branch
+  has_one :flagship_manager, class_name: 'Employee'
+end
+
+module Shop
+  class Client
+    has_many :addresses, class_name: 'PostalAddress'
+  end
+end
+
+class PostalAddress
+end
+
+class Employee
+end`)
+
+	tests := []struct {
+		name     string
+		wantFull string
+	}{
+		{"buyers", "Shop::Client"},       // through + source
+		{"addresses", "PostalAddress"},   // nested through chain
+		{"flagship_manager", "Employee"}, // has_one through
+	}
+
+	for _, tt := range tests {
+		results := idx.FindDefinitions(tt.name)
+		if len(results) != 1 || results[0].FullName != tt.wantFull {
+			t.Errorf("%s: expected %s, got %+v", tt.name, tt.wantFull, results)
+		}
+	}
+
+	// Targeting queries see the resolved class too
+	rels := idx.FindAssociationsTargeting("PostalAddress")
+	if len(rels) != 2 {
+		t.Errorf("expected Store#addresses and Shop::Client#addresses to target PostalAddress, got %d", len(rels))
+	}
+}
+
+func TestFindDefinitions_ThroughCycle(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/loop.rb", `class Node
+  has_many :links, through: :links
+end`)
+
+	// A self-referencing chain falls back to the inferred name without hanging
+	if results := idx.FindDefinitions("links"); len(results) != 0 {
+		t.Errorf("expected no Link class, got %+v", results)
+	}
+}
+
+func TestClosestClass(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/app/models/criterion.rb", "class Criterion\nend")
+	idx.addContent("/app/models/analysis.rb", "class Analysis\nend")
+	idx.addContent("/app/models/billing/status.rb", "module Billing\n  class Status\n  end\nend")
+	idx.addContent("/app/models/reporting/status.rb", "module Reporting\n  class Status\n  end\nend")
+	idx.addContent("/app/models/billing/invoice.rb", `module Billing
+  class Invoice
+    has_many :criteria
+    has_many :analyses
+    belongs_to :statu
+    has_many :widgets
+  end
+end`)
+
+	want := map[string]string{
+		"criteria": "Criterion",
+		"analyses": "Analysis",
+		"statu":    "Billing::Status", // Resolved from the relation's namespace
+		"widgets":  "",
+	}
+	for _, sym := range idx.byFile["/app/models/billing/invoice.rb"] {
+		if sym.Kind != KindRelation {
+			continue
+		}
+		if got := idx.ClosestClass(sym); got != want[sym.Name] {
+			t.Errorf("ClosestClass(%s) = %q, want %q", sym.Name, got, want[sym.Name])
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package index
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/parser"
+)
+
+// app/ subdirectories Rails never autoloads
+var nonAutoloadAppDirs = map[string]bool{
+	"assets":     true,
+	"javascript": true,
+	"views":      true,
+}
+
+// config.autoload_paths << "#{root}/lib"
+// config.eager_load_paths += %W(#{config.root}/app/lib)
+// config.autoload_once_paths.push(Rails.root.join("extras"))
+var autoloadPathPattern = regexp.MustCompile(`\b(?:autoload_paths|eager_load_paths|autoload_once_paths)\s*(?:<<|\+=|\.(?:push|concat|unshift|append)\b)\s*(.*)$`)
+
+// config.autoload_lib(ignore: %w[assets tasks])
+var autoloadLibPattern = regexp.MustCompile(`\bautoload_lib(?:_once)?\b`)
+
+var (
+	joinArgsPattern     = regexp.MustCompile(`\.join\(([^)]*)\)`)
+	quotedPattern       = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+	wordArrayPattern    = regexp.MustCompile(`%[wW](?:\(([^)]*)\)|\[([^\]]*)\])`)
+	expandPathPattern   = regexp.MustCompile(`File\.expand_path\(\s*["']([^"']+)["']\s*,\s*__dir__\s*\)`)
+	interpolationPrefix = regexp.MustCompile(`^#\{[^}]*\}/?`)
+	underscoreAcronym   = regexp.MustCompile(`([A-Z\d]+)([A-Z][a-z])`)
+	underscoreWord      = regexp.MustCompile(`([a-z\d])([A-Z])`)
+)
+
+// AutoloadRoots maps between file paths and the constants Zeitwerk
+// expects them to define, based on the app's autoload directories
+type AutoloadRoots struct {
+	dirs []string // Absolute paths, longest first so nested roots win
+}
+
+// LoadAutoloadRoots collects the default app/* roots of the application
+// and any engines, plus paths added in config/application.rb or engine.rb
+func LoadAutoloadRoots(rootPath string) *AutoloadRoots {
+	seen := make(map[string]bool)
+	var dirs []string
+	add := func(dir string) {
+		dir = filepath.Clean(dir)
+		if seen[dir] {
+			return
+		}
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	addApp := func(appRoot, configFile string) {
+		entries, _ := os.ReadDir(filepath.Join(appRoot, "app"))
+		for _, e := range entries {
+			if !e.IsDir() || nonAutoloadAppDirs[e.Name()] {
+				continue
+			}
+			add(filepath.Join(appRoot, "app", e.Name()))
+			add(filepath.Join(appRoot, "app", e.Name(), "concerns"))
+		}
+		for _, dir := range configuredAutoloadPaths(appRoot, configFile) {
+			add(dir)
+		}
+	}
+
+	addApp(rootPath, filepath.Join(rootPath, "config", "application.rb"))
+
+	// Engines: <engine root>/lib/<name>/engine.rb, at the top level or one
+	// directory down (engines/billing, components/billing)
+	for _, pattern := range []string{"lib/*/engine.rb", "*/*/lib/*/engine.rb"} {
+		matches, _ := filepath.Glob(filepath.Join(rootPath, pattern))
+		for _, engineFile := range matches {
+			engineRoot := filepath.Dir(filepath.Dir(filepath.Dir(engineFile)))
+			addApp(engineRoot, engineFile)
+		}
+	}
+
+	sort.SliceStable(dirs, func(i, j int) bool { return len(dirs[i]) > len(dirs[j]) })
+	return &AutoloadRoots{dirs: dirs}
+}
+
+// configuredAutoloadPaths extracts directories added to the autoload and
+// eager load paths in a Rails config file. Paths are resolved against
+// appRoot, except File.expand_path(..., __dir__) which is file-relative.
+func configuredAutoloadPaths(appRoot, configFile string) []string {
+	f, err := os.Open(configFile)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var paths []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		if autoloadLibPattern.MatchString(line) {
+			paths = append(paths, filepath.Join(appRoot, "lib"))
+			continue
+		}
+
+		match := autoloadPathPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		for _, rel := range autoloadPathArgs(match[1], filepath.Dir(configFile)) {
+			if !filepath.IsAbs(rel) {
+				rel = filepath.Join(appRoot, rel)
+			}
+			paths = append(paths, expandGlob(rel)...)
+		}
+	}
+	return paths
+}
+
+// autoloadPathArgs extracts the path expressions from the right-hand side
+// of an autoload path addition. Relative results are app-root relative.
+func autoloadPathArgs(expr, configDir string) []string {
+	if m := expandPathPattern.FindStringSubmatch(expr); m != nil {
+		return []string{filepath.Join(configDir, m[1])}
+	}
+
+	// Rails.root.join("app", "lib") / root.join('extras')
+	if m := joinArgsPattern.FindStringSubmatch(expr); m != nil {
+		var parts []string
+		for _, q := range quotedPattern.FindAllStringSubmatch(m[1], -1) {
+			parts = append(parts, q[1]+q[2])
+		}
+		if len(parts) > 0 {
+			return []string{filepath.Join(parts...)}
+		}
+		return nil
+	}
+
+	var raw []string
+	if m := wordArrayPattern.FindStringSubmatch(expr); m != nil {
+		raw = strings.Fields(m[1] + " " + m[2])
+	} else {
+		for _, q := range quotedPattern.FindAllStringSubmatch(expr, -1) {
+			raw = append(raw, q[1]+q[2])
+		}
+	}
+
+	var paths []string
+	for _, p := range raw {
+		// "#{root}/lib" and "#{config.root}/lib" are app-root relative
+		p = interpolationPrefix.ReplaceAllString(p, "")
+		if p != "" && !strings.Contains(p, "#{") {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// expandGlob resolves Dir[...]-style wildcards to existing directories
+func expandGlob(path string) []string {
+	if !strings.ContainsAny(path, "*?[") {
+		return []string{path}
+	}
+	matches, _ := filepath.Glob(strings.TrimSuffix(path, "/"))
+	return matches
+}
+
+// Dirs returns the autoload root directories, longest first
+func (r *AutoloadRoots) Dirs() []string {
+	return r.dirs
+}
+
+// ConstantForPath returns the constant Zeitwerk expects the file to define
+// (app/services/billing/tax_calculator.rb → Billing::TaxCalculator)
+func (r *AutoloadRoots) ConstantForPath(path string) (string, bool) {
+	if filepath.Ext(path) != ".rb" {
+		return "", false
+	}
+	for _, dir := range r.dirs {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		segments := strings.Split(strings.TrimSuffix(rel, ".rb"), string(filepath.Separator))
+		for i, seg := range segments {
+			segments[i] = parser.CamelCase(seg)
+		}
+		return strings.Join(segments, "::"), true
+	}
+	return "", false
+}
+
+// PathsForConstant returns existing files under any root that would define
+// the constant by Zeitwerk's naming convention
+func (r *AutoloadRoots) PathsForConstant(name string) []string {
+	segments := strings.Split(strings.TrimPrefix(name, "::"), "::")
+	for i, seg := range segments {
+		segments[i] = underscore(seg)
+	}
+	rel := filepath.Join(segments...) + ".rb"
+
+	var paths []string
+	for _, dir := range r.dirs {
+		path := filepath.Join(dir, rel)
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// underscore converts a CamelCase constant segment to its file name,
+// splitting acronyms the way ActiveSupport does (HTMLParser → html_parser)
+func underscore(name string) string {
+	name = underscoreAcronym.ReplaceAllString(name, "${1}_${2}")
+	name = underscoreWord.ReplaceAllString(name, "${1}_${2}")
+	return strings.ToLower(name)
+}
+
+// isAutoloadConfig reports whether changes to path can alter autoload roots
+func isAutoloadConfig(path string) bool {
+	base := filepath.Base(path)
+	return base == "engine.rb" || (base == "application.rb" && filepath.Base(filepath.Dir(path)) == "config")
+}
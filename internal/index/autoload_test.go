@@ -0,0 +1,92 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAutoloadRoots(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"config/application.rb": `module Shop
+  class Application < Rails::Application
+    config.autoload_paths << "#{root}/extras"
+    config.eager_load_paths += %W(#{config.root}/app/lib/core)
+    # config.autoload_paths << "#{root}/ignored"
+    config.autoload_once_paths.push(Rails.root.join("vendor_ext", "ruby"))
+  end
+end`,
+		"app/models/order.rb":                          "class Order\nend",
+		"app/models/concerns/auditable.rb":             "module Auditable\nend",
+		"app/services/billing/tax_calculator.rb":       "module Billing\n  class TaxCalculator\n  end\nend",
+		"app/views/orders/index.html.erb":              "",
+		"app/lib/core/html_parser.rb":                  "class HTMLParser\nend",
+		"extras/money.rb":                              "class Money\nend",
+		"ignored/thing.rb":                             "class Thing\nend",
+		"vendor_ext/ruby/shim.rb":                      "class Shim\nend",
+		"engines/payments/lib/payments/engine.rb":      "module Payments\n  class Engine < Rails::Engine\n  end\nend",
+		"engines/payments/app/models/payments/card.rb": "module Payments\n  class Card\n  end\nend",
+	})
+
+	roots := LoadAutoloadRoots(root)
+
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"app/models/order.rb", "Order", true},
+		{"app/models/concerns/auditable.rb", "Auditable", true},
+		{"app/services/billing/tax_calculator.rb", "Billing::TaxCalculator", true},
+		{"extras/money.rb", "Money", true},
+		{"vendor_ext/ruby/shim.rb", "Shim", true},
+		{"engines/payments/app/models/payments/card.rb", "Payments::Card", true},
+		{"ignored/thing.rb", "", false},
+		{"app/views/orders/index.html.erb", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := roots.ConstantForPath(filepath.Join(root, tt.path))
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("ConstantForPath(%s) = %q, %v; want %q, %v", tt.path, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	paths := roots.PathsForConstant("HTMLParser")
+	if len(paths) != 1 || paths[0] != filepath.Join(root, "app/lib/core/html_parser.rb") {
+		t.Errorf("PathsForConstant(HTMLParser) = %v", paths)
+	}
+	paths = roots.PathsForConstant("Billing::TaxCalculator")
+	if len(paths) != 1 {
+		t.Errorf("PathsForConstant(Billing::TaxCalculator) = %v", paths)
+	}
+}
+
+func TestUnderscore(t *testing.T) {
+	tests := map[string]string{
+		"TaxCalculator": "tax_calculator",
+		"HTMLParser":    "html_parser",
+		"User":          "user",
+		"OAuth2Client":  "o_auth2_client",
+	}
+	for input, want := range tests {
+		if got := underscore(input); got != want {
+			t.Errorf("underscore(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
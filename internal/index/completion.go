@@ -0,0 +1,39 @@
+package index
+
+import (
+	"sort"
+	"strings"
+)
+
+// SymbolsWithPrefix returns one symbol per full name whose short name
+// starts with prefix and which keep accepts (nil accepts all), sorted by
+// short name then full name and capped at limit (0 for no cap)
+func (idx *Index) SymbolsWithPrefix(prefix string, keep func(*Symbol) bool, limit int) []*Symbol {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var names []string
+	for name := range idx.shortNames {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var results []*Symbol
+	for _, name := range names {
+		for _, fullName := range idx.fullNamesLocked(name) {
+			for _, sym := range sortedCopy(idx.symbols[fullName]) {
+				if sym.Name != name || (keep != nil && !keep(sym)) {
+					continue
+				}
+				results = append(results, sym)
+				if limit > 0 && len(results) >= limit {
+					return results
+				}
+				break
+			}
+		}
+	}
+	return results
+}
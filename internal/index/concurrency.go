@@ -0,0 +1,63 @@
+package index
+
+import (
+	"os"
+	"runtime"
+)
+
+// smallFileSize is the average size below which files are read and parsed
+// quickly enough that I/O waits are a large share of each, so workers
+// outnumber CPUs to keep them busy
+const smallFileSize = 4 << 10
+
+// sizeSample is how many of a build's files are checked to estimate their
+// average size
+const sizeSample = 32
+
+// parallelSearchFiles is how many candidate files a text search needs
+// before it's split across workers
+const parallelSearchFiles = 64
+
+// SetMaxWorkers caps how many files are indexed or searched at once; 0
+// (the default) lets the CPU count decide
+func (idx *Index) SetMaxWorkers(n int) {
+	idx.maxWorkers.Store(int32(max(n, 0)))
+	idx.trigram.maxWorkers.Store(int32(max(n, 0)))
+}
+
+// workerCount picks how many files to index at once: one per CPU the Go
+// scheduler may use, twice that for small files, capped by the configured
+// maximum and the number of files
+func (idx *Index) workerCount(files []string) int {
+	n := runtime.GOMAXPROCS(0)
+	if size := averageFileSize(files); size > 0 && size < smallFileSize {
+		n *= 2
+	}
+	return clampWorkers(n, int(idx.maxWorkers.Load()), len(files))
+}
+
+// clampWorkers limits n workers to limit (when set) and to jobs, keeping
+// at least one
+func clampWorkers(n, limit, jobs int) int {
+	if limit > 0 {
+		n = min(n, limit)
+	}
+	return max(1, min(n, jobs))
+}
+
+// averageFileSize estimates the average size of files from an evenly
+// spaced sample of them, or 0 when none can be read
+func averageFileSize(files []string) int64 {
+	step := max(1, len(files)/sizeSample)
+	var total, count int64
+	for i := 0; i < len(files); i += step {
+		if info, err := os.Stat(files[i]); err == nil {
+			total += info.Size()
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / count
+}
@@ -0,0 +1,81 @@
+package index
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestWorkerCount(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, size int) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(strings.Repeat("#", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	var small, large []string
+	for i := range 200 {
+		small = append(small, write(fmt.Sprintf("small%d.rb", i), 100))
+	}
+	for i := range 200 {
+		large = append(large, write(fmt.Sprintf("large%d.rb", i), 2*smallFileSize))
+	}
+
+	idx := newTestIndex()
+	procs := runtime.GOMAXPROCS(0)
+	if got, want := idx.workerCount(small), min(2*procs, len(small)); got != want {
+		t.Errorf("expected %d workers for small files, got %d", want, got)
+	}
+	if got, want := idx.workerCount(large), min(procs, len(large)); got != want {
+		t.Errorf("expected %d workers for large files, got %d", want, got)
+	}
+	if got := idx.workerCount(small[:1]); got != 1 {
+		t.Errorf("expected one worker for one file, got %d", got)
+	}
+
+	idx.SetMaxWorkers(1)
+	if got := idx.workerCount(small); got != 1 {
+		t.Errorf("expected the configured maximum, got %d", got)
+	}
+}
+
+func TestClampWorkers(t *testing.T) {
+	tests := []struct {
+		n, limit, jobs, want int
+	}{
+		{8, 0, 100, 8},
+		{8, 4, 100, 4},
+		{8, 16, 100, 8},
+		{8, 0, 3, 3},
+		{8, 0, 0, 1},
+	}
+	for _, tt := range tests {
+		if got := clampWorkers(tt.n, tt.limit, tt.jobs); got != tt.want {
+			t.Errorf("clampWorkers(%d, %d, %d) = %d, want %d", tt.n, tt.limit, tt.jobs, got, tt.want)
+		}
+	}
+}
+
+func TestParallelSearch(t *testing.T) {
+	idx := newTestIndex()
+	idx.SetMaxWorkers(3)
+	for i := range 2 * parallelSearchFiles {
+		idx.UpdateContent(fmt.Sprintf("/test/job%d.rb", i), []byte("class Job\n  def perform\n    Invoice.send_reminders\n  end\nend\n"), 0)
+	}
+
+	refs := idx.FindReferences("send_reminders")
+	if len(refs) != 2*parallelSearchFiles {
+		t.Fatalf("expected a reference per file, got %d", len(refs))
+	}
+	for i := 1; i < len(refs); i++ {
+		if refs[i-1].FilePath >= refs[i].FilePath {
+			t.Fatalf("expected references sorted by path, got %s before %s", refs[i-1].FilePath, refs[i].FilePath)
+		}
+	}
+}
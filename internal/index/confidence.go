@@ -0,0 +1,51 @@
+package index
+
+// Confidence grades how a definition was resolved. The index is heuristic,
+// so clients can use it to decide which results to trust or show.
+type Confidence int
+
+const (
+	// ConfidenceExact is a match on the fully qualified name
+	ConfidenceExact Confidence = iota
+	// ConfidenceContextual was resolved through the enclosing scope, or a
+	// relation declared there
+	ConfidenceContextual
+	// ConfidenceFuzzy matched by short name only, in any class
+	ConfidenceFuzzy
+	// ConfidenceGhost was inferred from metaprogramming
+	ConfidenceGhost
+)
+
+var confidenceNames = map[Confidence]string{
+	ConfidenceExact:      "exact",
+	ConfidenceContextual: "contextual",
+	ConfidenceFuzzy:      "fuzzy",
+	ConfidenceGhost:      "ghost",
+}
+
+// String returns the confidence name (e.g., "contextual")
+func (c Confidence) String() string {
+	if name, ok := confidenceNames[c]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseConfidence converts a name (as returned by String) back to a Confidence
+func ParseConfidence(name string) (Confidence, bool) {
+	for c, n := range confidenceNames {
+		if n == name {
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// SymbolConfidence is the confidence of one result in a set resolved with
+// confidence c: symbols inferred from metaprogramming are always ghosts
+func SymbolConfidence(sym *Symbol, c Confidence) Confidence {
+	if sym.Synthetic && c < ConfidenceGhost {
+		return ConfidenceGhost
+	}
+	return c
+}
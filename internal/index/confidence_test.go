@@ -0,0 +1,116 @@
+package index
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveDefinitionsConfidence(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/billing.rb", `module Billing
+  class Invoice
+    def total
+    end
+
+    def recalc
+      total
+    end
+  end
+
+  class Report
+    def render
+      Invoice.new.total
+    end
+  end
+end`)
+	idx.addContent("/test/finder.rb", `class Finder
+  def method_missing(name, *args)
+    if name.to_s.start_with?("find_by_")
+      super
+    end
+  end
+end`)
+
+	tests := []struct {
+		name string
+		word string
+		file string
+		line int
+		want Confidence
+	}{
+		{"qualified name", "Billing::Invoice", "/test/other.rb", 1, ConfidenceExact},
+		{"name in enclosing namespace", "Invoice", "/test/billing.rb", 13, ConfidenceContextual},
+		{"method on enclosing class", "total", "/test/billing.rb", 7, ConfidenceContextual},
+		{"method from another class", "total", "/test/billing.rb", 13, ConfidenceFuzzy},
+		{"method_missing prefix", "find_by_email", "/test/finder.rb", 4, ConfidenceGhost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, got := idx.ResolveDefinitions(tt.word, tt.file, tt.line)
+			if len(results) == 0 {
+				t.Fatalf("no definitions for %s", tt.word)
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseConfidence(t *testing.T) {
+	for _, c := range []Confidence{ConfidenceExact, ConfidenceContextual, ConfidenceFuzzy, ConfidenceGhost} {
+		if parsed, ok := ParseConfidence(c.String()); !ok || parsed != c {
+			t.Errorf("round trip of %s failed", c)
+		}
+	}
+	if _, ok := ParseConfidence("certain"); ok {
+		t.Error("expected unknown confidence to fail")
+	}
+}
+
+func TestResolveDefinitionsConventionFallback(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"app/services/tax_calculator.rb": "module Calculators\n  class Tax\n  end\nend\n\nObject.const_set(:TaxCalculator, Calculators::Tax)",
+		"lib/legacy/shipping_rate.rb":    "class Rates\nend",
+		"app/models/order.rb":            "class Order\n  def tax\n    TaxCalculator.new\n  end\nend",
+	})
+
+	idx := newTestIndex()
+	idx.rootPath = root
+	idx.loadAutoloadRoots()
+	for _, rel := range []string{"app/services/tax_calculator.rb", "lib/legacy/shipping_rate.rb", "app/models/order.rb"} {
+		if err := idx.AddFile(filepath.Join(root, rel)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"TaxCalculator", "Calculators"},   // Autoload path
+		{"::TaxCalculator", "Calculators"}, // Absolute name
+		{"ShippingRate", "Rates"},          // File name outside autoload roots
+		{"Legacy::ShippingRate", "Rates"},  // Qualified name matches the path
+	}
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			results, c := idx.ResolveDefinitions(tt.word, filepath.Join(root, "app/models/order.rb"), 3)
+			if len(results) != 1 || results[0].FullName != tt.want {
+				t.Fatalf("expected %s, got %v", tt.want, results)
+			}
+			if c != ConfidenceFuzzy {
+				t.Errorf("expected fuzzy confidence, got %s", c)
+			}
+		})
+	}
+
+	// Lowercase names and constants without a matching file resolve to
+	// nothing. Absolute names only use autoload paths
+	for _, word := range []string{"tax_calculator", "Missing", "::ShippingRate"} {
+		if results, _ := idx.ResolveDefinitions(word, filepath.Join(root, "app/models/order.rb"), 3); len(results) != 0 {
+			t.Errorf("expected no fallback for %s, got %v", word, results)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package index
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// conventionFallbackLocked finds definitions for a constant nothing defines
+// by file naming convention: the file an autoload root maps it to
+// (app/services/tax_calculator.rb for TaxCalculator), trying the enclosing
+// namespaces first, then any indexed file whose path ends with the name's
+// (billing/invoice.rb for Billing::Invoice). Absolute names only use the
+// autoload roots. Each match is represented by its file's top symbol.
+// Caller must hold the lock.
+func (idx *Index) conventionFallbackLocked(name string, scope []string) []*Symbol {
+	absolute := strings.HasPrefix(name, "::")
+	name = strings.TrimPrefix(name, "::")
+	last := lastSegment(name)
+	if last == "" || last[0] < 'A' || last[0] > 'Z' {
+		return nil
+	}
+
+	var results []*Symbol
+	for i := len(scope); i >= 0 && len(results) == 0; i-- {
+		candidate := name
+		if i > 0 {
+			candidate = strings.Join(scope[:i], "::") + "::" + name
+		}
+		for _, path := range idx.autoload.PathsForConstant(candidate) {
+			if sym := topSymbol(idx.byFile[path], last); sym != nil {
+				results = append(results, sym)
+			}
+		}
+	}
+	if len(results) > 0 || absolute {
+		return results
+	}
+
+	suffix := string(filepath.Separator) + filepath.FromSlash(underscorePath(name)) + ".rb"
+	var paths []string
+	for path := range idx.byFile {
+		if strings.HasSuffix(path, suffix) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if sym := topSymbol(idx.byFile[path], last); sym != nil {
+			results = append(results, sym)
+		}
+	}
+	return results
+}
+
+// underscorePath converts a constant name to its relative file path
+// without extension (Billing::TaxCalculator → billing/tax_calculator)
+func underscorePath(name string) string {
+	parts := strings.Split(name, "::")
+	for i, part := range parts {
+		parts[i] = underscore(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// topSymbol picks the symbol representing a file: the class or module
+// named name if there is one, else the first class or module, else the
+// first symbol
+func topSymbol(syms []*Symbol, name string) *Symbol {
+	var first *Symbol
+	for _, sym := range syms {
+		if sym.Kind != types.KindClass && sym.Kind != types.KindModule {
+			continue
+		}
+		if sym.Name == name {
+			return sym
+		}
+		if first == nil {
+			first = sym
+		}
+	}
+	if first == nil && len(syms) > 0 {
+		first = syms[0]
+	}
+	return first
+}
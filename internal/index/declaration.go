@@ -0,0 +1,54 @@
+package index
+
+import "strings"
+
+// Declarations narrows definitions to the original declaration of each
+// name among them, leaving out the places a class or module is reopened
+// and a method redefined. Ruby has no such distinction, so it's inferred:
+//   - the definition in the file Zeitwerk expects to define the constant
+//     (a method's, the file of its class or module)
+//   - for a class, the definition naming its superclass
+//   - otherwise the first definition by path and line
+func (idx *Index) Declarations(symbols []*Symbol) []*Symbol {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var order []string
+	byName := make(map[string][]*Symbol)
+	for _, sym := range symbols {
+		if _, ok := byName[sym.FullName]; !ok {
+			order = append(order, sym.FullName)
+		}
+		byName[sym.FullName] = append(byName[sym.FullName], sym)
+	}
+
+	result := make([]*Symbol, 0, len(order))
+	for _, name := range order {
+		result = append(result, idx.declarationLocked(sortedCopy(byName[name])))
+	}
+	return result
+}
+
+// declarationLocked picks the declaration among the sorted definitions of
+// one name. Caller must hold the lock.
+func (idx *Index) declarationLocked(defs []*Symbol) *Symbol {
+	if len(defs) == 1 {
+		return defs[0]
+	}
+
+	for _, sym := range defs {
+		constant := sym.FullName
+		if !isTypeKind(sym.Kind) && sym.Kind != KindConstant {
+			constant = strings.Join(sym.Scope, "::")
+		}
+		if expected, ok := idx.autoload.ConstantForPath(sym.FilePath); ok && expected == constant {
+			return sym
+		}
+	}
+	for _, sym := range defs {
+		if sym.Superclass != "" {
+			return sym
+		}
+	}
+	return defs[0]
+}
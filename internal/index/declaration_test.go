@@ -0,0 +1,47 @@
+package index
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDeclarations(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"app/models/invoice.rb":          "class Invoice\n  def total\n  end\nend\n",
+		"app/models/concerns/exports.rb": "class Invoice\n  def total\n  end\n\n  def to_csv\n  end\nend\n",
+		"lib/ext/report.rb":              "class Report\n  def title\n  end\nend\n",
+		"lib/report.rb":                  "class Report < Base\nend\n",
+		"lib/a_patch.rb":                 "class Report\n  def title\n  end\nend\n",
+	}
+	writeTree(t, root, files)
+
+	idx := newTestIndex()
+	idx.rootPath = root
+	idx.loadAutoloadRoots()
+	for rel := range files {
+		if err := idx.AddFile(filepath.Join(root, rel)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Invoice", "app/models/invoice.rb"},       // Autoload path
+		{"Invoice#total", "app/models/invoice.rb"}, // Its class's autoload path
+		{"Invoice#to_csv", "app/models/concerns/exports.rb"},
+		{"Report", "lib/report.rb"},        // Names its superclass
+		{"Report#title", "lib/a_patch.rb"}, // First by path
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defs := idx.FindDefinitions(tt.name)
+			got := idx.Declarations(defs)
+			if len(got) != 1 || got[0].FilePath != filepath.Join(root, tt.want) {
+				t.Errorf("expected the declaration in %s, got %v of %d definitions", tt.want, got, len(defs))
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package index
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/parser"
+)
+
+// A constant path as written: Billing::Invoice, ::User, Order
+var constantPathPattern = regexp.MustCompile(`(?:::)?\b[A-Z]\w*(?:::[A-Z]\w*)*`)
+
+// maxDependencyExamples bounds the example references kept per dependency
+const maxDependencyExamples = 3
+
+// NamespaceReport lists the top-level namespaces of a project and the
+// references between them
+type NamespaceReport struct {
+	Namespaces   []NamespaceInfo       `json:"namespaces"`
+	Dependencies []NamespaceDependency `json:"dependencies"`
+}
+
+// NamespaceInfo is a top-level module or class and the number of files
+// defining something in it
+type NamespaceInfo struct {
+	Name  string `json:"name"`
+	Files int    `json:"files"`
+}
+
+// NamespaceDependency counts the references from code in one top-level
+// namespace to constants defined in another. Examples are a few of the
+// references, as path:line relative to the project root.
+type NamespaceDependency struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Count    int      `json:"count"`
+	Examples []string `json:"examples"`
+}
+
+// NamespaceDependencies reports which top-level namespaces reference
+// which. Each constant written inside a class or module is resolved like
+// a definition lookup from that scope; a reference counts toward a
+// dependency when it resolves to a class or module under another top-level
+// namespace. Strings, comments, and unresolved names are skipped.
+func (idx *Index) NamespaceDependencies() NamespaceReport {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	files := make(map[string]map[string]bool) // Namespace -> defining files
+	var paths []string
+	for path, syms := range idx.byFile {
+		paths = append(paths, path)
+		for _, sym := range syms {
+			if !isTypeKind(sym.Kind) || sym.Refines != "" {
+				continue
+			}
+			ns := topNamespace(append(append([]string{}, sym.Scope...), sym.Name))
+			if files[ns] == nil {
+				files[ns] = make(map[string]bool)
+			}
+			files[ns][path] = true
+		}
+	}
+	sort.Strings(paths)
+
+	deps := make(map[[2]string]*NamespaceDependency)
+	for _, path := range paths {
+		content, ok := idx.trigram.Content(path)
+		if !ok {
+			continue
+		}
+		for i, line := range strings.Split(content, "\n") {
+			scope := idx.lexicalScopeLocked(path, i+1)
+			if len(scope) == 0 {
+				continue
+			}
+			from := scope[0]
+
+			masked := parser.MaskStrings(line)
+			for _, loc := range constantPathPattern.FindAllStringIndex(masked, -1) {
+				to := topNamespace(strings.Split(idx.resolveConstantPathLocked(masked[loc[0]:loc[1]], scope), "::"))
+				if to == "" || to == from {
+					continue
+				}
+				key := [2]string{from, to}
+				dep := deps[key]
+				if dep == nil {
+					dep = &NamespaceDependency{From: from, To: to, Examples: []string{}}
+					deps[key] = dep
+				}
+				dep.Count++
+				if len(dep.Examples) < maxDependencyExamples {
+					dep.Examples = append(dep.Examples, fmt.Sprintf("%s:%d", idx.relativePath(path), i+1))
+				}
+			}
+		}
+	}
+
+	report := NamespaceReport{Namespaces: []NamespaceInfo{}, Dependencies: []NamespaceDependency{}}
+	for ns, defining := range files {
+		report.Namespaces = append(report.Namespaces, NamespaceInfo{Name: ns, Files: len(defining)})
+	}
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		return report.Namespaces[i].Name < report.Namespaces[j].Name
+	})
+	for _, dep := range deps {
+		report.Dependencies = append(report.Dependencies, *dep)
+	}
+	sort.Slice(report.Dependencies, func(i, j int) bool {
+		a, b := report.Dependencies[i], report.Dependencies[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return report
+}
+
+// resolveConstantPathLocked resolves a constant path written in scope to
+// the full name of the class or module it is or belongs to
+// (Billing::Invoice::STATES resolves to Billing::Invoice), or "". Caller
+// must hold at least a read lock.
+func (idx *Index) resolveConstantPathLocked(name string, scope []string) string {
+	for name != "" {
+		if resolved := idx.resolveTypeLocked(name, scope); resolved != "" {
+			return resolved
+		}
+		i := strings.LastIndex(name, "::")
+		if i <= 0 {
+			return ""
+		}
+		name = name[:i]
+	}
+	return ""
+}
+
+// relativePath returns path relative to the project root when it's inside
+func (idx *Index) relativePath(path string) string {
+	if rel, err := filepath.Rel(idx.rootPath, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// topNamespace returns the outermost segment of a namespace path
+func topNamespace(parts []string) string {
+	if len(parts) == 0 {
+		return ""
+	}
+	return parts[0]
+}
+
+// DOT renders the report in Graphviz DOT: a node per namespace and an edge
+// per dependency, labeled with its reference count
+func (r NamespaceReport) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph namespaces {\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, ns := range r.Namespaces {
+		fmt.Fprintf(&b, "  %s;\n", strconv.Quote(ns.Name))
+	}
+	for _, dep := range r.Dependencies {
+		fmt.Fprintf(&b, "  %s -> %s [label=\"%d\"];\n", strconv.Quote(dep.From), strconv.Quote(dep.To), dep.Count)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
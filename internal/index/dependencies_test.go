@@ -0,0 +1,63 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNamespaceDependencies(t *testing.T) {
+	idx := newTestIndex()
+	idx.UpdateContent("/test/app/models/billing/invoice.rb", []byte(`module Billing
+  class Invoice
+    STATES = %w[open paid]
+  end
+end`), 0)
+	idx.UpdateContent("/test/app/models/shop/order.rb", []byte(`module Shop
+  class Order < ApplicationRecord
+    # Billing::Invoice in a comment doesn't count
+    def invoice
+      Billing::Invoice.new(note: "Billing::Invoice")
+    end
+
+    def states
+      Billing::Invoice::STATES + Shop::Cart::LIMITS + Unknown::Thing
+    end
+  end
+
+  class Cart
+    LIMITS = []
+  end
+end`), 0)
+	idx.UpdateContent("/test/app/models/shipping.rb", []byte(`class Shipping
+  def order
+    ::Shop::Order.last
+  end
+end`), 0)
+
+	report := idx.NamespaceDependencies()
+
+	var namespaces []string
+	for _, ns := range report.Namespaces {
+		namespaces = append(namespaces, fmt.Sprintf("%s:%d", ns.Name, ns.Files))
+	}
+	if got, want := strings.Join(namespaces, " "), "Billing:1 Shipping:1 Shop:1"; got != want {
+		t.Errorf("expected namespaces %q, got %q", want, got)
+	}
+
+	var deps []string
+	for _, dep := range report.Dependencies {
+		deps = append(deps, fmt.Sprintf("%s -> %s (%d) %v", dep.From, dep.To, dep.Count, dep.Examples))
+	}
+	want := []string{
+		"Shipping -> Shop (1) [app/models/shipping.rb:3]",
+		"Shop -> Billing (2) [app/models/shop/order.rb:5 app/models/shop/order.rb:9]",
+	}
+	if strings.Join(deps, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected dependencies\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(deps, "\n"))
+	}
+
+	if dot := report.DOT(); !strings.Contains(dot, `  "Shop" -> "Billing" [label="2"];`+"\n") {
+		t.Errorf("unexpected DOT output\n%s", dot)
+	}
+}
@@ -0,0 +1,73 @@
+package index
+
+import (
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// FindEndpoints returns the Sinatra and Grape endpoints a request for path
+// would reach, for a request spec's get "/orders/12": the endpoints whose
+// path matches all of it first, then those matching its end (under a
+// prefix or version the app is mounted at). A :param segment in an
+// endpoint's path matches any segment; an interpolated segment of the
+// request matches only a param. An empty verb matches any endpoint.
+func (idx *Index) FindEndpoints(verb, path string) []*Symbol {
+	requested := endpointSegments(path)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var exact, suffix []*Symbol
+	for _, syms := range idx.byFile {
+		for _, sym := range syms {
+			if sym.Kind != types.KindRoute || sym.Options["path"] == "" ||
+				(verb != "" && !strings.EqualFold(sym.Macro, verb)) {
+				continue
+			}
+			segments := endpointSegments(sym.Options["path"])
+			if len(segments) > len(requested) || !segmentsMatch(segments, requested[len(requested)-len(segments):]) {
+				continue
+			}
+			if len(segments) == len(requested) {
+				exact = append(exact, sym)
+			} else if len(segments) > 0 {
+				suffix = append(suffix, sym)
+			}
+		}
+	}
+	return append(sortSymbols(exact), sortSymbols(suffix)...)
+}
+
+// endpointSegments splits a path into its segments, leaving out the query
+// string and a format extension (/orders/12.json?page=2 → orders, 12)
+func endpointSegments(path string) []string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	segments := strings.Split(path, "/")
+	last := segments[len(segments)-1]
+	if i := strings.LastIndexByte(last, '.'); i > 0 && !strings.Contains(last, "#{") {
+		segments[len(segments)-1] = last[:i]
+	}
+	return segments
+}
+
+// segmentsMatch reports whether an endpoint's path segments match those of
+// a request
+func segmentsMatch(endpoint, request []string) bool {
+	for i, segment := range endpoint {
+		switch {
+		case strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*"):
+		case strings.Contains(request[i], "#{"):
+			return false
+		case segment != request[i]:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package index
+
+import "testing"
+
+func TestFindEndpoints(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/app/app/api/orders.rb", `class Orders < Grape::API
+  resource :orders do
+    get do
+    end
+
+    route_param :id do
+      get do
+      end
+
+      post "refund" do
+      end
+    end
+  end
+end`)
+	idx.addContent("/app/storefront.rb", `class Storefront < Sinatra::Base
+  get "/orders/:id" do
+  end
+
+  get "/" do
+  end
+end`)
+
+	tests := []struct {
+		verb, path string
+		want       string
+	}{
+		{"get", "/orders", "GET /orders"},
+		{"get", "/orders/12.json?expand=lines", "GET /orders/:id,GET /orders/:id"},
+		{"post", "/api/v1/orders/12/refund", "POST /orders/:id/refund"}, // Mounted under a prefix
+		{"get", `/orders/#{order.id}`, "GET /orders/:id,GET /orders/:id"},
+		{"get", `/#{section}`, ""},
+		{"get", "/", "GET /"},
+		{"delete", "/orders/12", ""},
+		{"", "/orders/12/refund", "POST /orders/:id/refund"},
+	}
+	for _, tt := range tests {
+		if got := fullNames(idx.FindEndpoints(tt.verb, tt.path)); got != tt.want {
+			t.Errorf("FindEndpoints(%q, %q) = %s, want %s", tt.verb, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package index
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// AssociationGraph is the model association graph: a node per model that
+// declares or is the target of an association, and an edge per association
+type AssociationGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a model in the association graph. Path and Line locate its
+// class definition, and are empty for targets nothing indexed defines.
+type GraphNode struct {
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+// GraphEdge is an association from the model declaring it to its target
+type GraphEdge struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Macro   string `json:"macro"`
+	Name    string `json:"name"`
+	Through string `json:"through,omitempty"`
+}
+
+// Label describes the edge as it's declared: "has_many :items through :orders"
+func (e GraphEdge) Label() string {
+	label := e.Macro + " :" + e.Name
+	if e.Through != "" {
+		label += " through :" + e.Through
+	}
+	return label
+}
+
+// AssociationGraph walks every indexed association. Targets resolve to the
+// full name of their indexed class where possible, following through:
+// chains. Polymorphic belongs_to associations have no single target and
+// are left out.
+func (idx *Index) AssociationGraph() AssociationGraph {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var rels []*Symbol
+	for _, syms := range idx.byFile {
+		for _, sym := range syms {
+			if sym.Kind == types.KindRelation && sym.Options["polymorphic"] == "" {
+				rels = append(rels, sym)
+			}
+		}
+	}
+
+	graph := AssociationGraph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	nodes := make(map[string]bool)
+	addNode := func(name string) {
+		if nodes[name] {
+			return
+		}
+		nodes[name] = true
+		node := GraphNode{Name: name}
+		if sym := idx.typeSymbolLocked(name); sym != nil {
+			node.Path, node.Line = sym.FilePath, sym.Line
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+
+	for _, rel := range sortSymbols(rels) {
+		owner := strings.Join(rel.Scope, "::")
+		target := idx.relationTargetLocked(rel)
+		if resolved := idx.resolveTypeLocked(target, rel.Scope); resolved != "" {
+			target = resolved
+		}
+		target = strings.TrimPrefix(target, "::")
+
+		addNode(owner)
+		addNode(target)
+		graph.Edges = append(graph.Edges, GraphEdge{
+			From:    owner,
+			To:      target,
+			Macro:   rel.RelationType,
+			Name:    rel.Name,
+			Through: rel.Options["through"],
+		})
+	}
+
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].Name < graph.Nodes[j].Name
+	})
+	return graph
+}
+
+// DOT renders the graph in Graphviz DOT, with edges labeled by their
+// association
+func (g AssociationGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph associations {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "  %s;\n", strconv.Quote(node.Name))
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n",
+			strconv.Quote(edge.From), strconv.Quote(edge.To), strconv.Quote(edge.Label()))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
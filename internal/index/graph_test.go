@@ -0,0 +1,67 @@
+package index
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAssociationGraph(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/app/models/shop/order.rb", `module Shop
+  class Order
+    belongs_to :customer
+    has_many :line_items
+    has_many :products, through: :line_items
+  end
+end`)
+	idx.addContent("/test/app/models/shop/line_item.rb", `module Shop
+  class LineItem
+    belongs_to :product
+  end
+end`)
+	idx.addContent("/test/app/models/comment.rb", `class Comment
+  belongs_to :commentable, polymorphic: true
+end`)
+	idx.addContent("/test/app/models/product.rb", `class Product
+end`)
+
+	graph := idx.AssociationGraph()
+
+	var nodes []string
+	for _, node := range graph.Nodes {
+		nodes = append(nodes, node.Name)
+		if node.Name == "Product" && (node.Path != "/test/app/models/product.rb" || node.Line != 1) {
+			t.Errorf("expected Product located at its definition, got %+v", node)
+		}
+		if node.Name == "Customer" && node.Path != "" {
+			t.Errorf("expected the unindexed Customer to have no location, got %+v", node)
+		}
+	}
+	if got, want := strings.Join(nodes, " "), "Customer Product Shop::LineItem Shop::Order"; got != want {
+		t.Errorf("expected nodes %q, got %q", want, got)
+	}
+
+	var edges []string
+	for _, edge := range graph.Edges {
+		edges = append(edges, edge.From+" -> "+edge.To+" ["+edge.Label()+"]")
+	}
+	want := []string{
+		"Shop::LineItem -> Product [belongs_to :product]",
+		"Shop::Order -> Customer [belongs_to :customer]",
+		"Shop::Order -> Shop::LineItem [has_many :line_items]",
+		"Shop::Order -> Product [has_many :products through :line_items]",
+	}
+	if strings.Join(edges, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected edges\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(edges, "\n"))
+	}
+
+	dot := graph.DOT()
+	for _, line := range []string{
+		`  "Shop::Order";`,
+		`  "Shop::Order" -> "Product" [label="has_many :products through :line_items"];`,
+	} {
+		if !strings.Contains(dot, line+"\n") {
+			t.Errorf("expected DOT to contain %q, got\n%s", line, dot)
+		}
+	}
+}
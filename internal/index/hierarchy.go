@@ -0,0 +1,227 @@
+package index
+
+import (
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// supertypeNames returns the supertypes a class or module declares as
+// written, each with the scope Ruby resolves it in: the superclass from
+// the enclosing namespace, mixins from inside the body
+func supertypeNames(sym *Symbol) (names []string, scopes [][]string) {
+	if sym.Superclass != "" {
+		names = append(names, sym.Superclass)
+		scopes = append(scopes, sym.Scope)
+	}
+	body := append(append([]string{}, sym.Scope...), sym.Name)
+	for _, name := range sym.Includes {
+		names = append(names, name)
+		scopes = append(scopes, body)
+	}
+	return names, scopes
+}
+
+// addSubtypesLocked records sym under the last segment of each supertype it
+// declares. Caller must hold the write lock.
+func (idx *Index) addSubtypesLocked(sym *Symbol) {
+	names, _ := supertypeNames(sym)
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		key := lastSegment(name)
+		if !seen[key] {
+			seen[key] = true
+			idx.subtypes[key] = append(idx.subtypes[key], sym)
+		}
+	}
+}
+
+// removeSubtypesLocked drops the subtype records of sym's file. Caller
+// must hold the write lock.
+func (idx *Index) removeSubtypesLocked(sym *Symbol, path string) {
+	names, _ := supertypeNames(sym)
+	for _, name := range names {
+		key := lastSegment(name)
+		kept := idx.subtypes[key][:0]
+		for _, s := range idx.subtypes[key] {
+			if s.FilePath != path {
+				kept = append(kept, s)
+			}
+		}
+		if len(kept) == 0 {
+			delete(idx.subtypes, key)
+		} else {
+			idx.subtypes[key] = kept
+		}
+	}
+}
+
+// Supertypes returns the classes and modules fullName inherits from or
+// mixes in, across every definition of it: superclasses first, then
+// modules in the order they're included. Names nothing indexed defines are
+// left out.
+func (idx *Index) Supertypes(fullName string) []*Symbol {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var result []*Symbol
+	seen := make(map[string]bool)
+	for _, sym := range sortedCopy(idx.symbols[fullName]) {
+		if !isTypeKind(sym.Kind) {
+			continue
+		}
+		names, scopes := supertypeNames(sym)
+		for i, name := range names {
+			resolved := idx.resolveTypeLocked(name, scopes[i])
+			if resolved == "" || resolved == fullName || seen[resolved] {
+				continue
+			}
+			seen[resolved] = true
+			result = append(result, idx.typeSymbolLocked(resolved))
+		}
+	}
+	return result
+}
+
+// Subtypes returns the class and module definitions that name fullName as
+// their superclass or mix it in, one per subtype
+func (idx *Index) Subtypes(fullName string) []*Symbol {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return idx.subtypesLocked(fullName)
+}
+
+// Descendants returns the classes and modules that inherit from or mix in
+// fullName, directly or through others, nearest first: one definition each
+func (idx *Index) Descendants(fullName string) []*Symbol {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return idx.descendantsLocked(fullName)
+}
+
+// descendantsLocked implements Descendants. Caller must hold the lock.
+func (idx *Index) descendantsLocked(fullName string) []*Symbol {
+	var result []*Symbol
+	seen := map[string]bool{fullName: true}
+	for queue := []string{fullName}; len(queue) > 0; queue = queue[1:] {
+		for _, sub := range idx.subtypesLocked(queue[0]) {
+			if !seen[sub.FullName] {
+				seen[sub.FullName] = true
+				result = append(result, sub)
+				queue = append(queue, sub.FullName)
+			}
+		}
+	}
+	return result
+}
+
+// Overrides returns the definitions of a method in the descendants of the
+// class or module defining it (see Descendants), nearest first. Instance
+// methods are matched with instance methods and attributes, class methods
+// with class methods of subclasses (a module's own class methods aren't
+// inherited by the classes including it).
+func (idx *Index) Overrides(method *Symbol) []*Symbol {
+	separator := "#"
+	switch method.Kind {
+	case types.KindSingletonMethod:
+		separator = "."
+	case types.KindMethod, types.KindAttrReader, types.KindAttrWriter, types.KindAttrAccessor:
+	default:
+		return nil
+	}
+	if len(method.Scope) == 0 {
+		return nil // Top-level methods belong to Object
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	owner := strings.Join(method.Scope, "::")
+	if separator == "." {
+		if typ := idx.typeSymbolLocked(owner); typ != nil && typ.Kind == types.KindModule {
+			return nil
+		}
+	}
+
+	var result []*Symbol
+	for _, sub := range idx.descendantsLocked(owner) {
+		result = append(result, sortedCopy(idx.symbols[sub.FullName+separator+method.Name])...)
+	}
+	return result
+}
+
+// subtypesLocked implements Subtypes. Caller must hold the lock.
+func (idx *Index) subtypesLocked(fullName string) []*Symbol {
+	var result []*Symbol
+	seen := make(map[string]bool)
+	for _, sym := range sortedCopy(idx.subtypes[lastSegment(fullName)]) {
+		if seen[sym.FullName] || sym.FullName == fullName {
+			continue
+		}
+		names, scopes := supertypeNames(sym)
+		for i, name := range names {
+			if idx.resolveTypeLocked(name, scopes[i]) == fullName {
+				seen[sym.FullName] = true
+				result = append(result, sym)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// resolveTypeLocked resolves a class or module name as written in scope
+// to the full name of an indexed class or module, or "". Like Ruby, it
+// looks in the innermost enclosing namespace first; failing that, a unique
+// class or module with the same short name is taken. Caller must hold the
+// lock.
+func (idx *Index) resolveTypeLocked(name string, scope []string) string {
+	if strings.HasPrefix(name, "::") {
+		name = strings.TrimPrefix(name, "::")
+		if idx.typeSymbolLocked(name) != nil {
+			return name
+		}
+		return ""
+	}
+
+	for i := len(scope); i >= 0; i-- {
+		candidate := name
+		if i > 0 {
+			candidate = strings.Join(scope[:i], "::") + "::" + name
+		}
+		if idx.typeSymbolLocked(candidate) != nil {
+			return candidate
+		}
+	}
+
+	var match string
+	for _, fullName := range idx.fullNamesLocked(lastSegment(name)) {
+		if !strings.HasSuffix("::"+fullName, "::"+name) || idx.typeSymbolLocked(fullName) == nil {
+			continue
+		}
+		if match != "" {
+			return "" // Ambiguous
+		}
+		match = fullName
+	}
+	return match
+}
+
+// typeSymbolLocked returns the first class or module definition of
+// fullName, or nil. Caller must hold the lock.
+func (idx *Index) typeSymbolLocked(fullName string) *Symbol {
+	var best *Symbol
+	for _, sym := range idx.symbols[fullName] {
+		if isTypeKind(sym.Kind) && (best == nil || symbolLess(sym, best)) {
+			best = sym
+		}
+	}
+	return best
+}
+
+// isTypeKind reports whether kind is a class or module
+func isTypeKind(kind SymbolKind) bool {
+	return kind == types.KindClass || kind == types.KindModule
+}
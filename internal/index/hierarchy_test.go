@@ -0,0 +1,110 @@
+package index
+
+import (
+	"strings"
+	"testing"
+)
+
+func fullNames(syms []*Symbol) string {
+	names := make([]string, len(syms))
+	for i, sym := range syms {
+		names[i] = sym.FullName
+	}
+	return strings.Join(names, ",")
+}
+
+func TestTypeHierarchy(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/record.rb", "class Record\nend\n")
+	idx.addContent("/test/auditable.rb", "module Auditable\nend\n")
+	idx.addContent("/test/billing/auditable.rb", "module Billing\n  module Auditable\n  end\nend\n")
+	idx.addContent("/test/billing/invoice.rb", `module Billing
+  class Invoice < Record
+    include Auditable
+    include Comparable
+  end
+end`)
+	idx.addContent("/test/billing/invoice/export.rb", `module Billing
+  class Invoice
+    prepend ::Auditable
+  end
+end`)
+	idx.addContent("/test/billing/credit_note.rb", `module Billing
+  class CreditNote < Invoice
+  end
+end`)
+
+	// Mixins resolve from inside the body, so Auditable is Billing::Auditable;
+	// Comparable isn't indexed and is left out
+	if got := fullNames(idx.Supertypes("Billing::Invoice")); got != "Record,Billing::Auditable,Auditable" {
+		t.Errorf("unexpected supertypes of Billing::Invoice: %s", got)
+	}
+	if got := fullNames(idx.Subtypes("Billing::Invoice")); got != "Billing::CreditNote" {
+		t.Errorf("unexpected subtypes of Billing::Invoice: %s", got)
+	}
+	if got := fullNames(idx.Subtypes("Auditable")); got != "Billing::Invoice" {
+		t.Errorf("unexpected subtypes of Auditable: %s", got)
+	}
+	if got := fullNames(idx.Subtypes("Record")); got != "Billing::Invoice" {
+		t.Errorf("unexpected subtypes of Record: %s", got)
+	}
+
+	idx.mu.Lock()
+	idx.removeFileLocked("/test/billing/invoice/export.rb")
+	idx.mu.Unlock()
+	if got := fullNames(idx.Subtypes("Auditable")); got != "" {
+		t.Errorf("expected subtypes to follow file removal, got %s", got)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/payable.rb", `module Payable
+  def total
+  end
+
+  def self.build
+  end
+end`)
+	idx.addContent("/test/invoice.rb", `class Invoice
+  include Payable
+
+  def total
+  end
+
+  def self.build
+  end
+end`)
+	idx.addContent("/test/credit_note.rb", `class CreditNote < Invoice
+  def total
+  end
+
+  def self.build
+  end
+end`)
+	idx.addContent("/test/receipt.rb", `class Receipt
+  def total
+  end
+end`)
+
+	payable := idx.FindDefinitions("Payable#total")[0]
+	if got := fullNames(idx.Overrides(payable)); got != "Invoice#total,CreditNote#total" {
+		t.Errorf("unexpected overrides of Payable#total: %s", got)
+	}
+	if got := fullNames(idx.Descendants("Payable")); got != "Invoice,CreditNote" {
+		t.Errorf("unexpected descendants of Payable: %s", got)
+	}
+
+	// Class methods are overridden by subclasses' class methods; a
+	// module's aren't inherited by its includers
+	build := idx.FindDefinitions("Invoice.build")[0]
+	if got := fullNames(idx.Overrides(build)); got != "CreditNote.build" {
+		t.Errorf("unexpected overrides of Invoice.build: %s", got)
+	}
+	if got := idx.Overrides(idx.FindDefinitions("Payable.build")[0]); len(got) != 0 {
+		t.Errorf("expected no overrides of Payable.build, got %s", fullNames(got))
+	}
+	if got := idx.Overrides(idx.FindDefinitions("Receipt#total")[0]); len(got) != 0 {
+		t.Errorf("expected no overrides of Receipt#total, got %s", fullNames(got))
+	}
+}
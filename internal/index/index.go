@@ -2,12 +2,16 @@ package index
 
 import (
 	"context"
+	"fmt"
 	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/jarredhawkins/goruby-lsp/internal/parser"
 	"github.com/jarredhawkins/goruby-lsp/internal/types"
@@ -20,37 +24,160 @@ type Index struct {
 	// Primary index: FullName -> definitions
 	symbols map[string][]*Symbol
 
-	// Short name index: Name -> FullNames (for fuzzy lookup)
-	shortNames map[string][]string
+	// Short name index: Name -> FullName -> number of symbols with that
+	// FullName (for fuzzy lookup)
+	shortNames map[string]map[string]int
 
 	// File index: FilePath -> symbols in file
 	byFile map[string][]*Symbol
 
+	// Redirect index: Name -> symbols with a TargetName (relations, etc.)
+	redirects map[string][]*Symbol
+
+	// Synthetic prefix symbols from method_missing (e.g., "find_by_*")
+	ghostPrefixes []*Symbol
+
+	// Namespace index: namespace -> classes and modules defined inside it,
+	// so namespaces only ever opened through compact paths (Admin::Users in
+	// `class Admin::Users::Importer`) still resolve
+	namespaces map[string][]*Symbol
+
+	// Subtype index: last segment of a superclass or mixin name -> classes
+	// and modules declaring it, resolved against their scope at query time
+	subtypes map[string][]*Symbol
+
 	// Trigram index for text search
 	trigram *TrigramIndex
 
+	// Zeitwerk autoload roots for path <-> constant mapping
+	autoload *AutoloadRoots
+
+	// Matchers that fired in each file, for targeted reindexing
+	matchedBy map[string][]string
+
+	// Document version each file was last parsed from, for files indexed
+	// from an open editor buffer. Files indexed from disk have no entry.
+	versions map[string]int
+
+	// Hash of the content each file was last indexed from, for spotting
+	// files that changed on disk without a watcher event
+	hashes map[string]uint64
+
+	// Last file checked by a verification pass; the next starts after it
+	verifyCursor string
+
+	// Timing from the most recent Build, nil until one completes
+	buildStats *BuildStats
+
+	// Consecutive failures to index each file, and who's told of repeated
+	// ones
+	failMu    sync.Mutex
+	failures  map[string]int
+	onFailure FailureHandler
+
 	rootPath string
-	scanner  *parser.Scanner
+	// Directories indexed besides rootPath, such as a vendored gem's ext/
+	extraRoots []string
+	// Time Build works between pauses, 0 to never pause (see SetBuildSlice)
+	buildSlice time.Duration
+	// Most files indexed at once, 0 to size by CPU count (see SetMaxWorkers)
+	maxWorkers atomic.Int32
+	registry   *parser.Registry
+	scanner    *parser.Scanner
 }
 
 // New creates a new index for the given root path
 func New(rootPath string, registry *parser.Registry) *Index {
 	return &Index{
 		symbols:    make(map[string][]*Symbol),
-		shortNames: make(map[string][]string),
+		shortNames: make(map[string]map[string]int),
+		redirects:  make(map[string][]*Symbol),
+		namespaces: make(map[string][]*Symbol),
+		subtypes:   make(map[string][]*Symbol),
 		byFile:     make(map[string][]*Symbol),
 		trigram:    NewTrigramIndex(),
+		autoload:   &AutoloadRoots{},
+		matchedBy:  make(map[string][]string),
+		versions:   make(map[string]int),
+		hashes:     make(map[string]uint64),
+		failures:   make(map[string]int),
 		rootPath:   rootPath,
+		registry:   registry,
 		scanner:    parser.NewScanner(registry),
 	}
 }
 
+// BuildProgress is told, one call at a time, how many of a build's files
+// have been indexed
+type BuildProgress func(done, total int)
+
 // Build performs the initial indexing of all Ruby files
 func (idx *Index) Build(ctx context.Context) error {
+	return idx.BuildWithProgress(ctx, nil)
+}
+
+// BuildWithProgress is Build, calling progress (when not nil) before the
+// first file and after each one is indexed
+func (idx *Index) BuildWithProgress(ctx context.Context, progress BuildProgress) error {
 	log.Printf("building index for %s", idx.rootPath)
+	start := time.Now()
+
+	idx.loadAutoloadRoots()
+
+	files, err := rubyFilesUnder(ctx, idx.rootPath)
+	if err != nil {
+		return err
+	}
+	for _, root := range idx.extraRoots {
+		extra, err := rubyFilesUnder(ctx, root)
+		if err != nil {
+			return err
+		}
+		files = append(files, extra...)
+	}
+	files = dedupe(files)
 
+	log.Printf("found %d Ruby files", len(files))
+
+	stats := parser.NewParseStats()
+	idx.scanner.SetStats(stats)
+	slicer := newTimeSlicer(idx.buildSlice, slicePause)
+	add := func(path string) error {
+		if slicer.wait(ctx) != nil {
+			return nil // Canceled; the build reports it once the workers stop
+		}
+		return idx.AddFile(path)
+	}
+	if progress == nil {
+		idx.forEachFile(files, add)
+	} else {
+		var mu sync.Mutex
+		done := 0
+		progress(done, len(files))
+		idx.forEachFile(files, func(path string) error {
+			err := add(path)
+			mu.Lock()
+			done++
+			progress(done, len(files))
+			mu.Unlock()
+			return err
+		})
+	}
+	idx.scanner.SetStats(nil)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	idx.recordBuild(start, len(files), stats)
+	log.Printf("indexed %d symbols in %s", idx.SymbolCount(), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// rubyFilesUnder returns the Ruby files under root, skipping hidden,
+// vendored, and node_modules directories
+func rubyFilesUnder(ctx context.Context, root string) ([]string, error) {
 	var files []string
-	err := filepath.WalkDir(idx.rootPath, func(path string, d fs.DirEntry, err error) error {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil // Skip errors
 		}
@@ -62,10 +189,10 @@ func (idx *Index) Build(ctx context.Context) error {
 		default:
 		}
 
-		// Skip hidden directories and vendor
+		// Skip hidden directories and vendor, unless asked to index one
 		if d.IsDir() {
 			name := d.Name()
-			if strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules" {
+			if path != root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules") {
 				return filepath.SkipDir
 			}
 			return nil
@@ -77,15 +204,45 @@ func (idx *Index) Build(ctx context.Context) error {
 		}
 		return nil
 	})
-	if err != nil {
-		return err
+	return files, err
+}
+
+// SetExtraRoots sets directories to index besides the project root, such
+// as a vendored gem or a sibling library. Relative paths are taken from
+// the project root. Ruby files under them are indexed by the next Build;
+// only the project root is watched for changes.
+func (idx *Index) SetExtraRoots(roots []string) {
+	idx.extraRoots = nil
+	for _, root := range roots {
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(idx.rootPath, root)
+		}
+		idx.extraRoots = append(idx.extraRoots, filepath.Clean(root))
 	}
+}
 
-	log.Printf("found %d Ruby files", len(files))
+// dedupe returns paths without repeats, keeping the first of each
+func dedupe(paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	kept := paths[:0]
+	for _, path := range paths {
+		if !seen[path] {
+			seen[path] = true
+			kept = append(kept, path)
+		}
+	}
+	return kept
+}
 
-	// Index files concurrently
+// SetScanLimits sets the parser's line-length and per-file time guards
+func (idx *Index) SetScanLimits(limits parser.Limits) {
+	idx.scanner.SetLimits(limits)
+}
+
+// forEachFile runs fn over files concurrently, logging failures
+func (idx *Index) forEachFile(files []string, fn func(path string) error) {
 	var wg sync.WaitGroup
-	sem := make(chan struct{}, 8) // Limit concurrency
+	sem := make(chan struct{}, idx.workerCount(files)) // Limit concurrency
 
 	for _, file := range files {
 		wg.Add(1)
@@ -94,15 +251,62 @@ func (idx *Index) Build(ctx context.Context) error {
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			if err := idx.AddFile(path); err != nil {
+			err := indexSafely(fn, path)
+			if err != nil {
 				log.Printf("failed to index %s: %v", path, err)
 			}
+			idx.recordResult(path, err)
 		}(file)
 	}
 
 	wg.Wait()
-	log.Printf("indexed %d symbols", idx.SymbolCount())
-	return nil
+}
+
+// indexSafely runs fn for path, turning a panic while parsing into an
+// error so one malformed file can't take the server down
+func indexSafely(fn func(path string) error, path string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic while parsing: %v", r)
+		}
+	}()
+	return fn(path)
+}
+
+// failureLimit is how many times in a row a file fails to index before
+// it's reported, so a file caught mid-write isn't
+const failureLimit = 3
+
+// FailureHandler is told of a file that repeatedly failed to index
+type FailureHandler func(path string, err error)
+
+// OnRepeatedFailure sets the handler told when a file fails to index
+// failureLimit times in a row. It's told once, until the file indexes again.
+func (idx *Index) OnRepeatedFailure(handler FailureHandler) {
+	idx.failMu.Lock()
+	defer idx.failMu.Unlock()
+	idx.onFailure = handler
+}
+
+// recordResult counts a file's consecutive failures to index, telling the
+// failure handler when they reach failureLimit
+func (idx *Index) recordResult(path string, err error) {
+	idx.failMu.Lock()
+	if err == nil {
+		delete(idx.failures, path)
+		idx.failMu.Unlock()
+		return
+	}
+	idx.failures[path]++
+	handler := idx.onFailure
+	if idx.failures[path] != failureLimit {
+		handler = nil
+	}
+	idx.failMu.Unlock()
+
+	if handler != nil {
+		handler(path, err)
+	}
 }
 
 // AddFile parses and indexes a single file
@@ -112,29 +316,192 @@ func (idx *Index) AddFile(path string) error {
 		return err
 	}
 
-	symbols := idx.scanner.Parse(path, content)
+	idx.indexContent(path, content, 0)
+	return nil
+}
+
+// UpdateContent reindexes a file from an open document's content, recording
+// the document version it was parsed from. Non-Ruby files are ignored.
+// The content is parsed before taking the lock, and the old symbols are
+// swapped for the new ones in one critical section, so queries never see
+// the file missing.
+func (idx *Index) UpdateContent(path string, content []byte, version int) {
+	if !isRubyFile(path) {
+		return
+	}
+	symbols, matched := idx.scanner.ParseMatched(path, content)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeFileLocked(path)
+	idx.storeFileLocked(path, content, symbols, matched, version)
+}
+
+// FileVersion returns the document version a file was last indexed from,
+// or 0 when it was indexed from disk (or not at all)
+func (idx *Index) FileVersion(path string) int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.versions[path]
+}
+
+// indexContent parses and indexes content for path. A version of 0 means the
+// content came from disk.
+func (idx *Index) indexContent(path string, content []byte, version int) {
+	symbols, matched := idx.scanner.ParseMatched(path, content)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.storeFileLocked(path, content, symbols, matched, version)
+}
+
+// storeFileLocked records a parsed file in every index. Caller must hold
+// the write lock.
+func (idx *Index) storeFileLocked(path string, content []byte, symbols []*Symbol, matched []string, version int) {
+	idx.indexSymbolsLocked(path, symbols)
+	idx.matchedBy[path] = matched
+	idx.hashes[path] = contentHash(content)
+	if version != 0 {
+		idx.versions[path] = version
+	} else {
+		delete(idx.versions, path)
+	}
+
+	// Add to trigram index
+	idx.trigram.AddFile(path, content)
+}
+
+// parsedFile is a file read and parsed ahead of taking the write lock
+type parsedFile struct {
+	content []byte
+	symbols []*Symbol
+	matched []string
+}
+
+// ApplyBatch applies a batch of file changes from the watcher. Changed
+// files are read and parsed outside the lock, then every result is swapped
+// in within a single critical section, so queries never observe a
+// half-applied batch. Changed files that can no longer be read are
+// removed; ones whose content is already indexed are left alone, so a
+// change reported twice (by the watcher and the client) is only applied
+// once, and changed paths that aren't Ruby files are ignored. Files indexed
+// from an open document are left alone too: the editor's buffer is
+// authoritative until it's closed. A removed directory removes every file
+// indexed under it, and a removed file paired with a new file of the same
+// content is moved rather than reindexed (see MoveFile).
+func (idx *Index) ApplyBatch(changed, removed []string) {
+	var mu sync.Mutex
+	parsed := make(map[string]*parsedFile, len(changed))
+	current := make(map[string]bool)
+	reloadAutoload := false
+	ruby := make([]string, 0, len(changed))
+	for _, path := range changed {
+		if isRubyFile(path) {
+			ruby = append(ruby, path)
+			reloadAutoload = reloadAutoload || isAutoloadConfig(path)
+		}
+	}
+	changed = ruby
+
+	idx.forEachFile(changed, func(path string) error {
+		if idx.FileVersion(path) != 0 {
+			mu.Lock()
+			current[path] = true
+			mu.Unlock()
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if idx.isCurrent(path, content) {
+			mu.Lock()
+			current[path] = true
+			mu.Unlock()
+			return nil
+		}
+		symbols, matched := idx.scanner.ParseMatched(path, content)
+
+		mu.Lock()
+		parsed[path] = &parsedFile{content: content, symbols: symbols, matched: matched}
+		mu.Unlock()
+		return nil
+	})
+
+	if reloadAutoload {
+		idx.loadAutoloadRoots()
+	}
 
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
 
+	removed = idx.expandRemovedLocked(removed)
+	moved := idx.pairMovesLocked(changed, removed, parsed)
+	for _, path := range removed {
+		if moved[path] == "" {
+			idx.removeFileLocked(path)
+		}
+	}
+	for _, path := range changed {
+		if moved[path] != "" || current[path] {
+			continue
+		}
+		idx.removeFileLocked(path)
+		if f := parsed[path]; f != nil {
+			idx.storeFileLocked(path, f.content, f.symbols, f.matched, 0)
+		}
+	}
+}
+
+// isCurrent reports whether content from disk is what path was last
+// indexed from
+func (idx *Index) isCurrent(path string, content []byte) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	hash, ok := idx.hashes[path]
+	return ok && hash == contentHash(content)
+}
+
+// indexSymbolsLocked stores a file's parsed symbols in the lookup maps.
+// Caller must hold the write lock.
+func (idx *Index) indexSymbolsLocked(path string, symbols []*Symbol) {
 	// Store in file index
 	idx.byFile[path] = symbols
 
 	// Store in symbol indexes
 	for _, sym := range symbols {
+		if isFileLocal(sym) {
+			continue
+		}
+
+		// A prefix like find_by_* isn't a name to look up or search for;
+		// resolution falls back to it (see ghostPrefixesLocked)
+		if sym.IsGhostPrefix() {
+			idx.ghostPrefixes = append(idx.ghostPrefixes, sym)
+			continue
+		}
+
 		// Primary index by full name
 		idx.symbols[sym.FullName] = append(idx.symbols[sym.FullName], sym)
 
 		// Short name index
-		if !contains(idx.shortNames[sym.Name], sym.FullName) {
-			idx.shortNames[sym.Name] = append(idx.shortNames[sym.Name], sym.FullName)
-		}
-	}
+		idx.addShortNameLocked(sym.Name, sym.FullName)
 
-	// Add to trigram index
-	idx.trigram.AddFile(path, content)
+		if sym.TargetName != "" {
+			idx.redirects[sym.Name] = append(idx.redirects[sym.Name], sym)
+		}
 
-	return nil
+		if isTypeKind(sym.Kind) {
+			for i := 1; i <= len(sym.Scope); i++ {
+				ns := strings.Join(sym.Scope[:i], "::")
+				idx.namespaces[ns] = append(idx.namespaces[ns], sym)
+			}
+			idx.addSubtypesLocked(sym)
+		}
+	}
 }
 
 // RemoveFile removes all symbols from a file
@@ -142,10 +509,22 @@ func (idx *Index) RemoveFile(path string) {
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
 
+	idx.removeFileLocked(path)
+}
+
+// removeFileLocked removes a file's symbols. Caller must hold the write lock.
+func (idx *Index) removeFileLocked(path string) {
 	symbols := idx.byFile[path]
 	delete(idx.byFile, path)
+	delete(idx.matchedBy, path)
+	delete(idx.versions, path)
+	delete(idx.hashes, path)
 
 	for _, sym := range symbols {
+		if isFileLocal(sym) || sym.IsGhostPrefix() {
+			continue
+		}
+
 		// Remove from primary index
 		existing := idx.symbols[sym.FullName]
 		filtered := make([]*Symbol, 0, len(existing))
@@ -161,32 +540,120 @@ func (idx *Index) RemoveFile(path string) {
 		}
 
 		// Clean up short name index
-		fullNames := idx.shortNames[sym.Name]
-		if len(idx.symbols[sym.FullName]) == 0 {
-			filtered := make([]string, 0, len(fullNames))
-			for _, fn := range fullNames {
-				if fn != sym.FullName {
-					filtered = append(filtered, fn)
-				}
-			}
-			if len(filtered) == 0 {
-				delete(idx.shortNames, sym.Name)
-			} else {
-				idx.shortNames[sym.Name] = filtered
+		idx.removeShortNameLocked(sym.Name, sym.FullName)
+
+		if sym.TargetName != "" {
+			idx.removeRedirectLocked(sym.Name, path)
+		}
+
+		if isTypeKind(sym.Kind) {
+			for i := 1; i <= len(sym.Scope); i++ {
+				idx.removeNamespaceLocked(strings.Join(sym.Scope[:i], "::"), path)
 			}
+			idx.removeSubtypesLocked(sym, path)
 		}
 	}
 
+	// Remove ghost prefixes defined by this file
+	ghosts := idx.ghostPrefixes[:0]
+	for _, sym := range idx.ghostPrefixes {
+		if sym.FilePath != path {
+			ghosts = append(ghosts, sym)
+		}
+	}
+	idx.ghostPrefixes = ghosts
+
 	// Remove from trigram index
 	idx.trigram.RemoveFile(path)
 }
 
+// addShortNameLocked counts one more symbol named name with fullName.
+// Caller must hold the write lock.
+func (idx *Index) addShortNameLocked(name, fullName string) {
+	fullNames := idx.shortNames[name]
+	if fullNames == nil {
+		fullNames = make(map[string]int)
+		idx.shortNames[name] = fullNames
+	}
+	fullNames[fullName]++
+}
+
+// removeShortNameLocked counts one fewer symbol named name with fullName,
+// dropping entries no symbol refers to. Caller must hold the write lock.
+func (idx *Index) removeShortNameLocked(name, fullName string) {
+	fullNames := idx.shortNames[name]
+	if fullNames[fullName] <= 1 {
+		delete(fullNames, fullName)
+		if len(fullNames) == 0 {
+			delete(idx.shortNames, name)
+		}
+		return
+	}
+	fullNames[fullName]--
+}
+
+// removeRedirectLocked drops the redirects named name defined in path.
+// Caller must hold the write lock.
+func (idx *Index) removeRedirectLocked(name, path string) {
+	existing := idx.redirects[name]
+	filtered := existing[:0]
+	for _, sym := range existing {
+		if sym.FilePath != path {
+			filtered = append(filtered, sym)
+		}
+	}
+	if len(filtered) == 0 {
+		delete(idx.redirects, name)
+	} else {
+		idx.redirects[name] = filtered
+	}
+}
+
+// fullNamesLocked returns the full names defined for a short name, sorted.
+// Caller must hold the lock.
+func (idx *Index) fullNamesLocked(name string) []string {
+	fullNames := make([]string, 0, len(idx.shortNames[name]))
+	for fullName := range idx.shortNames[name] {
+		fullNames = append(fullNames, fullName)
+	}
+	sort.Strings(fullNames)
+	return fullNames
+}
+
 // UpdateFile removes then re-adds a file
 func (idx *Index) UpdateFile(path string) error {
+	if isAutoloadConfig(path) {
+		idx.loadAutoloadRoots()
+	}
 	idx.RemoveFile(path)
 	return idx.AddFile(path)
 }
 
+// loadAutoloadRoots (re)reads the Zeitwerk roots from the app config
+func (idx *Index) loadAutoloadRoots() {
+	roots := LoadAutoloadRoots(idx.rootPath)
+
+	idx.mu.Lock()
+	idx.autoload = roots
+	idx.mu.Unlock()
+}
+
+// ConstantForPath returns the constant a file is expected to define
+// according to the workspace's autoload roots
+func (idx *Index) ConstantForPath(path string) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.autoload.ConstantForPath(path)
+}
+
+// PathsForConstant returns the files expected to define a constant
+// according to the workspace's autoload roots
+func (idx *Index) PathsForConstant(name string) []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.autoload.PathsForConstant(name)
+}
+
 // FindDefinitions returns definitions matching the symbol name
 // Supports both short names ("MyClass") and full names ("MyModule::MyClass")
 func (idx *Index) FindDefinitions(name string) []*Symbol {
@@ -200,25 +667,65 @@ func (idx *Index) FindDefinitions(name string) []*Symbol {
 // Caller must hold at least a read lock.
 func (idx *Index) findDefinitionsLocked(name string) []*Symbol {
 	// Check symbols with TargetName first - clicking :address in "belongs_to :address"
-	// should navigate to the Address class
-	for _, syms := range idx.byFile {
-		for _, sym := range syms {
-			if sym.Name == name && sym.TargetName != "" {
-				return idx.findDefinitionsLocked(sym.TargetName)
-			}
+	// should navigate to the Address class. Without a requesting location,
+	// any file's redirect applies.
+	if syms := idx.redirects[name]; len(syms) > 0 {
+		results, _ := idx.redirectTargetLocked(sortedCopy(syms)[0])
+		return results
+	}
+	results, _ := idx.lookupDefinitionsLocked(name)
+	return results
+}
+
+// redirectTargetLocked resolves the definitions a redirecting symbol
+// (e.g., a relation) points at. Caller must hold the lock.
+func (idx *Index) redirectTargetLocked(sym *Symbol) ([]*Symbol, Confidence) {
+	if sym.Kind == types.KindRelation {
+		return idx.lookupDefinitionsLocked(idx.relationTargetLocked(sym))
+	}
+	return idx.lookupDefinitionsLocked(sym.TargetName)
+}
+
+// scopedRedirectLocked returns the redirect for name declared where a
+// request comes from: in the enclosing class at line, or anywhere in the
+// file when line is outside any class. Caller must hold the lock.
+func (idx *Index) scopedRedirectLocked(name, filePath string, line int) *Symbol {
+	scope := strings.Join(idx.lexicalScopeLocked(filePath, line), "::")
+	for _, sym := range sortedCopy(idx.redirects[name]) {
+		if scope == "" && sym.FilePath == filePath {
+			return sym
+		}
+		if scope != "" && strings.Join(sym.Scope, "::") == scope {
+			return sym
 		}
 	}
+	return nil
+}
+
+// lookupDefinitionsLocked finds definitions by full or short name, ignoring
+// redirects, each definition once. Caller must hold the lock.
+func (idx *Index) lookupDefinitionsLocked(name string) ([]*Symbol, Confidence) {
+	results, c := idx.lookupAllDefinitionsLocked(name)
+	return uniqueSymbols(results), c
+}
 
+// lookupAllDefinitionsLocked implements lookupDefinitionsLocked, possibly
+// reaching a definition more than once. Caller must hold the lock.
+func (idx *Index) lookupAllDefinitionsLocked(name string) ([]*Symbol, Confidence) {
 	// Try exact full name match
 	if syms, ok := idx.symbols[name]; ok {
-		result := make([]*Symbol, len(syms))
-		copy(result, syms)
-		return result
+		return sortedCopy(syms), ConfidenceExact
+	}
+
+	// A namespace nothing defines but classes or modules are declared in
+	// (class Admin::Users::Importer implies Admin::Users) resolves to those
+	// declarations
+	if members := idx.namespaces[strings.TrimPrefix(name, "::")]; len(members) > 0 {
+		return impliedNamespace(members), ConfidenceFuzzy
 	}
 
 	// Try short name lookup
-	fullNames, ok := idx.shortNames[name]
-	if ok {
+	if fullNames := idx.fullNamesLocked(name); len(fullNames) > 0 {
 		var result []*Symbol
 		for _, fullName := range fullNames {
 			if syms, ok := idx.symbols[fullName]; ok {
@@ -226,49 +733,152 @@ func (idx *Index) findDefinitionsLocked(name string) []*Symbol {
 			}
 		}
 		if len(result) > 0 {
-			return result
+			return sortSymbols(result), ConfidenceFuzzy
 		}
 	}
 
-	return nil
+	return nil, ConfidenceExact
+}
+
+// ghostPrefixesLocked returns the method_missing prefixes covering name
+// (find_by_* covers find_by_email) that className responds to: those
+// declared by it or by a class or module it inherits from or mixes in.
+// Caller must hold the lock.
+func (idx *Index) ghostPrefixesLocked(name, className string) []*Symbol {
+	var ghosts []*Symbol
+	for _, sym := range idx.ghostPrefixes {
+		if !strings.HasPrefix(name, strings.TrimSuffix(sym.Name, "*")) {
+			continue
+		}
+		owner := strings.Join(sym.Scope, "::")
+		if owner == className || isDescendant(idx.descendantsLocked(owner), className) {
+			ghosts = append(ghosts, sym)
+		}
+	}
+	return sortSymbols(ghosts)
+}
+
+func isDescendant(descendants []*Symbol, fullName string) bool {
+	for _, sym := range descendants {
+		if sym.FullName == fullName {
+			return true
+		}
+	}
+	return false
 }
 
 // FindDefinitionsInContext resolves a name using the enclosing scope at the given line.
 // It handles partially-qualified (Foo::Bar), absolutely-qualified (::Foo::Bar), and
 // unqualified names by prepending enclosing namespaces.
 func (idx *Index) FindDefinitionsInContext(name, filePath string, line int) []*Symbol {
+	results, _ := idx.ResolveDefinitions(name, filePath, line)
+	return results
+}
+
+// ResolveDefinitions is FindDefinitionsInContext, also reporting how
+// confident the resolution is. Use SymbolConfidence for each result.
+func (idx *Index) ResolveDefinitions(name, filePath string, line int) ([]*Symbol, Confidence) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	results, c := idx.resolveDefinitionsLocked(name, filePath, line)
+	return uniqueSymbols(results), c
+}
+
+// resolveDefinitionsLocked implements ResolveDefinitions. Caller must hold
+// the lock.
+func (idx *Index) resolveDefinitionsLocked(name, filePath string, line int) ([]*Symbol, Confidence) {
 	// Absolute scope: strip leading :: and do exact lookup only
 	if strings.HasPrefix(name, "::") {
-		return idx.FindDefinitions(strings.TrimPrefix(name, "::"))
+		if results, c := idx.lookupDefinitionsLocked(strings.TrimPrefix(name, "::")); len(results) > 0 {
+			return results, c
+		}
+		if results := idx.conventionFallbackLocked(name, nil); len(results) > 0 {
+			return results, ConfidenceFuzzy
+		}
+		return nil, ConfidenceExact
 	}
 
+	scope := idx.lexicalScopeLocked(filePath, line)
+
 	// If name contains ::, try namespace-aware resolution
 	if strings.Contains(name, "::") {
-		// Read file content to determine scope
-		content, err := os.ReadFile(filePath)
-		if err == nil {
-			scope := idx.scanner.ScopeAtLine(content, line)
-			// Try prepending enclosing namespaces, most specific first
-			for i := len(scope); i > 0; i-- {
-				candidate := strings.Join(scope[:i], "::") + "::" + name
-				if results := idx.FindDefinitions(candidate); len(results) > 0 {
-					return results
-				}
+		// Try prepending enclosing namespaces, most specific first
+		for i := len(scope); i > 0; i-- {
+			candidate := strings.Join(scope[:i], "::") + "::" + name
+			if results, c := idx.lookupDefinitionsLocked(candidate); len(results) > 0 {
+				return results, max(c, ConfidenceContextual)
 			}
 		}
 		// Try bare qualified name
-		if results := idx.FindDefinitions(name); len(results) > 0 {
-			return results
+		if results, c := idx.lookupDefinitionsLocked(name); len(results) > 0 {
+			return results, c
+		}
+	}
+
+	// Relations declared by the requesting class redirect to their target;
+	// elsewhere the name resolves normally (e.g., to the declaration itself)
+	if sym := idx.scopedRedirectLocked(name, filePath, line); sym != nil {
+		if results, c := idx.redirectTargetLocked(sym); len(results) > 0 {
+			return results, max(c, ConfidenceContextual)
 		}
 	}
 
 	// Unqualified or fallback: use existing logic with file preference
-	return idx.FindDefinitionsInFile(name, filePath)
+	results, c := idx.definitionsInFileLocked(name, filePath)
+	if c == ConfidenceFuzzy && len(scope) > 0 {
+		// A short-name match defined on the enclosing class, or a constant
+		// in an enclosing namespace (as Ruby's lexical lookup finds it), is
+		// contextual
+		enclosing := strings.Join(scope, "::")
+		for _, sym := range results {
+			symScope := strings.Join(sym.Scope, "::")
+			if symScope == enclosing ||
+				(sym.Kind.IsConstant() && strings.HasPrefix(enclosing, symScope+"::")) {
+				c = ConfidenceContextual
+				break
+			}
+		}
+	}
+	if len(results) > 0 {
+		return results, c
+	}
+
+	// A namespace only implied by compact class paths, looked up from the
+	// innermost enclosing namespace out
+	if results := idx.impliedNamespaceInScopeLocked(name, scope); len(results) > 0 {
+		return results, ConfidenceFuzzy
+	}
+
+	// Last resort for constants: a file named after the constant
+	if results := idx.conventionFallbackLocked(name, scope); len(results) > 0 {
+		return results, ConfidenceFuzzy
+	}
+
+	// Degrade to the method_missing prefixes of the enclosing class; from
+	// anywhere else the receiver is unknown, so a prefix could belong to
+	// any class
+	if len(scope) > 0 {
+		if ghosts := idx.ghostPrefixesLocked(name, strings.Join(scope, "::")); len(ghosts) > 0 {
+			return ghosts, ConfidenceGhost
+		}
+	}
+	return nil, c
 }
 
 // FindReferences finds all references to the given name using trigram search
 func (idx *Index) FindReferences(name string) []*Reference {
-	return idx.trigram.Search(name)
+	// Held so searches never see a half-applied ApplyBatch
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return sortReferences(idx.trigram.Search(name))
+}
+
+// FindReferencesInContent finds the references to name in one file's
+// content, ordered by position
+func (idx *Index) FindReferencesInContent(name, path, content string) []*Reference {
+	return sortReferences(idx.trigram.SearchContent(path, content, name))
 }
 
 // FindTargetingSymbols finds all symbols that target the given name
@@ -280,19 +890,30 @@ func (idx *Index) FindTargetingSymbols(targetName string) []*Symbol {
 	var result []*Symbol
 	for _, syms := range idx.byFile {
 		for _, sym := range syms {
-			if sym.TargetName == targetName {
+			if sym.TargetName != "" && targetMatches(sym.TargetName, targetName) {
 				result = append(result, sym)
 			}
 		}
 	}
-	return result
+	return sortSymbols(result)
 }
 
-// FindDefinitionsInFile returns definitions matching the name, preferring those in the given file
+// FindDefinitionsInFile returns definitions matching the name, preferring those in the given file.
+// Relation redirects are not applied; FindDefinitionsInContext scopes them to the requesting class.
 func (idx *Index) FindDefinitionsInFile(name, filePath string) []*Symbol {
-	all := idx.FindDefinitions(name)
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	results, _ := idx.definitionsInFileLocked(name, filePath)
+	return results
+}
+
+// definitionsInFileLocked implements FindDefinitionsInFile. Caller must
+// hold the lock.
+func (idx *Index) definitionsInFileLocked(name, filePath string) ([]*Symbol, Confidence) {
+	all, c := idx.lookupDefinitionsLocked(name)
 	if len(all) == 0 {
-		return nil
+		return nil, c
 	}
 
 	// Sort: same file first
@@ -305,7 +926,7 @@ func (idx *Index) FindDefinitionsInFile(name, filePath string) []*Symbol {
 		}
 	}
 
-	return append(sameFile, otherFiles...)
+	return append(sameFile, otherFiles...), c
 }
 
 // FindLocalVariable finds a local variable definition in the method containing cursorLine.
@@ -314,6 +935,12 @@ func (idx *Index) FindLocalVariable(name, filePath string, cursorLine int) *Symb
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
 
+	return idx.findLocalVariableLocked(name, filePath, cursorLine)
+}
+
+// findLocalVariableLocked is FindLocalVariable for callers holding the lock
+func (idx *Index) findLocalVariableLocked(name, filePath string, cursorLine int) *Symbol {
+
 	syms := idx.byFile[filePath]
 	if syms == nil {
 		return nil
@@ -333,18 +960,98 @@ func (idx *Index) FindLocalVariable(name, filePath string, cursorLine int) *Symb
 		return nil
 	}
 
-	// Find first local variable with matching name in that method
+	// Find first local variable with matching name in that method. One
+	// scoped to a lambda around the cursor shadows it, the innermost lambda
+	// winning.
+	var found *Symbol
 	for _, sym := range syms {
-		if sym.Kind == types.KindLocalVariable &&
-			sym.Name == name &&
-			sym.MethodFullName == containingMethod.FullName &&
-			sym.Line > containingMethod.Line &&
-			sym.Line <= containingMethod.EndLine {
-			return sym
+		if sym.Kind != types.KindLocalVariable || sym.Name != name ||
+			sym.Line <= containingMethod.Line || sym.Line > containingMethod.EndLine {
+			continue
+		}
+		switch {
+		case sym.MethodFullName == containingMethod.FullName:
+			if found == nil {
+				found = sym
+			}
+		case strings.HasPrefix(sym.MethodFullName, containingMethod.FullName+"@"):
+			first, last := lambdaRange(syms, sym)
+			if cursorLine >= first && cursorLine <= last &&
+				(found == nil || strings.Count(sym.MethodFullName, "@") > strings.Count(found.MethodFullName, "@")) {
+				found = sym
+			}
 		}
 	}
 
-	return nil
+	return found
+}
+
+// FindInstanceVariable finds where an instance variable of the class
+// enclosing the given 1-indexed line is assigned a value of known class,
+// preferring assignments in the same file. Returns nil if there's none.
+func (idx *Index) FindInstanceVariable(name, filePath string, line int) *Symbol {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	fullName := strings.Join(idx.lexicalScopeLocked(filePath, line), "::") + "#" + name
+	match := func(syms []*Symbol) *Symbol {
+		for _, sym := range syms {
+			if sym.Kind == types.KindInstanceVariable && sym.FullName == fullName {
+				return sym
+			}
+		}
+		return nil
+	}
+
+	if sym := match(idx.byFile[filePath]); sym != nil {
+		return sym
+	}
+	// A class reopened elsewhere shares its instance variables
+	var found *Symbol
+	for path, syms := range idx.byFile {
+		if path == filePath {
+			continue
+		}
+		if sym := match(syms); sym != nil && (found == nil || symbolLess(sym, found)) {
+			found = sym
+		}
+	}
+	return found
+}
+
+// lambdaRange returns the lines of the lambda a local is scoped to (as
+// one of its parameters or assigned in its body), or 0, -1 when it can't
+// be found
+func lambdaRange(syms []*Symbol, local *Symbol) (int, int) {
+	for _, sym := range syms {
+		if sym.Kind != types.KindLocalVariable || sym.FullName != local.MethodFullName {
+			continue
+		}
+		last := max(sym.EndLine, sym.Line)
+		if sym.Line <= local.Line && local.Line <= last {
+			return sym.Line, last
+		}
+	}
+	return 0, -1
+}
+
+// ParseContent parses content as if it were the file at path, without
+// indexing it (for open documents with unsaved edits)
+func (idx *Index) ParseContent(path, content string) []*Symbol {
+	return idx.scanner.Parse(path, []byte(content))
+}
+
+// BlocksInContent returns the blocks closed by `end` in content parsed as
+// the file at path, ordered by start line
+func (idx *Index) BlocksInContent(path, content string) []parser.Block {
+	return idx.scanner.Blocks(path, []byte(content))
+}
+
+// ProblemsInContent returns the structural errors in content parsed as the
+// file at path: unbalanced ends, unterminated blocks, and unclosed
+// multi-line parentheses
+func (idx *Index) ProblemsInContent(path, content string) []parser.Problem {
+	return idx.scanner.Problems(path, []byte(content))
 }
 
 // SymbolsInFile returns all symbols defined in a file
@@ -381,12 +1088,12 @@ func isRubyFile(path string) bool {
 	base := filepath.Base(path)
 
 	switch ext {
-	case ".rb", ".rake", ".gemspec":
+	case ".rb", ".rake", ".gemspec", ".cap":
 		return true
 	}
 
 	switch base {
-	case "Gemfile", "Rakefile", "Guardfile", "Vagrantfile":
+	case "Gemfile", "Rakefile", "Guardfile", "Vagrantfile", "Capfile":
 		return true
 	}
 
@@ -1,8 +1,11 @@
 package index
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/jarredhawkins/goruby-lsp/internal/parser"
@@ -18,11 +21,7 @@ func newTestIndex() *Index {
 // addContent parses content and adds symbols to the index (test helper)
 func (idx *Index) addContent(path string, content string) {
 	symbols := idx.scanner.Parse(path, []byte(content))
-	idx.byFile[path] = symbols
-	for _, sym := range symbols {
-		idx.symbols[sym.FullName] = append(idx.symbols[sym.FullName], sym)
-		idx.shortNames[sym.Name] = append(idx.shortNames[sym.Name], sym.FullName)
-	}
+	idx.indexSymbolsLocked(path, symbols)
 }
 
 func TestFindDefinitions_RelationRedirect(t *testing.T) {
@@ -139,6 +138,51 @@ end`), 0644)
 	}
 }
 
+func TestFindDefinitions_ImpliedNamespace(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/admin/users/bulk_importer.rb", `module Admin
+  class Admin::Users::BulkImporter < BaseImporter
+  end
+end`)
+	idx.addContent("/test/admin/dashboard.rb", `module Admin
+  class Dashboard
+    def importer
+      Users::BulkImporter
+    end
+  end
+end`)
+
+	// No file defines Admin::Users; the compact declaration stands in for it
+	results, c := idx.ResolveDefinitions("Admin::Users", "/test/other.rb", 1)
+	if len(results) != 1 || results[0].FullName != "Admin::Users::BulkImporter" || c != ConfidenceFuzzy {
+		t.Errorf("expected Admin::Users via BulkImporter (fuzzy), got %+v (%s)", results, c)
+	}
+	results = idx.FindDefinitionsInContext("Users", "/test/admin/dashboard.rb", 4)
+	if len(results) != 1 || results[0].FullName != "Admin::Users::BulkImporter" {
+		t.Errorf("expected Users inside Admin to resolve, got %+v", results)
+	}
+	results = idx.FindDefinitionsInContext("Users::BulkImporter", "/test/admin/dashboard.rb", 4)
+	if len(results) != 1 || results[0].FullName != "Admin::Users::BulkImporter" {
+		t.Errorf("expected Users::BulkImporter inside Admin to resolve, got %+v", results)
+	}
+
+	// An explicit definition wins, and implied namespaces go with their file
+	idx.addContent("/test/admin/users.rb", `module Admin
+  module Users
+  end
+end`)
+	if results := idx.FindDefinitions("Admin::Users"); len(results) != 1 || results[0].Kind != KindModule {
+		t.Errorf("expected the explicit module, got %+v", results)
+	}
+	idx.mu.Lock()
+	idx.removeFileLocked("/test/admin/users.rb")
+	idx.removeFileLocked("/test/admin/users/bulk_importer.rb")
+	idx.mu.Unlock()
+	if results := idx.FindDefinitions("Admin::Users"); len(results) != 0 {
+		t.Errorf("expected Admin::Users gone with its files, got %+v", results)
+	}
+}
+
 func TestNestedModule_ReferencesAndDefinition(t *testing.T) {
 	tmpDir, _ := os.MkdirTemp("", "index-test-*")
 	defer os.RemoveAll(tmpDir)
@@ -318,3 +362,390 @@ end
 		t.Errorf("expected FullName 'Printer#output', got %q", results[0].FullName)
 	}
 }
+
+func TestFindDefinitions_GhostPrefixFallback(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/finder.rb", `class Finder
+  def method_missing(name, *args)
+    return super unless name.to_s.start_with?("find_by_")
+    lookup(name)
+  end
+
+  def find_by_id(id)
+  end
+end`)
+	idx.addContent("/test/user_finder.rb", `class UserFinder < Finder
+  def active
+    find_by_email(email)
+  end
+end`)
+	idx.addContent("/test/report.rb", `class Report
+  def build
+    find_by_email(email)
+  end
+end`)
+
+	results, c := idx.ResolveDefinitions("find_by_email", "/test/finder.rb", 4)
+	if len(results) != 1 || results[0].FullName != "Finder#find_by_*" || !results[0].Synthetic || c != ConfidenceGhost {
+		t.Errorf("expected synthetic Finder#find_by_* ghost, got %+v (%s)", results, c)
+	}
+
+	// Subclasses inherit the prefix
+	if results, _ := idx.ResolveDefinitions("find_by_email", "/test/user_finder.rb", 3); len(results) != 1 || results[0].FullName != "Finder#find_by_*" {
+		t.Errorf("expected the ghost from a subclass, got %+v", results)
+	}
+
+	// Other classes, and callers whose receiver is unknown, don't
+	if results, _ := idx.ResolveDefinitions("find_by_email", "/test/report.rb", 3); len(results) != 0 {
+		t.Errorf("expected no ghost in an unrelated class, got %+v", results)
+	}
+	if results := idx.FindDefinitions("find_by_email"); len(results) != 0 {
+		t.Errorf("expected no ghost without a requesting class, got %+v", results)
+	}
+
+	// The prefix isn't a symbol to search for or complete
+	for _, sym := range idx.SearchSymbols(ParseSymbolQuery("find_by"), 10) {
+		if sym.Synthetic {
+			t.Errorf("expected no ghost in symbol search, got %s", sym.FullName)
+		}
+	}
+	for _, sym := range idx.SymbolsWithPrefix("find_by", nil, 10) {
+		if sym.Synthetic {
+			t.Errorf("expected no ghost among completions, got %s", sym.FullName)
+		}
+	}
+
+	// Real definitions still win over the ghost
+	results, _ = idx.ResolveDefinitions("find_by_id", "/test/finder.rb", 4)
+	if len(results) != 1 || results[0].Synthetic {
+		t.Errorf("expected real find_by_id definition, got %+v", results)
+	}
+
+	idx.RemoveFile("/test/finder.rb")
+	if results, _ := idx.ResolveDefinitions("find_by_email", "/test/user_finder.rb", 3); len(results) != 0 {
+		t.Errorf("expected ghost to be removed with its file, got %+v", results)
+	}
+}
+
+func TestShortNamesSurviveUpdateCycles(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	// Invoice is reopened in two files and also defined under Billing
+	base := write("invoice.rb", "class Invoice\n  def total\n  end\nend\n")
+	reopened := write("invoice_ext.rb", "class Invoice\n  def total\n  end\nend\n")
+	billing := write("billing.rb", "module Billing\n  class Invoice\n  end\nend\n")
+
+	idx := New(tmpDir, newTestIndex().registry)
+	for _, path := range []string{base, reopened, billing} {
+		if err := idx.AddFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := idx.UpdateFile(reopened); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := idx.shortNames["Invoice"]; len(got) != 2 || got["Invoice"] != 2 || got["Billing::Invoice"] != 1 {
+		t.Errorf("unexpected Invoice entries after updates: %v", got)
+	}
+	if got := idx.shortNames["total"]; len(got) != 1 || got["Invoice#total"] != 2 {
+		t.Errorf("unexpected total entries after updates: %v", got)
+	}
+
+	// Removing one definition keeps the name while another file defines it
+	idx.RemoveFile(reopened)
+	if got := idx.FindDefinitions("total"); len(got) != 1 || got[0].FilePath != base {
+		t.Errorf("expected total from %s, got %+v", base, got)
+	}
+
+	idx.RemoveFile(base)
+	if _, ok := idx.shortNames["total"]; ok {
+		t.Errorf("expected total to be dropped, got %v", idx.shortNames["total"])
+	}
+	if got := idx.shortNames["Invoice"]; len(got) != 1 || got["Billing::Invoice"] != 1 {
+		t.Errorf("expected only Billing::Invoice left, got %v", got)
+	}
+}
+
+func TestRedirectsFollowFileChanges(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/address.rb", "class Address\nend")
+	idx.addContent("/test/user.rb", "class User\n  belongs_to :address\nend")
+
+	results := idx.FindDefinitions("address")
+	if len(results) != 1 || results[0].Name != "Address" {
+		t.Fatalf("expected redirect to Address, got %+v", results)
+	}
+
+	idx.RemoveFile("/test/user.rb")
+	if len(idx.redirects) != 0 {
+		t.Errorf("expected no redirects after removing user.rb, got %v", idx.redirects)
+	}
+	if results := idx.FindDefinitions("address"); len(results) != 0 {
+		t.Errorf("expected no definitions for address, got %+v", results)
+	}
+}
+
+func TestFindDefinitionsInContext_RedirectScopedToDeclaringClass(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/line_item.rb", `class LineItem
+end`)
+	idx.addContent("/test/order.rb", `class Order
+  has_many :items, class_name: 'LineItem'
+
+  def total
+    items.sum(&:price)
+  end
+end`)
+	idx.addContent("/test/cart.rb", `class Cart
+  def items
+    @items ||= []
+  end
+
+  def empty?
+    items.empty?
+  end
+end`)
+
+	// Inside Order, items follows the relation to LineItem
+	results := idx.FindDefinitionsInContext("items", "/test/order.rb", 5)
+	if len(results) != 1 || results[0].Name != "LineItem" {
+		t.Errorf("expected LineItem from Order, got %+v", results)
+	}
+
+	// Cart's own method isn't hijacked by Order's relation
+	results = idx.FindDefinitionsInContext("items", "/test/cart.rb", 7)
+	if len(results) == 0 || results[0].FullName != "Cart#items" {
+		t.Errorf("expected Cart#items first, got %+v", results)
+	}
+	for _, sym := range results {
+		if sym.Name == "LineItem" {
+			t.Errorf("unexpected redirect to LineItem from Cart: %+v", results)
+		}
+	}
+}
+
+func TestApplyBatchIsAtomic(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a.rb")
+	b := filepath.Join(tmpDir, "b.rb")
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(a, "class Mover\nend\n")
+	write(b, "class Other\nend\n")
+
+	idx := New(tmpDir, newTestIndex().registry)
+	idx.ApplyBatch([]string{a, b}, nil)
+
+	// Readers must always see exactly one Mover while it moves between files
+	done := make(chan struct{})
+	failed := make(chan string, 1)
+	go func() {
+		for {
+			select {
+			case <-done:
+				close(failed)
+				return
+			default:
+			}
+			if got := len(idx.FindDefinitions("Mover")); got != 1 {
+				failed <- fmt.Sprintf("saw %d definitions of Mover", got)
+				close(failed)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 50; i++ {
+		from, to := a, b
+		if i%2 == 1 {
+			from, to = b, a
+		}
+		write(from, "class Other\nend\n")
+		write(to, "class Mover\nend\n")
+		idx.ApplyBatch([]string{a, b}, nil)
+	}
+	close(done)
+	if msg, ok := <-failed; ok {
+		t.Fatal(msg)
+	}
+
+	// Removed and unreadable files drop out of the index
+	os.Remove(a)
+	idx.ApplyBatch([]string{a}, []string{b})
+	if files := idx.Files(); len(files) != 0 {
+		t.Errorf("expected an empty index, got %v", files)
+	}
+}
+
+func TestApplyBatchKeepsOpenBuffers(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "order.rb")
+	if err := os.WriteFile(path, []byte("class Order\nend\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	idx := New(tmpDir, newTestIndex().registry)
+	idx.UpdateContent(path, []byte("class Order\n  def draft\n  end\nend\n"), 3)
+
+	// The file on disk lacks the buffer's unsaved method
+	idx.ApplyBatch([]string{path}, nil)
+	if len(idx.FindDefinitions("Order#draft")) != 1 {
+		t.Error("expected the open buffer's method to survive a batch")
+	}
+	if v := idx.FileVersion(path); v != 3 {
+		t.Errorf("expected version 3 kept, got %d", v)
+	}
+}
+
+func TestUpdateContentIsAtomic(t *testing.T) {
+	idx := newTestIndex()
+	idx.UpdateContent("/test/order.rb", []byte("class Order\nend\n"), 1)
+
+	// Readers must never see the file missing while it's reparsed
+	done := make(chan struct{})
+	failed := make(chan string, 1)
+	go func() {
+		for {
+			select {
+			case <-done:
+				close(failed)
+				return
+			default:
+			}
+			if got := len(idx.FindDefinitions("Order")); got != 1 {
+				failed <- fmt.Sprintf("saw %d definitions of Order", got)
+				close(failed)
+				return
+			}
+		}
+	}()
+
+	for i := 2; i < 100; i++ {
+		idx.UpdateContent("/test/order.rb", []byte("class Order\n  def total\n  end\nend\n"), i)
+	}
+	close(done)
+	if msg, ok := <-failed; ok {
+		t.Fatal(msg)
+	}
+}
+
+func TestSymbolsWithPrefix(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/order.rb", `class Order
+  ORDER_LIMIT = 10
+
+  def order_total
+  end
+end`)
+	idx.addContent("/test/billing.rb", `module Billing
+  class Order
+  end
+
+  class OrderLine
+  end
+end`)
+
+	var got []string
+	for _, sym := range idx.SymbolsWithPrefix("Order", nil, 0) {
+		got = append(got, sym.FullName)
+	}
+	want := []string{"Billing::Order", "Order", "Billing::OrderLine"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	methods := idx.SymbolsWithPrefix("ord", func(sym *Symbol) bool { return sym.Kind == KindMethod }, 0)
+	if len(methods) != 1 || methods[0].FullName != "Order#order_total" {
+		t.Errorf("expected order_total, got %+v", methods)
+	}
+
+	if limited := idx.SymbolsWithPrefix("", nil, 2); len(limited) != 2 {
+		t.Errorf("expected limit of 2, got %d", len(limited))
+	}
+}
+
+func TestBuildIndexesExtAndExtraRoots(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"ext/nokogiri/nokogiri.rb":         "module Nokogiri\n  def self.parse\n  end\nend\n",
+		"ext/nokogiri/nokogiri.c":          "void Init_nokogiri(void) { /* Nokogiri */ }\n",
+		"ext/nokogiri/nokogiri.h":          "#define NOKOGIRI 1\n",
+		"vendor/gems/money/lib/money.rb":   "class Money\nend\n",
+		"vendor/gems/ignored/lib/other.rb": "class Ignored\nend\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	idx := New(root, newTestIndex().registry)
+	idx.SetExtraRoots([]string{"vendor/gems/money"})
+	if err := idx.Build(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(idx.FindDefinitions("Nokogiri.parse")) != 1 {
+		t.Error("expected the Ruby shim under ext/ to be indexed")
+	}
+	if len(idx.FindDefinitions("Money")) != 1 {
+		t.Error("expected the extra root to be indexed, though it's under vendor")
+	}
+	if len(idx.FindDefinitions("Ignored")) != 0 {
+		t.Error("expected the rest of vendor to stay unindexed")
+	}
+	if refs := idx.FindReferences("Nokogiri"); len(refs) != 1 || filepath.Ext(refs[0].FilePath) != ".rb" {
+		t.Errorf("expected C sources kept out of text search, got %+v", refs)
+	}
+}
+
+func TestRepeatedFailuresAreReported(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "flaky.rb")
+
+	idx := newTestIndex()
+	var reported []string
+	idx.OnRepeatedFailure(func(path string, err error) {
+		reported = append(reported, path)
+	})
+
+	// Missing, so reading it fails every time
+	for i := 0; i < failureLimit+2; i++ {
+		idx.Reindex([]string{path})
+	}
+	if len(reported) != 1 || reported[0] != path {
+		t.Fatalf("expected one report after %d failures, got %v", failureLimit, reported)
+	}
+
+	// Indexing again resets the count
+	if err := os.WriteFile(path, []byte("class Flaky\nend\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	idx.Reindex([]string{path})
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < failureLimit; i++ {
+		idx.Reindex([]string{path})
+	}
+	if len(reported) != 2 {
+		t.Errorf("expected a second report after recovering, got %v", reported)
+	}
+}
@@ -0,0 +1,33 @@
+package index
+
+import (
+	"slices"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// FindConstantsWithValue returns the constants whose literal value is
+// value: those listing it among their array's elements (ROLES =
+// %w[admin member]) or assigned it (ADMIN = "admin".freeze), so a string
+// or symbol can be traced back to the constant declaring it
+func (idx *Index) FindConstantsWithValue(value string) []*Symbol {
+	if value == "" {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var found []*Symbol
+	for _, syms := range idx.byFile {
+		for _, sym := range syms {
+			if sym.Kind != types.KindConstant {
+				continue
+			}
+			if slices.Contains(sym.Values, value) || sym.Options["value"] == value {
+				found = append(found, sym)
+			}
+		}
+	}
+	return sortSymbols(found)
+}
@@ -0,0 +1,34 @@
+package index
+
+import "testing"
+
+func TestFindConstantsWithValue(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/app/models/user.rb", `class User
+  ROLES = %w[admin member guest].freeze
+  STATES = %i(
+    active
+    suspended
+  ).freeze
+  ADMIN = "admin".freeze
+end`)
+	idx.addContent("/app/models/account.rb", `class Account
+  STATES = [:active, :closed]
+end`)
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"admin", "User::ROLES,User::ADMIN"},
+		{"active", "Account::STATES,User::STATES"},
+		{"suspended", "User::STATES"},
+		{"owner", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := fullNames(idx.FindConstantsWithValue(tt.value)); got != tt.want {
+			t.Errorf("FindConstantsWithValue(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package index
+
+import "log"
+
+// SetDisabledMatchers disables the named matchers (re-enabling any others)
+// and returns the files that need reindexing. Disabling only affects files
+// the matcher fired in; re-enabling may add symbols anywhere, so every file
+// is returned.
+func (idx *Index) SetDisabledMatchers(names []string) []string {
+	disabled, enabled := idx.registry.SetDisabled(names)
+
+	var paths []string
+	switch {
+	case len(enabled) > 0:
+		paths = idx.Files()
+	case len(disabled) > 0:
+		paths = idx.filesMatchedBy(disabled)
+	}
+
+	if len(paths) > 0 {
+		log.Printf("matchers disabled=%v enabled=%v, %d files to reindex", disabled, enabled, len(paths))
+	}
+	return paths
+}
+
+// Reindex re-parses the given files with the current matchers
+func (idx *Index) Reindex(paths []string) {
+	idx.forEachFile(paths, idx.UpdateFile)
+}
+
+// Files returns the paths of all indexed files
+func (idx *Index) Files() []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	paths := make([]string, 0, len(idx.byFile))
+	for path := range idx.byFile {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// filesMatchedBy returns the files in which any of the named matchers fired
+func (idx *Index) filesMatchedBy(names []string) []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var paths []string
+	for path, matched := range idx.matchedBy {
+		for _, name := range matched {
+			if contains(names, name) {
+				paths = append(paths, path)
+				break
+			}
+		}
+	}
+	return paths
+}
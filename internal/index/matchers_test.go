@@ -0,0 +1,52 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetDisabledMatchersReindexes(t *testing.T) {
+	root := t.TempDir()
+	withLocals := filepath.Join(root, "order.rb")
+	withoutLocals := filepath.Join(root, "user.rb")
+	if err := os.WriteFile(withLocals, []byte("class Order\n  def total\n    sum = 0\n  end\nend\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(withoutLocals, []byte("class User\nend\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	idx := newTestIndex()
+	for _, path := range []string{withLocals, withoutLocals} {
+		if err := idx.AddFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if idx.FindLocalVariable("sum", withLocals, 3) == nil {
+		t.Fatal("expected local variable before disabling")
+	}
+
+	// Disabling only touches files the matcher fired in
+	paths := idx.SetDisabledMatchers([]string{"localvar"})
+	if len(paths) != 1 || paths[0] != withLocals {
+		t.Fatalf("expected only %s to need reindexing, got %v", withLocals, paths)
+	}
+	idx.Reindex(paths)
+	if idx.FindLocalVariable("sum", withLocals, 3) != nil {
+		t.Error("expected local variable removed after disabling localvar")
+	}
+	if len(idx.FindDefinitions("Order#total")) != 1 {
+		t.Error("expected other symbols to survive reindexing")
+	}
+
+	// Re-enabling reindexes everything
+	paths = idx.SetDisabledMatchers(nil)
+	if len(paths) != 2 {
+		t.Fatalf("expected all files to need reindexing, got %v", paths)
+	}
+	idx.Reindex(paths)
+	if idx.FindLocalVariable("sum", withLocals, 3) == nil {
+		t.Error("expected local variable back after re-enabling")
+	}
+}
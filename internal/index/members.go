@@ -0,0 +1,93 @@
+package index
+
+import (
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// maxAncestorDepth bounds superclass walks, guarding against cycles
+const maxAncestorDepth = 16
+
+// InstanceMembers returns the public members callable on instances of
+// className: def-based methods, attributes, associations, and schema
+// columns, including those inherited from indexed superclasses
+func (idx *Index) InstanceMembers(className string) []*Symbol {
+	return idx.members(className, func(sym *Symbol) bool {
+		switch sym.Kind {
+		case types.KindMethod, types.KindAttrReader, types.KindAttrWriter, types.KindAttrAccessor,
+			types.KindRelation, types.KindColumn:
+			return sym.Visibility == types.VisibilityPublic
+		}
+		return false
+	})
+}
+
+// ClassMembers returns the public class-level methods of className
+// (def self.x and named scopes), including inherited ones
+func (idx *Index) ClassMembers(className string) []*Symbol {
+	return idx.members(className, func(sym *Symbol) bool {
+		return sym.Kind == types.KindSingletonMethod && sym.Visibility == types.VisibilityPublic
+	})
+}
+
+// FindMember returns the definitions of an instance (or, when singleton is
+// set, class-level) member of className or its indexed superclasses,
+// regardless of visibility
+func (idx *Index) FindMember(className, name string, singleton bool) []*Symbol {
+	return idx.members(className, func(sym *Symbol) bool {
+		if sym.Name != name {
+			return false
+		}
+		if singleton {
+			return sym.Kind == types.KindSingletonMethod
+		}
+		switch sym.Kind {
+		case types.KindMethod, types.KindAttrReader, types.KindAttrWriter, types.KindAttrAccessor,
+			types.KindRelation, types.KindColumn:
+			return true
+		}
+		return false
+	})
+}
+
+func (idx *Index) members(className string, include func(*Symbol) bool) []*Symbol {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	ancestors := idx.ancestorsLocked(className)
+
+	var result []*Symbol
+	for _, syms := range idx.byFile {
+		for _, sym := range syms {
+			if include(sym) && ancestors[strings.Join(sym.Scope, "::")] {
+				result = append(result, sym)
+			}
+		}
+	}
+	return sortSymbols(result)
+}
+
+// ancestorsLocked returns the full names of className and its indexed
+// superclasses. Caller must hold at least a read lock.
+func (idx *Index) ancestorsLocked(className string) map[string]bool {
+	ancestors := make(map[string]bool)
+	pending := idx.classFullNamesLocked(className)
+
+	for depth := 0; len(pending) > 0 && depth < maxAncestorDepth; depth++ {
+		var next []string
+		for _, fullName := range pending {
+			if ancestors[fullName] {
+				continue
+			}
+			ancestors[fullName] = true
+			for _, sym := range idx.symbols[fullName] {
+				if sym.Kind == types.KindClass && sym.Superclass != "" {
+					next = append(next, idx.classFullNamesLocked(sym.Superclass)...)
+				}
+			}
+		}
+		pending = next
+	}
+	return ancestors
+}
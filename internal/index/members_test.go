@@ -0,0 +1,72 @@
+package index
+
+import (
+	"sort"
+	"testing"
+)
+
+func memberNames(syms []*Symbol) []string {
+	var names []string
+	for _, sym := range syms {
+		names = append(names, sym.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestInstanceMembersIncludesAssociations(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/record.rb", `class Record
+  def touch_all
+  end
+end`)
+	idx.addContent("/test/order.rb", `class Order < Record
+  has_many :items
+  belongs_to :customer
+  scope :recent, -> { order(created_at: :desc) }
+
+  def total
+  end
+
+  def self.import
+  end
+
+  private
+
+  def recalculate
+  end
+end`)
+
+	got := memberNames(idx.InstanceMembers("Order"))
+	want := []string{"customer", "items", "total", "touch_all"}
+	if len(got) != len(want) {
+		t.Fatalf("InstanceMembers(Order) = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("InstanceMembers(Order) = %v, want %v", got, want)
+		}
+	}
+
+	got = memberNames(idx.ClassMembers("Order"))
+	if len(got) != 2 || got[0] != "import" || got[1] != "recent" {
+		t.Errorf("ClassMembers(Order) = %v, want [import recent]", got)
+	}
+}
+
+func TestInstanceMembersSuperclassCycle(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/a.rb", `class A < B
+  def a_method
+  end
+end`)
+	idx.addContent("/test/b.rb", `class B < A
+  def b_method
+  end
+end`)
+
+	got := memberNames(idx.InstanceMembers("A"))
+	if len(got) != 2 {
+		t.Errorf("expected members of both classes, got %v", got)
+	}
+}
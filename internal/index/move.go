@@ -0,0 +1,147 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MoveFile records that the file at oldPath now lives at newPath. When it
+// still parses to the same symbols, the indexed symbols are kept and only
+// their FilePath is rewritten, so anything holding them sees the move.
+// Otherwise (the content changed, or a path-specific matcher such as
+// routes or factories applies differently at the new path) the file is
+// reindexed at newPath.
+func (idx *Index) MoveFile(oldPath, newPath string) error {
+	if !isRubyFile(newPath) {
+		idx.RemoveFile(oldPath)
+		return nil
+	}
+	content, err := os.ReadFile(newPath)
+	if err != nil {
+		return err
+	}
+	symbols, matched := idx.scanner.ParseMatched(newPath, content)
+	f := &parsedFile{content: content, symbols: symbols, matched: matched}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if !idx.moveFileLocked(oldPath, newPath, f) {
+		idx.removeFileLocked(oldPath)
+		idx.removeFileLocked(newPath)
+		idx.storeFileLocked(newPath, f.content, f.symbols, f.matched, 0)
+	}
+	return nil
+}
+
+// moveFileLocked moves oldPath's symbols to newPath in place when f, the
+// file parsed at newPath, matches what was indexed, and reports whether it
+// did. Caller must hold the write lock.
+func (idx *Index) moveFileLocked(oldPath, newPath string, f *parsedFile) bool {
+	symbols, ok := idx.byFile[oldPath]
+	if !ok || idx.hashes[oldPath] != contentHash(f.content) || !sameSymbols(symbols, f.symbols) {
+		return false
+	}
+	idx.removeFileLocked(newPath)
+
+	for _, sym := range symbols {
+		sym.FilePath = newPath
+	}
+	idx.byFile[newPath] = symbols
+	idx.matchedBy[newPath] = f.matched
+	idx.hashes[newPath] = idx.hashes[oldPath]
+	delete(idx.byFile, oldPath)
+	delete(idx.matchedBy, oldPath)
+	delete(idx.hashes, oldPath)
+	delete(idx.versions, oldPath)
+
+	idx.trigram.RemoveFile(oldPath)
+	idx.trigram.AddFile(newPath, f.content)
+	return true
+}
+
+// sameSymbols reports whether two parses of a file found the same symbols,
+// ignoring the path they were parsed at
+func sameSymbols(a, b []*Symbol) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		moved := *a[i]
+		moved.FilePath = b[i].FilePath
+		if !reflect.DeepEqual(&moved, b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// pairMovesLocked moves each removed file whose content reappeared at a
+// newly changed path in the same batch, as a rename produces. It returns
+// the moves in both directions, old path to new and new to old. Caller
+// must hold the write lock.
+func (idx *Index) pairMovesLocked(changed, removed []string, parsed map[string]*parsedFile) map[string]string {
+	moved := make(map[string]string)
+	if len(changed) == 0 || len(removed) == 0 {
+		return moved
+	}
+
+	byHash := make(map[uint64][]string)
+	for _, path := range removed {
+		if hash, ok := idx.hashes[path]; ok {
+			byHash[hash] = append(byHash[hash], path)
+		}
+	}
+	for _, newPath := range changed {
+		f := parsed[newPath]
+		if _, indexed := idx.byFile[newPath]; indexed || f == nil {
+			continue
+		}
+		for _, oldPath := range byHash[contentHash(f.content)] {
+			if moved[oldPath] == "" && idx.moveFileLocked(oldPath, newPath, f) {
+				moved[oldPath] = newPath
+				moved[newPath] = oldPath
+				break
+			}
+		}
+	}
+	return moved
+}
+
+// expandRemovedLocked replaces each removed path that isn't an indexed file
+// with the indexed files under it, for a removed or renamed directory.
+// Caller must hold the lock.
+func (idx *Index) expandRemovedLocked(removed []string) []string {
+	var expanded []string
+	for _, path := range removed {
+		expanded = append(expanded, idx.filesUnderLocked(path)...)
+	}
+	return expanded
+}
+
+// filesUnderLocked returns path if it's an indexed file, or else the
+// indexed files under it, sorted. Caller must hold the lock.
+func (idx *Index) filesUnderLocked(path string) []string {
+	if _, ok := idx.byFile[path]; ok {
+		return []string{path}
+	}
+	prefix := strings.TrimSuffix(path, string(filepath.Separator)) + string(filepath.Separator)
+	var under []string
+	for file := range idx.byFile {
+		if strings.HasPrefix(file, prefix) {
+			under = append(under, file)
+		}
+	}
+	sort.Strings(under)
+	return under
+}
+
+// HasFilesUnder reports whether any file at or under path is indexed
+func (idx *Index) HasFilesUnder(path string) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.filesUnderLocked(filepath.Clean(path))) > 0
+}
@@ -0,0 +1,104 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveFileKeepsSymbols(t *testing.T) {
+	root := t.TempDir()
+	oldPath := filepath.Join(root, "order.rb")
+	newPath := filepath.Join(root, "shop_order.rb")
+	if err := os.WriteFile(oldPath, []byte("class Order\n  def total\n  end\nend\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	idx := New(root, newTestIndex().registry)
+	if err := idx.AddFile(oldPath); err != nil {
+		t.Fatal(err)
+	}
+	before := idx.FindDefinitions("Order")[0]
+
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.MoveFile(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+
+	after := idx.FindDefinitions("Order")
+	if len(after) != 1 || after[0] != before || after[0].FilePath != newPath {
+		t.Fatalf("expected the same symbol moved to %s, got %+v", newPath, after)
+	}
+	if len(idx.SymbolsInFile(oldPath)) != 0 || len(idx.SymbolsInFile(newPath)) != 2 {
+		t.Error("expected the file's symbols under the new path only")
+	}
+	if refs := idx.FindReferences("total"); len(refs) != 1 || refs[0].FilePath != newPath {
+		t.Errorf("expected text search to find the new path, got %+v", refs)
+	}
+}
+
+func TestMoveFileReindexesPathSpecificSymbols(t *testing.T) {
+	root := t.TempDir()
+	content := []byte("FactoryBot.define do\n  factory :order do\n  end\nend\n")
+	oldPath := filepath.Join(root, "lib", "orders.rb")
+	newPath := filepath.Join(root, "spec", "factories", "orders.rb")
+	for _, dir := range []string{filepath.Dir(oldPath), filepath.Dir(newPath)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(newPath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	idx := New(root, newTestIndex().registry)
+	idx.UpdateContent(oldPath, content, 0)
+	if len(idx.FindDefinitions("order")) != 0 {
+		t.Fatal("expected no factory outside a factories directory")
+	}
+
+	// Factories are only recognized under spec/factories
+	if err := idx.MoveFile(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+	if defs := idx.FindDefinitions("order"); len(defs) != 1 || defs[0].FilePath != newPath {
+		t.Errorf("expected the factory indexed at its new path, got %+v", defs)
+	}
+}
+
+func TestApplyBatchPairsMoves(t *testing.T) {
+	root := t.TempDir()
+	models := filepath.Join(root, "models")
+	if err := os.MkdirAll(models, 0755); err != nil {
+		t.Fatal(err)
+	}
+	oldPath := filepath.Join(models, "order.rb")
+	if err := os.WriteFile(oldPath, []byte("class Order\nend\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	idx := New(root, newTestIndex().registry)
+	if err := idx.AddFile(oldPath); err != nil {
+		t.Fatal(err)
+	}
+	before := idx.FindDefinitions("Order")[0]
+
+	// A renamed directory is reported as the directory going away and
+	// each file appearing under the new name
+	renamed := filepath.Join(root, "records")
+	if err := os.Rename(models, renamed); err != nil {
+		t.Fatal(err)
+	}
+	newPath := filepath.Join(renamed, "order.rb")
+	idx.ApplyBatch([]string{newPath}, []string{models})
+
+	after := idx.FindDefinitions("Order")
+	if len(after) != 1 || after[0] != before || after[0].FilePath != newPath {
+		t.Errorf("expected the same symbol moved to %s, got %+v", newPath, after)
+	}
+	if len(idx.SymbolsInFile(oldPath)) != 0 {
+		t.Error("expected nothing left at the old path")
+	}
+}
@@ -0,0 +1,52 @@
+package index
+
+import "strings"
+
+// removeNamespaceLocked drops the namespace members defined in path.
+// Caller must hold the write lock.
+func (idx *Index) removeNamespaceLocked(ns, path string) {
+	members := idx.namespaces[ns][:0]
+	for _, sym := range idx.namespaces[ns] {
+		if sym.FilePath != path {
+			members = append(members, sym)
+		}
+	}
+	if len(members) == 0 {
+		delete(idx.namespaces, ns)
+	} else {
+		idx.namespaces[ns] = members
+	}
+}
+
+// impliedNamespace picks the declarations that stand for a namespace with
+// no definition of its own: those nested directly inside it, or failing
+// that the least deeply nested ones, in symbol order
+func impliedNamespace(members []*Symbol) []*Symbol {
+	depth := len(members[0].Scope)
+	for _, sym := range members[1:] {
+		depth = min(depth, len(sym.Scope))
+	}
+	var result []*Symbol
+	for _, sym := range members {
+		if len(sym.Scope) == depth {
+			result = append(result, sym)
+		}
+	}
+	return sortSymbols(result)
+}
+
+// impliedNamespaceInScopeLocked resolves a name to an implied namespace
+// the way Ruby looks up constants, from the innermost enclosing namespace
+// out. Caller must hold the lock.
+func (idx *Index) impliedNamespaceInScopeLocked(name string, scope []string) []*Symbol {
+	for i := len(scope); i >= 0; i-- {
+		candidate := name
+		if i > 0 {
+			candidate = strings.Join(scope[:i], "::") + "::" + name
+		}
+		if members := idx.namespaces[candidate]; len(members) > 0 {
+			return impliedNamespace(members)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package index
+
+import "sort"
+
+// Files are indexed concurrently, so the slices behind the maps hold
+// symbols in whatever order their files finished parsing. Every query that
+// returns several symbols or references sorts them first, so results don't
+// jump around between runs.
+
+// symbolLess orders symbols by file path, line, kind, then column and
+// full name
+func symbolLess(a, b *Symbol) bool {
+	if a.FilePath != b.FilePath {
+		return a.FilePath < b.FilePath
+	}
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	if a.Kind != b.Kind {
+		return a.Kind < b.Kind
+	}
+	if a.Column != b.Column {
+		return a.Column < b.Column
+	}
+	return a.FullName < b.FullName
+}
+
+// sortSymbols sorts syms in place by symbolLess and returns it
+func sortSymbols(syms []*Symbol) []*Symbol {
+	sort.SliceStable(syms, func(i, j int) bool {
+		return symbolLess(syms[i], syms[j])
+	})
+	return syms
+}
+
+// symbolIdentity is what makes two symbols the same definition: where it
+// is and what it is
+type symbolIdentity struct {
+	path   string
+	line   int
+	column int
+	kind   SymbolKind
+}
+
+// uniqueSymbols drops the symbols at the same position and of the same
+// kind as an earlier one, keeping the order. Overlapping lookups (a full
+// name and a short name reaching one symbol) or matchers indexing one
+// definition twice would otherwise list the same location more than once.
+func uniqueSymbols(syms []*Symbol) []*Symbol {
+	if len(syms) < 2 {
+		return syms
+	}
+	seen := make(map[symbolIdentity]bool, len(syms))
+	unique := syms[:0:0]
+	for _, sym := range syms {
+		id := symbolIdentity{sym.FilePath, sym.Line, sym.Column, sym.Kind}
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, sym)
+		}
+	}
+	return unique
+}
+
+// sortedCopy returns the symbols sorted by symbolLess, leaving syms as is
+func sortedCopy(syms []*Symbol) []*Symbol {
+	result := make([]*Symbol, len(syms))
+	copy(result, syms)
+	return sortSymbols(result)
+}
+
+// sortReferences sorts refs in place by file path, line, and column and
+// returns it
+func sortReferences(refs []*Reference) []*Reference {
+	sort.SliceStable(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		if a.FilePath != b.FilePath {
+			return a.FilePath < b.FilePath
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return refs
+}
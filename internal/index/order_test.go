@@ -0,0 +1,86 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMultiResultQueriesAreOrdered(t *testing.T) {
+	files := map[string]string{
+		"/test/b/order.rb":   "class Order\n  def total\n  end\nend\n",
+		"/test/a/order.rb":   "class Order\n  def total\n  end\nend\n",
+		"/test/c/invoice.rb": "class Invoice\n  belongs_to :order\n  def total\n  end\nend\n",
+		"/test/a/cart.rb":    "class Cart\n  has_many :orders\n  # total\n  def total\n  end\nend\n",
+	}
+	orders := [][]string{
+		{"/test/b/order.rb", "/test/a/order.rb", "/test/c/invoice.rb", "/test/a/cart.rb"},
+		{"/test/a/cart.rb", "/test/c/invoice.rb", "/test/a/order.rb", "/test/b/order.rb"},
+	}
+
+	var runs []string
+	for _, order := range orders {
+		idx := newTestIndex()
+		for _, path := range order {
+			idx.addContent(path, files[path])
+			idx.trigram.AddFile(path, []byte(files[path]))
+		}
+
+		var b strings.Builder
+		for _, sym := range idx.FindDefinitions("total") {
+			fmt.Fprintf(&b, "def %s:%d\n", sym.FilePath, sym.Line)
+		}
+		for _, sym := range idx.FindDefinitions("Order") {
+			fmt.Fprintf(&b, "class %s:%d\n", sym.FilePath, sym.Line)
+		}
+		for _, sym := range idx.FindTargetingSymbols("Order") {
+			fmt.Fprintf(&b, "target %s:%d\n", sym.FilePath, sym.Line)
+		}
+		for _, ref := range idx.FindReferences("total") {
+			fmt.Fprintf(&b, "ref %s:%d:%d\n", ref.FilePath, ref.Line, ref.Column)
+		}
+		runs = append(runs, b.String())
+	}
+
+	if runs[0] != runs[1] {
+		t.Errorf("results depend on indexing order:\n%s\nvs\n%s", runs[0], runs[1])
+	}
+	if !strings.HasPrefix(runs[0], "def /test/a/cart.rb:4\ndef /test/a/order.rb:2\ndef /test/b/order.rb:2\n") {
+		t.Errorf("expected definitions sorted by path and line, got\n%s", runs[0])
+	}
+}
+
+func TestDefinitionsAreUnique(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/order.rb", "module Shop\n  class Order\n    def total\n    end\n  end\nend\n")
+
+	// A second matcher indexing the same definition
+	var total *Symbol
+	for _, sym := range idx.byFile["/test/order.rb"] {
+		if sym.FullName == "Shop::Order#total" {
+			total = sym
+		}
+	}
+	copied := *total
+	idx.indexSymbolsLocked("/test/order.rb", append(idx.byFile["/test/order.rb"], &copied))
+
+	for _, name := range []string{"Shop::Order#total", "total"} {
+		if got := idx.FindDefinitions(name); len(got) != 1 {
+			t.Errorf("FindDefinitions(%q) returned %d definitions, want 1", name, len(got))
+		}
+	}
+	if got := idx.FindDefinitionsInContext("total", "/test/order.rb", 3); len(got) != 1 {
+		t.Errorf("expected one definition in context, got %d", len(got))
+	}
+
+	// Different kinds at one position are different definitions
+	syms := []*Symbol{
+		{FilePath: "/a.rb", Line: 1, Column: 2, Kind: KindMethod},
+		{FilePath: "/a.rb", Line: 1, Column: 2, Kind: KindMethod},
+		{FilePath: "/a.rb", Line: 1, Column: 2, Kind: KindConstant},
+		{FilePath: "/b.rb", Line: 1, Column: 2, Kind: KindMethod},
+	}
+	if got := uniqueSymbols(syms); len(got) != 3 || got[1].Kind != KindConstant {
+		t.Errorf("expected the repeated method dropped, got %+v", got)
+	}
+}
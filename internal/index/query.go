@@ -0,0 +1,161 @@
+package index
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// SymbolQuery is a parsed symbol search query such as "kind:method,attr_reader save"
+type SymbolQuery struct {
+	Text  string       // Remaining search text
+	Kinds []SymbolKind // Allowed kinds; empty allows every kind
+}
+
+// ParseSymbolQuery splits `kind:` filters from the search text. Unknown kind
+// names are ignored so a typo doesn't silently hide every result.
+func ParseSymbolQuery(query string) SymbolQuery {
+	var q SymbolQuery
+	var text []string
+
+	for _, field := range strings.Fields(query) {
+		names, ok := strings.CutPrefix(field, "kind:")
+		if !ok {
+			text = append(text, field)
+			continue
+		}
+		q.Kinds = append(q.Kinds, ParseSymbolKinds(strings.Split(names, ","))...)
+	}
+
+	q.Text = strings.Join(text, " ")
+	return q
+}
+
+// ParseSymbolKinds converts kind names to SymbolKinds, skipping unknown names
+func ParseSymbolKinds(names []string) []SymbolKind {
+	var kinds []SymbolKind
+	for _, name := range names {
+		if kind, ok := types.ParseSymbolKind(strings.TrimSpace(name)); ok {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// AllowsKind reports whether the query accepts symbols of the given kind
+func (q SymbolQuery) AllowsKind(kind SymbolKind) bool {
+	if len(q.Kinds) == 0 {
+		return true
+	}
+	for _, k := range q.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchSymbols returns symbols whose short or full name contains the
+// query text, ignoring case, best matches first: exact short names, then
+// short-name prefixes, then other matches, each ordered by full name.
+// Local variables are only searched when the query asks for their kind.
+// At most limit symbols are returned (0 for no limit).
+func (idx *Index) SearchSymbols(q SymbolQuery, limit int) []*Symbol {
+	text := strings.ToLower(q.Text)
+	includeLocals := len(q.Kinds) > 0 && q.AllowsKind(types.KindLocalVariable)
+
+	idx.mu.RLock()
+	type match struct {
+		sym  *Symbol
+		rank int
+	}
+	var matches []match
+	for fullName, syms := range idx.symbols {
+		lowerFull := strings.ToLower(fullName)
+		for _, sym := range syms {
+			if !q.AllowsKind(sym.Kind) || (sym.Kind == types.KindLocalVariable && !includeLocals) {
+				continue
+			}
+			name := strings.ToLower(sym.Name)
+			switch {
+			case name == text:
+				matches = append(matches, match{sym, 0})
+			case strings.HasPrefix(name, text):
+				matches = append(matches, match{sym, 1})
+			case strings.Contains(name, text) || strings.Contains(lowerFull, text):
+				matches = append(matches, match{sym, 2})
+			}
+		}
+	}
+	idx.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.sym.FullName != b.sym.FullName {
+			return a.sym.FullName < b.sym.FullName
+		}
+		return symbolLess(a.sym, b.sym)
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	result := make([]*Symbol, len(matches))
+	for i, m := range matches {
+		result[i] = m.sym
+	}
+	return result
+}
+
+// ListSymbols returns every symbol of the given kinds defined in a file
+// under pathPrefix, ordered by file and position. The prefix is relative to
+// the project root unless absolute; an empty prefix covers the whole
+// project. Like SearchSymbols, local variables are only listed when asked
+// for by kind.
+func (idx *Index) ListSymbols(kinds []SymbolKind, pathPrefix string) []*Symbol {
+	q := SymbolQuery{Kinds: kinds}
+	includeLocals := len(kinds) > 0 && q.AllowsKind(types.KindLocalVariable)
+	if pathPrefix != "" && !filepath.IsAbs(pathPrefix) {
+		pathPrefix = filepath.Join(idx.rootPath, pathPrefix)
+	}
+
+	idx.mu.RLock()
+	var result []*Symbol
+	for path, syms := range idx.byFile {
+		if !strings.HasPrefix(path, pathPrefix) {
+			continue
+		}
+		for _, sym := range syms {
+			if q.AllowsKind(sym.Kind) && (sym.Kind != types.KindLocalVariable || includeLocals) {
+				result = append(result, sym)
+			}
+		}
+	}
+	idx.mu.RUnlock()
+
+	return sortSymbols(result)
+}
+
+// ConstantKind returns whether the short name belongs to indexed classes,
+// modules, or constants, preferring class over module over constant when
+// it is used for several
+func (idx *Index) ConstantKind(name string) (SymbolKind, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	best, found := SymbolKind(0), false
+	rank := map[SymbolKind]int{types.KindClass: 3, types.KindModule: 2, types.KindConstant: 1}
+	for fullName := range idx.shortNames[name] {
+		for _, sym := range idx.symbols[fullName] {
+			if r := rank[sym.Kind]; r > 0 && (!found || r > rank[best]) {
+				best, found = sym.Kind, true
+			}
+		}
+	}
+	return best, found
+}
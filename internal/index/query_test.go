@@ -0,0 +1,143 @@
+package index
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseSymbolQuery(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantText  string
+		wantKinds []SymbolKind
+	}{
+		{"User", "User", nil},
+		{"kind:method save", "save", []SymbolKind{KindMethod}},
+		{"save kind:class,module", "save", []SymbolKind{KindClass, KindModule}},
+		{"kind:relation kind:constant", "", []SymbolKind{KindRelation, KindConstant}},
+		{"kind:instance_variable total", "total", []SymbolKind{KindInstanceVariable}},
+		{"kind:bogus save", "save", nil},
+	}
+
+	for _, tt := range tests {
+		q := ParseSymbolQuery(tt.query)
+		if q.Text != tt.wantText {
+			t.Errorf("%q: expected text %q, got %q", tt.query, tt.wantText, q.Text)
+		}
+		if len(q.Kinds) != len(tt.wantKinds) {
+			t.Errorf("%q: expected kinds %v, got %v", tt.query, tt.wantKinds, q.Kinds)
+			continue
+		}
+		for i, k := range tt.wantKinds {
+			if q.Kinds[i] != k {
+				t.Errorf("%q: expected kinds %v, got %v", tt.query, tt.wantKinds, q.Kinds)
+				break
+			}
+		}
+	}
+}
+
+func TestSymbolQueryAllowsKind(t *testing.T) {
+	unfiltered := ParseSymbolQuery("save")
+	if !unfiltered.AllowsKind(KindLocalVariable) {
+		t.Error("query without kind filter should allow every kind")
+	}
+
+	methods := ParseSymbolQuery("kind:method save")
+	if !methods.AllowsKind(KindMethod) {
+		t.Error("expected kind:method to allow methods")
+	}
+	if methods.AllowsKind(KindLocalVariable) {
+		t.Error("expected kind:method to reject local variables")
+	}
+}
+
+func TestSearchSymbols(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/billing.rb", `module Billing
+  class Invoice
+    def invoice_total
+      total = 0
+    end
+  end
+
+  class InvoiceLine
+  end
+end
+
+class ProformaInvoice
+end`)
+
+	names := func(q SymbolQuery, limit int) []string {
+		var got []string
+		for _, sym := range idx.SearchSymbols(q, limit) {
+			got = append(got, sym.FullName)
+		}
+		return got
+	}
+
+	// Exact short name, then prefix, then substring; locals are skipped
+	got := names(ParseSymbolQuery("invoice"), 0)
+	want := []string{"Billing::Invoice", "Billing::Invoice#invoice_total", "Billing::InvoiceLine", "ProformaInvoice"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// Full names match too
+	if got := names(ParseSymbolQuery("billing::invoiceline"), 0); fmt.Sprint(got) != "[Billing::InvoiceLine]" {
+		t.Errorf("expected full name match, got %v", got)
+	}
+
+	if got := names(ParseSymbolQuery("kind:class invoice"), 2); fmt.Sprint(got) != "[Billing::Invoice Billing::InvoiceLine]" {
+		t.Errorf("expected two classes, got %v", got)
+	}
+
+	if got := names(ParseSymbolQuery("kind:local_variable total"), 0); fmt.Sprint(got) != "[Billing::Invoice#invoice_total@total]" {
+		t.Errorf("expected the local when asked for, got %v", got)
+	}
+}
+
+func TestListSymbols(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/app/models/order.rb", `class Order
+  has_many :items
+  belongs_to :customer
+
+  def total
+    sum = 0
+  end
+end`)
+	idx.addContent("/test/lib/report.rb", `class Report
+  has_one :owner
+end`)
+
+	names := func(syms []*Symbol) []string {
+		var result []string
+		for _, sym := range syms {
+			result = append(result, sym.FullName)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name   string
+		kinds  []SymbolKind
+		prefix string
+		want   []string
+	}{
+		{"relations under a relative prefix", []SymbolKind{KindRelation}, "app/models", []string{"Order::items", "Order::customer"}},
+		{"relations everywhere", []SymbolKind{KindRelation}, "", []string{"Order::items", "Order::customer", "Report::owner"}},
+		{"absolute prefix", []SymbolKind{KindClass}, "/test/lib", []string{"Report"}},
+		{"locals only when asked", []SymbolKind{KindLocalVariable}, "app", []string{"Order#total@sum"}},
+		{"every kind but locals", nil, "app/models", []string{"Order", "Order::items", "Order::customer", "Order#total"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(idx.ListSymbols(tt.kinds, tt.prefix))
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package index
+
+import (
+	"regexp"
+	"strings"
+)
+
+// commonNames are method names defined and called so widely that an
+// unqualified text search for them is mostly noise
+var commonNames = map[string]bool{
+	"new": true, "call": true, "name": true, "id": true, "type": true,
+	"to_s": true, "to_h": true, "to_a": true, "inspect": true,
+	"each": true, "map": true, "size": true, "count": true, "first": true, "last": true,
+	"all": true, "find": true, "where": true, "create": true, "update": true,
+	"save": true, "destroy": true, "delete": true, "build": true, "run": true,
+	"perform": true, "process": true, "value": true, "status": true, "params": true,
+	"valid?": true, "present?": true, "empty?": true, "nil?": true,
+}
+
+// IsCommonName reports whether references to name should be qualified by
+// a receiver or scope before they're worth listing
+func IsCommonName(name string) bool {
+	return commonNames[name]
+}
+
+// receiverPattern captures the receiver before a method call at the end of
+// the text preceding it: User. Billing::User. user. self. user&.
+var receiverPattern = regexp.MustCompile(`((?:::)?[A-Za-z_]\w*(?:::[A-Z]\w*)*)(?:\.|&\.|::)$`)
+
+// ReceiverBefore returns the receiver of a method call whose name starts
+// right after text (User for "User.", user for "user&."), or ""
+func ReceiverBefore(text string) string {
+	if m := receiverPattern.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// definitionPrefixPattern matches the text before a method name in its
+// definition: def name, def self.name
+var definitionPrefixPattern = regexp.MustCompile(`\bdef\s+(?:self\.)?$`)
+
+// QualifiedReferences keeps the references to name that are tied to one of
+// the owner classes or modules (full names): calls on the owner's constant
+// (User.find), on a local named after it or assigned an instance of it
+// (user.name), on self or without a receiver inside the owner, and the
+// method's definitions.
+func (idx *Index) QualifiedReferences(refs []*Reference, owners []string) []*Reference {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	owned := make(map[string]bool)
+	locals := make(map[string]bool)
+	for _, owner := range owners {
+		owned[strings.TrimPrefix(owner, "::")] = true
+		locals[underscore(lastSegment(owner))] = true
+	}
+
+	var result []*Reference
+	for _, ref := range refs {
+		if ref.Column > len(ref.LineText) {
+			continue
+		}
+		before := ref.LineText[:ref.Column]
+
+		insideOwner := func() bool {
+			return owned[strings.Join(idx.lexicalScopeLocked(ref.FilePath, ref.Line), "::")]
+		}
+
+		receiver := ReceiverBefore(before)
+		switch {
+		case definitionPrefixPattern.MatchString(before):
+			if insideOwner() {
+				result = append(result, ref)
+			}
+		case receiver == "":
+			// A bare call: only a call on the owner from inside it
+			if !strings.HasSuffix(strings.TrimRight(before, " "), ".") && insideOwner() {
+				result = append(result, ref)
+			}
+		case receiver == "self":
+			if insideOwner() {
+				result = append(result, ref)
+			}
+		case receiver[0] >= 'a' && receiver[0] <= 'z' || receiver[0] == '_':
+			if locals[receiver] || idx.localOwnedLocked(receiver, ref, owned) {
+				result = append(result, ref)
+			}
+		default:
+			if idx.constantOwnedLocked(receiver, ref, owned) {
+				result = append(result, ref)
+			}
+		}
+	}
+	return result
+}
+
+// localOwnedLocked reports whether the local variable receiver at ref was
+// assigned an instance of one of the owned classes. Caller must hold the
+// lock.
+func (idx *Index) localOwnedLocked(receiver string, ref *Reference, owned map[string]bool) bool {
+	local := idx.findLocalVariableLocked(receiver, ref.FilePath, ref.Line)
+	if local == nil || local.TypeName == "" {
+		return false
+	}
+	return idx.constantOwnedLocked(local.TypeName, ref, owned)
+}
+
+// constantOwnedLocked reports whether the constant name, as written at
+// ref, is one of the owned classes or modules. Caller must hold the lock.
+func (idx *Index) constantOwnedLocked(name string, ref *Reference, owned map[string]bool) bool {
+	if strings.HasPrefix(name, "::") {
+		return owned[strings.TrimPrefix(name, "::")]
+	}
+	scope := idx.lexicalScopeLocked(ref.FilePath, ref.Line)
+	for i := len(scope); i >= 0; i-- {
+		candidate := name
+		if i > 0 {
+			candidate = strings.Join(scope[:i], "::") + "::" + name
+		}
+		if owned[candidate] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package index
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestQualifiedReferences(t *testing.T) {
+	idx := newTestIndex()
+	files := map[string]string{
+		"/test/user.rb": `class User
+  def name
+  end
+
+  def greeting
+    "Hi #{name} and #{self.name}"
+  end
+end`,
+		"/test/team.rb": `class Team
+  def name
+  end
+
+  def label
+    name
+  end
+end`,
+		"/test/report.rb": `class Report
+  def rows
+    user.name
+    User.find(1).name
+    owner = User.new
+    owner.name
+    team.name
+    name
+  end
+end`,
+	}
+	for path, content := range files {
+		idx.addContent(path, content)
+		idx.trigram.AddFile(path, []byte(content))
+	}
+
+	var got []string
+	for _, ref := range idx.QualifiedReferences(idx.FindReferences("name"), []string{"User"}) {
+		got = append(got, fmt.Sprintf("%s:%d", ref.FilePath, ref.Line))
+	}
+	sort.Strings(got)
+
+	want := []string{
+		"/test/report.rb:3", // user.name
+		"/test/report.rb:6", // owner = User.new
+		"/test/user.rb:2",   // def name
+		"/test/user.rb:6",   // name and self.name
+		"/test/user.rb:6",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
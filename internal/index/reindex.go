@@ -0,0 +1,51 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ReindexResult summarizes a ReindexPath call
+type ReindexResult struct {
+	Files   int `json:"files"`   // Ruby files found and brought up to date
+	Removed int `json:"removed"` // Indexed files that no longer exist
+}
+
+// ReindexPath brings the index up to date with a file or directory on disk,
+// such as after a generator wrote files the watcher missed: Ruby files
+// under it are indexed or reindexed, and indexed files under it that are
+// gone are dropped. Files whose content is already indexed are skipped.
+func (idx *Index) ReindexPath(ctx context.Context, path string) (ReindexResult, error) {
+	path = filepath.Clean(path)
+	var files []string
+	if _, err := os.Stat(path); err == nil {
+		if files, err = rubyFilesUnder(ctx, path); err != nil {
+			return ReindexResult{}, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return ReindexResult{}, err
+	}
+
+	present := make(map[string]bool, len(files))
+	for _, file := range files {
+		present[file] = true
+	}
+	idx.mu.RLock()
+	var removed []string
+	for _, file := range idx.filesUnderLocked(path) {
+		if !present[file] {
+			removed = append(removed, file)
+		}
+	}
+	idx.mu.RUnlock()
+
+	if len(files) == 0 && len(removed) == 0 {
+		return ReindexResult{}, fmt.Errorf("no Ruby files at %s", path)
+	}
+	idx.ApplyBatch(files, removed)
+	return ReindexResult{Files: len(files), Removed: len(removed)}, nil
+}
@@ -0,0 +1,82 @@
+package index
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Kinds of rename conflict
+const (
+	ConflictExistingDefinition = "existing_definition"
+	ConflictAutoloadPath       = "autoload_path"
+)
+
+// RenameConflict is a reason renaming a constant would clash with code
+// already in the project
+type RenameConflict struct {
+	Kind    string
+	Message string
+	Symbol  *Symbol // Clashing definition, for existing_definition
+	Path    string  // Clashing file, for autoload_path
+}
+
+// RenamedFullName replaces the last segment of fullName with newName
+// (Billing::Invoice, Bill → Billing::Bill)
+func RenamedFullName(fullName, newName string) string {
+	if i := strings.LastIndex(fullName, "::"); i >= 0 {
+		return fullName[:i+2] + newName
+	}
+	return newName
+}
+
+// RenameConflicts checks whether renaming the class, module, or constant
+// fullName to newName would clash with an existing definition of the new
+// full name, or with a file already at the new name's autoload path.
+// Reopenings of fullName itself don't count.
+func (idx *Index) RenameConflicts(fullName, newName string) []RenameConflict {
+	newFullName := RenamedFullName(fullName, newName)
+	if newFullName == fullName {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var conflicts []RenameConflict
+	for _, sym := range idx.symbols[newFullName] {
+		if !sym.Kind.IsConstant() {
+			continue
+		}
+		conflicts = append(conflicts, RenameConflict{
+			Kind:    ConflictExistingDefinition,
+			Message: fmt.Sprintf("%s %s is already defined in %s:%d", sym.Kind, newFullName, sym.FilePath, sym.Line),
+			Symbol:  sym,
+		})
+	}
+
+	// Files that define the constant being renamed may move to the new
+	// path, and files already reported above aren't reported twice
+	skip := make(map[string]bool)
+	for _, sym := range idx.symbols[fullName] {
+		skip[sym.FilePath] = true
+	}
+	for _, c := range conflicts {
+		skip[c.Symbol.FilePath] = true
+	}
+	for _, path := range idx.autoload.PathsForConstant(newFullName) {
+		if skip[path] {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		conflicts = append(conflicts, RenameConflict{
+			Kind:    ConflictAutoloadPath,
+			Message: fmt.Sprintf("%s is autoloaded from %s, which already exists", newFullName, path),
+			Path:    path,
+		})
+	}
+
+	return conflicts
+}
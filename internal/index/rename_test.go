@@ -0,0 +1,42 @@
+package index
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenameConflicts(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"app/models/invoice.rb":         "class Invoice\nend\n",
+		"app/models/receipt.rb":         "class Receipt\nend\n",
+		"app/models/bill.rb":            "# Placeholder, no class yet\n",
+		"app/models/billing/invoice.rb": "module Billing\n  class Invoice\n  end\nend\n",
+	})
+
+	idx := New(root, newTestIndex().registry)
+	if err := idx.Build(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	conflicts := idx.RenameConflicts("Invoice", "Receipt")
+	if len(conflicts) != 1 || conflicts[0].Kind != ConflictExistingDefinition ||
+		conflicts[0].Symbol.FullName != "Receipt" {
+		t.Errorf("expected Receipt to already exist, got %+v", conflicts)
+	}
+
+	conflicts = idx.RenameConflicts("Invoice", "Bill")
+	if len(conflicts) != 1 || conflicts[0].Kind != ConflictAutoloadPath ||
+		conflicts[0].Path != filepath.Join(root, "app/models/bill.rb") {
+		t.Errorf("expected bill.rb autoload collision, got %+v", conflicts)
+	}
+
+	// Only the renamed constant's namespace is checked
+	if conflicts := idx.RenameConflicts("Billing::Invoice", "Receipt"); len(conflicts) != 0 {
+		t.Errorf("expected Billing::Receipt to be free, got %+v", conflicts)
+	}
+	if conflicts := idx.RenameConflicts("Invoice", "Invoice"); len(conflicts) != 0 {
+		t.Errorf("expected no conflicts renaming to the same name, got %+v", conflicts)
+	}
+}
@@ -0,0 +1,73 @@
+package index
+
+import "github.com/jarredhawkins/goruby-lsp/internal/types"
+
+// ScopeAt returns the innermost class, module, or method symbol whose range
+// covers the given 1-indexed line, or nil at the top level
+func (idx *Index) ScopeAt(filePath string, line int) *Symbol {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return innermostContainer(idx.byFile[filePath], line, func(sym *Symbol) bool {
+		switch sym.Kind {
+		case types.KindClass, types.KindModule, types.KindMethod, types.KindSingletonMethod:
+			return true
+		}
+		return false
+	})
+}
+
+// LocalScope returns the lines a local variable is visible on: the lambda
+// it's scoped to, or else its method (to the end of the file if the method
+// is unclosed)
+func (idx *Index) LocalScope(local *Symbol) (int, int) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	syms := idx.byFile[local.FilePath]
+	for _, sym := range syms {
+		if sym.Kind == types.KindLocalVariable && sym.FullName == local.MethodFullName {
+			return lambdaRange(syms, local)
+		}
+	}
+
+	method := innermostContainer(syms, local.Line, func(sym *Symbol) bool {
+		return sym.Kind == types.KindMethod || sym.Kind == types.KindSingletonMethod
+	})
+	if method == nil {
+		return 1, int(^uint(0) >> 1)
+	}
+	if method.EndLine == 0 {
+		return method.Line, int(^uint(0) >> 1)
+	}
+	return method.Line, method.EndLine
+}
+
+// lexicalScopeLocked returns the namespace nesting at a line, e.g.
+// ["Billing", "Invoice"] inside class Billing::Invoice. Caller must hold
+// at least a read lock.
+func (idx *Index) lexicalScopeLocked(filePath string, line int) []string {
+	sym := innermostContainer(idx.byFile[filePath], line, func(sym *Symbol) bool {
+		return sym.Kind == types.KindClass || sym.Kind == types.KindModule
+	})
+	if sym == nil {
+		return nil
+	}
+	return append(append([]string{}, sym.Scope...), sym.Name)
+}
+
+// innermostContainer returns the matching symbol with the latest start whose
+// range covers line. A container that was never closed (EndLine 0) extends
+// to the end of the file.
+func innermostContainer(syms []*Symbol, line int, include func(*Symbol) bool) *Symbol {
+	var best *Symbol
+	for _, sym := range syms {
+		if !include(sym) || sym.Line > line || (sym.EndLine != 0 && sym.EndLine < line) {
+			continue
+		}
+		if best == nil || sym.Line > best.Line || (sym.Line == best.Line && len(sym.Scope) > len(best.Scope)) {
+			best = sym
+		}
+	}
+	return best
+}
@@ -0,0 +1,87 @@
+package index
+
+import "testing"
+
+func TestScopeAt(t *testing.T) {
+	idx := newTestIndex()
+	path := "/test/billing/invoice.rb"
+	idx.addContent(path, `module Billing
+  class Invoice
+    def total
+      lines.sum do |l|
+        l.amount
+      end
+    end
+
+    def self.build
+    end
+  end
+
+  VERSION = 1
+end`)
+
+	tests := []struct {
+		line int
+		want string
+	}{
+		{1, "Billing"},
+		{2, "Billing::Invoice"},
+		{5, "Billing::Invoice#total"},
+		{7, "Billing::Invoice#total"},
+		{8, "Billing::Invoice"},
+		{9, "Billing::Invoice.build"},
+		{13, "Billing"},
+		{15, ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if sym := idx.ScopeAt(path, tt.line); sym != nil {
+			got = sym.FullName
+		}
+		if got != tt.want {
+			t.Errorf("ScopeAt(line %d) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	if sym := idx.ScopeAt("/test/missing.rb", 1); sym != nil {
+		t.Errorf("expected nil for unknown file, got %+v", sym)
+	}
+}
+
+func TestLambdaScopedLocals(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/dispatcher.rb", `class Dispatcher
+  def run(events)
+    event = events.first
+    handler = ->(event) { process(event) }
+    logger = lambda do |message|
+      line = format(message)
+      puts line
+    end
+    puts event
+  end
+end`)
+
+	// A lambda parameter shadows the method's local inside the lambda only
+	if sym := idx.FindLocalVariable("event", "/test/dispatcher.rb", 4); sym == nil || sym.FullName != "Dispatcher#run@handler@event" {
+		t.Errorf("expected the lambda parameter on its line, got %+v", sym)
+	}
+	if sym := idx.FindLocalVariable("event", "/test/dispatcher.rb", 9); sym == nil || sym.FullName != "Dispatcher#run@event" {
+		t.Errorf("expected the method's local after the lambda, got %+v", sym)
+	}
+
+	// Locals in a multi-line lambda body are only visible inside it
+	line := idx.FindLocalVariable("line", "/test/dispatcher.rb", 7)
+	if line == nil || line.FullName != "Dispatcher#run@logger@line" {
+		t.Fatalf("expected the lambda body's local, got %+v", line)
+	}
+	if sym := idx.FindLocalVariable("line", "/test/dispatcher.rb", 9); sym != nil {
+		t.Errorf("expected line to be out of scope after the lambda, got %+v", sym)
+	}
+	if first, last := idx.LocalScope(line); first != 5 || last != 8 {
+		t.Errorf("expected line's scope to be the lambda (5-8), got %d-%d", first, last)
+	}
+	if first, last := idx.LocalScope(idx.FindLocalVariable("handler", "/test/dispatcher.rb", 9)); first != 2 || last != 10 {
+		t.Errorf("expected handler's scope to be the method (2-10), got %d-%d", first, last)
+	}
+}
@@ -0,0 +1,70 @@
+package index
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// slicePause is how long a time-sliced build yields between slices
+const slicePause = 10 * time.Millisecond
+
+// SetBuildSlice makes Build index in slices of roughly this much time,
+// pausing every worker between slices so requests interleave with
+// indexing instead of queueing behind it on machines with few cores.
+// Zero (the default) indexes without pausing.
+func (idx *Index) SetBuildSlice(slice time.Duration) {
+	idx.buildSlice = slice
+}
+
+// timeSlicer pauses the workers of a build once each slice is used up
+type timeSlicer struct {
+	slice time.Duration
+	pause time.Duration
+	now   func() time.Time
+
+	mu    sync.Mutex
+	start time.Time // When the current slice started, or starts after a pause
+}
+
+// newTimeSlicer returns a slicer, or nil when slice is zero
+func newTimeSlicer(slice, pause time.Duration) *timeSlicer {
+	if slice <= 0 {
+		return nil
+	}
+	return &timeSlicer{slice: slice, pause: pause, now: time.Now, start: time.Now()}
+}
+
+// wait is called by a worker before each file. Once the slice is used up
+// the first worker to notice schedules a pause, and every worker arriving
+// before it's over waits out the rest of it. It returns early with the
+// context's error if the build is canceled. A nil slicer never waits.
+func (t *timeSlicer) wait(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	delay := t.delay()
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// delay returns how long a worker arriving now should pause, starting the
+// next slice after the pause once this one is used up
+func (t *timeSlicer) delay() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	if now.Sub(t.start) >= t.slice {
+		t.start = now.Add(t.pause)
+	}
+	return max(t.start.Sub(now), 0)
+}
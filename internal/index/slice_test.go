@@ -0,0 +1,71 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/parser"
+)
+
+func TestTimeSlicer(t *testing.T) {
+	var none *timeSlicer
+	if err := none.wait(context.Background()); err != nil {
+		t.Errorf("expected slicing off never to wait, got %v", err)
+	}
+
+	clock := time.Unix(0, 0)
+	slicer := newTimeSlicer(10*time.Millisecond, 20*time.Millisecond)
+	slicer.now = func() time.Time { return clock }
+	slicer.start = clock
+
+	steps := []struct {
+		advance time.Duration
+		want    time.Duration
+	}{
+		{0, 0}, // Within the slice
+		{10 * time.Millisecond, 20 * time.Millisecond}, // Slice used up, pause starts
+		{5 * time.Millisecond, 15 * time.Millisecond},  // Another worker waits out the rest
+		{15 * time.Millisecond, 0},                     // Pause over, next slice
+		{9 * time.Millisecond, 0},
+		{time.Millisecond, 20 * time.Millisecond},
+	}
+	for i, step := range steps {
+		clock = clock.Add(step.advance)
+		if got := slicer.delay(); got != step.want {
+			t.Errorf("step %d: expected a pause of %s, got %s", i, step.want, got)
+		}
+	}
+
+	// A canceled build stops waiting
+	clock = clock.Add(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := slicer.wait(ctx); err != context.Canceled {
+		t.Errorf("expected the wait to be canceled, got %v", err)
+	}
+}
+
+func TestSlicedBuild(t *testing.T) {
+	root := t.TempDir()
+	for i := range 5 {
+		content := fmt.Sprintf("class Model%d\nend\n", i)
+		if err := os.WriteFile(filepath.Join(root, fmt.Sprintf("model%d.rb", i)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	registry := parser.NewRegistry()
+	parser.RegisterDefaults(registry)
+	idx := New(root, registry)
+	idx.SetBuildSlice(time.Nanosecond)
+	if err := idx.Build(context.Background()); err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	if len(idx.Files()) != 5 {
+		t.Errorf("expected every file indexed, got %d", len(idx.Files()))
+	}
+}
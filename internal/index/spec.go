@@ -0,0 +1,143 @@
+package index
+
+import (
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/parser"
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// isFileLocal reports whether a symbol is only meaningful within its own
+// file (example groups, let helpers, and refinement blocks, which would
+// otherwise shadow the class they refine) and so stays out of name lookups
+func isFileLocal(sym *Symbol) bool {
+	return sym.Kind == types.KindExampleGroup || sym.Kind == types.KindLet ||
+		sym.Kind == types.KindInstanceVariable || sym.Refines != ""
+}
+
+// FindLet finds the let/subject helper visible at the given 1-indexed line.
+// A let in an inner example group shadows one with the same name in an
+// enclosing group.
+func (idx *Index) FindLet(name, filePath string, line int) *Symbol {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	syms := idx.byFile[filePath]
+
+	var best *Symbol
+	bestStart := -1
+	for _, sym := range syms {
+		if sym.Kind != types.KindLet || sym.Name != name {
+			continue
+		}
+
+		// Lets outside any group are visible throughout the file
+		start, end := 0, line
+		if group := innermostGroup(syms, sym.Line); group != nil {
+			start, end = group.Line, group.EndLine
+		}
+		if line < start || line > end {
+			continue
+		}
+		if start > bestStart {
+			best, bestStart = sym, start
+		}
+	}
+	return best
+}
+
+// DescribedClass returns the class described by the example groups around
+// the given 1-indexed line (Billing::Invoice in RSpec.describe
+// Billing::Invoice), or "". Nested contexts describing strings keep their
+// enclosing group's class; a nested group describing a constant replaces it.
+func (idx *Index) DescribedClass(filePath string, line int) string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	syms := idx.byFile[filePath]
+	best := ""
+	bestStart := -1
+	for _, sym := range syms {
+		if sym.Kind != types.KindExampleGroup || sym.TypeName == "" || sym.Line <= bestStart {
+			continue
+		}
+		if line > sym.Line && (sym.EndLine == 0 || line <= sym.EndLine) {
+			best, bestStart = sym.TypeName, sym.Line
+		}
+	}
+	return best
+}
+
+// LetClass returns the class of the value a let/subject helper returns,
+// as written in the spec (User, Billing::Invoice), or "" when it can't be
+// told: the class its body constructs, the described class for
+// described_class.new, or the model built by the factory it creates
+func (idx *Index) LetClass(let *Symbol) string {
+	if let.TypeName == "described_class" {
+		return idx.DescribedClass(let.FilePath, let.Line)
+	}
+	if let.TypeName != "" {
+		return let.TypeName
+	}
+	if factory := let.Options["factory"]; factory != "" {
+		idx.mu.RLock()
+		defer idx.mu.RUnlock()
+		return idx.factoryClassLocked(factory)
+	}
+	return ""
+}
+
+// factoryClassLocked returns the model a FactoryBot factory builds: its
+// class: option, else the class of the factory it's nested in, else the
+// class named after it (user → User)
+func (idx *Index) factoryClassLocked(name string) string {
+	for _, sym := range idx.symbols[name] {
+		if sym.Kind != types.KindFactory {
+			continue
+		}
+		if class := sym.Options["class"]; class != "" {
+			return strings.TrimPrefix(class, ":")
+		}
+		parent := innermostContainer(idx.byFile[sym.FilePath], sym.Line-1, func(s *Symbol) bool {
+			return s.Kind == types.KindFactory && s.Line < sym.Line
+		})
+		if parent != nil && parent.EndLine >= sym.Line {
+			return idx.factoryClassLocked(parent.Name)
+		}
+		break
+	}
+	return parser.CamelCase(name)
+}
+
+// innermostGroup returns the example group or shared example block
+// most tightly enclosing line
+func innermostGroup(syms []*Symbol, line int) *Symbol {
+	var group *Symbol
+	for _, sym := range syms {
+		if sym.Kind != types.KindExampleGroup && sym.Kind != types.KindSharedExample {
+			continue
+		}
+		if sym.EndLine == 0 || line <= sym.Line || line > sym.EndLine {
+			continue
+		}
+		if group == nil || sym.Line > group.Line {
+			group = sym
+		}
+	}
+	return group
+}
+
+// FindSharedExamples returns shared_examples/shared_context definitions
+// with the given name
+func (idx *Index) FindSharedExamples(name string) []*Symbol {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var result []*Symbol
+	for _, sym := range idx.symbols[name] {
+		if sym.Kind == types.KindSharedExample {
+			result = append(result, sym)
+		}
+	}
+	return sortSymbols(result)
+}
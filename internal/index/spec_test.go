@@ -0,0 +1,176 @@
+package index
+
+import "testing"
+
+func TestFindLetShadowing(t *testing.T) {
+	idx := newTestIndex()
+	path := "/test/spec/models/invoice_spec.rb"
+	idx.addContent(path, `RSpec.describe Invoice do
+  let(:user) { create(:user) }
+
+  context "when paid" do
+    let(:user) { create(:admin) }
+
+    it "is closed" do
+      user
+    end
+  end
+
+  it "is open" do
+    user
+  end
+end`)
+
+	if sym := idx.FindLet("user", path, 8); sym == nil || sym.Line != 5 {
+		t.Errorf("expected inner let on line 5, got %+v", sym)
+	}
+	if sym := idx.FindLet("user", path, 13); sym == nil || sym.Line != 2 {
+		t.Errorf("expected outer let on line 2, got %+v", sym)
+	}
+	if sym := idx.FindLet("account", path, 8); sym != nil {
+		t.Errorf("expected no let for account, got %+v", sym)
+	}
+
+	// Lets and example groups stay out of global lookups
+	if syms := idx.FindDefinitions("user"); len(syms) != 0 {
+		t.Errorf("expected lets to be file-local, got %+v", syms)
+	}
+	if syms := idx.FindDefinitions("Invoice"); len(syms) != 0 {
+		t.Errorf("expected example group not to define Invoice, got %+v", syms)
+	}
+}
+
+func TestDescribedClass(t *testing.T) {
+	idx := newTestIndex()
+	path := "/test/spec/models/invoice_spec.rb"
+	idx.addContent(path, `RSpec.describe Billing::Invoice, type: :model do
+  context "when paid" do
+    it "is closed" do
+      described_class
+    end
+  end
+
+  describe LineItem do
+    it "totals" do
+      described_class
+    end
+  end
+end
+
+describe "helpers" do
+  it "formats" do
+  end
+end`)
+
+	tests := []struct {
+		line int
+		want string
+	}{
+		{4, "Billing::Invoice"},
+		{10, "LineItem"},
+		{13, "Billing::Invoice"},
+		{1, ""},
+		{16, ""},
+	}
+	for _, tt := range tests {
+		if got := idx.DescribedClass(path, tt.line); got != tt.want {
+			t.Errorf("line %d: expected %q, got %q", tt.line, tt.want, got)
+		}
+	}
+}
+
+func TestLetClass(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/spec/factories/users.rb", `FactoryBot.define do
+  factory :user do
+    name { "Ada" }
+
+    factory :admin do
+      admin { true }
+    end
+  end
+
+  factory :refund, class: "Billing::Refund" do
+  end
+end`)
+	path := "/test/spec/models/invoice_spec.rb"
+	idx.addContent(path, `RSpec.describe Billing::Invoice do
+  subject { described_class.new(order) }
+  let(:user) { create(:user) }
+  let(:admin) { create(:admin) }
+  let(:refund) { build(:refund) }
+  let(:line_item) { build(:line_item) }
+  let(:order) { Order.new }
+  let(:total) { order.total }
+end`)
+
+	want := map[string]string{
+		"subject":   "Billing::Invoice",
+		"user":      "User",
+		"admin":     "User",
+		"refund":    "Billing::Refund",
+		"line_item": "LineItem",
+		"order":     "Order",
+		"total":     "",
+	}
+	for name, class := range want {
+		let := idx.FindLet(name, path, 8)
+		if let == nil {
+			t.Errorf("let %s not found", name)
+		} else if got := idx.LetClass(let); got != class {
+			t.Errorf("%s: expected %q, got %q", name, class, got)
+		}
+	}
+}
+
+func TestFindSharedExamples(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/spec/support/auditable.rb", `RSpec.shared_examples "an auditable model" do
+  let(:auditor) { create(:user) }
+
+  it "records changes" do
+  end
+end`)
+
+	syms := idx.FindSharedExamples("an auditable model")
+	if len(syms) != 1 || syms[0].Line != 1 {
+		t.Fatalf("expected shared example on line 1, got %+v", syms)
+	}
+
+	if sym := idx.FindLet("auditor", "/test/spec/support/auditable.rb", 4); sym == nil {
+		t.Error("expected let inside shared example to resolve")
+	}
+
+	idx.RemoveFile("/test/spec/support/auditable.rb")
+	if syms := idx.FindSharedExamples("an auditable model"); len(syms) != 0 {
+		t.Errorf("expected shared example removed, got %+v", syms)
+	}
+}
+
+func TestRefinementsStayOutOfLookups(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/string_extensions.rb", `module StringExtensions
+  refine String do
+    def shout
+      upcase + "!"
+    end
+  end
+end`)
+
+	// The refinement doesn't stand in for the class it refines
+	if results := idx.FindDefinitions("String"); len(results) != 0 {
+		t.Errorf("expected no definition of String, got %+v", results)
+	}
+	if results := idx.FindDefinitionsInContext("String", "/test/string_extensions.rb", 4); len(results) != 0 {
+		t.Errorf("expected String inside the module not to resolve to the refinement, got %+v", results)
+	}
+
+	// Its methods are indexed under the refined class's name
+	results := idx.FindDefinitions("shout")
+	if len(results) != 1 || results[0].FullName != "StringExtensions::String#shout" {
+		t.Errorf("expected StringExtensions::String#shout, got %+v", results)
+	}
+	if syms := idx.SymbolsInFile("/test/string_extensions.rb"); len(syms) != 3 {
+		t.Errorf("expected the refinement in the file's symbols, got %+v", syms)
+	}
+}
@@ -0,0 +1,57 @@
+package index
+
+import (
+	"time"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/parser"
+)
+
+// slowestFileCount is how many of the slowest files a build reports
+const slowestFileCount = 20
+
+// BuildStats describes the timing of an index build
+type BuildStats struct {
+	StartedAt    time.Time              `json:"startedAt"`
+	Duration     time.Duration          `json:"duration"`
+	Files        int                    `json:"files"`
+	Matchers     []parser.MatcherTiming `json:"matchers"`     // Most expensive first
+	SlowestFiles []parser.FileTiming    `json:"slowestFiles"` // Slowest first
+}
+
+// Status summarizes the index contents and its last build
+type Status struct {
+	RootPath string      `json:"rootPath"`
+	Files    int         `json:"files"`
+	Symbols  int         `json:"symbols"`
+	Build    *BuildStats `json:"build,omitempty"`
+}
+
+// recordBuild stores the timing collected during Build
+func (idx *Index) recordBuild(start time.Time, files int, stats *parser.ParseStats) {
+	build := &BuildStats{
+		StartedAt:    start,
+		Duration:     time.Since(start),
+		Files:        files,
+		Matchers:     stats.Matchers(),
+		SlowestFiles: stats.SlowestFiles(slowestFileCount),
+	}
+
+	idx.mu.Lock()
+	idx.buildStats = build
+	idx.mu.Unlock()
+}
+
+// Status returns the current index size and the last build's timing
+func (idx *Index) Status() Status {
+	symbols := idx.SymbolCount()
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return Status{
+		RootPath: idx.rootPath,
+		Files:    len(idx.byFile),
+		Symbols:  symbols,
+		Build:    idx.buildStats,
+	}
+}
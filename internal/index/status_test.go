@@ -0,0 +1,49 @@
+package index
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildRecordsStatus(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"order.rb": "class Order\n  def total\n  end\nend\n",
+		"user.rb":  "class User\nend\n",
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	idx := newTestIndex()
+	idx.rootPath = root
+	if status := idx.Status(); status.Build != nil {
+		t.Fatalf("expected no build stats before Build, got %+v", status.Build)
+	}
+
+	if err := idx.Build(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	status := idx.Status()
+	if status.Files != 2 || status.Symbols != 3 {
+		t.Errorf("expected 2 files and 3 symbols, got %+v", status)
+	}
+	if status.Build == nil || status.Build.Files != 2 {
+		t.Fatalf("expected build stats for 2 files, got %+v", status.Build)
+	}
+	if len(status.Build.SlowestFiles) != 2 || len(status.Build.Matchers) == 0 {
+		t.Errorf("expected file and matcher timings, got %+v", status.Build)
+	}
+
+	// Timing is only collected during Build
+	if err := idx.UpdateFile(filepath.Join(root, "user.rb")); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(idx.Status().Build.SlowestFiles); got != 2 {
+		t.Errorf("expected reindexing outside Build not to be timed, got %d files", got)
+	}
+}
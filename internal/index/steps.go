@@ -0,0 +1,129 @@
+package index
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// Compiled step patterns keyed by step definition name
+var stepRegexps sync.Map
+
+// Cucumber expression parameter types
+var stepParameters = map[string]string{
+	"int":    `(-?\d+)`,
+	"float":  `(-?\d*\.?\d+)`,
+	"word":   `(\S+)`,
+	"string": `("[^"]*"|'[^']*')`,
+}
+
+// FindStepDefinitions returns the Cucumber step definitions whose pattern
+// matches a plain-text step from a .feature file
+func (idx *Index) FindStepDefinitions(text string) []*Symbol {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var result []*Symbol
+	for _, syms := range idx.byFile {
+		for _, sym := range syms {
+			if sym.Kind != types.KindStep {
+				continue
+			}
+			re, err := cachedStepRegexp(sym.Name)
+			if err != nil {
+				continue
+			}
+			if re.MatchString(text) {
+				result = append(result, sym)
+			}
+		}
+	}
+	return sortSymbols(result)
+}
+
+func cachedStepRegexp(name string) (*regexp.Regexp, error) {
+	if re, ok := stepRegexps.Load(name); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := StepRegexp(name)
+	if err != nil {
+		return nil, err
+	}
+	stepRegexps.Store(name, re)
+	return re, nil
+}
+
+// StepRegexp compiles a step definition name, either a Ruby regexp literal
+// (/^I log in$/i) or a quoted Cucumber expression ("I have {int} cukes")
+func StepRegexp(name string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(name, "/") {
+		end := strings.LastIndex(name, "/")
+		source, flags := name[1:end], name[end+1:]
+
+		var prefix string
+		if strings.Contains(flags, "i") {
+			prefix += "i"
+		}
+		if strings.Contains(flags, "m") {
+			prefix += "s" // Ruby's /m lets . match newlines
+		}
+		if prefix != "" {
+			source = "(?" + prefix + ")" + source
+		}
+		return regexp.Compile(source)
+	}
+
+	expr := name
+	if len(expr) >= 2 {
+		expr = expr[1 : len(expr)-1]
+	}
+	expr = strings.ReplaceAll(expr, `\"`, `"`)
+	expr = strings.ReplaceAll(expr, `\'`, `'`)
+	return regexp.Compile("^" + cucumberExpression(expr) + "$")
+}
+
+// cucumberExpression converts a Cucumber expression to regexp source:
+// {int} parameters, (s) optional text, and word/alternatives
+func cucumberExpression(expr string) string {
+	words := strings.Split(expr, " ")
+	for i, word := range words {
+		if strings.Contains(word, "/") && !strings.ContainsAny(word, "{(") {
+			alternatives := strings.Split(word, "/")
+			for j, alt := range alternatives {
+				alternatives[j] = regexp.QuoteMeta(alt)
+			}
+			words[i] = "(?:" + strings.Join(alternatives, "|") + ")"
+			continue
+		}
+		words[i] = cucumberWord(word)
+	}
+	return strings.Join(words, " ")
+}
+
+func cucumberWord(word string) string {
+	var b strings.Builder
+	for i := 0; i < len(word); i++ {
+		switch word[i] {
+		case '{':
+			if end := strings.IndexByte(word[i:], '}'); end >= 0 {
+				param, ok := stepParameters[word[i+1:i+end]]
+				if !ok {
+					param = `(.*)`
+				}
+				b.WriteString(param)
+				i += end
+				continue
+			}
+		case '(':
+			if end := strings.IndexByte(word[i:], ')'); end >= 0 {
+				b.WriteString("(?:" + regexp.QuoteMeta(word[i+1:i+end]) + ")?")
+				i += end
+				continue
+			}
+		}
+		b.WriteString(regexp.QuoteMeta(word[i : i+1]))
+	}
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package index
+
+import "testing"
+
+func TestStepRegexp(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		match bool
+	}{
+		{`/^I log in as "([^"]*)"$/`, `I log in as "alice"`, true},
+		{`/^I log in$/`, `I log in as "alice"`, false},
+		{`/^i visit the (\w+) page$/i`, `I visit the orders page`, true},
+		{`"I have {int} cuke(s) in my belly"`, `I have 3 cukes in my belly`, true},
+		{`"I have {int} cuke(s) in my belly"`, `I have 1 cuke in my belly`, true},
+		{`"I have {int} cuke(s) in my belly"`, `I have many cukes in my belly`, false},
+		{`'I click {string}'`, `I click "Save"`, true},
+		{`"I am on the home/landing page"`, `I am on the landing page`, true},
+		{`"the total is {float}"`, `the total is 12.50`, true},
+		{`"I pay $5 (now)"`, `I pay $5 now`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.text, func(t *testing.T) {
+			re, err := StepRegexp(tt.name)
+			if err != nil {
+				t.Fatalf("StepRegexp(%s) failed: %v", tt.name, err)
+			}
+			if got := re.MatchString(tt.text); got != tt.match {
+				t.Errorf("%s matching %q = %v, want %v (regexp %s)", tt.name, tt.text, got, tt.match, re)
+			}
+		})
+	}
+}
+
+func TestFindStepDefinitions(t *testing.T) {
+	idx := newTestIndex()
+	idx.addContent("/test/features/step_definitions/orders_steps.rb", `Given(/^I have (\d+) orders?$/) do |count|
+  create_list(:order, count.to_i)
+end
+
+When "I cancel order {int}" do |id|
+  Order.find(id).cancel!
+end`)
+
+	syms := idx.FindStepDefinitions("I have 2 orders")
+	if len(syms) != 1 || syms[0].Line != 1 {
+		t.Fatalf("expected step on line 1, got %+v", syms)
+	}
+	syms = idx.FindStepDefinitions("I cancel order 7")
+	if len(syms) != 1 || syms[0].Line != 5 {
+		t.Fatalf("expected step on line 5, got %+v", syms)
+	}
+	if syms := idx.FindStepDefinitions("I refund order 7"); len(syms) != 0 {
+		t.Errorf("expected no step, got %+v", syms)
+	}
+}
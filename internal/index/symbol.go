@@ -6,17 +6,38 @@ import "github.com/jarredhawkins/goruby-lsp/internal/types"
 type Symbol = types.Symbol
 type SymbolKind = types.SymbolKind
 type Reference = types.Reference
+type Visibility = types.Visibility
 
 // Re-export constants
 const (
-	KindClass           = types.KindClass
-	KindModule          = types.KindModule
-	KindMethod          = types.KindMethod
-	KindSingletonMethod = types.KindSingletonMethod
-	KindConstant        = types.KindConstant
-	KindAttrReader      = types.KindAttrReader
-	KindAttrWriter      = types.KindAttrWriter
-	KindAttrAccessor    = types.KindAttrAccessor
-	KindLocalVariable   = types.KindLocalVariable
-	KindCustom          = types.KindCustom
+	KindClass            = types.KindClass
+	KindModule           = types.KindModule
+	KindMethod           = types.KindMethod
+	KindSingletonMethod  = types.KindSingletonMethod
+	KindConstant         = types.KindConstant
+	KindAttrReader       = types.KindAttrReader
+	KindAttrWriter       = types.KindAttrWriter
+	KindAttrAccessor     = types.KindAttrAccessor
+	KindLocalVariable    = types.KindLocalVariable
+	KindCustom           = types.KindCustom
+	KindRelation         = types.KindRelation
+	KindColumn           = types.KindColumn
+	KindExampleGroup     = types.KindExampleGroup
+	KindSharedExample    = types.KindSharedExample
+	KindLet              = types.KindLet
+	KindStep             = types.KindStep
+	KindNamespace        = types.KindNamespace
+	KindTask             = types.KindTask
+	KindEnumValue        = types.KindEnumValue
+	KindRoute            = types.KindRoute
+	KindFactory          = types.KindFactory
+	KindGem              = types.KindGem
+	KindGemAttribute     = types.KindGemAttribute
+	KindInstanceVariable = types.KindInstanceVariable
+)
+
+const (
+	VisibilityPublic    = types.VisibilityPublic
+	VisibilityProtected = types.VisibilityProtected
+	VisibilityPrivate   = types.VisibilityPrivate
 )
@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"os"
 	"regexp"
+	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // TrigramIndex provides text search across the codebase
@@ -18,6 +20,9 @@ type TrigramIndex struct {
 
 	// File content cache for verification
 	files map[string]string
+
+	// Most files verified at once by a search, 0 to size by CPU count
+	maxWorkers atomic.Int32
 }
 
 // NewTrigramIndex creates a new trigram index
@@ -84,19 +89,35 @@ func (t *TrigramIndex) Search(pattern string) []*Reference {
 	// Build word boundary regex for verification
 	pinfo := buildPatternInfo(pattern)
 
-	var refs []*Reference
-
+	paths := make([]string, 0, len(candidates))
 	for path := range candidates {
-		content, ok := t.files[path]
-		if !ok {
-			continue
+		if _, ok := t.files[path]; ok {
+			paths = append(paths, path)
 		}
+	}
 
-		// Verify matches line by line
-		lineRefs := t.searchInContentWithInfo(path, content, pinfo, len(pattern))
-		refs = append(refs, lineRefs...)
+	// Verify matches line by line, splitting many files across workers
+	workers := 1
+	if len(paths) >= parallelSearchFiles {
+		workers = clampWorkers(runtime.GOMAXPROCS(0), int(t.maxWorkers.Load()), len(paths))
 	}
+	found := make([][]*Reference, workers)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w; i < len(paths); i += workers {
+				found[w] = append(found[w], t.searchInContentWithInfo(paths[i], t.files[paths[i]], pinfo, len(pattern))...)
+			}
+		}()
+	}
+	wg.Wait()
 
+	var refs []*Reference
+	for _, part := range found {
+		refs = append(refs, part...)
+	}
 	return refs
 }
 
@@ -199,6 +220,21 @@ func (t *TrigramIndex) SearchFile(path, pattern string) []*Reference {
 	return t.searchInContent(path, content, wordPattern)
 }
 
+// Content returns the indexed content of a file
+func (t *TrigramIndex) Content(path string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	content, ok := t.files[path]
+	return content, ok
+}
+
+// SearchContent searches one file's content, such as an editor buffer with
+// unsaved edits, instead of the indexed copy
+func (t *TrigramIndex) SearchContent(path, content, pattern string) []*Reference {
+	return t.searchInContentWithInfo(path, content, buildPatternInfo(pattern), len(pattern))
+}
+
 // rubyMethodSuffix tracks if a pattern ends with Ruby method suffix
 type patternInfo struct {
 	regex           *regexp.Regexp
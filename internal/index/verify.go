@@ -0,0 +1,104 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"io/fs"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// contentHash fingerprints file content for drift checks
+func contentHash(content []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(content)
+	return h.Sum64()
+}
+
+// VerifyResult lists the files a verification pass repaired
+type VerifyResult struct {
+	Checked int
+	Changed []string // Reindexed: the file on disk no longer matched the index
+	Removed []string // Dropped: the file no longer exists
+}
+
+// Verify compares the indexed content of up to n files with the files on
+// disk and reindexes or drops the ones that drifted, such as after a missed
+// watcher event. Each call continues where the last left off, so repeated
+// calls cycle through the whole index. Files last indexed from an open
+// editor buffer are skipped: the buffer, not the disk, is their source.
+func (idx *Index) Verify(n int) VerifyResult {
+	idx.mu.Lock()
+	var paths []string
+	for path := range idx.hashes {
+		if _, open := idx.versions[path]; !open {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	start := sort.SearchStrings(paths, idx.verifyCursor)
+	if start < len(paths) && paths[start] == idx.verifyCursor {
+		start++
+	}
+	if start >= len(paths) {
+		start = 0
+	}
+	sample := paths[start:min(start+n, len(paths))]
+	if len(sample) > 0 {
+		idx.verifyCursor = sample[len(sample)-1]
+	}
+
+	want := make(map[string]uint64, len(sample))
+	for _, path := range sample {
+		want[path] = idx.hashes[path]
+	}
+	idx.mu.Unlock()
+
+	result := VerifyResult{Checked: len(sample)}
+	for _, path := range sample {
+		content, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			result.Removed = append(result.Removed, path)
+		case err != nil:
+			log.Printf("verify: can't read %s: %v", path, err)
+		case contentHash(content) != want[path]:
+			result.Changed = append(result.Changed, path)
+		}
+	}
+
+	if len(result.Changed) > 0 || len(result.Removed) > 0 {
+		idx.ApplyBatch(result.Changed, result.Removed)
+		for _, path := range result.Changed {
+			log.Printf("verify: reindexed %s, which changed without a watcher event", path)
+		}
+		for _, path := range result.Removed {
+			log.Printf("verify: dropped %s, which was deleted without a watcher event", path)
+		}
+	}
+	return result
+}
+
+// RunVerifier checks sample files against the disk every interval until ctx
+// is done, repairing drift the watcher missed. It's meant to run in the
+// background at a gentle pace: at the defaults, a 10,000-file project is
+// fully rechecked about every half hour.
+func (idx *Index) RunVerifier(ctx context.Context, interval time.Duration, sample int) {
+	if interval <= 0 || sample <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			idx.Verify(sample)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyRepairsDrift(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	order := write("order.rb", "class Order\nend\n")
+	invoice := write("invoice.rb", "class Invoice\nend\n")
+	cart := write("cart.rb", "class Cart\nend\n")
+	open := write("draft.rb", "class Draft\nend\n")
+
+	idx := New(root, newTestIndex().registry)
+	for _, path := range []string{order, invoice, cart} {
+		if err := idx.AddFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx.UpdateContent(open, []byte("class Draft\n  def unsaved\n  end\nend\n"), 3)
+
+	// Changes the watcher never reported
+	write("order.rb", "class Order\n  def total\n  end\nend\n")
+	if err := os.Remove(cart); err != nil {
+		t.Fatal(err)
+	}
+
+	// Passes cycle through the files in order; the open buffer is skipped
+	first := idx.Verify(2)
+	second := idx.Verify(2)
+	if first.Checked != 2 || second.Checked != 1 {
+		t.Fatalf("expected 2 then 1 files checked, got %d and %d", first.Checked, second.Checked)
+	}
+	changed := append(first.Changed, second.Changed...)
+	removed := append(first.Removed, second.Removed...)
+	if len(changed) != 1 || changed[0] != order {
+		t.Errorf("expected %s reindexed, got %v", order, changed)
+	}
+	if len(removed) != 1 || removed[0] != cart {
+		t.Errorf("expected %s dropped, got %v", cart, removed)
+	}
+
+	if len(idx.FindDefinitions("Order#total")) != 1 {
+		t.Error("expected the changed file's new method to be indexed")
+	}
+	if len(idx.FindDefinitions("Cart")) != 0 {
+		t.Error("expected the deleted file's class to be dropped")
+	}
+	if len(idx.FindDefinitions("Draft#unsaved")) != 1 {
+		t.Error("expected the open buffer's content to be kept")
+	}
+
+	// Nothing left to repair
+	if result := idx.Verify(10); len(result.Changed)+len(result.Removed) != 0 {
+		t.Errorf("expected no more drift, got %+v", result)
+	}
+}
@@ -0,0 +1,90 @@
+package index
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/parser"
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// A constant path with at least one qualifier: Billing::TIMEOUT,
+// ::Billing::Gateway::Client. The first group is what precedes it, so a
+// path isn't matched from the middle of a name.
+var qualifiedConstantPattern = regexp.MustCompile(`(^|[^\w:])((?:::)?[A-Z]\w*(?:::[A-Z]\w*)+)`)
+
+// PrivateConstantReference is a constant referenced through its namespace
+// from outside it, after the namespace made it private with
+// private_constant (Ruby raises NameError)
+type PrivateConstantReference struct {
+	Line      int // 1-based
+	Column    int // Start of the constant's name
+	EndColumn int
+	Constant  string // Full name of the private constant
+	Owner     string // The class or module that made it private
+}
+
+// PrivateConstantReferences finds the references in content, parsed as the
+// file at path, to private constants of a class or module made from
+// outside it. Inside the owner (or a namespace nested in it) the constant
+// is accessible, as it is when referenced unqualified.
+func (idx *Index) PrivateConstantReferences(path, content string) []PrivateConstantReference {
+	symbols := idx.scanner.Parse(path, []byte(content))
+	namespaceAt := func(line int) string {
+		sym := innermostContainer(symbols, line, func(sym *Symbol) bool {
+			return sym.Kind == types.KindClass || sym.Kind == types.KindModule
+		})
+		if sym == nil {
+			return ""
+		}
+		return sym.FullName
+	}
+
+	var result []PrivateConstantReference
+	for i, line := range strings.Split(content, "\n") {
+		masked := parser.MaskStrings(line)
+		for _, loc := range qualifiedConstantPattern.FindAllStringSubmatchIndex(masked, -1) {
+			start := loc[4]
+			segments := strings.Split(strings.TrimPrefix(masked[start:loc[5]], "::"), "::")
+			if strings.HasPrefix(masked[start:], "::") {
+				start += 2
+			}
+			// Each step of the path is checked against the namespace before it
+			col := start + len(segments[0]) + 2
+			for n := 1; n < len(segments); n++ {
+				qualifier := masked[loc[4] : col-2]
+				if ref, ok := idx.privateConstantReference(qualifier, segments[n], path, i+1, namespaceAt(i+1)); ok {
+					ref.Column, ref.EndColumn = col, col+len(segments[n])
+					result = append(result, ref)
+				}
+				col += len(segments[n]) + 2
+			}
+		}
+	}
+	return result
+}
+
+// privateConstantReference checks a reference to name through qualifier,
+// written at line of path inside the namespace enclosing
+func (idx *Index) privateConstantReference(qualifier, name, path string, line int, enclosing string) (PrivateConstantReference, bool) {
+	owners, _ := idx.ResolveDefinitions(qualifier, path, line)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	for _, owner := range owners {
+		if owner.Kind != types.KindClass && owner.Kind != types.KindModule {
+			continue
+		}
+		if enclosing == owner.FullName || strings.HasPrefix(enclosing, owner.FullName+"::") {
+			continue
+		}
+		fullName := owner.FullName + "::" + name
+		for _, sym := range idx.symbols[fullName] {
+			if sym.Visibility == types.VisibilityPrivate {
+				return PrivateConstantReference{Line: line, Constant: fullName, Owner: owner.FullName}, true
+			}
+		}
+	}
+	return PrivateConstantReference{}, false
+}
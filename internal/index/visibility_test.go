@@ -0,0 +1,57 @@
+package index
+
+import "testing"
+
+func TestPrivateConstantReferences(t *testing.T) {
+	idx := newTestIndex()
+	gateway := `module Billing
+  class Gateway
+    TIMEOUT = 5
+    RETRIES = 3
+    class Client
+    end
+    private_constant :TIMEOUT, :Client
+
+    def call
+      Gateway::TIMEOUT
+    end
+  end
+end`
+	idx.addContent("/test/billing/gateway.rb", gateway)
+
+	content := `class Checkout
+  def run
+    Billing::Gateway::TIMEOUT + Billing::Gateway::RETRIES
+    ::Billing::Gateway::Client.new # Billing::Gateway::TIMEOUT
+    "Billing::Gateway::TIMEOUT"
+  end
+end
+
+module Billing
+  class Gateway
+    class Retry
+      def wait
+        Billing::Gateway::TIMEOUT
+      end
+    end
+  end
+end`
+	refs := idx.PrivateConstantReferences("/test/checkout.rb", content)
+	if len(refs) != 2 {
+		t.Fatalf("expected TIMEOUT and Client from Checkout, got %+v", refs)
+	}
+	want := []PrivateConstantReference{
+		{Line: 3, Column: 22, EndColumn: 29, Constant: "Billing::Gateway::TIMEOUT", Owner: "Billing::Gateway"},
+		{Line: 4, Column: 24, EndColumn: 30, Constant: "Billing::Gateway::Client", Owner: "Billing::Gateway"},
+	}
+	for i, w := range want {
+		if refs[i] != w {
+			t.Errorf("expected %+v, got %+v", w, refs[i])
+		}
+	}
+
+	// Inside the owner the constant is accessible
+	if refs := idx.PrivateConstantReferences("/test/billing/gateway.rb", gateway); len(refs) != 0 {
+		t.Errorf("expected no references flagged inside Gateway, got %+v", refs)
+	}
+}
@@ -0,0 +1,273 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"github.com/jarredhawkins/goruby-lsp/internal/lsp"
+	"github.com/jarredhawkins/goruby-lsp/internal/parser"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// callTimeout bounds each request so a hung server fails the test instead
+// of stalling the run
+const callTimeout = 10 * time.Second
+
+// client is the editor side of a server started over in-memory pipes
+type client struct {
+	t    *testing.T
+	root string // Absolute path of the fixture project
+	conn jsonrpc2.Conn
+
+	initialized lsp.InitializeResult // The server's reply to initialize
+
+	mu            sync.Mutex
+	notifications []notification // Sent by the server, in order
+}
+
+// notification is a server-to-client notification
+type notification struct {
+	method string
+	params json.RawMessage
+}
+
+// startServer indexes the fixture project testdata/<fixture>, serves it on
+// in-memory pipes, and completes the initialize handshake. The server is
+// shut down when the test ends.
+func startServer(t *testing.T, fixture string) *client {
+	t.Helper()
+
+	root, err := filepath.Abs(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatalf("failed to resolve fixture: %v", err)
+	}
+	if _, err := os.Stat(root); err != nil {
+		t.Fatalf("missing fixture: %v", err)
+	}
+
+	registry := parser.NewRegistry()
+	parser.RegisterDefaults(registry)
+	idx := index.New(root, registry)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := idx.Build(ctx); err != nil {
+		cancel()
+		t.Fatalf("failed to build index: %v", err)
+	}
+
+	// Two pipes make a full-duplex connection: the client writes what the
+	// server reads and vice versa
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	server := lsp.NewServer(idx)
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(ctx, serverIn, serverOut)
+	}()
+
+	c := &client{t: t, root: root}
+	c.conn = jsonrpc2.NewConn(jsonrpc2.NewStream(pipeConn{clientIn, clientOut}))
+	c.conn.Go(ctx, c.handle)
+
+	t.Cleanup(func() {
+		shutdownCtx, done := context.WithTimeout(context.Background(), callTimeout)
+		defer done()
+		if _, err := c.conn.Call(shutdownCtx, "shutdown", nil, nil); err != nil {
+			t.Errorf("shutdown failed: %v", err)
+		}
+		if err := c.conn.Notify(shutdownCtx, "exit", nil); err != nil {
+			t.Errorf("exit failed: %v", err)
+		}
+		clientOut.Close()
+		serverOut.Close()
+		select {
+		case err := <-served:
+			if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrClosedPipe) {
+				t.Errorf("server stopped with error: %v", err)
+			}
+		case <-shutdownCtx.Done():
+			t.Error("server did not stop after the connection closed")
+		}
+		cancel()
+	})
+
+	c.call("initialize", map[string]interface{}{
+		"processId": os.Getpid(),
+		"rootUri":   "file://" + root,
+		"capabilities": map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"definition": map[string]interface{}{"linkSupport": false},
+			},
+		},
+	}, &c.initialized)
+	if c.initialized.ServerInfo == nil || c.initialized.ServerInfo.Name == "" {
+		t.Fatalf("expected serverInfo in initialize result, got %+v", c.initialized)
+	}
+	c.notify("initialized", map[string]interface{}{})
+
+	return c
+}
+
+// handle records server notifications and declines server requests
+func (c *client) handle(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	if _, isCall := req.(*jsonrpc2.Call); isCall {
+		return reply(ctx, nil, jsonrpc2.ErrMethodNotFound)
+	}
+	c.mu.Lock()
+	c.notifications = append(c.notifications, notification{method: req.Method(), params: req.Params()})
+	c.mu.Unlock()
+	return nil
+}
+
+// call sends a request and decodes its result into result, failing the
+// test on an error response
+func (c *client) call(method string, params, result interface{}) {
+	c.t.Helper()
+	if err := c.tryCall(method, params, result); err != nil {
+		c.t.Fatalf("%s failed: %v", method, err)
+	}
+}
+
+// tryCall sends a request and returns its error response, if any
+func (c *client) tryCall(method string, params, result interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	_, err := c.conn.Call(ctx, method, params, result)
+	return err
+}
+
+// notify sends a notification
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	if err := c.conn.Notify(ctx, method, params); err != nil {
+		c.t.Fatalf("%s failed: %v", method, err)
+	}
+}
+
+// path returns the absolute path of a fixture file
+func (c *client) path(rel string) string {
+	return filepath.Join(c.root, filepath.FromSlash(rel))
+}
+
+// uri returns the URI of a fixture file
+func (c *client) uri(rel string) string {
+	return "file://" + c.path(rel)
+}
+
+// read returns the content of a fixture file on disk
+func (c *client) read(rel string) string {
+	c.t.Helper()
+	content, err := os.ReadFile(c.path(rel))
+	if err != nil {
+		c.t.Fatalf("failed to read fixture: %v", err)
+	}
+	return string(content)
+}
+
+// open sends didOpen for a fixture file with its content on disk
+func (c *client) open(rel string) {
+	c.t.Helper()
+	c.notify("textDocument/didOpen", lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{
+			URI:        c.uri(rel),
+			LanguageID: "ruby",
+			Version:    1,
+			Text:       c.read(rel),
+		},
+	})
+}
+
+// change replaces the open document's content as an unsaved edit
+func (c *client) change(rel string, version int, text string) {
+	c.t.Helper()
+	params := lsp.DidChangeTextDocumentParams{
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: text}},
+	}
+	params.TextDocument.URI = c.uri(rel)
+	params.TextDocument.Version = version
+	c.notify("textDocument/didChange", params)
+}
+
+// at returns the position of the first occurrence of needle in a fixture
+// file, offset by the given number of characters. The needle may span
+// lines, e.g. "amount\n" for a bare use at the end of a line.
+func (c *client) at(rel, needle string, offset int) lsp.TextDocumentPositionParams {
+	c.t.Helper()
+	return positionIn(c.t, c.uri(rel), c.read(rel), needle, offset)
+}
+
+// positionIn finds needle in content, as at does
+func positionIn(t *testing.T, uri, content, needle string, offset int) lsp.TextDocumentPositionParams {
+	t.Helper()
+	i := strings.Index(content, needle)
+	if i < 0 {
+		t.Fatalf("%q not found in %s", needle, uri)
+	}
+	i += offset
+	line := strings.Count(content[:i], "\n")
+	col := i - (strings.LastIndex(content[:i], "\n") + 1)
+	return lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: uri},
+		Position:     lsp.Position{Line: uint32(line), Character: uint32(col)},
+	}
+}
+
+// definition requests the definitions at params, accepting both a single
+// Location and a list
+func (c *client) definition(params lsp.TextDocumentPositionParams) []lsp.Location {
+	c.t.Helper()
+	var raw json.RawMessage
+	c.call("textDocument/definition", params, &raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var locations []lsp.Location
+	if raw[0] == '{' {
+		var loc lsp.Location
+		if err := json.Unmarshal(raw, &loc); err != nil {
+			c.t.Fatalf("failed to decode definition: %v", err)
+		}
+		return []lsp.Location{loc}
+	}
+	if err := json.Unmarshal(raw, &locations); err != nil {
+		c.t.Fatalf("failed to decode definitions: %v", err)
+	}
+	return locations
+}
+
+// pipeConn joins the two halves of the client's side of the connection
+type pipeConn struct {
+	io.Reader
+	io.WriteCloser
+}
+
+// waitNotification returns the params of the first notification with the
+// given method, waiting briefly for it to arrive
+func (c *client) waitNotification(method string) json.RawMessage {
+	c.t.Helper()
+	deadline := time.Now().Add(callTimeout)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		for _, n := range c.notifications {
+			if n.method == method {
+				c.mu.Unlock()
+				return n.params
+			}
+		}
+		c.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.t.Fatalf("no %s notification received", method)
+	return nil
+}
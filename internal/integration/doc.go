@@ -0,0 +1,4 @@
+// Package integration tests the language server end to end: a real
+// Server.Serve loop over in-memory pipes, driven through JSON-RPC by a test
+// client against the fixture projects in testdata.
+package integration
@@ -0,0 +1,150 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/lsp"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// locationKeys renders locations as sorted "file:line:char" keys, with the
+// file relative to the fixture root and 1-based lines as in an editor
+func locationKeys(c *client, locations []lsp.Location) []string {
+	keys := make([]string, 0, len(locations))
+	for _, loc := range locations {
+		rel := strings.TrimPrefix(loc.URI, "file://"+c.root+"/")
+		keys = append(keys, fmt.Sprintf("%s:%d:%d", rel, loc.Range.Start.Line+1, loc.Range.Start.Character))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// editKeys renders a workspace edit as sorted "file:line:char=newText" keys
+func editKeys(c *client, edit lsp.WorkspaceEdit) []string {
+	var keys []string
+	for uri, edits := range edit.Changes {
+		rel := strings.TrimPrefix(uri, "file://"+c.root+"/")
+		for _, e := range edits {
+			keys = append(keys, fmt.Sprintf("%s:%d:%d=%s", rel, e.Range.Start.Line+1, e.Range.Start.Character, e.NewText))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func expectKeys(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("%s:\n  want %v\n   got %v", what, want, got)
+	}
+}
+
+func TestInitializeAdvertisesCapabilities(t *testing.T) {
+	c := startServer(t, "shop")
+
+	caps := c.initialized.Capabilities
+	if !caps.DefinitionProvider || !caps.ReferencesProvider || !caps.RenameProvider {
+		t.Errorf("expected definition, references, and rename support, got %+v", caps)
+	}
+	if caps.TextDocumentSync == nil || !caps.TextDocumentSync.OpenClose {
+		t.Errorf("expected open/close sync, got %+v", caps.TextDocumentSync)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	c := startServer(t, "shop")
+	c.open("app/services/checkout.rb")
+
+	tests := []struct {
+		name   string
+		needle string
+		offset int
+		want   []string
+	}{
+		{"qualified constant", "Shop::Order.find", 7, []string{"app/models/order.rb:2:8"}},
+		{"method on typed local", "order.total", 6, []string{"app/models/order.rb:5:8"}},
+		{"local variable", "    amount\n", 4, []string{"app/services/checkout.rb:8:4"}},
+	}
+	for _, tt := range tests {
+		locations := c.definition(c.at("app/services/checkout.rb", tt.needle, tt.offset))
+		expectKeys(t, tt.name, locationKeys(c, locations), tt.want)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	c := startServer(t, "shop")
+	c.open("app/models/order.rb")
+
+	params := lsp.ReferenceParams{TextDocumentPositionParams: c.at("app/models/order.rb", "def total", 4)}
+	params.Context.IncludeDeclaration = true
+
+	var locations []lsp.Location
+	c.call("textDocument/references", params, &locations)
+	expectKeys(t, "references", locationKeys(c, locations), []string{
+		"app/models/order.rb:5:8",
+		"app/services/checkout.rb:8:19",
+	})
+}
+
+func TestReferencesWarnAboutCommonNames(t *testing.T) {
+	c := startServer(t, "shop")
+	c.open("app/services/checkout.rb")
+
+	params := lsp.ReferenceParams{TextDocumentPositionParams: c.at("app/services/checkout.rb", "def call", 4)}
+	var locations []lsp.Location
+	c.call("textDocument/references", params, &locations)
+	expectKeys(t, "references", locationKeys(c, locations), []string{
+		"app/controllers/orders_controller.rb:5:13",
+		"app/services/checkout.rb:6:6",
+	})
+
+	var msg lsp.ShowMessageParams
+	if err := json.Unmarshal(c.waitNotification("window/showMessage"), &msg); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	if msg.Type != lsp.MessageTypeWarning || !strings.Contains(msg.Message, "call") {
+		t.Errorf("expected a warning about call, got %+v", msg)
+	}
+}
+
+func TestRename(t *testing.T) {
+	c := startServer(t, "shop")
+	c.open("app/models/order.rb")
+
+	var edit lsp.WorkspaceEdit
+	c.call("textDocument/rename", lsp.RenameParams{
+		TextDocumentPositionParams: c.at("app/models/order.rb", "def place!", 4),
+		NewName:                    "submit!",
+	}, &edit)
+	expectKeys(t, "rename edits", editKeys(c, edit), []string{
+		"app/models/order.rb:9:8=submit",
+		"app/services/checkout.rb:9:10=submit",
+	})
+
+	// Renaming to a keyword is refused with an error response
+	err := c.tryCall("textDocument/rename", lsp.RenameParams{
+		TextDocumentPositionParams: c.at("app/models/order.rb", "def total", 4),
+		NewName:                    "class",
+	}, &edit)
+	var rpcErr *jsonrpc2.Error
+	if !errors.As(err, &rpcErr) || !strings.Contains(rpcErr.Message, "keyword") {
+		t.Errorf("expected a keyword error, got %v", err)
+	}
+}
+
+func TestUnsavedEditsAreIndexed(t *testing.T) {
+	c := startServer(t, "shop")
+	c.open("app/models/line_item.rb")
+
+	edited := strings.Replace(c.read("app/models/line_item.rb"), "def price", "def discounted_price", 1)
+	c.change("app/models/line_item.rb", 2, edited)
+
+	uri := c.uri("app/models/line_item.rb")
+	locations := c.definition(positionIn(t, uri, edited, "discounted_price", 0))
+	expectKeys(t, "definitions", locationKeys(c, locations), []string{"app/models/line_item.rb:5:8"})
+}
@@ -0,0 +1,8 @@
+class OrdersController < ApplicationController
+  def create
+    order = Shop::Order.find(params[:id])
+    checkout = Checkout.new(order)
+    checkout.call
+    redirect_to order
+  end
+end
@@ -0,0 +1,13 @@
+module Shop
+  class LineItem < ApplicationRecord
+    belongs_to :order
+
+    def price
+      quantity * unit_price
+    end
+
+    def notify(callback)
+      callback.call(self)
+    end
+  end
+end
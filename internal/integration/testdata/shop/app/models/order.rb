@@ -0,0 +1,13 @@
+module Shop
+  class Order < ApplicationRecord
+    has_many :line_items
+
+    def total
+      line_items.sum(&:price)
+    end
+
+    def place!
+      update!(placed: true)
+    end
+  end
+end
@@ -0,0 +1,12 @@
+class Checkout
+  def initialize(order)
+    @order = order
+  end
+
+  def call
+    order = Shop::Order.find(@order.id)
+    amount = order.total
+    order.place!
+    amount
+  end
+end
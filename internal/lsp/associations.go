@@ -0,0 +1,79 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+// AssociationReference is an association targeting a class, returned by the
+// goruby/associationReferences request
+type AssociationReference struct {
+	Location Location `json:"location"`
+	// Label reads like "Order has_many :items (inverse: Item belongs_to :order)"
+	Label   string    `json:"label"`
+	Owner   string    `json:"owner"`
+	Macro   string    `json:"macro"`
+	Name    string    `json:"name"`
+	Inverse *Location `json:"inverse,omitempty"`
+}
+
+// handleAssociationReferences lists the associations targeting the class
+// under the cursor, paired with their inverse association when one exists
+func (s *Server) handleAssociationReferences(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	content := s.getDocumentContent(params.TextDocument.URI)
+	s.syncDocument(params.TextDocument.URI)
+	word := extractWordAt(content, int(params.Position.Line), int(params.Position.Character))
+	if word == "" {
+		return reply(ctx, nil, nil)
+	}
+	className := strings.TrimPrefix(word, "::")
+
+	results := s.associationReferences(className)
+	log.Printf("association references for %s: %d", className, len(results))
+	return reply(ctx, results, nil)
+}
+
+// associationReferences lists the associations targeting a class, each
+// paired with its inverse association when one exists
+func (s *Server) associationReferences(className string) []AssociationReference {
+	rels := s.index.FindAssociationsTargeting(className)
+	results := make([]AssociationReference, 0, len(rels))
+	for _, rel := range rels {
+		owner := strings.Join(rel.Scope, "::")
+		ref := AssociationReference{
+			Location: symbolToLocation(rel),
+			Label:    fmt.Sprintf("%s %s :%s", owner, rel.RelationType, rel.Name),
+			Owner:    owner,
+			Macro:    rel.RelationType,
+			Name:     rel.Name,
+		}
+		if inverse := s.index.FindInverseAssociation(rel); inverse != nil {
+			loc := symbolToLocation(inverse)
+			ref.Inverse = &loc
+			ref.Label += fmt.Sprintf(" (inverse: %s %s :%s)",
+				strings.Join(inverse.Scope, "::"), inverse.RelationType, inverse.Name)
+		}
+		results = append(results, ref)
+	}
+
+	return results
+}
+
+// handleAssociationGraph replies with the model association graph, for
+// clients that draw the domain model
+func (s *Server) handleAssociationGraph(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	return reply(ctx, s.index.AssociationGraph(), nil)
+}
@@ -0,0 +1,61 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAssociationReferences(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "lsp-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"order.rb": `class Order
+  has_many :items, inverse_of: :order
+end
+`,
+		"item.rb": `class Item
+  belongs_to :order
+end
+`,
+	}
+	s := newTestServer(tmpDir)
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		if err := s.index.AddFile(path); err != nil {
+			t.Fatalf("failed to index %s: %v", name, err)
+		}
+	}
+
+	raw, err := callHandler(t, s, "goruby/associationReferences", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: pathToURI(filepath.Join(tmpDir, "item.rb"))},
+		Position:     Position{Line: 0, Character: 7}, // on "Item"
+	})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	var refs []AssociationReference
+	if err := json.Unmarshal(raw, &refs); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(refs) != 1 {
+		t.Fatalf("expected 1 association, got %d: %s", len(refs), raw)
+	}
+
+	want := "Order has_many :items (inverse: Item belongs_to :order)"
+	if refs[0].Label != want {
+		t.Errorf("expected label %q, got %q", want, refs[0].Label)
+	}
+	if refs[0].Inverse == nil || refs[0].Inverse.Range.Start.Line != 1 {
+		t.Errorf("expected inverse location on line 1, got %+v", refs[0].Inverse)
+	}
+}
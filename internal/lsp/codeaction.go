@@ -0,0 +1,161 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// CodeActionQuickFix is the kind of the built-in code actions
+const CodeActionQuickFix = "quickfix"
+
+// CodeActionParams for textDocument/codeAction
+type CodeActionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Context      struct {
+		Only []string `json:"only,omitempty"`
+	} `json:"context"`
+}
+
+// CodeAction is an edit the client can offer to apply
+type CodeAction struct {
+	Title string         `json:"title"`
+	Kind  string         `json:"kind,omitempty"`
+	Edit  *WorkspaceEdit `json:"edit,omitempty"`
+}
+
+// codeActionRequest is what a code action provider sees: the document and
+// the selected range (0-based lines)
+type codeActionRequest struct {
+	uri     string
+	path    string
+	content string
+	rng     Range
+}
+
+// codeActionProvider offers the actions that apply to a request, or none
+type codeActionProvider func(s *Server, req codeActionRequest) []CodeAction
+
+// codeActionProviders are consulted in order; add new actions here
+var codeActionProviders = []codeActionProvider{
+	explicitClassNameActions,
+	qualifyConstantActions,
+}
+
+// actionKindAllowed reports whether kind is requested by only (all kinds
+// when empty). A requested kind also allows its sub-kinds.
+func actionKindAllowed(kind string, only []string) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, k := range only {
+		if kind == k || strings.HasPrefix(kind, k+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// singleEdit is a workspace edit replacing one range of a document
+func singleEdit(uri string, rng Range, newText string) *WorkspaceEdit {
+	return &WorkspaceEdit{Changes: map[string][]TextEdit{
+		uri: {{Range: rng, NewText: newText}},
+	}}
+}
+
+// explicitClassNameActions offers to spell out the class an association
+// infers from its name (has_many :comments → class_name: "Comment"). When
+// nothing defines the inferred class (has_many :criteria infers Criteria),
+// the closest indexed class is offered instead (class_name: "Criterion").
+func explicitClassNameActions(s *Server, req codeActionRequest) []CodeAction {
+	var actions []CodeAction
+	for _, sym := range s.index.ParseContent(req.path, req.content) {
+		line := uint32(sym.Line - 1)
+		if sym.Kind != index.KindRelation || line < req.rng.Start.Line || line > req.rng.End.Line {
+			continue
+		}
+		if sym.Options["class_name"] != "" || sym.Options["through"] != "" || sym.Options["polymorphic"] != "" {
+			continue
+		}
+		target, fullName := s.index.RelationTarget(sym)
+		title := fmt.Sprintf("Add explicit class_name: %q to %s", fullName, sym.Name)
+		if fullName == "" {
+			// The inferred class isn't defined; suggest the closest one
+			if fullName = s.index.ClosestClass(sym); fullName == "" {
+				continue
+			}
+			title = fmt.Sprintf("Add class_name: %q to %s (%s isn't defined)", fullName, sym.Name, target)
+		}
+		at := Position{Line: line, Character: uint32(sym.Column + len(sym.Name))}
+		actions = append(actions, CodeAction{
+			Title: title,
+			Kind:  CodeActionQuickFix,
+			Edit:  singleEdit(req.uri, Range{Start: at, End: at}, fmt.Sprintf(", class_name: %q", fullName)),
+		})
+	}
+	return actions
+}
+
+// qualifyConstantActions offers to replace the constant at the start of the
+// range with its fully qualified name (Invoice → Billing::Invoice)
+func qualifyConstantActions(s *Server, req codeActionRequest) []CodeAction {
+	line, char := int(req.rng.Start.Line), int(req.rng.Start.Character)
+	word, start, end := wordRangeAt(req.content, line, char)
+	if word == "" || !unicode.IsUpper(rune(strings.TrimPrefix(word, "::")[0])) {
+		return nil
+	}
+
+	fullName := ""
+	for _, sym := range s.index.FindDefinitionsInContext(word, req.path, line+1) {
+		if !sym.Kind.IsConstant() || (fullName != "" && sym.FullName != fullName) {
+			return nil // Ambiguous, or not a constant
+		}
+		fullName = sym.FullName
+	}
+	if fullName == "" || fullName == strings.TrimPrefix(word, "::") {
+		return nil
+	}
+
+	rng := Range{
+		Start: Position{Line: uint32(line), Character: uint32(start)},
+		End:   Position{Line: uint32(line), Character: uint32(end)},
+	}
+	return []CodeAction{{
+		Title: "Qualify as " + fullName,
+		Kind:  CodeActionQuickFix,
+		Edit:  singleEdit(req.uri, rng, fullName),
+	}}
+}
+
+// handleCodeAction collects the actions every provider offers for the
+// selected range
+func (s *Server) handleCodeAction(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params CodeActionParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	content := s.getDocumentContent(uri)
+	s.syncDocument(uri)
+
+	request := codeActionRequest{uri: uri, path: uriToPath(uri), content: content, rng: params.Range}
+	actions := []CodeAction{}
+	for _, provide := range codeActionProviders {
+		for _, action := range provide(s, request) {
+			if actionKindAllowed(action.Kind, params.Context.Only) {
+				actions = append(actions, action)
+			}
+		}
+	}
+	return reply(ctx, actions, nil)
+}
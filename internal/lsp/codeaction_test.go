@@ -0,0 +1,105 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCodeActions(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/comment.rb", []byte("class Comment\nend\n"), 0)
+	s.index.UpdateContent("/app/billing/invoice.rb", []byte("module Billing\n  class Invoice\n  end\nend\n"), 0)
+
+	uri := "file:///app/billing/account.rb"
+	content := `module Billing
+  class Account
+    has_many :comments
+    has_many :invoices, dependent: :destroy
+    has_many :notes, class_name: "Comment"
+    belongs_to :owner
+
+    def latest
+      Invoice.last
+    end
+  end
+end
+`
+	s.documents.Open(uri, 1, content)
+
+	actions := func(rng Range, only ...string) []CodeAction {
+		t.Helper()
+		params := CodeActionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Range: rng}
+		params.Context.Only = only
+		raw, err := callHandler(t, s, "textDocument/codeAction", params)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var result []CodeAction
+		if err := json.Unmarshal(raw, &result); err != nil {
+			t.Fatalf("failed to decode actions: %v", err)
+		}
+		return result
+	}
+	edit := func(action CodeAction) TextEdit {
+		t.Helper()
+		edits := action.Edit.Changes[uri]
+		if len(edits) != 1 {
+			t.Fatalf("expected one edit to %s, got %+v", uri, action.Edit)
+		}
+		return edits[0]
+	}
+
+	// Only associations whose target is indexed and not already explicit
+	got := actions(Range{Start: Position{Line: 2}, End: Position{Line: 5}})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 class_name actions, got %+v", got)
+	}
+	if e := edit(got[0]); e.NewText != `, class_name: "Comment"` || e.Range.Start != (Position{Line: 2, Character: 22}) {
+		t.Errorf("unexpected edit for comments: %+v", e)
+	}
+	if e := edit(got[1]); e.NewText != `, class_name: "Billing::Invoice"` || e.Range.Start != (Position{Line: 3, Character: 22}) {
+		t.Errorf("unexpected edit for invoices: %+v", e)
+	}
+
+	// A constant resolved through the enclosing namespace
+	at := Position{Line: 8, Character: 8}
+	got = actions(Range{Start: at, End: at})
+	if len(got) != 1 || got[0].Title != "Qualify as Billing::Invoice" || got[0].Kind != CodeActionQuickFix {
+		t.Fatalf("expected a qualify action, got %+v", got)
+	}
+	if e := edit(got[0]); e.NewText != "Billing::Invoice" || e.Range.Start.Character != 6 || e.Range.End.Character != 13 {
+		t.Errorf("unexpected qualify edit: %+v", e)
+	}
+
+	// Kinds the client didn't ask for are filtered out
+	if got := actions(Range{Start: at, End: at}, "refactor"); len(got) != 0 {
+		t.Errorf("expected no refactor actions, got %+v", got)
+	}
+}
+
+func TestClosestClassNameAction(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/criterion.rb", []byte("class Criterion\nend\n"), 0)
+
+	uri := "file:///app/survey.rb"
+	s.documents.Open(uri, 1, "class Survey\n  has_many :criteria\n  has_many :gadgets\nend\n")
+
+	raw, err := callHandler(t, s, "textDocument/codeAction", CodeActionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Range:        Range{Start: Position{Line: 1}, End: Position{Line: 2}},
+	})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var actions []CodeAction
+	if err := json.Unmarshal(raw, &actions); err != nil {
+		t.Fatalf("failed to decode actions: %v", err)
+	}
+	if len(actions) != 1 || actions[0].Title != `Add class_name: "Criterion" to criteria (Criteria isn't defined)` {
+		t.Fatalf("expected a class_name action for criteria only, got %+v", actions)
+	}
+	edits := actions[0].Edit.Changes[uri]
+	if len(edits) != 1 || edits[0].NewText != `, class_name: "Criterion"` || edits[0].Range.Start != (Position{Line: 1, Character: 20}) {
+		t.Errorf("unexpected edit: %+v", edits)
+	}
+}
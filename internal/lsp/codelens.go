@@ -0,0 +1,102 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+// runTestCommand is the client-side command code lenses invoke, with the
+// test's "file:line" (relative to the workspace root) as its one argument
+const runTestCommand = "goruby.runTest"
+
+// describe Order do / RSpec.describe "checkout" do / it "totals" do /
+// it { is_expected.to be_valid } / test "totals" do, but not context = {}
+var testBlockPattern = regexp.MustCompile(`^\s*(?:RSpec\.)?(?:describe|context|feature|it|specify|example|scenario|test)(?:\s+|\()(?:\{|[^=\s].*(?:\bdo\b|\{))`)
+
+// def test_totals (Minitest)
+var testMethodPattern = regexp.MustCompile(`^\s*def\s+test_\w+`)
+
+// CodeLensOptions advertises code lens support
+type CodeLensOptions struct {
+	ResolveProvider bool `json:"resolveProvider"`
+}
+
+// CodeLensParams for textDocument/codeLens
+type CodeLensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// Command is a client command with its arguments
+type Command struct {
+	Title     string        `json:"title"`
+	Command   string        `json:"command"`
+	Arguments []interface{} `json:"arguments,omitempty"`
+}
+
+// CodeLens is a command shown above a range
+type CodeLens struct {
+	Range   Range    `json:"range"`
+	Command *Command `json:"command,omitempty"`
+}
+
+// isTestPath reports whether a file holds RSpec or Minitest tests
+func isTestPath(path string) bool {
+	return strings.HasSuffix(path, "_spec.rb") || strings.HasSuffix(path, "_test.rb")
+}
+
+// testLenses returns a "Run test" lens above each example group, example,
+// and test method, with location (a path) and the 1-based line as payload
+func testLenses(location, content string) []CodeLens {
+	lenses := []CodeLens{}
+	for i, line := range strings.Split(content, "\n") {
+		if !testBlockPattern.MatchString(line) && !testMethodPattern.MatchString(line) {
+			continue
+		}
+		indent := uint32(len(line) - len(strings.TrimLeft(line, " \t")))
+		lenses = append(lenses, CodeLens{
+			Range: Range{
+				Start: Position{Line: uint32(i), Character: indent},
+				End:   Position{Line: uint32(i), Character: uint32(len(strings.TrimRight(line, " \t\r")))},
+			},
+			Command: &Command{
+				Title:     "Run test",
+				Command:   runTestCommand,
+				Arguments: []interface{}{fmt.Sprintf("%s:%d", location, i+1)},
+			},
+		})
+	}
+	return lenses
+}
+
+func (s *Server) handleCodeLens(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params CodeLensParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	path := uriToPath(uri)
+	if isBundlerFile(path) {
+		return reply(ctx, s.gemfileLenses(uri, path), nil)
+	}
+	if !isTestPath(path) {
+		return reply(ctx, []CodeLens{}, nil)
+	}
+
+	// Test runners are started from the project root, so pass them a
+	// path relative to it when the file is inside
+	location := path
+	if rel, err := filepath.Rel(s.index.RootPath(), path); err == nil && !strings.HasPrefix(rel, "..") {
+		location = filepath.ToSlash(rel)
+	}
+	return reply(ctx, testLenses(location, s.getDocumentContent(uri)), nil)
+}
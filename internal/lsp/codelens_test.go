@@ -0,0 +1,97 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCodeLensRunsTests(t *testing.T) {
+	s := newTestServer("/app")
+
+	uri := "file:///app/spec/models/order_spec.rb"
+	content := `require "rails_helper"
+
+RSpec.describe Order do
+  let(:order) { build(:order) }
+
+  context "when paid" do
+    it "is complete" do
+      expect(order).to be_complete
+    end
+
+    it { is_expected.to be_valid }
+  end
+
+  def helper
+    context = { paid: true }
+  end
+end
+`
+	s.documents.Open(uri, 1, content)
+
+	raw, err := callHandler(t, s, "textDocument/codeLens", CodeLensParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var lenses []CodeLens
+	if err := json.Unmarshal(raw, &lenses); err != nil {
+		t.Fatalf("failed to decode lenses: %v", err)
+	}
+
+	want := []string{
+		"spec/models/order_spec.rb:3",
+		"spec/models/order_spec.rb:6",
+		"spec/models/order_spec.rb:7",
+		"spec/models/order_spec.rb:11",
+	}
+	if len(lenses) != len(want) {
+		t.Fatalf("expected %d lenses, got %+v", len(want), lenses)
+	}
+	for i, lens := range lenses {
+		cmd := lens.Command
+		if cmd == nil || cmd.Command != runTestCommand || cmd.Title != "Run test" || len(cmd.Arguments) != 1 || cmd.Arguments[0] != want[i] {
+			t.Errorf("lens %d: expected to run %s, got %+v", i, want[i], cmd)
+		}
+	}
+	if lenses[2].Range.Start.Line != 6 || lenses[2].Range.Start.Character != 4 {
+		t.Errorf("expected the example's lens on its line, got %+v", lenses[2].Range)
+	}
+}
+
+func TestCodeLensMinitest(t *testing.T) {
+	s := newTestServer("/app")
+
+	uri := "file:///app/test/models/order_test.rb"
+	s.documents.Open(uri, 1, `class OrderTest < ActiveSupport::TestCase
+  test "totals line items" do
+    assert_equal 3, orders(:one).total
+  end
+
+  def test_empty_order
+    assert Order.new.empty?
+  end
+end
+`)
+	raw, _ := callHandler(t, s, "textDocument/codeLens", CodeLensParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+	var lenses []CodeLens
+	if err := json.Unmarshal(raw, &lenses); err != nil {
+		t.Fatalf("failed to decode lenses: %v", err)
+	}
+	if len(lenses) != 2 || lenses[0].Command.Arguments[0] != "test/models/order_test.rb:2" || lenses[1].Command.Arguments[0] != "test/models/order_test.rb:6" {
+		t.Errorf("expected lenses on lines 2 and 6, got %+v", lenses)
+	}
+
+	// Other files get none
+	uri = "file:///app/app/models/order.rb"
+	s.documents.Open(uri, 1, "class Order\n  def test_mode\n  end\nend\n")
+	raw, _ = callHandler(t, s, "textDocument/codeLens", CodeLensParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+	if err := json.Unmarshal(raw, &lenses); err != nil || len(lenses) != 0 {
+		t.Errorf("expected no lenses outside tests, got %+v (%v)", lenses, err)
+	}
+}
@@ -0,0 +1,128 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+// ExecuteCommandParams for workspace/executeCommand
+type ExecuteCommandParams struct {
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments,omitempty"`
+}
+
+// ExecuteCommandOptions lists the commands the server executes
+type ExecuteCommandOptions struct {
+	Commands []string `json:"commands"`
+}
+
+// command runs a workspace/executeCommand request with its arguments
+type command func(s *Server, ctx context.Context, args []json.RawMessage) (interface{}, error)
+
+// commands are the server's executeCommand commands by name
+var commands = map[string]command{
+	"goruby.reindex":       (*Server).reindexCommand,
+	"goruby.reindexPath":   (*Server).reindexPathCommand,
+	"goruby.indexStats":    (*Server).indexStatsCommand,
+	"goruby.findTargeting": (*Server).findTargetingCommand,
+	indexGemsCommand:       (*Server).indexGemsCommand,
+}
+
+// indexGemsCommand is the command the Gemfile's code lens runs
+const indexGemsCommand = "goruby.indexGems"
+
+// commandNames returns the names of the server's commands, sorted
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Server) handleExecuteCommand(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params ExecuteCommandParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	run, ok := commands[params.Command]
+	if !ok {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: "unknown command: " + params.Command,
+		})
+	}
+	result, err := run(s, ctx, params.Arguments)
+	if err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    codeRequestFailed,
+			Message: err.Error(),
+		})
+	}
+	return reply(ctx, result, nil)
+}
+
+// reindexPathCommand refreshes the index from a file or directory on disk,
+// given by URI, such as app/models after a generator run. It returns the
+// number of files brought up to date and removed.
+func (s *Server) reindexPathCommand(ctx context.Context, args []json.RawMessage) (interface{}, error) {
+	var uri string
+	if len(args) != 1 || json.Unmarshal(args[0], &uri) != nil || uri == "" {
+		return nil, fmt.Errorf("goruby.reindexPath takes a file or directory URI")
+	}
+	return s.index.ReindexPath(ctx, uriToPath(uri))
+}
+
+// reindexCommand refreshes the whole project's index from disk, for when
+// files changed without the watcher noticing (a branch switch with the
+// editor closed). It returns the number of files brought up to date and
+// removed.
+func (s *Server) reindexCommand(ctx context.Context, args []json.RawMessage) (interface{}, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("goruby.reindex takes no arguments")
+	}
+	return s.index.ReindexPath(ctx, s.index.RootPath())
+}
+
+// indexStatsCommand returns the index size and the timing of its last
+// build, like the goruby/indexStatus request
+func (s *Server) indexStatsCommand(ctx context.Context, args []json.RawMessage) (interface{}, error) {
+	return s.index.Status(), nil
+}
+
+// findTargetingCommand lists the associations targeting a class, given by
+// name (Order, Billing::Invoice), like the goruby/associationReferences
+// request does for the class under the cursor
+func (s *Server) findTargetingCommand(ctx context.Context, args []json.RawMessage) (interface{}, error) {
+	var className string
+	if len(args) != 1 || json.Unmarshal(args[0], &className) != nil || className == "" {
+		return nil, fmt.Errorf("goruby.findTargeting takes a class name")
+	}
+	return s.associationReferences(strings.TrimPrefix(className, "::")), nil
+}
+
+// indexGemsCommand indexes the installed gems a Bundler file's lockfile
+// resolves, given the file's URI (the project's Gemfile by default), for
+// definitions inside dependencies. It returns the number of files indexed.
+func (s *Server) indexGemsCommand(ctx context.Context, args []json.RawMessage) (interface{}, error) {
+	path := filepath.Join(s.index.RootPath(), "Gemfile")
+	if len(args) > 0 {
+		var uri string
+		if len(args) != 1 || json.Unmarshal(args[0], &uri) != nil || uri == "" {
+			return nil, fmt.Errorf("goruby.indexGems takes an optional Gemfile URI")
+		}
+		path = uriToPath(uri)
+	}
+	return s.indexGems(ctx, lockfilePath(path))
+}
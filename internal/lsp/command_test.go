@@ -0,0 +1,130 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+func TestReindexPathCommand(t *testing.T) {
+	root := t.TempDir()
+	models := filepath.Join(root, "app", "models")
+	if err := os.MkdirAll(models, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stale := filepath.Join(models, "stale.rb")
+	write(stale, "class Stale\nend\n")
+	write(filepath.Join(root, "other.rb"), "class Other\nend\n")
+
+	s := newTestServer(root)
+	if err := s.index.AddFile(stale); err != nil {
+		t.Fatal(err)
+	}
+
+	// A generator adds a model and removes another without the watcher noticing
+	write(filepath.Join(models, "invoice.rb"), "class Invoice\nend\n")
+	if err := os.Remove(stale); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := callHandler(t, s, "workspace/executeCommand", ExecuteCommandParams{
+		Command:   "goruby.reindexPath",
+		Arguments: []json.RawMessage{json.RawMessage(`"` + pathToURI(models) + `"`)},
+	})
+	if err != nil {
+		t.Fatalf("executeCommand failed: %v", err)
+	}
+	var result struct{ Files, Removed int }
+	if err := json.Unmarshal(raw, &result); err != nil || result.Files != 1 || result.Removed != 1 {
+		t.Errorf("expected 1 file reindexed and 1 removed, got %s", raw)
+	}
+	if len(s.index.FindDefinitions("Invoice")) != 1 || len(s.index.FindDefinitions("Stale")) != 0 {
+		t.Error("expected the directory's changes applied")
+	}
+	if len(s.index.FindDefinitions("Other")) != 0 {
+		t.Error("expected files outside the path to be left alone")
+	}
+
+	// Bad arguments and unknown commands are rejected
+	_, err = callHandler(t, s, "workspace/executeCommand", ExecuteCommandParams{Command: "goruby.reindexPath"})
+	if rpcErr, ok := err.(*jsonrpc2.Error); !ok || rpcErr.Code != codeRequestFailed {
+		t.Errorf("expected a failed request without a URI, got %v", err)
+	}
+	_, err = callHandler(t, s, "workspace/executeCommand", ExecuteCommandParams{Command: "goruby.nope"})
+	if rpcErr, ok := err.(*jsonrpc2.Error); !ok || rpcErr.Code != jsonrpc2.InvalidParams {
+		t.Errorf("expected invalid params for an unknown command, got %v", err)
+	}
+}
+
+func TestIndexCommands(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	order := write("order.rb", "class Order < ApplicationRecord\n  belongs_to :customer\nend\n")
+	customer := write("customer.rb", "class Customer < ApplicationRecord\n  has_many :orders\nend\n")
+
+	s := newTestServer(root)
+	for _, path := range []string{order, customer} {
+		if err := s.index.AddFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	raw, err := callHandler(t, s, "workspace/executeCommand", ExecuteCommandParams{
+		Command:   "goruby.findTargeting",
+		Arguments: []json.RawMessage{json.RawMessage(`"Customer"`)},
+	})
+	if err != nil {
+		t.Fatalf("findTargeting failed: %v", err)
+	}
+	var refs []AssociationReference
+	if err := json.Unmarshal(raw, &refs); err != nil {
+		t.Fatalf("failed to decode references: %v", err)
+	}
+	if len(refs) != 1 || refs[0].Owner != "Order" || refs[0].Inverse == nil {
+		t.Errorf("expected Order belongs_to :customer with its inverse, got %s", raw)
+	}
+
+	// A file deleted behind the watcher's back drops out on reindex
+	if err := os.Remove(order); err != nil {
+		t.Fatal(err)
+	}
+	write("invoice.rb", "class Invoice\nend\n")
+	raw, err = callHandler(t, s, "workspace/executeCommand", ExecuteCommandParams{Command: "goruby.reindex"})
+	if err != nil {
+		t.Fatalf("reindex failed: %v", err)
+	}
+	var result struct{ Files, Removed int }
+	if err := json.Unmarshal(raw, &result); err != nil || result.Removed != 1 {
+		t.Errorf("expected order.rb removed, got %s", raw)
+	}
+
+	raw, err = callHandler(t, s, "workspace/executeCommand", ExecuteCommandParams{Command: "goruby.indexStats"})
+	if err != nil {
+		t.Fatalf("indexStats failed: %v", err)
+	}
+	var stats struct{ Files int }
+	if err := json.Unmarshal(raw, &stats); err != nil || stats.Files != 2 {
+		t.Errorf("expected 2 indexed files, got %s", raw)
+	}
+
+	_, err = callHandler(t, s, "workspace/executeCommand", ExecuteCommandParams{Command: "goruby.findTargeting"})
+	if rpcErr, ok := err.(*jsonrpc2.Error); !ok || rpcErr.Code != codeRequestFailed {
+		t.Errorf("expected a failed request without a class name, got %v", err)
+	}
+}
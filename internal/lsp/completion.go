@@ -0,0 +1,276 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// completionLimit caps the items returned for one request; the list is
+// marked incomplete so clients ask again as the prefix grows
+const completionLimit = 100
+
+// CompletionItemKind is the LSP completion item kind
+type CompletionItemKind int
+
+const (
+	CompletionItemKindMethod   CompletionItemKind = 2
+	CompletionItemKindField    CompletionItemKind = 5
+	CompletionItemKindClass    CompletionItemKind = 7
+	CompletionItemKindModule   CompletionItemKind = 9
+	CompletionItemKindProperty CompletionItemKind = 10
+	CompletionItemKindConstant CompletionItemKind = 21
+)
+
+// CompletionOptions advertises completion support
+type CompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+}
+
+// CompletionParams for textDocument/completion
+type CompletionParams struct {
+	TextDocumentPositionParams
+}
+
+// CompletionItem is one suggestion
+type CompletionItem struct {
+	Label  string             `json:"label"`
+	Kind   CompletionItemKind `json:"kind,omitempty"`
+	Detail string             `json:"detail,omitempty"`
+}
+
+// CompletionList is the result of textDocument/completion
+type CompletionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+// completionContext describes what is being typed at the cursor
+type completionContext struct {
+	prefix    string // Partial identifier before the cursor
+	qualifier string // Namespace before a trailing :: (Billing in Billing::In)
+	afterDot  bool   // Prefix follows a method call dot
+	receiver  string // Identifier before the dot (user in user.na)
+}
+
+// completionContextAt reads the partial identifier before char on a line
+// and what precedes it
+func completionContextAt(content string, line, char int) completionContext {
+	lines := strings.Split(content, "\n")
+	if line < 0 || line >= len(lines) {
+		return completionContext{}
+	}
+	text := lines[line]
+	if char > len(text) {
+		char = len(text)
+	}
+
+	start := char
+	for start > 0 && isWordChar(text[start-1]) {
+		start--
+	}
+	c := completionContext{prefix: text[start:char]}
+
+	switch {
+	case start > 0 && text[start-1] == '.':
+		c.afterDot = true
+		rstart := start - 1
+		for rstart > 0 && isWordChar(text[rstart-1]) {
+			rstart--
+		}
+		c.receiver = text[rstart : start-1]
+	case start > 1 && text[start-1] == ':' && text[start-2] == ':':
+		end := start - 2
+		qstart := end
+		for qstart > 0 && (isWordChar(text[qstart-1]) || text[qstart-1] == ':') {
+			qstart--
+		}
+		c.qualifier = strings.Trim(text[qstart:end], ":")
+	}
+	return c
+}
+
+// isMethodKind reports whether a symbol is called like a method
+func isMethodKind(kind index.SymbolKind) bool {
+	switch kind {
+	case index.KindMethod, index.KindSingletonMethod,
+		index.KindAttrReader, index.KindAttrWriter, index.KindAttrAccessor,
+		index.KindRelation, index.KindColumn:
+		return true
+	}
+	return false
+}
+
+// completionFilter picks the symbol kinds that fit the completion context:
+// methods after a dot, constants in the qualifier's namespace after ::,
+// and otherwise constants or methods by the prefix's case
+func completionFilter(c completionContext) func(*index.Symbol) bool {
+	switch {
+	case c.afterDot:
+		return func(sym *index.Symbol) bool { return isMethodKind(sym.Kind) }
+	case c.qualifier != "":
+		suffix := c.qualifier + "::"
+		return func(sym *index.Symbol) bool {
+			if !sym.Kind.IsConstant() {
+				return false
+			}
+			namespace := strings.TrimSuffix(sym.FullName, sym.Name)
+			return namespace == suffix || strings.HasSuffix(namespace, "::"+suffix)
+		}
+	case c.prefix[0] >= 'A' && c.prefix[0] <= 'Z':
+		return func(sym *index.Symbol) bool { return sym.Kind.IsConstant() }
+	default:
+		return func(sym *index.Symbol) bool { return isMethodKind(sym.Kind) }
+	}
+}
+
+// completionItemKind maps an index symbol kind to a completion item kind
+func completionItemKind(kind index.SymbolKind) CompletionItemKind {
+	switch kind {
+	case index.KindClass:
+		return CompletionItemKindClass
+	case index.KindModule:
+		return CompletionItemKindModule
+	case index.KindConstant:
+		return CompletionItemKindConstant
+	case index.KindAttrReader, index.KindAttrWriter, index.KindAttrAccessor:
+		return CompletionItemKindProperty
+	case index.KindRelation, index.KindColumn:
+		return CompletionItemKindField
+	default:
+		return CompletionItemKindMethod
+	}
+}
+
+func (s *Server) handleCompletion(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params CompletionParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	s.syncDocument(uri)
+	content := s.getDocumentContent(uri)
+
+	path := uriToPath(uri)
+	if isBundlerFile(path) {
+		if list, ok := gemCompletions(path, content, int(params.Position.Line), int(params.Position.Character)); ok {
+			return reply(ctx, list, nil)
+		}
+	}
+
+	c := completionContextAt(content, int(params.Position.Line), int(params.Position.Character))
+	list := CompletionList{Items: []CompletionItem{}}
+
+	// Without a prefix or trigger, every symbol would match
+	if c.prefix == "" && !c.afterDot && c.qualifier == "" {
+		return reply(ctx, list, nil)
+	}
+
+	line := int(params.Position.Line) + 1
+	var symbols []*index.Symbol
+	if owners, singleton := s.receiverClasses(c.receiver, path, line); len(owners) > 0 {
+		symbols = s.receiverMembers(owners, singleton, c.prefix)
+	}
+	if len(symbols) == 0 {
+		symbols = s.index.SymbolsWithPrefix(c.prefix, completionFilter(c), completionLimit)
+	}
+
+	seen := make(map[string]bool)
+	for _, sym := range symbols {
+		// Reopened classes and same-named methods collapse into one item
+		// per name and kind, detailed with the first full name
+		key := sym.Name + "\x00" + sym.Kind.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		list.Items = append(list.Items, CompletionItem{
+			Label:  sym.Name,
+			Kind:   completionItemKind(sym.Kind),
+			Detail: completionDetail(sym),
+		})
+	}
+	list.IsIncomplete = len(symbols) >= completionLimit
+	return reply(ctx, list, nil)
+}
+
+// receiverMembers returns the public members starting with prefix that
+// can be called on instances of owners (or, when singleton is set, on the
+// classes themselves), including those inherited from superclasses
+func (s *Server) receiverMembers(owners map[string]bool, singleton bool, prefix string) []*index.Symbol {
+	classes := make([]string, 0, len(owners))
+	for class := range owners {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	var result []*index.Symbol
+	for _, class := range classes {
+		members := s.index.InstanceMembers
+		if singleton {
+			members = s.index.ClassMembers
+		}
+		for _, sym := range members(class) {
+			if strings.HasPrefix(sym.Name, prefix) {
+				result = append(result, sym)
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	if len(result) > completionLimit {
+		result = result[:completionLimit]
+	}
+	return result
+}
+
+// completionDetail is the full name of a completed symbol, followed for a
+// constant by the literal it holds (User::ROLES = [admin, member]), so the
+// allowed values show while picking one
+func completionDetail(sym *index.Symbol) string {
+	if sym.Kind == index.KindConstant && (len(sym.Values) > 0 || sym.Options["value"] != "") {
+		return sym.FullName + " " + symbolDetail(sym)
+	}
+	return sym.FullName
+}
+
+// receiverClasses returns the full names of the class a constant receiver
+// names, the class a local variable receiver was assigned from
+// (user = User.find(id)), the class a let helper returns (see
+// index.LetClass), or the class described_class refers to, or nil when
+// it's unknown. singleton reports whether the receiver is
+// the class itself rather than an instance of it. line is 1-based.
+func (s *Server) receiverClasses(receiver, path string, line int) (owners map[string]bool, singleton bool) {
+	if receiver == "" {
+		return nil, false
+	}
+	class, at := "", line
+	if receiver[0] >= 'A' && receiver[0] <= 'Z' {
+		class, singleton = receiver, true
+	} else if receiver == "described_class" {
+		class, singleton = s.index.DescribedClass(path, line), true
+	} else if local := s.index.FindLocalVariable(receiver, path, line); local != nil {
+		class, at = local.TypeName, local.Line
+	} else if let := s.index.FindLet(receiver, path, line); let != nil {
+		class, at = s.index.LetClass(let), let.Line
+	}
+	if class == "" {
+		return nil, false
+	}
+
+	classes, _ := s.index.ResolveDefinitions(class, path, at)
+	owners = make(map[string]bool)
+	for _, sym := range classes {
+		if sym.Kind == index.KindClass {
+			owners[sym.FullName] = true
+		}
+	}
+	return owners, singleton
+}
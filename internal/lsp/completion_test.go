@@ -0,0 +1,292 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCompletion(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/billing.rb", []byte(`module Billing
+  class Invoice
+    INVOICE_PREFIX = "INV"
+
+    def invoice_number
+    end
+
+    def total
+    end
+  end
+
+  class Ledger
+  end
+end`), 0)
+	s.index.UpdateContent("/app/inventory.rb", []byte("class Inventory\nend"), 0)
+
+	uri := "file:///app/report.rb"
+	content := "Inv\nBilling::L\ninvoice.to\ninv\n"
+	s.documents.Open(uri, 1, content)
+
+	labels := func(line, char int) map[string]CompletionItemKind {
+		t.Helper()
+		raw, err := callHandler(t, s, "textDocument/completion", CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: uri},
+				Position:     Position{Line: uint32(line), Character: uint32(char)},
+			},
+		})
+		if err != nil {
+			t.Fatalf("completion failed: %v", err)
+		}
+		var list CompletionList
+		if err := json.Unmarshal(raw, &list); err != nil {
+			t.Fatalf("failed to decode completion list: %v", err)
+		}
+		got := make(map[string]CompletionItemKind)
+		for _, item := range list.Items {
+			got[item.Label] = item.Kind
+		}
+		return got
+	}
+
+	// Capitalized prefixes complete constants only
+	got := labels(0, 3)
+	if got["Invoice"] != CompletionItemKindClass || got["Inventory"] != CompletionItemKindClass || len(got) != 2 {
+		t.Errorf("expected Invoice and Inventory, got %v", got)
+	}
+
+	// After ::, constants in that namespace
+	got = labels(1, 10)
+	if len(got) != 1 || got["Ledger"] != CompletionItemKindClass {
+		t.Errorf("expected Ledger, got %v", got)
+	}
+
+	// After a dot, methods
+	got = labels(2, 10)
+	if len(got) != 1 || got["total"] != CompletionItemKindMethod {
+		t.Errorf("expected total, got %v", got)
+	}
+
+	// Lowercase prefixes complete methods, not constants
+	got = labels(3, 3)
+	if len(got) != 1 || got["invoice_number"] != CompletionItemKindMethod {
+		t.Errorf("expected invoice_number, got %v", got)
+	}
+
+	// An empty line offers nothing
+	if got = labels(4, 0); len(got) != 0 {
+		t.Errorf("expected no items on an empty line, got %v", got)
+	}
+}
+
+func TestCompletionUsesLocalVariableType(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/models.rb", []byte(`class User
+  def name
+  end
+
+  def notify
+  end
+end
+
+class Order
+  def number
+  end
+end`), 0)
+
+	uri := "file:///app/orders.rb"
+	content := "class Orders\n  def show\n    user = User.find(1)\n    user.n\n  end\nend\n"
+	s.index.UpdateContent("/app/orders.rb", []byte(content), 0)
+	s.documents.Open(uri, 0, content)
+
+	raw, err := callHandler(t, s, "textDocument/completion", CompletionParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: 3, Character: 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("completion failed: %v", err)
+	}
+	var list CompletionList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		t.Fatalf("failed to decode completion list: %v", err)
+	}
+
+	var labels []string
+	for _, item := range list.Items {
+		labels = append(labels, item.Label)
+	}
+	if strings.Join(labels, ",") != "name,notify" {
+		t.Errorf("expected only User methods, got %v", labels)
+	}
+}
+
+func TestLiteralConstants(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/user.rb", []byte(`class User
+  ROLES = %w[
+    admin
+    member
+  ].freeze
+  DEFAULT_ROLE = "member".freeze
+end`), 0)
+
+	uri := "file:///app/policy.rb"
+	s.documents.Open(uri, 1, "User::R\nuser.role == \"admin\"\nuser.admin\n")
+
+	raw, err := callHandler(t, s, "textDocument/completion", CompletionParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: 0, Character: 7},
+		},
+	})
+	if err != nil {
+		t.Fatalf("completion failed: %v", err)
+	}
+	var list CompletionList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		t.Fatalf("failed to decode completion list: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Detail != "User::ROLES = [admin, member]" {
+		t.Errorf("expected ROLES detailed with its values, got %+v", list.Items)
+	}
+
+	references := func(line, char int) []Location {
+		t.Helper()
+		raw, err := callHandler(t, s, "textDocument/references", ReferenceParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: uri},
+				Position:     Position{Line: uint32(line), Character: uint32(char)},
+			},
+		})
+		if err != nil {
+			t.Fatalf("references failed: %v", err)
+		}
+		var locations []Location
+		if err := json.Unmarshal(raw, &locations); err != nil {
+			t.Fatalf("failed to decode locations: %v", err)
+		}
+		return locations
+	}
+
+	found := false
+	for _, loc := range references(1, 15) {
+		if loc.URI == "file:///app/user.rb" && loc.Range.Start.Line == 1 {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected the string's references to include User::ROLES")
+	}
+
+	// A method named like a value isn't the value
+	for _, loc := range references(2, 7) {
+		if loc.URI == "file:///app/user.rb" && loc.Range.Start.Line == 1 {
+			t.Errorf("expected no constants for a method call, got %+v", loc)
+		}
+	}
+}
+
+func TestCompletionOffersPublicAndInheritedMembers(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/models.rb", []byte(`class Record
+  def persisted?
+  end
+end
+
+class User < Record
+  def name
+  end
+
+  def password_hash
+  end
+
+  private
+
+  def pepper
+  end
+
+  protected
+
+  def peer_rank
+  end
+end`), 0)
+
+	uri := "file:///app/users.rb"
+	content := "class Users\n  def show\n    user = User.find(1)\n    user.p\n  end\nend\n"
+	s.index.UpdateContent("/app/users.rb", []byte(content), 0)
+	s.documents.Open(uri, 0, content)
+
+	raw, err := callHandler(t, s, "textDocument/completion", CompletionParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: 3, Character: 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("completion failed: %v", err)
+	}
+	var list CompletionList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		t.Fatalf("failed to decode completion list: %v", err)
+	}
+
+	var labels []string
+	for _, item := range list.Items {
+		labels = append(labels, item.Label)
+	}
+	if strings.Join(labels, ",") != "password_hash,persisted?" {
+		t.Errorf("expected public User and Record methods only, got %v", labels)
+	}
+}
+
+func TestCompletionOffersModelMembers(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/app/models/order.rb", []byte(`class Order < ApplicationRecord
+  has_many :items
+  belongs_to :customer
+  scope :active, -> { where(active: true) }
+
+  def self.import(rows)
+  end
+
+  def item_count
+  end
+end`), 0)
+
+	uri := "file:///app/app/controllers/orders_controller.rb"
+	content := "class OrdersController\n  def show\n    order = Order.find(1)\n    order.i\n    Order.\n  end\nend\n"
+	s.index.UpdateContent(uriToPath(uri), []byte(content), 0)
+	s.documents.Open(uri, 0, content)
+
+	complete := func(line, char int) []string {
+		raw, err := callHandler(t, s, "textDocument/completion", CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: uri},
+				Position:     Position{Line: uint32(line), Character: uint32(char)},
+			},
+		})
+		if err != nil {
+			t.Fatalf("completion failed: %v", err)
+		}
+		var list CompletionList
+		if err := json.Unmarshal(raw, &list); err != nil {
+			t.Fatalf("failed to decode completion list: %v", err)
+		}
+		var labels []string
+		for _, item := range list.Items {
+			labels = append(labels, item.Label)
+		}
+		return labels
+	}
+
+	// Associations on an instance, scopes and class methods on the class
+	if got := complete(3, 11); strings.Join(got, ",") != "item_count,items" {
+		t.Errorf("expected the instance's association and method, got %v", got)
+	}
+	if got := complete(4, 10); strings.Join(got, ",") != "active,import" {
+		t.Errorf("expected the class's scope and class method, got %v", got)
+	}
+}
@@ -0,0 +1,128 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// Config holds settings sent by the client in initializationOptions
+type Config struct {
+	// SymbolKinds limits symbol search to these kinds (e.g., ["class", "module"])
+	// unless the query carries its own kind: filter
+	SymbolKinds []string `json:"symbolKinds,omitempty"`
+
+	// DisabledMatchers turns off parser matchers by name (e.g., ["localvar"])
+	DisabledMatchers []string `json:"disabledMatchers,omitempty"`
+
+	// MinConfidence hides definition results resolved with less confidence
+	// ("exact", "contextual", "fuzzy", or "ghost"; default shows all)
+	MinConfidence string `json:"minConfidence,omitempty"`
+
+	// ReferenceLimit caps the locations returned for references (default 1000)
+	ReferenceLimit int `json:"referenceLimit,omitempty"`
+
+	// UnqualifiedReferences lists every textual match for common method
+	// names like new or name instead of only receiver-qualified ones
+	UnqualifiedReferences bool `json:"unqualifiedReferences,omitempty"`
+
+	// Formatter is the binary textDocument/formatting runs (e.g.,
+	// "bin/rubocop"); default is rubocop, or standardrb for projects with a
+	// .standard.yml and no .rubocop.yml
+	Formatter string `json:"formatter,omitempty"`
+
+	// FormatterArgs replace the formatter's default arguments; "--stdin" and
+	// the file path are always appended
+	FormatterArgs []string `json:"formatterArgs,omitempty"`
+
+	// ReadOnly stops the server from writing to the workspace or running
+	// project code, for untrusted repositories
+	ReadOnly bool `json:"readOnly,omitempty"`
+
+	// IndexGems indexes the installed gems Gemfile.lock resolves after the
+	// project, so definitions reach into dependencies. Off by default, as
+	// it can double the index; goruby.indexGems does the same on demand.
+	IndexGems bool `json:"indexGems,omitempty"`
+
+	// MaxWorkers caps how many files are indexed or searched at once
+	// (default: sized from the CPU count and file sizes)
+	MaxWorkers int `json:"maxWorkers,omitempty"`
+}
+
+// defaultReferenceLimit is the reference cap when none is configured
+const defaultReferenceLimit = 1000
+
+// DidChangeConfigurationParams is sent when client settings change. Settings
+// may hold the config directly or nested under a "goruby" section.
+type DidChangeConfigurationParams struct {
+	Settings json.RawMessage `json:"settings"`
+}
+
+// symbolQuery parses a symbol search query, falling back to the configured
+// kind filter when the query doesn't specify one
+func (c *Config) symbolQuery(query string) index.SymbolQuery {
+	q := index.ParseSymbolQuery(query)
+	if len(q.Kinds) == 0 {
+		q.Kinds = index.ParseSymbolKinds(c.SymbolKinds)
+	}
+	return q
+}
+
+// minConfidence returns the weakest confidence to show definitions at
+func (c *Config) minConfidence() index.Confidence {
+	if conf, ok := index.ParseConfidence(c.MinConfidence); ok {
+		return conf
+	}
+	return index.ConfidenceGhost
+}
+
+// referenceLimit returns the most reference locations to return
+func (c *Config) referenceLimit() int {
+	if c.ReferenceLimit > 0 {
+		return c.ReferenceLimit
+	}
+	return defaultReferenceLimit
+}
+
+// handleDidChangeConfiguration replaces the config with the client's new
+// settings and applies matcher toggles
+func (s *Server) handleDidChangeConfiguration(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params DidChangeConfigurationParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	settings := params.Settings
+	var section struct {
+		Goruby json.RawMessage `json:"goruby"`
+	}
+	if err := json.Unmarshal(settings, &section); err == nil && len(section.Goruby) > 0 {
+		settings = section.Goruby
+	}
+
+	var config Config
+	if err := json.Unmarshal(settings, &config); err != nil {
+		log.Printf("ignoring invalid settings: %v", err)
+		return reply(ctx, nil, nil)
+	}
+	s.config.Store(&config)
+	s.applyMatcherConfig(&config)
+
+	return reply(ctx, nil, nil)
+}
+
+// applyMatcherConfig disables config's matchers and caps the
+// index's workers. The affected files are reindexed in the background so
+// the request loop isn't blocked.
+func (s *Server) applyMatcherConfig(config *Config) {
+	s.index.SetMaxWorkers(config.MaxWorkers)
+	if paths := s.index.SetDisabledMatchers(config.DisabledMatchers); len(paths) > 0 {
+		go s.index.Reindex(paths)
+	}
+}
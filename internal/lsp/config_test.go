@@ -0,0 +1,129 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"github.com/jarredhawkins/goruby-lsp/internal/parser"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// callHandler invokes the server handler directly and returns the raw reply
+func callHandler(t *testing.T, s *Server, method string, params interface{}) (json.RawMessage, error) {
+	t.Helper()
+
+	req, err := jsonrpc2.NewCall(jsonrpc2.NewNumberID(1), method, params)
+	if err != nil {
+		t.Fatalf("failed to build %s request: %v", method, err)
+	}
+
+	var result json.RawMessage
+	var replyErr error
+	reply := func(ctx context.Context, res interface{}, err error) error {
+		replyErr = err
+		if res != nil {
+			result, _ = json.Marshal(res)
+		}
+		return nil
+	}
+
+	if err := s.handler(context.Background(), reply, req); err != nil {
+		t.Fatalf("%s handler failed: %v", method, err)
+	}
+	return result, replyErr
+}
+
+func newTestServer(rootPath string) *Server {
+	registry := parser.NewRegistry()
+	parser.RegisterDefaults(registry)
+	return NewServer(index.New(rootPath, registry))
+}
+
+func TestInitializeReadsConfig(t *testing.T) {
+	s := newTestServer("/test")
+
+	_, err := callHandler(t, s, "initialize", map[string]interface{}{
+		"processId": 1,
+		"initializationOptions": map[string]interface{}{
+			"symbolKinds": []string{"class", "module"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+
+	q := s.settings().symbolQuery("User")
+	if !q.AllowsKind(index.KindClass) || q.AllowsKind(index.KindLocalVariable) {
+		t.Errorf("expected configured class/module filter, got %v", q.Kinds)
+	}
+
+	// An explicit kind: filter in the query overrides the configuration
+	q = s.settings().symbolQuery("kind:method save")
+	if !q.AllowsKind(index.KindMethod) || q.AllowsKind(index.KindClass) {
+		t.Errorf("expected query filter to override config, got %v", q.Kinds)
+	}
+}
+
+func TestDidChangeConfiguration(t *testing.T) {
+	s := newTestServer("/test")
+
+	_, err := callHandler(t, s, "workspace/didChangeConfiguration", map[string]interface{}{
+		"settings": map[string]interface{}{
+			"goruby": map[string]interface{}{
+				"symbolKinds":      []string{"method"},
+				"disabledMatchers": []string{"localvar"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("didChangeConfiguration failed: %v", err)
+	}
+
+	config := s.settings()
+	if len(config.SymbolKinds) != 1 || config.SymbolKinds[0] != "method" {
+		t.Errorf("expected symbolKinds [method], got %v", config.SymbolKinds)
+	}
+	if len(config.DisabledMatchers) != 1 || config.DisabledMatchers[0] != "localvar" {
+		t.Errorf("expected disabledMatchers [localvar], got %v", config.DisabledMatchers)
+	}
+
+	// Settings without a goruby section are read directly
+	_, err = callHandler(t, s, "workspace/didChangeConfiguration", map[string]interface{}{
+		"settings": map[string]interface{}{"symbolKinds": []string{"class"}},
+	})
+	if err != nil {
+		t.Fatalf("didChangeConfiguration failed: %v", err)
+	}
+	if config := s.settings(); len(config.SymbolKinds) != 1 || config.SymbolKinds[0] != "class" || len(config.DisabledMatchers) != 0 {
+		t.Errorf("expected config replaced, got %+v", config)
+	}
+}
+
+func TestDidChangeConfigurationWhileReading(t *testing.T) {
+	s := newTestServer("/test")
+
+	// The index build and handlers read the config off the request loop
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			s.isReadOnly()
+			s.settings().referenceLimit()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_, err := callHandler(t, s, "workspace/didChangeConfiguration", map[string]interface{}{
+			"settings": map[string]interface{}{"readOnly": i%2 == 0},
+		})
+		if err != nil {
+			t.Fatalf("didChangeConfiguration failed: %v", err)
+		}
+	}
+	<-done
+
+	if s.isReadOnly() {
+		t.Error("expected the last settings to win")
+	}
+}
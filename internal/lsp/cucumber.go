@@ -0,0 +1,28 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Given I log in as "alice" / * I have 3 cukes
+var gherkinStepPattern = regexp.MustCompile(`^\s*(?:Given|When|Then|And|But|\*)\s+(.+?)\s*$`)
+
+// isFeatureFile reports whether a path is a Gherkin .feature file
+func isFeatureFile(path string) bool {
+	return strings.HasSuffix(path, ".feature")
+}
+
+// gherkinStepAt returns the step text on a line of a .feature file,
+// without its keyword
+func gherkinStepAt(content string, line int) string {
+	lines := strings.Split(content, "\n")
+	if line < 0 || line >= len(lines) {
+		return ""
+	}
+	match := gherkinStepPattern.FindStringSubmatch(lines[line])
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
@@ -0,0 +1,24 @@
+package lsp
+
+import "testing"
+
+func TestGherkinStepAt(t *testing.T) {
+	content := `Feature: Checkout
+  Scenario: Paying
+    Given I have 2 orders
+    * I cancel order 7
+    When I pay`
+
+	tests := map[int]string{
+		0: "",
+		1: "",
+		2: "I have 2 orders",
+		3: "I cancel order 7",
+		4: "I pay",
+	}
+	for line, want := range tests {
+		if got := gherkinStepAt(content, line); got != want {
+			t.Errorf("gherkinStepAt(%d) = %q, want %q", line, got, want)
+		}
+	}
+}
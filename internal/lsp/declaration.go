@@ -0,0 +1,52 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// handleDeclaration resolves the name under the cursor like definition,
+// but to one site per name: where a class or module is originally
+// declared, or a method first defined, rather than every reopening (see
+// index.Declarations)
+func (s *Server) handleDeclaration(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	filePath := uriToPath(uri)
+	line := int(params.Position.Line)
+	content := s.getDocumentContent(uri)
+	if content == "" {
+		return reply(ctx, nil, nil)
+	}
+	s.syncDocument(uri)
+
+	word, start, end := wordRangeAt(content, line, int(params.Position.Character))
+	if word == "" {
+		return reply(ctx, nil, nil)
+	}
+	origin := &Range{
+		Start: Position{Line: uint32(line), Character: uint32(start)},
+		End:   Position{Line: uint32(line), Character: uint32(end)},
+	}
+
+	// Locals and let helpers are declared where they're assigned
+	if sym, confidence := s.localDefinition(word, filePath, line+1); sym != nil {
+		return reply(ctx, s.definitionResult([]*index.Symbol{sym}, origin, confidence), nil)
+	}
+
+	symbols, confidence := s.index.ResolveDefinitions(word, filePath, line+1)
+	if len(symbols) == 0 {
+		return reply(ctx, nil, nil)
+	}
+	return reply(ctx, s.definitionResult(s.index.Declarations(symbols), origin, confidence), nil)
+}
@@ -0,0 +1,40 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDeclarationSkipsReopenings(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/lib/invoice.rb", []byte("class Invoice < Record\nend\n"), 0)
+	s.index.UpdateContent("/app/lib/exports.rb", []byte("class Invoice\n  def to_csv\n  end\nend\n"), 0)
+
+	uri := "file:///app/lib/report.rb"
+	s.documents.Open(uri, 1, "class Report\n  def build\n    Invoice.new\n  end\nend\n")
+	position := TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: 2, Character: 6},
+	}
+
+	raw, err := callHandler(t, s, "textDocument/definition", position)
+	if err != nil {
+		t.Fatalf("definition failed: %v", err)
+	}
+	var definitions []Location
+	if err := json.Unmarshal(raw, &definitions); err != nil || len(definitions) != 2 {
+		t.Fatalf("expected both definitions of Invoice, got %s", raw)
+	}
+
+	raw, err = callHandler(t, s, "textDocument/declaration", position)
+	if err != nil {
+		t.Fatalf("declaration failed: %v", err)
+	}
+	var declaration Location
+	if err := json.Unmarshal(raw, &declaration); err != nil {
+		t.Fatalf("failed to decode declaration: %v", err)
+	}
+	if declaration.URI != "file:///app/lib/invoice.rb" {
+		t.Errorf("expected the declaration naming the superclass, got %+v", declaration)
+	}
+}
@@ -0,0 +1,131 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"github.com/jarredhawkins/goruby-lsp/internal/parser"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// DiagnosticSeverity is the LSP diagnostic severity
+type DiagnosticSeverity int
+
+const (
+	DiagnosticSeverityError   DiagnosticSeverity = 1
+	DiagnosticSeverityWarning DiagnosticSeverity = 2
+)
+
+// Diagnostic is a problem reported in a document
+type Diagnostic struct {
+	Range    Range              `json:"range"`
+	Severity DiagnosticSeverity `json:"severity,omitempty"`
+	Source   string             `json:"source,omitempty"`
+	Message  string             `json:"message"`
+}
+
+// PublishDiagnosticsParams for the textDocument/publishDiagnostics
+// notification
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     int          `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// DidSaveTextDocumentParams for textDocument/didSave. Text is the saved
+// content, sent by clients that include it.
+type DidSaveTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Text         *string                `json:"text,omitempty"`
+}
+
+// diagnostics turns structural problems into diagnostics. They're warnings,
+// as the line-based block tracking can still be fooled by valid Ruby.
+func diagnostics(problems []parser.Problem) []Diagnostic {
+	result := make([]Diagnostic, 0, len(problems))
+	for _, p := range problems {
+		line := uint32(p.Line - 1)
+		result = append(result, Diagnostic{
+			Range: Range{
+				Start: Position{Line: line, Character: uint32(p.Column)},
+				End:   Position{Line: line, Character: uint32(p.EndColumn)},
+			},
+			Severity: DiagnosticSeverityWarning,
+			Source:   "goruby",
+			Message:  p.Message,
+		})
+	}
+	return result
+}
+
+// privateConstantDiagnostics warns about references to private constants
+// from outside the class or module that owns them
+func privateConstantDiagnostics(refs []index.PrivateConstantReference) []Diagnostic {
+	result := make([]Diagnostic, 0, len(refs))
+	for _, ref := range refs {
+		line := uint32(ref.Line - 1)
+		result = append(result, Diagnostic{
+			Range: Range{
+				Start: Position{Line: line, Character: uint32(ref.Column)},
+				End:   Position{Line: line, Character: uint32(ref.EndColumn)},
+			},
+			Severity: DiagnosticSeverityWarning,
+			Source:   "goruby",
+			Message:  fmt.Sprintf("private constant %s referenced outside %s", ref.Constant, ref.Owner),
+		})
+	}
+	return result
+}
+
+// publishDiagnostics sends the structural problems of an open document,
+// and its references to other namespaces' private constants, to the
+// client, or clears them once it's closed
+func (s *Server) publishDiagnostics(ctx context.Context, uri string) {
+	if s.client == nil {
+		return
+	}
+	params := PublishDiagnosticsParams{URI: uri, Diagnostics: []Diagnostic{}}
+	if doc, ok := s.documents.Snapshot(uri); ok {
+		params.Version = doc.Version
+		path := uriToPath(uri)
+		params.Diagnostics = diagnostics(s.index.ProblemsInContent(path, doc.Content))
+		params.Diagnostics = append(params.Diagnostics, privateConstantDiagnostics(s.index.PrivateConstantReferences(path, doc.Content))...)
+	}
+	if err := s.client.Notify(ctx, "textDocument/publishDiagnostics", params); err != nil {
+		log.Printf("failed to publish diagnostics: %v", err)
+	}
+}
+
+func (s *Server) handleDidSave(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params DidSaveTextDocumentParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, err)
+	}
+
+	s.reindexSaved(params.TextDocument.URI, params.Text)
+	s.publishDiagnostics(ctx, params.TextDocument.URI)
+	return reply(ctx, nil, nil)
+}
+
+// reindexSaved reparses a saved file right away rather than waiting for
+// the watcher's debounced event, which leaves open documents to their
+// buffer and finds a closed file's content already indexed. An open
+// document is reindexed from the saved text when the
+// client sends it, otherwise from its buffer; a file that isn't open is
+// read from disk, the way the watcher would.
+func (s *Server) reindexSaved(uri string, text *string) {
+	path := uriToPath(uri)
+	doc, ok := s.documents.Snapshot(uri)
+	if !ok {
+		s.index.ApplyBatch([]string{path}, nil)
+		return
+	}
+	if text != nil {
+		s.documents.Update(uri, doc.Version, *text)
+		doc.Content = *text
+	}
+	s.index.UpdateContent(path, []byte(doc.Content), doc.Version)
+}
@@ -0,0 +1,133 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPublishDiagnostics(t *testing.T) {
+	s := newTestServer("/test")
+	client := &fakeClient{}
+	s.client = client
+	uri := "file:///test/app/models/order.rb"
+
+	latest := func() PublishDiagnosticsParams {
+		t.Helper()
+		if len(client.diagnostics) == 0 {
+			t.Fatal("expected diagnostics to be published")
+		}
+		return client.diagnostics[len(client.diagnostics)-1]
+	}
+
+	// A method missing its end leaves the class unterminated
+	callHandler(t, s, "textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "ruby", Version: 1, Text: "class Order\n  def total\n    items.sum\nend\n"},
+	})
+	got := latest()
+	if got.URI != uri || got.Version != 1 || len(got.Diagnostics) != 1 {
+		t.Fatalf("expected one diagnostic for version 1, got %+v", got)
+	}
+	diag := got.Diagnostics[0]
+	if diag.Message != "class Order is missing its end" || diag.Severity != DiagnosticSeverityWarning {
+		t.Errorf("unexpected diagnostic %+v", diag)
+	}
+	if diag.Range.Start != (Position{Line: 0, Character: 0}) || diag.Range.End != (Position{Line: 0, Character: 11}) {
+		t.Errorf("expected the class line, got %+v", diag.Range)
+	}
+
+	// Fixing it clears the diagnostics; an extra end reports a new one
+	callHandler(t, s, "textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{TextDocumentIdentifier: TextDocumentIdentifier{URI: uri}, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "class Order\n  def total\n    items.sum\n  end\nend\n"}},
+	})
+	if got := latest(); got.Version != 2 || len(got.Diagnostics) != 0 {
+		t.Errorf("expected no diagnostics for version 2, got %+v", got)
+	}
+	callHandler(t, s, "textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{TextDocumentIdentifier: TextDocumentIdentifier{URI: uri}, Version: 3},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "class Order\nend\nend\n"}},
+	})
+	if got := latest(); len(got.Diagnostics) != 1 || got.Diagnostics[0].Range.Start.Line != 2 {
+		t.Errorf("expected a stray end on line 2, got %+v", got)
+	}
+
+	count := len(client.diagnostics)
+	callHandler(t, s, "textDocument/didSave", DidSaveTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+	if len(client.diagnostics) != count+1 {
+		t.Error("expected diagnostics to be republished on save")
+	}
+
+	// Closing the document clears them
+	callHandler(t, s, "textDocument/didClose", DidCloseTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+	if got := latest(); len(got.Diagnostics) != 0 {
+		t.Errorf("expected diagnostics cleared on close, got %+v", got)
+	}
+}
+
+func TestDidSaveReindexes(t *testing.T) {
+	root := t.TempDir()
+	s := newTestServer(root)
+
+	// A file saved outside an open buffer is read from disk
+	path := filepath.Join(root, "app", "models", "invoice.rb")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("class Invoice\nend\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	callHandler(t, s, "textDocument/didSave", DidSaveTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: pathToURI(path)},
+	})
+	if len(s.index.FindDefinitions("Invoice")) != 1 {
+		t.Fatal("expected Invoice indexed on save")
+	}
+
+	// An open document takes the saved text when the client includes it
+	uri := pathToURI(filepath.Join(root, "app", "models", "order.rb"))
+	s.documents.Open(uri, 4, "class Order\nend\n")
+	text := "class Order\n  def total\n  end\nend\n"
+	callHandler(t, s, "textDocument/didSave", DidSaveTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Text:         &text,
+	})
+	if len(s.index.FindDefinitions("Order#total")) != 1 {
+		t.Error("expected Order#total indexed from the saved text")
+	}
+	if doc, _ := s.documents.Snapshot(uri); doc.Content != text || doc.Version != 4 {
+		t.Errorf("expected the buffer to match the saved text, got %+v", doc)
+	}
+	if s.index.FileVersion(uriToPath(uri)) != 4 {
+		t.Error("expected the index to record the document version")
+	}
+}
+
+func TestPrivateConstantDiagnostics(t *testing.T) {
+	s := newTestServer("/test")
+	client := &fakeClient{}
+	s.client = client
+	s.index.UpdateContent("/test/app/models/order.rb", []byte("class Order\n  LIMIT = 10\n  private_constant :LIMIT\nend\n"), 0)
+
+	uri := "file:///test/app/services/checkout.rb"
+	callHandler(t, s, "textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "ruby", Version: 1, Text: "class Checkout\n  def run\n    Order::LIMIT\n  end\nend\n"},
+	})
+	if len(client.diagnostics) == 0 {
+		t.Fatal("expected diagnostics to be published")
+	}
+	got := client.diagnostics[len(client.diagnostics)-1].Diagnostics
+	if len(got) != 1 {
+		t.Fatalf("expected one diagnostic, got %+v", got)
+	}
+	if got[0].Severity != DiagnosticSeverityWarning || got[0].Message != "private constant Order::LIMIT referenced outside Order" {
+		t.Errorf("unexpected diagnostic %+v", got[0])
+	}
+	if got[0].Range.Start != (Position{Line: 2, Character: 11}) || got[0].Range.End != (Position{Line: 2, Character: 16}) {
+		t.Errorf("expected the range of LIMIT, got %+v", got[0].Range)
+	}
+}
@@ -62,6 +62,17 @@ func (ds *DocumentStore) Get(uri string) (string, bool) {
 	return "", false
 }
 
+// Snapshot returns a copy of an open document, with its content and
+// version read together
+func (ds *DocumentStore) Snapshot(uri string) (Document, bool) {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	if doc, ok := ds.docs[uri]; ok {
+		return *doc, true
+	}
+	return Document{}, false
+}
+
 // IsOpen checks if a document is open
 func (ds *DocumentStore) IsOpen(uri string) bool {
 	ds.mu.RLock()
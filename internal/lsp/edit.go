@@ -0,0 +1,220 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+// Failure handling modes a client can advertise for workspace edits
+const (
+	FailureHandlingAbort                 = "abort"
+	FailureHandlingTransactional         = "transactional"
+	FailureHandlingUndo                  = "undo"
+	FailureHandlingTextOnlyTransactional = "textOnlyTransactional"
+)
+
+// errReadOnly is returned for actions read-only mode forbids
+var errReadOnly = errors.New("the server is in read-only mode")
+
+// clientCaller sends requests and notifications to the client (satisfied
+// by jsonrpc2.Conn)
+type clientCaller interface {
+	Call(ctx context.Context, method string, params, result interface{}) (jsonrpc2.ID, error)
+	Notify(ctx context.Context, method string, params interface{}) error
+}
+
+// PreviewWorkspaceEditParams for the goruby/previewWorkspaceEdit request
+type PreviewWorkspaceEditParams struct {
+	Edit WorkspaceEdit `json:"edit"`
+}
+
+// EditPreview is a human-readable summary of a workspace edit
+type EditPreview struct {
+	Summary string        `json:"summary"`
+	Files   []FilePreview `json:"files"`
+	Edits   int           `json:"edits"`
+}
+
+// FilePreview lists the changed hunks of one file
+type FilePreview struct {
+	URI   string `json:"uri"`
+	Path  string `json:"path"`
+	Edits int    `json:"edits"`
+	Hunks []Hunk `json:"hunks"`
+}
+
+// Hunk shows lines before and after an edit. Line is 1-based.
+type Hunk struct {
+	Line   int      `json:"line"`
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+}
+
+// handlePreviewWorkspaceEdit describes what a workspace edit would change,
+// so clients can show it before applying a refactoring
+func (s *Server) handlePreviewWorkspaceEdit(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params PreviewWorkspaceEditParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+	return reply(ctx, previewWorkspaceEdit(params.Edit, s.getDocumentContent), nil)
+}
+
+// previewWorkspaceEdit applies edit to each document's content in memory
+// and reports the changed lines, grouping edits on adjacent lines
+func previewWorkspaceEdit(edit WorkspaceEdit, content func(uri string) string) EditPreview {
+	preview := EditPreview{Files: []FilePreview{}}
+
+	for _, uri := range sortedURIs(edit) {
+		edits := sortedEdits(edit.Changes[uri])
+		if len(edits) == 0 {
+			continue
+		}
+		lines := strings.Split(content(uri), "\n")
+
+		file := FilePreview{URI: uri, Path: uriToPath(uri), Edits: len(edits)}
+		for start := 0; start < len(edits); {
+			first := int(edits[start].Range.Start.Line)
+			last := int(edits[start].Range.End.Line)
+			end := start + 1
+			for end < len(edits) && int(edits[end].Range.Start.Line) <= last+1 {
+				if l := int(edits[end].Range.End.Line); l > last {
+					last = l
+				}
+				end++
+			}
+			file.Hunks = append(file.Hunks, hunkFor(lines, first, last, edits[start:end]))
+			start = end
+		}
+
+		preview.Files = append(preview.Files, file)
+		preview.Edits += len(edits)
+	}
+
+	preview.Summary = fmt.Sprintf("%d %s in %d %s",
+		preview.Edits, plural(preview.Edits, "edit", "edits"),
+		len(preview.Files), plural(len(preview.Files), "file", "files"))
+	return preview
+}
+
+// hunkFor applies edits, all within lines first..last, to those lines
+func hunkFor(lines []string, first, last int, edits []TextEdit) Hunk {
+	var before []string
+	for i := first; i <= last && i < len(lines); i++ {
+		before = append(before, lines[i])
+	}
+	text := strings.Join(before, "\n")
+
+	// Apply back to front so earlier offsets stay valid
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		from := offsetIn(before, int(e.Range.Start.Line)-first, int(e.Range.Start.Character))
+		to := offsetIn(before, int(e.Range.End.Line)-first, int(e.Range.End.Character))
+		text = text[:from] + e.NewText + text[to:]
+	}
+
+	return Hunk{Line: first + 1, Before: before, After: strings.Split(text, "\n")}
+}
+
+// offsetIn converts a line/column within lines to an offset into the
+// newline-joined text, clamping out-of-range positions
+func offsetIn(lines []string, line, char int) int {
+	offset := 0
+	for i := 0; i < line && i < len(lines); i++ {
+		offset += len(lines[i]) + 1
+	}
+	if line >= len(lines) {
+		if offset > 0 {
+			offset-- // Past the last line: clamp to the end of the text
+		}
+		return offset
+	}
+	if char > len(lines[line]) {
+		char = len(lines[line])
+	}
+	return offset + char
+}
+
+// applyWorkspaceEdit asks the client to apply edit. Clients that can roll
+// back a failed edit get it in one request; otherwise each file is sent
+// separately and the first failure stops the rest, so no file is left
+// half-edited and the error names exactly what was applied.
+func (s *Server) applyWorkspaceEdit(ctx context.Context, label string, edit WorkspaceEdit) error {
+	if s.isReadOnly() {
+		return errReadOnly
+	}
+	if s.client == nil {
+		return fmt.Errorf("no client connection")
+	}
+
+	uris := sortedURIs(edit)
+	switch s.capabilities.Workspace.WorkspaceEdit.FailureHandling {
+	case FailureHandlingTransactional, FailureHandlingUndo:
+		return s.sendEdit(ctx, label, edit)
+	}
+	if len(uris) <= 1 {
+		return s.sendEdit(ctx, label, edit)
+	}
+
+	for i, uri := range uris {
+		single := WorkspaceEdit{Changes: map[string][]TextEdit{uri: edit.Changes[uri]}}
+		if err := s.sendEdit(ctx, label, single); err != nil {
+			return fmt.Errorf("%w (applied %d of %d files)", err, i, len(uris))
+		}
+	}
+	return nil
+}
+
+// sendEdit sends one workspace/applyEdit request
+func (s *Server) sendEdit(ctx context.Context, label string, edit WorkspaceEdit) error {
+	var result ApplyWorkspaceEditResult
+	if _, err := s.client.Call(ctx, "workspace/applyEdit", ApplyWorkspaceEditParams{Label: label, Edit: edit}, &result); err != nil {
+		return err
+	}
+	if !result.Applied {
+		if result.FailureReason != "" {
+			return fmt.Errorf("edit not applied: %s", result.FailureReason)
+		}
+		return fmt.Errorf("edit not applied")
+	}
+	return nil
+}
+
+// sortedURIs returns the documents an edit touches, in a stable order
+func sortedURIs(edit WorkspaceEdit) []string {
+	uris := make([]string, 0, len(edit.Changes))
+	for uri := range edit.Changes {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+// sortedEdits returns edits ordered by start position
+func sortedEdits(edits []TextEdit) []TextEdit {
+	sorted := append([]TextEdit(nil), edits...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Range.Start, sorted[j].Range.Start
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Character < b.Character
+	})
+	return sorted
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
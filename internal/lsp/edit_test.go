@@ -0,0 +1,158 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+func editAt(line, start, end int, text string) TextEdit {
+	return TextEdit{
+		Range: Range{
+			Start: Position{Line: uint32(line), Character: uint32(start)},
+			End:   Position{Line: uint32(line), Character: uint32(end)},
+		},
+		NewText: text,
+	}
+}
+
+func TestPreviewWorkspaceEdit(t *testing.T) {
+	files := map[string]string{
+		"file:///app/order.rb":  "class Order\n  def total\n    total_cents / 100\n  end\nend",
+		"file:///app/report.rb": "order.total\nputs order.total",
+	}
+	edit := WorkspaceEdit{Changes: map[string][]TextEdit{
+		"file:///app/report.rb": {editAt(1, 11, 16, "sum"), editAt(0, 6, 11, "sum")},
+		"file:///app/order.rb":  {editAt(1, 6, 11, "sum")},
+	}}
+
+	s := newTestServer("/app")
+	for uri, content := range files {
+		s.documents.Open(uri, 1, content)
+	}
+	raw, err := callHandler(t, s, "goruby/previewWorkspaceEdit", PreviewWorkspaceEditParams{Edit: edit})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var preview EditPreview
+	if err := json.Unmarshal(raw, &preview); err != nil {
+		t.Fatalf("failed to decode preview: %v", err)
+	}
+
+	if preview.Summary != "3 edits in 2 files" {
+		t.Errorf("unexpected summary %q", preview.Summary)
+	}
+	if len(preview.Files) != 2 || preview.Files[0].Path != "/app/order.rb" {
+		t.Fatalf("expected files in URI order, got %+v", preview.Files)
+	}
+
+	order := preview.Files[0]
+	if len(order.Hunks) != 1 || order.Hunks[0].Line != 2 || order.Hunks[0].After[0] != "  def sum" {
+		t.Errorf("unexpected order.rb hunks %+v", order.Hunks)
+	}
+
+	// Edits on adjacent lines share a hunk
+	report := preview.Files[1]
+	if len(report.Hunks) != 1 {
+		t.Fatalf("expected one merged hunk, got %+v", report.Hunks)
+	}
+	if got := strings.Join(report.Hunks[0].After, "\n"); got != "order.sum\nputs order.sum" {
+		t.Errorf("unexpected merged hunk %q", got)
+	}
+}
+
+// fakeClient records workspace/applyEdit requests, failing the one at
+// failAt, capability registrations, progress, and notifications
+type fakeClient struct {
+	edits         []WorkspaceEdit
+	failAt        int
+	registrations []Registration
+	notifications []string
+	diagnostics   []PublishDiagnosticsParams
+	progress      []interface{} // $/progress values
+	created       []interface{} // Tokens from window/workDoneProgress/create
+	traces        []LogTraceParams
+}
+
+func (c *fakeClient) Notify(ctx context.Context, method string, params interface{}) error {
+	c.notifications = append(c.notifications, method)
+	if p, ok := params.(ShowMessageParams); ok {
+		c.notifications[len(c.notifications)-1] += ": " + p.Message
+	}
+	if p, ok := params.(PublishDiagnosticsParams); ok {
+		c.diagnostics = append(c.diagnostics, p)
+	}
+	if p, ok := params.(ProgressParams); ok {
+		c.progress = append(c.progress, p.Value)
+	}
+	if p, ok := params.(LogTraceParams); ok {
+		c.traces = append(c.traces, p)
+	}
+	return nil
+}
+
+func (c *fakeClient) Call(ctx context.Context, method string, params, result interface{}) (jsonrpc2.ID, error) {
+	if p, ok := params.(RegistrationParams); ok {
+		c.registrations = append(c.registrations, p.Registrations...)
+		return jsonrpc2.NewNumberID(int32(len(c.registrations))), nil
+	}
+	if p, ok := params.(WorkDoneProgressCreateParams); ok {
+		c.created = append(c.created, p.Token)
+		return jsonrpc2.NewNumberID(int32(len(c.created))), nil
+	}
+	c.edits = append(c.edits, params.(ApplyWorkspaceEditParams).Edit)
+	res := result.(*ApplyWorkspaceEditResult)
+	res.Applied = len(c.edits) != c.failAt
+	if !res.Applied {
+		res.FailureReason = "file changed on disk"
+	}
+	return jsonrpc2.NewNumberID(int32(len(c.edits))), nil
+}
+
+func TestApplyWorkspaceEditFailureHandling(t *testing.T) {
+	edit := WorkspaceEdit{Changes: map[string][]TextEdit{
+		"file:///app/a.rb": {editAt(0, 0, 1, "x")},
+		"file:///app/b.rb": {editAt(0, 0, 1, "x")},
+		"file:///app/c.rb": {editAt(0, 0, 1, "x")},
+	}}
+
+	// Transactional clients get everything at once
+	s := newTestServer("/app")
+	client := &fakeClient{}
+	s.client = client
+	s.capabilities.Workspace.WorkspaceEdit.FailureHandling = FailureHandlingTransactional
+	if err := s.applyWorkspaceEdit(context.Background(), "Rename", edit); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if len(client.edits) != 1 || len(client.edits[0].Changes) != 3 {
+		t.Errorf("expected a single request with every file, got %+v", client.edits)
+	}
+
+	// Others get one file per request, stopping at the first failure
+	s = newTestServer("/app")
+	client = &fakeClient{failAt: 2}
+	s.client = client
+	s.capabilities.Workspace.WorkspaceEdit.FailureHandling = FailureHandlingAbort
+	err := s.applyWorkspaceEdit(context.Background(), "Rename", edit)
+	if err == nil || !strings.Contains(err.Error(), "applied 1 of 3 files") {
+		t.Errorf("expected partial failure error, got %v", err)
+	}
+	if len(client.edits) != 2 {
+		t.Errorf("expected to stop after the failed file, sent %d requests", len(client.edits))
+	}
+
+	// Read-only mode never asks the client to edit
+	s = newTestServer("/app")
+	client = &fakeClient{}
+	s.client = client
+	s.config.Store(&Config{ReadOnly: true})
+	if err := s.applyWorkspaceEdit(context.Background(), "Rename", edit); err != errReadOnly {
+		t.Errorf("expected read-only error, got %v", err)
+	}
+	if len(client.edits) != 0 {
+		t.Errorf("expected no requests in read-only mode, sent %d", len(client.edits))
+	}
+}
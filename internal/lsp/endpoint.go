@@ -0,0 +1,26 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// A request in a request or Rack::Test spec: get "/orders/12" /
+// post("/orders", params: {...}) / delete "/orders/#{order.id}"
+var requestPathPattern = regexp.MustCompile(`\b(get|post|put|patch|delete|head|options)\b\s*\(?\s*(?:"(/[^"]*)"|'(/[^']*)')`)
+
+// requestPathAt returns the verb and path of a request whose path string
+// is under the cursor, or ok false
+func requestPathAt(content string, line, char int) (verb, path string, ok bool) {
+	text := lineAt(content, line)
+	for _, loc := range requestPathPattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[4], loc[5]
+		if start < 0 {
+			start, end = loc[6], loc[7]
+		}
+		if char >= start-1 && char <= end {
+			return strings.ToLower(text[loc[2]:loc[3]]), text[start:end], true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,39 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDefinitionOfRequestPath(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/storefront.rb", []byte("class Storefront < Sinatra::Base\n  get \"/orders/:id\" do\n  end\nend\n"), 0)
+
+	uri := "file:///app/spec/requests/orders_spec.rb"
+	s.documents.Open(uri, 1, "it \"shows the order\" do\n  get \"/orders/#{order.id}\", headers: auth\n  post '/orders/12'\nend\n")
+
+	definition := func(line, char uint32) json.RawMessage {
+		t.Helper()
+		raw, err := callHandler(t, s, "textDocument/definition", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line, Character: char},
+		})
+		if err != nil {
+			t.Fatalf("definition failed: %v", err)
+		}
+		return raw
+	}
+
+	var loc Location
+	if err := json.Unmarshal(definition(1, 10), &loc); err != nil {
+		t.Fatalf("failed to decode location: %v", err)
+	}
+	if loc.URI != "file:///app/storefront.rb" || loc.Range.Start.Line != 1 {
+		t.Errorf("expected the GET /orders/:id endpoint, got %+v", loc)
+	}
+
+	// No POST endpoint serves the path
+	if raw := definition(2, 10); raw != nil && string(raw) != "null" {
+		t.Errorf("expected no definition, got %s", raw)
+	}
+}
@@ -0,0 +1,58 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/parser"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// FoldingRangeParams for textDocument/foldingRange
+type FoldingRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// FoldingRange is a foldable span of lines, 0-indexed
+type FoldingRange struct {
+	StartLine uint32 `json:"startLine"`
+	EndLine   uint32 `json:"endLine"`
+}
+
+// foldingRanges turns scanned blocks into folding ranges, folding up to
+// the line before each `end` so the end stays visible. One-line and
+// two-line blocks have nothing to fold and are skipped.
+func foldingRanges(blocks []parser.Block) []FoldingRange {
+	ranges := make([]FoldingRange, 0, len(blocks))
+	for _, b := range blocks {
+		if b.EndLine-b.StartLine < 2 {
+			continue
+		}
+		ranges = append(ranges, FoldingRange{
+			StartLine: uint32(b.StartLine - 1),
+			EndLine:   uint32(b.EndLine - 2),
+		})
+	}
+	return ranges
+}
+
+func (s *Server) handleFoldingRange(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params FoldingRangeParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	content := s.getDocumentContent(uri)
+	if strings.TrimSpace(content) == "" {
+		return reply(ctx, []FoldingRange{}, nil)
+	}
+
+	// Scan the live document so folds match unsaved edits
+	blocks := s.index.BlocksInContent(uriToPath(uri), content)
+	return reply(ctx, foldingRanges(blocks), nil)
+}
@@ -0,0 +1,48 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFoldingRange(t *testing.T) {
+	s := newTestServer("/app")
+
+	uri := "file:///app/order.rb"
+	content := `class Order
+  def self.build
+  end
+
+  def total
+    if items.any?
+      items.each do |item|
+        item.price
+      end
+    end
+  end
+end
+`
+	s.documents.Open(uri, 1, content)
+
+	raw, err := callHandler(t, s, "textDocument/foldingRange", FoldingRangeParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var ranges []FoldingRange
+	if err := json.Unmarshal(raw, &ranges); err != nil {
+		t.Fatalf("failed to decode ranges: %v", err)
+	}
+
+	// The empty self.build has nothing to fold; each fold stops before its end
+	want := []FoldingRange{{0, 10}, {4, 9}, {5, 8}, {6, 7}}
+	if len(ranges) != len(want) {
+		t.Fatalf("expected %v, got %v", want, ranges)
+	}
+	for i := range want {
+		if ranges[i] != want[i] {
+			t.Errorf("range %d: expected %v, got %v", i, want[i], ranges[i])
+		}
+	}
+}
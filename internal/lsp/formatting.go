@@ -0,0 +1,153 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+// formatTimeout bounds a formatter run; RuboCop's first run in a project
+// can take several seconds to load
+const formatTimeout = 30 * time.Second
+
+// DocumentFormattingParams for textDocument/formatting. The formatter's own
+// configuration decides indentation, so the options are ignored.
+type DocumentFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// formatter is an external command that reads Ruby on stdin and writes the
+// corrected source to stdout
+type formatter struct {
+	binary string
+	args   []string // Before --stdin <path>
+}
+
+// defaultFormatterArgs autocorrect, and send offense reports to stderr so
+// stdout holds only the corrected source
+var defaultFormatterArgs = map[string][]string{
+	"rubocop":    {"--autocorrect", "--stderr", "--format", "quiet"},
+	"standardrb": {"--fix", "--stderr", "--format", "quiet"},
+}
+
+// projectFormatter picks the formatter for the project at root: the
+// configured binary, else RuboCop when the project has a .rubocop.yml,
+// StandardRB when it has a .standard.yml, and RuboCop otherwise
+func (s *Server) projectFormatter(root string) formatter {
+	config := s.settings()
+	binary := config.Formatter
+	if binary == "" {
+		binary = "rubocop"
+		if !fileExists(filepath.Join(root, ".rubocop.yml")) && fileExists(filepath.Join(root, ".standard.yml")) {
+			binary = "standardrb"
+		}
+	}
+
+	args := config.FormatterArgs
+	if args == nil {
+		args = defaultFormatterArgs[filepath.Base(binary)]
+		if args == nil {
+			args = defaultFormatterArgs["rubocop"]
+		}
+	}
+	return formatter{binary: binary, args: args}
+}
+
+// run formats content as the file at path, from the project root so the
+// formatter finds the project's configuration
+func (f formatter) run(ctx context.Context, root, path, content string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, formatTimeout)
+	defer cancel()
+
+	args := append(append([]string{}, f.args...), "--stdin", path)
+	cmd := exec.CommandContext(ctx, f.binary, args...)
+	cmd.Dir = root
+	cmd.Stdin = strings.NewReader(content)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	// RuboCop and StandardRB exit 1 when offenses remain after correcting,
+	// which still leaves the corrected source on stdout
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 && stdout.Len() > 0 {
+		err = nil
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %v: %s", f.binary, err, msg)
+		}
+		return "", fmt.Errorf("%s: %v", f.binary, err)
+	}
+	return stdout.String(), nil
+}
+
+// formattingEdits turns a formatted copy of content into edits: a single
+// edit replacing the lines between the unchanged head and tail, or none
+// when nothing changed
+func formattingEdits(content, formatted string) []TextEdit {
+	if content == formatted {
+		return []TextEdit{}
+	}
+	before := strings.SplitAfter(content, "\n")
+	after := strings.SplitAfter(formatted, "\n")
+
+	head := 0
+	for head < len(before) && head < len(after) && before[head] == after[head] {
+		head++
+	}
+	tail := 0
+	for tail < len(before)-head && tail < len(after)-head &&
+		before[len(before)-1-tail] == after[len(after)-1-tail] {
+		tail++
+	}
+
+	// Replace from the start of the first changed line to the start of the
+	// first unchanged tail line (or the end of the document)
+	end := Position{Line: uint32(len(before) - tail)}
+	if tail == 0 {
+		last := before[len(before)-1]
+		end = Position{Line: uint32(len(before) - 1), Character: uint32(len(last))}
+	}
+	return []TextEdit{{
+		Range:   Range{Start: Position{Line: uint32(head)}, End: end},
+		NewText: strings.Join(after[head:len(after)-tail], ""),
+	}}
+}
+
+func (s *Server) handleFormatting(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params DocumentFormattingParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	// Formatters load the project's configuration, which can require code
+	if s.isReadOnly() {
+		return reply(ctx, nil, &jsonrpc2.Error{Code: codeRequestFailed, Message: errReadOnly.Error()})
+	}
+
+	uri := params.TextDocument.URI
+	content := s.getDocumentContent(uri)
+	root := s.index.RootPath()
+	formatted, err := s.projectFormatter(root).run(ctx, root, uriToPath(uri), content)
+	if err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{Code: codeRequestFailed, Message: err.Error()})
+	}
+	return reply(ctx, formattingEdits(content, formatted), nil)
+}
+
+// fileExists reports whether path is an existing regular file
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
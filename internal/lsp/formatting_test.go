@@ -0,0 +1,142 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFormattingEdits(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		formatted string
+		want      []TextEdit
+	}{
+		{"unchanged", "a\nb\n", "a\nb\n", []TextEdit{}},
+		{
+			"middle line",
+			"a\nb  \nc\n", "a\nb\nc\n",
+			[]TextEdit{{Range: Range{Start: Position{Line: 1}, End: Position{Line: 2}}, NewText: "b\n"}},
+		},
+		{
+			"appended newline",
+			"a\nb", "a\nb\n",
+			[]TextEdit{{Range: Range{Start: Position{Line: 1}, End: Position{Line: 1, Character: 1}}, NewText: "b\n"}},
+		},
+		{
+			"inserted line",
+			"a\nc\n", "a\nb\nc\n",
+			[]TextEdit{{Range: Range{Start: Position{Line: 1}, End: Position{Line: 1}}, NewText: "b\n"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formattingEdits(tt.content, tt.formatted)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+			if len(got) == 1 {
+				if applied := applyEdit(tt.content, got[0]); applied != tt.formatted {
+					t.Errorf("applying the edit gives %q, want %q", applied, tt.formatted)
+				}
+			}
+		})
+	}
+}
+
+// applyEdit applies a single text edit to content
+func applyEdit(content string, edit TextEdit) string {
+	offset := func(pos Position) int {
+		lines := strings.SplitAfter(content, "\n")
+		n := 0
+		for i := 0; i < int(pos.Line) && i < len(lines); i++ {
+			n += len(lines[i])
+		}
+		return n + int(pos.Character)
+	}
+	return content[:offset(edit.Range.Start)] + edit.NewText + content[offset(edit.Range.End):]
+}
+
+func TestFormatting(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the formatter")
+	}
+	root := t.TempDir()
+	argsFile := filepath.Join(root, "args")
+	script := filepath.Join(root, "fake-rubocop")
+	err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" > "+argsFile+"\nsed 's/[[:space:]]*$//'\nexit 1\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestServer(root)
+	s.config.Store(&Config{Formatter: script})
+	uri := pathToURI(filepath.Join(root, "app", "order.rb"))
+	s.documents.Open(uri, 1, "class Order  \n  def total\n  end\nend\n")
+
+	format := func() ([]TextEdit, error) {
+		raw, err := callHandler(t, s, "textDocument/formatting", DocumentFormattingParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+		})
+		if err != nil {
+			return nil, err
+		}
+		var edits []TextEdit
+		if err := json.Unmarshal(raw, &edits); err != nil {
+			t.Fatalf("failed to decode edits: %v", err)
+		}
+		return edits, nil
+	}
+
+	edits, err := format()
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	want := []TextEdit{{Range: Range{Start: Position{Line: 0}, End: Position{Line: 1}}, NewText: "class Order\n"}}
+	if !reflect.DeepEqual(edits, want) {
+		t.Errorf("expected %+v, got %+v", want, edits)
+	}
+	args, _ := os.ReadFile(argsFile)
+	if got := strings.TrimSpace(string(args)); got != "--autocorrect --stderr --format quiet --stdin "+uriToPath(uri) {
+		t.Errorf("unexpected formatter arguments %q", got)
+	}
+
+	s.config.Store(&Config{Formatter: script, ReadOnly: true})
+	if _, err := format(); err == nil {
+		t.Error("expected formatting to be refused in read-only mode")
+	}
+}
+
+func TestProjectFormatter(t *testing.T) {
+	root := t.TempDir()
+	s := newTestServer(root)
+
+	if f := s.projectFormatter(root); f.binary != "rubocop" {
+		t.Errorf("expected rubocop by default, got %+v", f)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, ".standard.yml"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if f := s.projectFormatter(root); f.binary != "standardrb" || f.args[0] != "--fix" {
+		t.Errorf("expected standardrb for a .standard.yml project, got %+v", f)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, ".rubocop.yml"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if f := s.projectFormatter(root); f.binary != "rubocop" {
+		t.Errorf("expected .rubocop.yml to win, got %+v", f)
+	}
+
+	s.config.Store(&Config{Formatter: "bin/rubocop", FormatterArgs: []string{"-A"}})
+	if f := s.projectFormatter(root); f.binary != "bin/rubocop" || !reflect.DeepEqual(f.args, []string{"-A"}) {
+		t.Errorf("expected the configured formatter, got %+v", f)
+	}
+}
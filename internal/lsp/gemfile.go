@@ -0,0 +1,252 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+)
+
+// A locked gem under specs: in Gemfile.lock, indented four spaces:
+// "    rails (7.1.2)" / "    nokogiri (1.15.4-x86_64-linux)"
+var lockedGemPattern = regexp.MustCompile(`^    ([\w.-]+) \(([^)]+)\)$`)
+
+// The start of a gem name being typed: gem "ra / spec.add_dependency 'ra
+var gemNamePrefixPattern = regexp.MustCompile(`(?:^\s*gem|\w\.add_(?:runtime_|development_)?dependency)\s*\(?\s*["']([\w.-]*)$`)
+
+// lockedGem is a gem Bundler resolved, and where the lockfile lists it
+type lockedGem struct {
+	name    string
+	version string
+	line    int // 0-based
+}
+
+// isBundlerFile reports whether path declares gem dependencies: a Gemfile,
+// gems.rb, or gemspec
+func isBundlerFile(path string) bool {
+	base := filepath.Base(path)
+	return base == "Gemfile" || base == "gems.rb" || filepath.Ext(path) == ".gemspec"
+}
+
+// lockfilePath returns the lockfile that resolves a Bundler file's
+// dependencies: gems.locked for gems.rb, otherwise the Gemfile.lock next
+// to it (a gem's own Gemfile usually just loads its gemspec)
+func lockfilePath(path string) string {
+	if filepath.Base(path) == "gems.rb" {
+		return filepath.Join(filepath.Dir(path), "gems.locked")
+	}
+	return filepath.Join(filepath.Dir(path), "Gemfile.lock")
+}
+
+// lockedGems reads the gems a lockfile resolves, in lockfile order. A
+// missing lockfile has none.
+func lockedGems(lockfile string) []lockedGem {
+	f, err := os.Open(lockfile)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var gems []lockedGem
+	scanner := bufio.NewScanner(f)
+	for line := 0; scanner.Scan(); line++ {
+		if m := lockedGemPattern.FindStringSubmatch(scanner.Text()); m != nil {
+			gems = append(gems, lockedGem{name: m[1], version: m[2], line: line})
+		}
+	}
+	return gems
+}
+
+// gemDependencyAt returns the gem whose name is under the cursor among a
+// file's symbols, or "". line is 0-based.
+func gemDependencyAt(symbols []*index.Symbol, line, char int) string {
+	for _, sym := range symbols {
+		if sym.Kind == index.KindGem && sym.Line == line+1 &&
+			char >= sym.Column && char <= sym.Column+len(sym.Name) {
+			return sym.Name
+		}
+	}
+	return ""
+}
+
+// gemLocations returns where a dependency of a Bundler file resolves: its
+// entry in the lockfile and the installed gem, when each can be found
+func gemLocations(path, name string) []Location {
+	var locations []Location
+	version := ""
+	lockfile := lockfilePath(path)
+	for _, gem := range lockedGems(lockfile) {
+		if gem.name != name {
+			continue
+		}
+		version = gem.version
+		start := Position{Line: uint32(gem.line), Character: 4}
+		end := Position{Line: uint32(gem.line), Character: uint32(4 + len(name))}
+		locations = append(locations, Location{URI: pathToURI(lockfile), Range: Range{Start: start, End: end}})
+		break
+	}
+
+	if target := installedGemFile(name, version); target != "" {
+		locations = append(locations, Location{URI: pathToURI(target)})
+	}
+	return locations
+}
+
+// installedGemFile finds the file that loads an installed gem under
+// GEM_HOME or GEM_PATH: lib/rails.rb, lib/net/http.rb for net-http, or
+// failing those the gem's specification. Without a locked version the
+// newest installed one is used.
+func installedGemFile(name, version string) string {
+	gemDir, dir := installedGem(name, version)
+	if dir == "" {
+		return ""
+	}
+
+	lib := filepath.Join(dir, "lib")
+	for _, file := range []string{name, strings.ReplaceAll(name, "-", "/")} {
+		if target := rubyFile(filepath.Join(lib, file)); target != "" {
+			return target
+		}
+	}
+	spec := filepath.Join(gemDir, "specifications", filepath.Base(dir)+".gemspec")
+	if fileExists(spec) {
+		return spec
+	}
+	return ""
+}
+
+// installedGem returns the directory a gem is installed in under GEM_HOME
+// or GEM_PATH (gems/rails-7.1.2), and the gem directory holding it, or ""
+// when it isn't installed. Without a locked version the newest installed
+// one is used.
+func installedGem(name, version string) (gemDir, dir string) {
+	for _, gemDir := range gemPaths() {
+		dir := filepath.Join(gemDir, "gems", name+"-"+version)
+		if version == "" {
+			// Sorted, the last match is usually the newest version
+			matches, _ := filepath.Glob(filepath.Join(gemDir, "gems", name+"-[0-9]*"))
+			if len(matches) == 0 {
+				continue
+			}
+			sort.Strings(matches)
+			dir = matches[len(matches)-1]
+		}
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return gemDir, dir
+		}
+	}
+	return "", ""
+}
+
+// gemIndexStatus is how many of the gems a lockfile resolves are indexed,
+// and the source directories of the installed ones that aren't
+type gemIndexStatus struct {
+	indexed int
+	skipped int
+	pending []string
+}
+
+// gemStatus checks which gems a lockfile resolves have files in the
+// index. A gem counts as indexed once any of its files are, whether by
+// goruby.indexGems or an extra root.
+func (s *Server) gemStatus(lockfile string) gemIndexStatus {
+	var status gemIndexStatus
+	for _, gem := range lockedGems(lockfile) {
+		_, dir := installedGem(gem.name, gem.version)
+		switch {
+		case dir != "" && s.index.HasFilesUnder(dir):
+			status.indexed++
+		case dir != "":
+			status.skipped++
+			status.pending = append(status.pending, gemSourceDir(dir))
+		default:
+			status.skipped++ // Not installed
+		}
+	}
+	return status
+}
+
+// gemSourceDir is the part of an installed gem worth indexing: its lib
+// directory, leaving out tests and examples, or the whole gem without one
+func gemSourceDir(dir string) string {
+	if info, err := os.Stat(filepath.Join(dir, "lib")); err == nil && info.IsDir() {
+		return filepath.Join(dir, "lib")
+	}
+	return dir
+}
+
+// indexGems indexes the installed gems a lockfile resolves that aren't
+// indexed yet. Gems without Ruby files are skipped. Gem files aren't
+// watched; they only change when the lockfile does.
+func (s *Server) indexGems(ctx context.Context, lockfile string) (index.ReindexResult, error) {
+	var total index.ReindexResult
+	for _, dir := range s.gemStatus(lockfile).pending {
+		result, err := s.index.ReindexPath(ctx, dir)
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+		if err != nil {
+			log.Printf("skipping gem %s: %v", dir, err)
+			continue
+		}
+		total.Files += result.Files
+		total.Removed += result.Removed
+	}
+	return total, nil
+}
+
+// gemfileLenses returns a lens atop a Bundler file summarizing how many of
+// its locked gems are indexed. While installed gems are left out, it runs
+// goruby.indexGems for the file.
+func (s *Server) gemfileLenses(uri, path string) []CodeLens {
+	lockfile := lockfilePath(path)
+	if len(lockedGems(lockfile)) == 0 {
+		return []CodeLens{}
+	}
+	status := s.gemStatus(lockfile)
+	lens := CodeLens{Command: &Command{
+		Title: fmt.Sprintf("%d %s indexed, %d skipped", status.indexed, plural(status.indexed, "gem", "gems"), status.skipped),
+	}}
+	if len(status.pending) > 0 {
+		lens.Command.Title += fmt.Sprintf(" (index %d installed)", len(status.pending))
+		lens.Command.Command = indexGemsCommand
+		lens.Command.Arguments = []interface{}{uri}
+	}
+	return []CodeLens{lens}
+}
+
+// gemCompletions offers the lockfile's gems while a dependency's name is
+// typed in a Bundler file. ok is false anywhere else on the line.
+func gemCompletions(path, content string, line, char int) (list CompletionList, ok bool) {
+	lines := strings.Split(content, "\n")
+	if line < 0 || line >= len(lines) {
+		return list, false
+	}
+	text := lines[line]
+	if char > len(text) {
+		char = len(text)
+	}
+	m := gemNamePrefixPattern.FindStringSubmatch(text[:char])
+	if m == nil {
+		return list, false
+	}
+
+	list.Items = []CompletionItem{}
+	for _, gem := range lockedGems(lockfilePath(path)) {
+		if strings.HasPrefix(gem.name, m[1]) {
+			list.Items = append(list.Items, CompletionItem{
+				Label:  gem.name,
+				Kind:   CompletionItemKindModule,
+				Detail: gem.version,
+			})
+		}
+	}
+	return list, true
+}
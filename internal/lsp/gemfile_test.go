@@ -0,0 +1,175 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGemfileNavigation(t *testing.T) {
+	root := t.TempDir()
+	gems := t.TempDir()
+	t.Setenv("GEM_HOME", "")
+	t.Setenv("GEM_PATH", gems)
+
+	lockfile := filepath.Join(root, "Gemfile.lock")
+	if err := os.WriteFile(lockfile, []byte(`GEM
+  remote: https://rubygems.org/
+  specs:
+    rails (7.1.2)
+      railties (= 7.1.2)
+    rspec-rails (6.1.0)
+
+DEPENDENCIES
+  rails (~> 7.1)
+  rspec-rails
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"rails-7.1.0/lib/rails.rb", "rails-7.1.2/lib/rails.rb"} {
+		path := filepath.Join(gems, "gems", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := newTestServer(root)
+	uri := pathToURI(filepath.Join(root, "Gemfile"))
+	s.documents.Open(uri, 1, `source "https://rubygems.org"
+
+gem "rails", "~> 7.1"
+
+group :development, :test do
+  gem "rspec-rails"
+  gem "rs
+end`)
+
+	raw, err := callHandler(t, s, "textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: 2, Character: 7},
+	})
+	if err != nil {
+		t.Fatalf("definition failed: %v", err)
+	}
+	var locations []Location
+	if err := json.Unmarshal(raw, &locations); err != nil {
+		t.Fatalf("failed to decode locations: %v", err)
+	}
+	if len(locations) != 2 {
+		t.Fatalf("expected the lockfile entry and installed gem, got %+v", locations)
+	}
+	if locations[0].URI != pathToURI(lockfile) || locations[0].Range.Start.Line != 3 || locations[0].Range.End.Character != 9 {
+		t.Errorf("expected rails on line 3 of the lockfile, got %+v", locations[0])
+	}
+	if want := pathToURI(filepath.Join(gems, "gems", "rails-7.1.2", "lib", "rails.rb")); locations[1].URI != want {
+		t.Errorf("expected the locked version's %s, got %s", want, locations[1].URI)
+	}
+
+	raw, err = callHandler(t, s, "textDocument/completion", CompletionParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: 6, Character: 9},
+		},
+	})
+	if err != nil {
+		t.Fatalf("completion failed: %v", err)
+	}
+	var list CompletionList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		t.Fatalf("failed to decode completions: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Label != "rspec-rails" || list.Items[0].Detail != "6.1.0" {
+		t.Errorf("expected rspec-rails 6.1.0, got %+v", list.Items)
+	}
+
+	raw, err = callHandler(t, s, "textDocument/documentSymbol", DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		t.Fatalf("documentSymbol failed: %v", err)
+	}
+	var outline []DocumentSymbol
+	if err := json.Unmarshal(raw, &outline); err != nil {
+		t.Fatalf("failed to decode outline: %v", err)
+	}
+	if len(outline) != 2 || outline[0].Detail != "gem ~> 7.1" || outline[1].Name != "development, test" {
+		t.Fatalf("expected rails and the group, got %+v", outline)
+	}
+	if children := outline[1].Children; len(children) != 1 || children[0].Name != "rspec-rails" {
+		t.Errorf("expected the group's gems nested under it, got %+v", children)
+	}
+}
+
+func TestGemfileIndexLens(t *testing.T) {
+	root := t.TempDir()
+	gems := t.TempDir()
+	t.Setenv("GEM_HOME", "")
+	t.Setenv("GEM_PATH", gems)
+
+	if err := os.WriteFile(filepath.Join(root, "Gemfile.lock"), []byte(`GEM
+  remote: https://rubygems.org/
+  specs:
+    money (6.19.0)
+    rspec-rails (6.1.0)
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"money-6.19.0/lib/money.rb":       "class Money\nend\n",
+		"money-6.19.0/spec/money_spec.rb": "describe Money do\nend\n",
+	} {
+		path := filepath.Join(gems, "gems", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := newTestServer(root)
+	uri := pathToURI(filepath.Join(root, "Gemfile"))
+	s.documents.Open(uri, 1, "gem \"money\"\ngem \"rspec-rails\"\n")
+
+	lenses := func() []CodeLens {
+		t.Helper()
+		raw, err := callHandler(t, s, "textDocument/codeLens", CodeLensParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+		if err != nil {
+			t.Fatalf("codeLens failed: %v", err)
+		}
+		var lenses []CodeLens
+		if err := json.Unmarshal(raw, &lenses); err != nil {
+			t.Fatalf("failed to decode lenses: %v", err)
+		}
+		return lenses
+	}
+
+	got := lenses()
+	if len(got) != 1 || got[0].Command.Title != "0 gems indexed, 2 skipped (index 1 installed)" || got[0].Command.Command != indexGemsCommand {
+		t.Fatalf("expected a lens offering to index money, got %+v", got)
+	}
+
+	raw, err := callHandler(t, s, "workspace/executeCommand", ExecuteCommandParams{
+		Command:   got[0].Command.Command,
+		Arguments: []json.RawMessage{json.RawMessage(`"` + uri + `"`)},
+	})
+	if err != nil {
+		t.Fatalf("executeCommand failed: %v", err)
+	}
+	var result struct{ Files int }
+	if err := json.Unmarshal(raw, &result); err != nil || result.Files != 1 {
+		t.Errorf("expected the gem's lib file indexed, got %s", raw)
+	}
+	if len(s.index.FindDefinitions("Money")) != 1 {
+		t.Error("expected Money defined by the gem")
+	}
+
+	got = lenses()
+	if len(got) != 1 || got[0].Command.Title != "1 gem indexed, 1 skipped" || got[0].Command.Command != "" {
+		t.Errorf("expected a summary once indexed, got %+v", got)
+	}
+}
@@ -0,0 +1,126 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// DocumentHighlightKind tells plain text matches from reads and writes
+type DocumentHighlightKind int
+
+const (
+	DocumentHighlightText  DocumentHighlightKind = 1
+	DocumentHighlightRead  DocumentHighlightKind = 2
+	DocumentHighlightWrite DocumentHighlightKind = 3
+)
+
+// DocumentHighlight is one occurrence of the symbol under the cursor
+type DocumentHighlight struct {
+	Range Range                 `json:"range"`
+	Kind  DocumentHighlightKind `json:"kind,omitempty"`
+}
+
+// An assignment or compound assignment following a name: = / += / ||=
+var compoundAssignmentAfterPattern = regexp.MustCompile(`^\s*(?:\|\||&&|\*\*|<<|>>|[-+*/%|&^])?=(?:[^=~>]|$)`)
+
+// highlight returns the highlight for a reference n bytes long
+func highlight(ref *index.Reference, n int, kind DocumentHighlightKind) DocumentHighlight {
+	line := uint32(ref.Line - 1)
+	return DocumentHighlight{
+		Range: Range{
+			Start: Position{Line: line, Character: uint32(ref.Column)},
+			End:   Position{Line: line, Character: uint32(ref.Column + n)},
+		},
+		Kind: kind,
+	}
+}
+
+// isLocalOccurrence reports whether a reference to a local variable n bytes
+// long is the variable itself rather than a method call, instance variable,
+// symbol, or predicate with the same name
+func isLocalOccurrence(ref *index.Reference, n int) bool {
+	if prev := charBefore(ref); prev == '.' || prev == '@' || prev == '$' || prev == ':' {
+		return false
+	}
+	next := charAfter(ref, n)
+	return next != '?' && next != '!'
+}
+
+// localOccurrences returns the references to a local variable within its
+// method, or the lambda it's scoped to
+func (s *Server) localOccurrences(local *index.Symbol, refs []*index.Reference) []*index.Reference {
+	first, last := s.index.LocalScope(local)
+
+	var occurrences []*index.Reference
+	for _, ref := range refs {
+		if ref.Line >= first && ref.Line <= last && isLocalOccurrence(ref, len(local.Name)) {
+			occurrences = append(occurrences, ref)
+		}
+	}
+	return occurrences
+}
+
+// localHighlights returns the occurrences of a local variable within its
+// method, marking assignments as writes
+func (s *Server) localHighlights(local *index.Symbol, refs []*index.Reference) []DocumentHighlight {
+	// Positions the parser recorded as assignments, including each name
+	// in a multiple assignment
+	assigned := make(map[[2]int]bool)
+	for _, sym := range s.index.SymbolsInFile(local.FilePath) {
+		if sym.Kind == index.KindLocalVariable && sym.Name == local.Name {
+			assigned[[2]int{sym.Line, sym.Column}] = true
+		}
+	}
+
+	n := len(local.Name)
+	highlights := []DocumentHighlight{}
+	for _, ref := range s.localOccurrences(local, refs) {
+		kind := DocumentHighlightRead
+		if assigned[[2]int{ref.Line, ref.Column}] || compoundAssignmentAfterPattern.MatchString(ref.LineText[ref.Column+n:]) {
+			kind = DocumentHighlightWrite
+		}
+		highlights = append(highlights, highlight(ref, n, kind))
+	}
+	return highlights
+}
+
+// handleDocumentHighlight highlights the occurrences of the identifier
+// under the cursor in the current file
+func (s *Server) handleDocumentHighlight(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	content := s.getDocumentContent(uri)
+	s.syncDocument(uri)
+
+	word := extractWordAt(content, int(params.Position.Line), int(params.Position.Character))
+	if i := strings.LastIndex(word, "::"); i >= 0 {
+		word = word[i+2:]
+	}
+	if word == "" || rubyKeywords[word] {
+		return reply(ctx, []DocumentHighlight{}, nil)
+	}
+
+	path := uriToPath(uri)
+	refs := s.index.FindReferencesInContent(word, path, content)
+	if local := s.index.FindLocalVariable(word, path, int(params.Position.Line)+1); local != nil {
+		return reply(ctx, s.localHighlights(local, refs), nil)
+	}
+
+	highlights := make([]DocumentHighlight, 0, len(refs))
+	for _, ref := range refs {
+		highlights = append(highlights, highlight(ref, ref.Length, DocumentHighlightText))
+	}
+	return reply(ctx, highlights, nil)
+}
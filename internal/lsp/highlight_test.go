@@ -0,0 +1,77 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDocumentHighlight(t *testing.T) {
+	s := newTestServer("/app")
+
+	uri := "file:///app/order.rb"
+	content := `class Order
+  def total
+    sum = 0
+    items.each do |item|
+      sum += item.price
+    end
+    sum, count = sum.round, items.size
+    sum
+  end
+
+  def tax
+    sum = total * RATE
+  end
+end
+`
+	s.documents.Open(uri, 1, content)
+
+	highlights := func(line, char uint32) []DocumentHighlight {
+		t.Helper()
+		raw, err := callHandler(t, s, "textDocument/documentHighlight", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line, Character: char},
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var result []DocumentHighlight
+		if err := json.Unmarshal(raw, &result); err != nil {
+			t.Fatalf("failed to decode highlights: %v", err)
+		}
+		return result
+	}
+
+	// A local variable is highlighted within its method, assignments as writes
+	got := highlights(7, 5)
+	want := []struct {
+		line, char uint32
+		kind       DocumentHighlightKind
+	}{
+		{2, 4, DocumentHighlightWrite},
+		{4, 6, DocumentHighlightWrite},
+		{6, 4, DocumentHighlightWrite},
+		{6, 17, DocumentHighlightRead},
+		{7, 4, DocumentHighlightRead},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d highlights, got %+v", len(want), got)
+	}
+	for i, w := range want {
+		h := got[i]
+		if h.Range.Start.Line != w.line || h.Range.Start.Character != w.char || h.Range.End.Character != w.char+3 || h.Kind != w.kind {
+			t.Errorf("highlight %d: expected %d:%d kind %d, got %+v", i, w.line, w.char, w.kind, h)
+		}
+	}
+
+	// Anything else is highlighted everywhere in the file as text
+	got = highlights(1, 7)
+	if len(got) != 2 || got[0].Kind != DocumentHighlightText || got[1].Range.Start.Line != 11 {
+		t.Errorf("expected total's definition and its call, got %+v", got)
+	}
+
+	// Keywords aren't highlighted
+	if got := highlights(3, 16); len(got) != 0 {
+		t.Errorf("expected no highlights for do, got %+v", got)
+	}
+}
@@ -0,0 +1,81 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// handleImplementation lists what implements the symbol under the cursor:
+// for a method, the definitions overriding it in subclasses and in classes
+// including its module; for a class or module, its descendants. Unlike
+// definition, the declaration itself isn't included.
+func (s *Server) handleImplementation(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	line := int(params.Position.Line)
+	content := s.getDocumentContent(uri)
+	if content == "" {
+		return reply(ctx, nil, nil)
+	}
+	s.syncDocument(uri)
+
+	word := extractWordAt(content, line, int(params.Position.Character))
+	if word == "" {
+		return reply(ctx, nil, nil)
+	}
+
+	var locations []Location
+	seen := make(map[*index.Symbol]bool)
+	for _, sym := range s.implementations(word, uriToPath(uri), line+1) {
+		if !seen[sym] {
+			seen[sym] = true
+			locations = append(locations, symbolToLocation(sym))
+		}
+	}
+	if len(locations) == 0 {
+		return reply(ctx, nil, nil)
+	}
+	return reply(ctx, locations, nil)
+}
+
+// implementations resolves name as written at line (1-based) of path and
+// returns the overrides or descendants of what it names. A method the
+// enclosing class or module defines is taken over same-named methods
+// elsewhere, as on its def line.
+func (s *Server) implementations(name, path string, line int) []*index.Symbol {
+	defs := s.index.FindDefinitionsInContext(name, path, line)
+	if scope := s.index.ScopeAt(path, line); scope != nil {
+		enclosing := strings.Join(scope.Scope, "::")
+		if scope.Kind == index.KindClass || scope.Kind == index.KindModule {
+			enclosing = strings.TrimPrefix(enclosing+"::"+scope.Name, "::")
+		}
+		for _, sym := range defs {
+			if isMethodKind(sym.Kind) && strings.Join(sym.Scope, "::") == enclosing {
+				defs = []*index.Symbol{sym}
+				break
+			}
+		}
+	}
+
+	var result []*index.Symbol
+	for _, sym := range defs {
+		switch {
+		case isMethodKind(sym.Kind):
+			result = append(result, s.index.Overrides(sym)...)
+		case sym.Kind == index.KindClass || sym.Kind == index.KindModule:
+			result = append(result, s.index.Descendants(sym.FullName)...)
+		}
+	}
+	return result
+}
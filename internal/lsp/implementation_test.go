@@ -0,0 +1,48 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestImplementation(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/invoice.rb", []byte("class Invoice\n  include Payable\n\n  def total\n  end\nend\n"), 0)
+	s.index.UpdateContent("/app/credit_note.rb", []byte("class CreditNote < Invoice\n  def total\n  end\nend\n"), 0)
+	s.index.UpdateContent("/app/receipt.rb", []byte("class Receipt\n  def total\n  end\nend\n"), 0)
+
+	uri := "file:///app/payable.rb"
+	s.documents.Open(uri, 1, "module Payable\n  def total\n  end\nend\n")
+
+	implementations := func(line, char uint32) []Location {
+		t.Helper()
+		raw, err := callHandler(t, s, "textDocument/implementation", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line, Character: char},
+		})
+		if err != nil {
+			t.Fatalf("implementation failed: %v", err)
+		}
+		var locations []Location
+		if string(raw) != "null" {
+			if err := json.Unmarshal(raw, &locations); err != nil {
+				t.Fatalf("failed to decode locations: %v", err)
+			}
+		}
+		return locations
+	}
+
+	// The module's method, not Receipt#total, is overridden by Invoice and
+	// then CreditNote; the declaration itself isn't included
+	got := implementations(1, 7)
+	if len(got) != 2 || got[0].URI != "file:///app/invoice.rb" || got[0].Range.Start.Line != 3 ||
+		got[1].URI != "file:///app/credit_note.rb" || got[1].Range.Start.Line != 1 {
+		t.Errorf("expected Invoice#total and CreditNote#total, got %+v", got)
+	}
+
+	// A module is implemented by the classes including it and their subclasses
+	got = implementations(0, 8)
+	if len(got) != 2 || got[0].URI != "file:///app/invoice.rb" || got[1].URI != "file:///app/credit_note.rb" {
+		t.Errorf("expected Invoice and CreditNote, got %+v", got)
+	}
+}
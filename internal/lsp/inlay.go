@@ -0,0 +1,80 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// InlayHintKind distinguishes type hints from parameter hints
+type InlayHintKind int
+
+const (
+	InlayHintKindType      InlayHintKind = 1
+	InlayHintKindParameter InlayHintKind = 2
+)
+
+// InlayHintParams for textDocument/inlayHint
+type InlayHintParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+// InlayHint is an annotation shown inline after a position
+type InlayHint struct {
+	Position    Position      `json:"position"`
+	Label       string        `json:"label"`
+	Kind        InlayHintKind `json:"kind,omitempty"`
+	Tooltip     string        `json:"tooltip,omitempty"`
+	PaddingLeft bool          `json:"paddingLeft,omitempty"`
+}
+
+// relationHint annotates an association with the class it targets, or
+// flags it when no indexed class matches
+func (s *Server) relationHint(rel *index.Symbol) InlayHint {
+	name, fullName := s.index.RelationTarget(rel)
+	hint := InlayHint{
+		Position:    Position{Line: uint32(rel.Line - 1), Character: uint32(rel.Column + len(rel.Name))},
+		Kind:        InlayHintKindType,
+		PaddingLeft: true,
+	}
+	if fullName != "" {
+		hint.Label = "→ " + fullName
+	} else {
+		hint.Label = "→ " + name + " (not found)"
+		hint.Tooltip = "No class " + name + " is defined in the indexed files"
+	}
+	return hint
+}
+
+// handleInlayHint annotates the associations in the requested range with
+// their target classes
+func (s *Server) handleInlayHint(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params InlayHintParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	content := s.getDocumentContent(uri)
+	if strings.TrimSpace(content) == "" {
+		return reply(ctx, []InlayHint{}, nil)
+	}
+
+	// Parse the live document so hints follow unsaved edits
+	hints := []InlayHint{}
+	for _, sym := range s.index.ParseContent(uriToPath(uri), content) {
+		line := uint32(sym.Line - 1)
+		if sym.Kind != index.KindRelation || line < params.Range.Start.Line || line > params.Range.End.Line {
+			continue
+		}
+		hints = append(hints, s.relationHint(sym))
+	}
+	return reply(ctx, hints, nil)
+}
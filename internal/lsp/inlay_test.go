@@ -0,0 +1,65 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestInlayHintsForAssociations(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/comment.rb", []byte("class Comment\nend\n"), 0)
+	s.index.UpdateContent("/app/billing/invoice.rb", []byte("module Billing\n  class Invoice\n  end\nend\n"), 0)
+
+	uri := "file:///app/billing/account.rb"
+	content := `module Billing
+  class Account
+    has_many :comments
+    has_many :invoices, dependent: :destroy
+    belongs_to :owner, class_name: "Person"
+  end
+end
+`
+	s.documents.Open(uri, 1, content)
+
+	raw, err := callHandler(t, s, "textDocument/inlayHint", InlayHintParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Range:        Range{Start: Position{Line: 0}, End: Position{Line: 7}},
+	})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var hints []InlayHint
+	if err := json.Unmarshal(raw, &hints); err != nil {
+		t.Fatalf("failed to decode hints: %v", err)
+	}
+
+	want := []struct {
+		line, char uint32
+		label      string
+	}{
+		{2, 22, "→ Comment"},
+		{3, 22, "→ Billing::Invoice"},
+		{4, 21, "→ Person (not found)"},
+	}
+	if len(hints) != len(want) {
+		t.Fatalf("expected %d hints, got %+v", len(want), hints)
+	}
+	for i, w := range want {
+		h := hints[i]
+		if h.Position.Line != w.line || h.Position.Character != w.char || h.Label != w.label {
+			t.Errorf("hint %d: expected %q at %d:%d, got %q at %d:%d", i, w.label, w.line, w.char, h.Label, h.Position.Line, h.Position.Character)
+		}
+	}
+	if hints[2].Tooltip == "" {
+		t.Error("expected a tooltip explaining the missing class")
+	}
+
+	// Only hints inside the requested range are returned
+	raw, _ = callHandler(t, s, "textDocument/inlayHint", InlayHintParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Range:        Range{Start: Position{Line: 3}, End: Position{Line: 3}},
+	})
+	if err := json.Unmarshal(raw, &hints); err != nil || len(hints) != 1 {
+		t.Errorf("expected 1 hint in range, got %+v (%v)", hints, err)
+	}
+}
@@ -0,0 +1,123 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+// lifecycleState is where the server is between initialize and exit
+type lifecycleState int
+
+const (
+	stateUninitialized lifecycleState = iota // Waiting for initialize
+	stateInitializing                        // Handling initialize
+	stateInitialized                         // Serving requests
+	stateShutdown                            // After shutdown, waiting for exit
+)
+
+// lifecycle tracks the server's state and when the client asked it to exit
+type lifecycle struct {
+	mu     sync.Mutex
+	state  lifecycleState
+	exited chan struct{} // Closed by the exit notification
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{exited: make(chan struct{})}
+}
+
+var (
+	errAlreadyServing      = errors.New("server is already serving a connection")
+	errExitWithoutShutdown = errors.New("client sent exit without shutdown")
+)
+
+// guard enforces the LSP lifecycle around handler: before initialize,
+// requests fail with ServerNotInitialized and notifications are dropped;
+// initialize is accepted once; after shutdown, requests fail with
+// InvalidRequest. exit is always accepted and stops Serve.
+func (l *lifecycle) guard(handler jsonrpc2.Handler) jsonrpc2.Handler {
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		_, isCall := req.(*jsonrpc2.Call)
+
+		l.mu.Lock()
+		state := l.state
+		switch method := req.Method(); {
+		case method == "exit":
+			l.mu.Unlock()
+			l.exit()
+			return reply(ctx, nil, nil)
+
+		case method == "initialize" && state == stateUninitialized:
+			l.state = stateInitializing
+			l.mu.Unlock()
+			inner := reply
+			reply = func(ctx context.Context, result interface{}, err error) error {
+				l.mu.Lock()
+				if err == nil {
+					l.state = stateInitialized
+				} else {
+					l.state = stateUninitialized
+				}
+				l.mu.Unlock()
+				return inner(ctx, result, err)
+			}
+			return handler(ctx, reply, req)
+
+		case method == "initialize":
+			l.mu.Unlock()
+			return reply(ctx, nil, &jsonrpc2.Error{
+				Code:    jsonrpc2.InvalidRequest,
+				Message: "server is already initialized",
+			})
+
+		case state == stateInitialized:
+			if method == "shutdown" {
+				l.state = stateShutdown
+			}
+			l.mu.Unlock()
+			return handler(ctx, reply, req)
+		}
+		l.mu.Unlock()
+
+		if !isCall {
+			log.Printf("dropping %s notification: server is not running", req.Method())
+			return reply(ctx, nil, nil)
+		}
+		if state == stateShutdown {
+			return reply(ctx, nil, &jsonrpc2.Error{
+				Code:    jsonrpc2.InvalidRequest,
+				Message: "server is shutting down",
+			})
+		}
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.ServerNotInitialized,
+			Message: "server is not initialized",
+		})
+	}
+}
+
+// exit records the exit notification; repeats are ignored
+func (l *lifecycle) exit() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-l.exited:
+	default:
+		close(l.exited)
+	}
+}
+
+// exitErr is what Serve returns after exit: nil when the client shut the
+// server down first, as the protocol requires
+func (l *lifecycle) exitErr() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.state != stateShutdown {
+		return errExitWithoutShutdown
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package lsp
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+func TestLifecycleGuard(t *testing.T) {
+	l := newLifecycle()
+	var handled []string
+	handler := l.guard(func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		handled = append(handled, req.Method())
+		return reply(ctx, nil, nil)
+	})
+
+	send := func(method string, notify bool) error {
+		t.Helper()
+		var req jsonrpc2.Request
+		var err error
+		if notify {
+			req, err = jsonrpc2.NewNotification(method, nil)
+		} else {
+			req, err = jsonrpc2.NewCall(jsonrpc2.NewNumberID(1), method, nil)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var replyErr error
+		handler(context.Background(), func(ctx context.Context, result interface{}, err error) error {
+			replyErr = err
+			return nil
+		}, req)
+		return replyErr
+	}
+	code := func(err error) jsonrpc2.Code {
+		if rpcErr, ok := err.(*jsonrpc2.Error); ok {
+			return rpcErr.Code
+		}
+		return 0
+	}
+
+	if err := send("textDocument/definition", false); code(err) != jsonrpc2.ServerNotInitialized {
+		t.Errorf("expected ServerNotInitialized before initialize, got %v", err)
+	}
+	send("textDocument/didOpen", true)
+	if len(handled) != 0 {
+		t.Fatalf("expected nothing handled before initialize, got %v", handled)
+	}
+
+	if err := send("initialize", false); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+	if err := send("initialize", false); code(err) != jsonrpc2.InvalidRequest {
+		t.Errorf("expected a second initialize to be refused, got %v", err)
+	}
+	if err := send("textDocument/definition", false); err != nil {
+		t.Errorf("expected requests served once initialized, got %v", err)
+	}
+
+	if err := send("shutdown", false); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if err := send("textDocument/definition", false); code(err) != jsonrpc2.InvalidRequest {
+		t.Errorf("expected InvalidRequest after shutdown, got %v", err)
+	}
+	if want := []string{"initialize", "textDocument/definition", "shutdown"}; len(handled) != len(want) {
+		t.Errorf("expected %v handled, got %v", want, handled)
+	}
+
+	send("exit", true)
+	select {
+	case <-l.exited:
+	default:
+		t.Fatal("expected exit to be recorded")
+	}
+	if err := l.exitErr(); err != nil {
+		t.Errorf("expected a clean exit after shutdown, got %v", err)
+	}
+}
+
+func TestServeOnlyOnce(t *testing.T) {
+	s := newTestServer("/test")
+	s.serving.Store(true)
+	if err := s.Serve(context.Background(), strings.NewReader(""), io.Discard); err != errAlreadyServing {
+		t.Errorf("expected a second Serve to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"github.com/jarredhawkins/goruby-lsp/internal/parser"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// LinkedEditingRanges are ranges that change together as one is typed in
+type LinkedEditingRanges struct {
+	Ranges      []Range `json:"ranges"`
+	WordPattern string  `json:"wordPattern,omitempty"`
+}
+
+// localWordPattern is what a linked local variable name may be edited to
+const localWordPattern = `[a-z_][a-zA-Z0-9_]*`
+
+// |item| / |key, value| / |(a, b), memo| on a block's opening line
+var blockParamsPattern = regexp.MustCompile(`\|([^|]*)\|`)
+
+// A name in a block's parameter list
+var paramNamePattern = regexp.MustCompile(`[a-z_]\w*`)
+
+// blockParamScope returns the first and last line (1-based) of the
+// innermost block around line that takes name as a parameter, or 0, 0.
+// A parameter list elsewhere on the line itself belongs to a one-line
+// block ({ |x| x * 2 }), which is then the scope.
+func blockParamScope(content string, blocks []parser.Block, name string, line int) (int, int) {
+	lines := strings.Split(content, "\n")
+	takes := func(l int) bool {
+		if l < 1 || l > len(lines) {
+			return false
+		}
+		for _, params := range blockParamsPattern.FindAllStringSubmatch(lines[l-1], -1) {
+			for _, param := range paramNamePattern.FindAllString(params[1], -1) {
+				if param == name {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	first, last := 0, 0
+	for _, b := range blocks {
+		if b.StartLine <= line && line <= b.EndLine && takes(b.StartLine) &&
+			(first == 0 || b.EndLine-b.StartLine < last-first) {
+			first, last = b.StartLine, b.EndLine
+		}
+	}
+	if first != line && takes(line) {
+		return line, line
+	}
+	return first, last
+}
+
+// linkedRanges returns the ranges of the local variable or block
+// parameter under the cursor that should be edited together
+func (s *Server) linkedRanges(content, uri string, line, char int) []Range {
+	word := extractWordAt(content, line, char)
+	if !identifierNamePattern.MatchString(word) || rubyKeywords[word] {
+		return nil
+	}
+
+	path := uriToPath(uri)
+	refs := s.index.FindReferencesInContent(word, path, content)
+
+	var occurrences []*index.Reference
+	if local := s.index.FindLocalVariable(word, path, line+1); local != nil {
+		occurrences = s.localOccurrences(local, refs)
+	} else if first, last := blockParamScope(content, s.index.BlocksInContent(path, content), word, line+1); first > 0 {
+		for _, ref := range refs {
+			if ref.Line >= first && ref.Line <= last && isLocalOccurrence(ref, len(word)) {
+				occurrences = append(occurrences, ref)
+			}
+		}
+	}
+
+	ranges := make([]Range, 0, len(occurrences))
+	for _, ref := range occurrences {
+		ranges = append(ranges, highlight(ref, len(word), DocumentHighlightText).Range)
+	}
+	return ranges
+}
+
+// handleLinkedEditingRange links the occurrences of a local variable or
+// block parameter so renaming one in place renames them all
+func (s *Server) handleLinkedEditingRange(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	content := s.getDocumentContent(uri)
+	s.syncDocument(uri)
+
+	ranges := s.linkedRanges(content, uri, int(params.Position.Line), int(params.Position.Character))
+	if len(ranges) < 2 {
+		return reply(ctx, nil, nil)
+	}
+	return reply(ctx, LinkedEditingRanges{Ranges: ranges, WordPattern: localWordPattern}, nil)
+}
@@ -0,0 +1,87 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLinkedEditingRange(t *testing.T) {
+	s := newTestServer("/app")
+
+	uri := "file:///app/order.rb"
+	content := `class Order
+  def total
+    sum = 0
+    items.each do |item|
+      sum += item.price
+    end
+    ids = items.map { |item| item.id }
+    sum
+  end
+
+  def tax
+    sum = total * RATE
+  end
+end
+`
+	s.documents.Open(uri, 1, content)
+
+	linked := func(line, char uint32) *LinkedEditingRanges {
+		t.Helper()
+		raw, err := callHandler(t, s, "textDocument/linkedEditingRange", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line, Character: char},
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var result *LinkedEditingRanges
+		if len(raw) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(raw, &result); err != nil {
+			t.Fatalf("failed to decode ranges: %v", err)
+		}
+		return result
+	}
+	starts := func(result *LinkedEditingRanges) [][2]uint32 {
+		var got [][2]uint32
+		if result != nil {
+			for _, r := range result.Ranges {
+				got = append(got, [2]uint32{r.Start.Line, r.Start.Character})
+			}
+		}
+		return got
+	}
+	expect := func(what string, got, want [][2]uint32) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Errorf("%s: expected %v, got %v", what, want, got)
+			return
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: expected %v, got %v", what, want, got)
+				return
+			}
+		}
+	}
+
+	// A local variable links within its method, not the other method's sum
+	result := linked(2, 5)
+	expect("local", starts(result), [][2]uint32{{2, 4}, {4, 6}, {7, 4}})
+	if result != nil && result.WordPattern != localWordPattern {
+		t.Errorf("expected the local word pattern, got %q", result.WordPattern)
+	}
+
+	// A do block parameter links within its block
+	expect("do block parameter", starts(linked(4, 14)), [][2]uint32{{3, 19}, {4, 13}})
+
+	// A one-line brace block parameter links within its line
+	expect("brace block parameter", starts(linked(6, 30)), [][2]uint32{{6, 23}, {6, 29}})
+
+	// Methods aren't linked
+	if result := linked(1, 7); result != nil {
+		t.Errorf("expected no linked ranges for a method, got %+v", result)
+	}
+}
@@ -0,0 +1,200 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+// DocumentLinkOptions advertises document link support
+type DocumentLinkOptions struct {
+	ResolveProvider bool `json:"resolveProvider"`
+}
+
+// DocumentLinkParams for textDocument/documentLink
+type DocumentLinkParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// DocumentLink is a range in a document that links to a file
+type DocumentLink struct {
+	Range   Range  `json:"range"`
+	Target  string `json:"target"`
+	Tooltip string `json:"tooltip,omitempty"`
+}
+
+// require_relative "../support/helpers" / require 'billing/invoice'
+var requirePattern = regexp.MustCompile(`^\s*(require_relative|require)\b\s*\(?\s*["']([^"'#]+)["']`)
+
+// render "shared/header" / render partial: "form" / render(template: 'orders/show')
+var renderPattern = regexp.MustCompile(`\brender\b\s*\(?\s*(?:(?::(partial|template|layout)\s*=>|(partial|template|layout):)\s*)?["']([^"'#]+)["']`)
+
+// linkTarget finds the file a require or render names, given the file
+// it's written in, or returns ""
+type linkTarget func(s *Server, from, name, option string) string
+
+// documentLinks returns a link for each require_relative, require, and
+// render call in content whose target file exists
+func (s *Server) documentLinks(path, content string) []DocumentLink {
+	links := []DocumentLink{}
+	for i, line := range strings.Split(content, "\n") {
+		if loc := requirePattern.FindStringSubmatchIndex(line); loc != nil {
+			resolve := linkTarget(requireTarget)
+			if line[loc[2]:loc[3]] == "require_relative" {
+				resolve = requireRelativeTarget
+			}
+			links = s.appendLink(links, i, loc[4], loc[5], line, path, "", resolve)
+		}
+		for _, loc := range renderPattern.FindAllStringSubmatchIndex(line, -1) {
+			var option string
+			for _, n := range []int{2, 4} {
+				if loc[n] >= 0 {
+					option = line[loc[n]:loc[n+1]]
+				}
+			}
+			links = s.appendLink(links, i, loc[6], loc[7], line, path, option, renderTarget)
+		}
+	}
+	return links
+}
+
+// appendLink adds a link over line[start:end] when resolve finds its target
+func (s *Server) appendLink(links []DocumentLink, lineNum, start, end int, line, path, option string, resolve linkTarget) []DocumentLink {
+	target := resolve(s, path, line[start:end], option)
+	if target == "" {
+		return links
+	}
+	return append(links, DocumentLink{
+		Range: Range{
+			Start: Position{Line: uint32(lineNum), Character: uint32(start)},
+			End:   Position{Line: uint32(lineNum), Character: uint32(end)},
+		},
+		Target:  pathToURI(target),
+		Tooltip: target,
+	})
+}
+
+// requireRelativeTarget resolves name against the requiring file's directory
+func requireRelativeTarget(s *Server, from, name, _ string) string {
+	return rubyFile(filepath.Join(filepath.Dir(from), name))
+}
+
+// requireTarget looks for name in the project's lib directory, then the
+// project root, then the lib directories of installed gems
+func requireTarget(s *Server, from, name, _ string) string {
+	root := s.index.RootPath()
+	for _, dir := range []string{filepath.Join(root, "lib"), root} {
+		if target := rubyFile(filepath.Join(dir, name)); target != "" {
+			return target
+		}
+	}
+
+	for _, gemDir := range gemPaths() {
+		matches, _ := filepath.Glob(filepath.Join(gemDir, "gems", "*", "lib", name+".rb"))
+		if len(matches) > 0 {
+			// Sorted, the last match is usually the newest version
+			sort.Strings(matches)
+			return matches[len(matches)-1]
+		}
+	}
+	return ""
+}
+
+// renderTarget finds the view a render call names under app/views: a
+// partial (_header.html.erb) unless template: or layout: says otherwise.
+// Names without a directory are looked up in the views of the current
+// controller or view.
+func renderTarget(s *Server, from, name, option string) string {
+	views := filepath.Join(s.index.RootPath(), "app", "views")
+	dir, base := filepath.Split(filepath.FromSlash(name))
+	switch {
+	case option == "layout":
+		dir = filepath.Join(views, "layouts", dir)
+	case dir != "":
+		dir = filepath.Join(views, dir)
+	default:
+		dir = viewDir(views, from)
+		if dir == "" {
+			return ""
+		}
+	}
+
+	var prefixes []string
+	switch option {
+	case "partial":
+		prefixes = []string{"_"}
+	case "template", "layout":
+		prefixes = []string{""}
+	default:
+		prefixes = []string{"_", ""}
+	}
+	for _, prefix := range prefixes {
+		matches, _ := filepath.Glob(filepath.Join(dir, prefix+base+".*"))
+		if len(matches) > 0 {
+			sort.Strings(matches)
+			return matches[0]
+		}
+	}
+	return ""
+}
+
+// viewDir returns the view directory a file renders from by default:
+// its own directory for a view, app/views/orders for OrdersController
+func viewDir(views, from string) string {
+	if rel, err := filepath.Rel(views, from); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.Dir(from)
+	}
+	slashed := filepath.ToSlash(from)
+	if i := strings.Index(slashed, "/app/controllers/"); i >= 0 {
+		controller := strings.TrimSuffix(slashed[i+len("/app/controllers/"):], "_controller.rb")
+		if controller != slashed[i+len("/app/controllers/"):] {
+			return filepath.Join(views, filepath.FromSlash(controller))
+		}
+	}
+	return ""
+}
+
+// rubyFile returns path, with .rb added when it's missing, if that file
+// exists
+func rubyFile(path string) string {
+	if filepath.Ext(path) != ".rb" {
+		path += ".rb"
+	}
+	if fileExists(path) {
+		return path
+	}
+	return ""
+}
+
+// gemPaths returns the directories RubyGems installs gems into, from
+// GEM_HOME and GEM_PATH
+func gemPaths() []string {
+	var dirs []string
+	for _, env := range []string{"GEM_HOME", "GEM_PATH"} {
+		for _, dir := range filepath.SplitList(os.Getenv(env)) {
+			if dir != "" {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return dirs
+}
+
+func (s *Server) handleDocumentLink(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params DocumentLinkParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	return reply(ctx, s.documentLinks(uriToPath(uri), s.getDocumentContent(uri)), nil)
+}
@@ -0,0 +1,95 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDocumentLinks(t *testing.T) {
+	root := t.TempDir()
+	gems := t.TempDir()
+	t.Setenv("GEM_HOME", "")
+	t.Setenv("GEM_PATH", gems)
+
+	files := []string{
+		"app/controllers/orders_controller.rb",
+		"app/controllers/concerns/paging.rb",
+		"app/views/orders/_line.html.erb",
+		"app/views/orders/show.html.erb",
+		"app/views/shared/_header.html.erb",
+		"app/views/layouts/print.html.erb",
+		"lib/billing/invoice.rb",
+	}
+	for _, name := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, version := range []string{"1.0.0", "1.2.0"} {
+		path := filepath.Join(gems, "gems", "money-"+version, "lib", "money.rb")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := newTestServer(root)
+	uri := pathToURI(filepath.Join(root, "app", "controllers", "orders_controller.rb"))
+	s.documents.Open(uri, 1, `require_relative "concerns/paging"
+require "billing/invoice"
+require 'money'
+require "missing"
+
+class OrdersController
+  def show
+    render "shared/header"
+    render partial: "line"
+    render template: "orders/show", layout: "print"
+    render :layout => "print"
+  end
+end`)
+
+	raw, err := callHandler(t, s, "textDocument/documentLink", DocumentLinkParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var links []DocumentLink
+	if err := json.Unmarshal(raw, &links); err != nil {
+		t.Fatalf("failed to decode links: %v", err)
+	}
+
+	want := []struct {
+		line, start, end uint32
+		target           string
+	}{
+		{0, 18, 33, filepath.Join(root, "app/controllers/concerns/paging.rb")},
+		{1, 9, 24, filepath.Join(root, "lib/billing/invoice.rb")},
+		{2, 9, 14, filepath.Join(gems, "gems/money-1.2.0/lib/money.rb")},
+		{7, 12, 25, filepath.Join(root, "app/views/shared/_header.html.erb")},
+		{8, 21, 25, filepath.Join(root, "app/views/orders/_line.html.erb")},
+		{9, 22, 33, filepath.Join(root, "app/views/orders/show.html.erb")},
+		{10, 23, 28, filepath.Join(root, "app/views/layouts/print.html.erb")},
+	}
+	if len(links) != len(want) {
+		t.Fatalf("expected %d links, got %+v", len(want), links)
+	}
+	for i, w := range want {
+		got := links[i]
+		if got.Range.Start.Line != w.line || got.Range.Start.Character != w.start || got.Range.End.Character != w.end {
+			t.Errorf("link %d: expected %d:%d-%d, got %+v", i, w.line, w.start, w.end, got.Range)
+		}
+		if got.Target != pathToURI(w.target) {
+			t.Errorf("link %d: expected %s, got %s", i, w.target, got.Target)
+		}
+	}
+}
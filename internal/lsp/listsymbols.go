@@ -0,0 +1,64 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// ListSymbolsParams for goruby/listSymbols
+type ListSymbolsParams struct {
+	// Kinds are symbol kind names as used by kind: filters (relation,
+	// class, ...); empty lists every kind but local variables
+	Kinds []string `json:"kinds,omitempty"`
+	// PathPrefix limits results to files under it, relative to the project
+	// root (app/models) unless absolute
+	PathPrefix string `json:"pathPrefix,omitempty"`
+}
+
+// ListedSymbol is a symbol returned by goruby/listSymbols
+type ListedSymbol struct {
+	Name          string   `json:"name"`
+	FullName      string   `json:"fullName"`
+	Kind          string   `json:"kind"`
+	Detail        string   `json:"detail,omitempty"`
+	ContainerName string   `json:"containerName,omitempty"`
+	Location      Location `json:"location"`
+}
+
+// handleListSymbols lists every symbol of the requested kinds under a path,
+// for client panels such as an association graph or a list of jobs
+func (s *Server) handleListSymbols(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params ListSymbolsParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	kinds := index.ParseSymbolKinds(params.Kinds)
+	if len(params.Kinds) > 0 && len(kinds) == 0 {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: "unknown symbol kinds: " + strings.Join(params.Kinds, ", "),
+		})
+	}
+
+	symbols := s.index.ListSymbols(kinds, params.PathPrefix)
+	result := make([]ListedSymbol, 0, len(symbols))
+	for _, sym := range symbols {
+		result = append(result, ListedSymbol{
+			Name:          sym.Name,
+			FullName:      sym.FullName,
+			Kind:          sym.Kind.String(),
+			Detail:        symbolDetail(sym),
+			ContainerName: symbolContainer(sym),
+			Location:      symbolToLocation(sym),
+		})
+	}
+	return reply(ctx, result, nil)
+}
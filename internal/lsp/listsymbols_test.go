@@ -0,0 +1,38 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestListSymbols(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/app/models/order.rb", []byte(`class Order
+  has_many :comments
+end`), 0)
+	s.index.UpdateContent("/app/app/jobs/cleanup_job.rb", []byte(`class CleanupJob
+end`), 0)
+
+	raw, err := callHandler(t, s, "goruby/listSymbols", ListSymbolsParams{Kinds: []string{"relation", "class"}, PathPrefix: "app/models"})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var result []ListedSymbol
+	if err := json.Unmarshal(raw, &result); err != nil {
+		t.Fatalf("failed to decode symbols: %v", err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("expected Order and its association, got %+v", result)
+	}
+	rel := result[1]
+	if rel.FullName != "Order::comments" || rel.Kind != "relation" || rel.Detail != "has_many Comment" || rel.ContainerName != "Order (has_many)" {
+		t.Errorf("unexpected association %+v", rel)
+	}
+	if rel.Location.URI != "file:///app/app/models/order.rb" || rel.Location.Range.Start.Line != 1 {
+		t.Errorf("unexpected location %+v", rel.Location)
+	}
+
+	if _, err := callHandler(t, s, "goruby/listSymbols", ListSymbolsParams{Kinds: []string{"job"}}); err == nil {
+		t.Error("expected unknown kinds to be refused")
+	}
+}
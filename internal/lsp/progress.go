@@ -0,0 +1,126 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// indexProgressToken identifies the server-initiated progress of the
+// index build
+const indexProgressToken = "goruby-index"
+
+// WorkDoneProgressParams carry the token a client sends with a request to
+// receive progress for it
+type WorkDoneProgressParams struct {
+	WorkDoneToken interface{} `json:"workDoneToken,omitempty"` // string or integer
+}
+
+// WorkDoneProgressCreateParams for window/workDoneProgress/create, which
+// asks the client to show progress the server starts on its own
+type WorkDoneProgressCreateParams struct {
+	Token interface{} `json:"token"`
+}
+
+// ProgressParams for the $/progress notification
+type ProgressParams struct {
+	Token interface{} `json:"token"`
+	Value interface{} `json:"value"`
+}
+
+// WorkDoneProgressBegin starts reporting progress
+type WorkDoneProgressBegin struct {
+	Kind        string `json:"kind"` // "begin"
+	Title       string `json:"title"`
+	Cancellable bool   `json:"cancellable,omitempty"`
+	Percentage  uint32 `json:"percentage"`
+}
+
+// WorkDoneProgressReport updates reported progress
+type WorkDoneProgressReport struct {
+	Kind       string `json:"kind"` // "report"
+	Message    string `json:"message,omitempty"`
+	Percentage uint32 `json:"percentage"`
+}
+
+// WorkDoneProgressEnd finishes reporting progress
+type WorkDoneProgressEnd struct {
+	Kind    string `json:"kind"` // "end"
+	Message string `json:"message,omitempty"`
+}
+
+// progressReporter sends $/progress for one request. A nil reporter, for
+// requests without a token, reports nothing.
+type progressReporter struct {
+	s          *Server
+	token      interface{}
+	percentage uint32
+}
+
+// beginProgress starts reporting progress under token, returning nil when
+// the client didn't ask for progress
+func (s *Server) beginProgress(ctx context.Context, token interface{}, title string, cancellable bool) *progressReporter {
+	if token == nil || s.client == nil {
+		return nil
+	}
+	p := &progressReporter{s: s, token: token}
+	p.notify(ctx, WorkDoneProgressBegin{Kind: "begin", Title: title, Cancellable: cancellable})
+	return p
+}
+
+// createProgress starts reporting progress of work no request asked for,
+// such as the index build, returning nil when the client can't show it
+func (s *Server) createProgress(ctx context.Context, token, title string) *progressReporter {
+	if s.client == nil || !s.capabilities.Window.WorkDoneProgress {
+		return nil
+	}
+	if _, err := s.client.Call(ctx, "window/workDoneProgress/create", WorkDoneProgressCreateParams{Token: token}, nil); err != nil {
+		log.Printf("client refused progress %s: %v", token, err)
+		return nil
+	}
+	return s.beginProgress(ctx, token, title, false)
+}
+
+// report sends the progress of done out of total steps, when it changed
+// by at least a percent
+func (p *progressReporter) report(ctx context.Context, message string, done, total int) {
+	if p == nil || total == 0 {
+		return
+	}
+	percentage := uint32(done * 100 / total)
+	if percentage == p.percentage {
+		return
+	}
+	p.percentage = percentage
+	p.notify(ctx, WorkDoneProgressReport{Kind: "report", Message: message, Percentage: percentage})
+}
+
+// end finishes reporting
+func (p *progressReporter) end(ctx context.Context, message string) {
+	if p == nil {
+		return
+	}
+	p.notify(ctx, WorkDoneProgressEnd{Kind: "end", Message: message})
+}
+
+func (p *progressReporter) notify(ctx context.Context, value interface{}) {
+	// Progress is best effort; a cancelled request still ends its progress
+	if err := p.s.client.Notify(context.WithoutCancel(ctx), "$/progress", ProgressParams{Token: p.token, Value: value}); err != nil {
+		log.Printf("failed to report progress: %v", err)
+	}
+}
+
+// countProgress describes done out of total items: "4,213/10,000 files"
+func countProgress(done, total int, noun string) string {
+	return fmt.Sprintf("%s/%s %s", groupDigits(done), groupDigits(total), noun)
+}
+
+// groupDigits formats n with commas between groups of three digits
+func groupDigits(n int) string {
+	digits := strconv.Itoa(n)
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+	return digits
+}
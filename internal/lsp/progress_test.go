@@ -0,0 +1,99 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildIndexReportsProgress(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 3; i++ {
+		path := filepath.Join(root, "app", "models", fmt.Sprintf("model%d.rb", i))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("class Model%d\nend\n", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := newTestServer(root)
+	client := &fakeClient{}
+	s.client = client
+	s.capabilities.Window.WorkDoneProgress = true
+	ready := make(chan error, 1)
+	s.BuildIndexOnInitialized(func(err error) { ready <- err })
+
+	if _, err := callHandler(t, s, "initialized", struct{}{}); err != nil {
+		t.Fatalf("initialized failed: %v", err)
+	}
+	if err := <-ready; err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	if s.index.SymbolCount() != 3 {
+		t.Errorf("expected 3 classes indexed, got %d", s.index.SymbolCount())
+	}
+	if len(client.created) != 1 || client.created[0] != indexProgressToken {
+		t.Fatalf("expected the progress token to be created, got %v", client.created)
+	}
+	n := len(client.progress)
+	if n < 3 {
+		t.Fatalf("expected begin, reports, and end, got %+v", client.progress)
+	}
+	if begin, ok := client.progress[0].(WorkDoneProgressBegin); !ok || begin.Title != "Indexing Ruby project" || begin.Cancellable {
+		t.Errorf("expected a begin that can't be cancelled, got %+v", client.progress[0])
+	}
+	if report, ok := client.progress[n-2].(WorkDoneProgressReport); !ok || report.Message != "3/3 files" || report.Percentage != 100 {
+		t.Errorf("expected the last report at 3/3 files, got %+v", client.progress[n-2])
+	}
+	if end, ok := client.progress[n-1].(WorkDoneProgressEnd); !ok || end.Message != "Indexed 3 files" {
+		t.Errorf("expected the end to count files, got %+v", client.progress[n-1])
+	}
+
+	// Clients that can't show server-initiated progress get none
+	s = newTestServer(root)
+	client = &fakeClient{}
+	s.client = client
+	s.BuildIndexOnInitialized(func(err error) { ready <- err })
+	s.buildIndex(t.Context())
+	if err := <-ready; err != nil || len(client.created) != 0 || len(client.progress) != 0 {
+		t.Errorf("expected a silent build, got %v %v %v", err, client.created, client.progress)
+	}
+
+	// A failed build is shown to the user
+	s = newTestServer(root)
+	client = &fakeClient{}
+	s.client = client
+	s.BuildIndexOnInitialized(func(err error) { ready <- err })
+	canceled, cancel := context.WithCancel(t.Context())
+	cancel()
+	s.buildIndex(canceled)
+	if err := <-ready; err == nil {
+		t.Fatal("expected the build to fail")
+	}
+	if len(client.notifications) != 1 || !strings.HasPrefix(client.notifications[0], "window/showMessage: Indexing failed: ") {
+		t.Errorf("expected the failure shown, got %v", client.notifications)
+	}
+}
+
+func TestCountProgress(t *testing.T) {
+	tests := []struct {
+		done, total int
+		want        string
+	}{
+		{0, 12, "0/12 files"},
+		{999, 1000, "999/1,000 files"},
+		{4213, 10000, "4,213/10,000 files"},
+		{1234567, 1234567, "1,234,567/1,234,567 files"},
+	}
+	for _, tt := range tests {
+		if got := countProgress(tt.done, tt.total, "files"); got != tt.want {
+			t.Errorf("countProgress(%d, %d) = %q, want %q", tt.done, tt.total, got, tt.want)
+		}
+	}
+}
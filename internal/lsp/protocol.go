@@ -1,6 +1,7 @@
 package lsp
 
 import (
+	"encoding/json"
 	"os"
 	"strings"
 
@@ -34,6 +35,22 @@ type Range struct {
 type Location struct {
 	URI   string `json:"uri"`
 	Range Range  `json:"range"`
+	// Confidence is an extension on definition results: exact,
+	// contextual, fuzzy, or ghost
+	Confidence string `json:"confidence,omitempty"`
+}
+
+// LocationLink is a definition target together with the range it was
+// requested from. ContainerName, Kind, and Confidence are extensions
+// clients can use to tell apart several matching definitions.
+type LocationLink struct {
+	OriginSelectionRange *Range `json:"originSelectionRange,omitempty"`
+	TargetURI            string `json:"targetUri"`
+	TargetRange          Range  `json:"targetRange"`
+	TargetSelectionRange Range  `json:"targetSelectionRange"`
+	ContainerName        string `json:"containerName,omitempty"`
+	Kind                 string `json:"kind,omitempty"`
+	Confidence           string `json:"confidence,omitempty"`
 }
 
 // TextDocumentIdentifier identifies a text document
@@ -76,13 +93,122 @@ type ReferenceParams struct {
 type TextDocumentSyncOptions struct {
 	OpenClose bool                 `json:"openClose,omitempty"`
 	Change    TextDocumentSyncKind `json:"change,omitempty"`
+	Save      *SaveOptions         `json:"save,omitempty"`
+}
+
+// SaveOptions asks the client to send textDocument/didSave
+type SaveOptions struct {
+	IncludeText bool `json:"includeText,omitempty"`
 }
 
 // ServerCapabilities defines what the server can do
 type ServerCapabilities struct {
-	TextDocumentSync   *TextDocumentSyncOptions `json:"textDocumentSync,omitempty"`
-	DefinitionProvider bool                     `json:"definitionProvider,omitempty"`
-	ReferencesProvider bool                     `json:"referencesProvider,omitempty"`
+	TextDocumentSync           *TextDocumentSyncOptions `json:"textDocumentSync,omitempty"`
+	DefinitionProvider         bool                     `json:"definitionProvider,omitempty"`
+	ReferencesProvider         bool                     `json:"referencesProvider,omitempty"`
+	DocumentSymbolProvider     bool                     `json:"documentSymbolProvider,omitempty"`
+	RenameProvider             bool                     `json:"renameProvider,omitempty"`
+	WorkspaceSymbolProvider    *WorkspaceSymbolOptions  `json:"workspaceSymbolProvider,omitempty"`
+	FoldingRangeProvider       bool                     `json:"foldingRangeProvider,omitempty"`
+	TypeHierarchyProvider      bool                     `json:"typeHierarchyProvider,omitempty"`
+	ImplementationProvider     bool                     `json:"implementationProvider,omitempty"`
+	DeclarationProvider        bool                     `json:"declarationProvider,omitempty"`
+	TypeDefinitionProvider     bool                     `json:"typeDefinitionProvider,omitempty"`
+	InlayHintProvider          bool                     `json:"inlayHintProvider,omitempty"`
+	DocumentHighlightProvider  bool                     `json:"documentHighlightProvider,omitempty"`
+	LinkedEditingRangeProvider bool                     `json:"linkedEditingRangeProvider,omitempty"`
+	CodeActionProvider         bool                     `json:"codeActionProvider,omitempty"`
+	DocumentFormattingProvider bool                     `json:"documentFormattingProvider,omitempty"`
+	CompletionProvider         *CompletionOptions       `json:"completionProvider,omitempty"`
+	CodeLensProvider           *CodeLensOptions         `json:"codeLensProvider,omitempty"`
+	DocumentLinkProvider       *DocumentLinkOptions     `json:"documentLinkProvider,omitempty"`
+	SemanticTokensProvider     *SemanticTokensOptions   `json:"semanticTokensProvider,omitempty"`
+	ExecuteCommandProvider     *ExecuteCommandOptions   `json:"executeCommandProvider,omitempty"`
+}
+
+// InitializeParams for the initialize request
+type InitializeParams struct {
+	ProcessID             *int               `json:"processId"`
+	RootURI               string             `json:"rootUri,omitempty"`
+	InitializationOptions json.RawMessage    `json:"initializationOptions,omitempty"`
+	Capabilities          ClientCapabilities `json:"capabilities"`
+	Trace                 string             `json:"trace,omitempty"` // Initial trace level (see $/setTrace)
+}
+
+// ClientCapabilities holds the client capabilities the server acts on
+type ClientCapabilities struct {
+	TextDocument struct {
+		Definition struct {
+			// LinkSupport means definition results may be LocationLinks
+			LinkSupport bool `json:"linkSupport,omitempty"`
+		} `json:"definition"`
+	} `json:"textDocument"`
+	Workspace struct {
+		WorkspaceEdit struct {
+			// FailureHandling is how the client handles a failed workspace
+			// edit: "abort", "transactional", "undo" or "textOnlyTransactional"
+			FailureHandling string `json:"failureHandling,omitempty"`
+		} `json:"workspaceEdit"`
+		Symbol struct {
+			ResolveSupport struct {
+				// Properties the client can resolve lazily through
+				// workspaceSymbol/resolve ("location.range")
+				Properties []string `json:"properties,omitempty"`
+			} `json:"resolveSupport"`
+		} `json:"symbol"`
+		DidChangeWatchedFiles struct {
+			// DynamicRegistration means the client watches files the server
+			// registers with client/registerCapability
+			DynamicRegistration bool `json:"dynamicRegistration,omitempty"`
+		} `json:"didChangeWatchedFiles"`
+	} `json:"workspace"`
+	Window struct {
+		// WorkDoneProgress means the client shows progress the server
+		// creates with window/workDoneProgress/create
+		WorkDoneProgress bool `json:"workDoneProgress,omitempty"`
+	} `json:"window"`
+}
+
+// TextEdit replaces a range of a document with new text
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// WorkspaceEdit holds text edits across documents, keyed by URI
+type WorkspaceEdit struct {
+	Changes map[string][]TextEdit `json:"changes"`
+}
+
+// ApplyWorkspaceEditParams for the workspace/applyEdit request sent to the client
+type ApplyWorkspaceEditParams struct {
+	Label string        `json:"label,omitempty"`
+	Edit  WorkspaceEdit `json:"edit"`
+}
+
+// ApplyWorkspaceEditResult is the client's reply to workspace/applyEdit
+type ApplyWorkspaceEditResult struct {
+	Applied       bool   `json:"applied"`
+	FailureReason string `json:"failureReason,omitempty"`
+	FailedChange  *int   `json:"failedChange,omitempty"`
+}
+
+// MessageType is the severity of a window/showMessage or window/logMessage
+// notification
+type MessageType int
+
+const (
+	MessageTypeError   MessageType = 1
+	MessageTypeWarning MessageType = 2
+	MessageTypeInfo    MessageType = 3
+	MessageTypeLog     MessageType = 4
+)
+
+// ShowMessageParams for the window/showMessage and window/logMessage
+// notifications
+type ShowMessageParams struct {
+	Type    MessageType `json:"type"`
+	Message string      `json:"message"`
 }
 
 // ServerInfo contains information about the server
@@ -153,11 +279,45 @@ func symbolToLocation(sym *index.Symbol) Location {
 	}
 }
 
+// symbolToLocationLink converts a symbol to a LocationLink whose target
+// range spans the symbol's whole definition when its end is known
+func symbolToLocationLink(sym *index.Symbol, origin *Range) LocationLink {
+	loc := symbolToLocation(sym)
+	target := loc.Range
+	if sym.EndLine > sym.Line {
+		target = Range{
+			Start: Position{Line: uint32(sym.Line - 1)},
+			End:   Position{Line: uint32(sym.EndLine - 1), Character: uint32(sym.EndColumn)},
+		}
+	}
+
+	container := strings.Join(sym.Scope, "::")
+	if sym.Kind == index.KindLocalVariable {
+		container = sym.MethodFullName
+	}
+
+	return LocationLink{
+		OriginSelectionRange: origin,
+		TargetURI:            loc.URI,
+		TargetRange:          target,
+		TargetSelectionRange: loc.Range,
+		ContainerName:        container,
+		Kind:                 sym.Kind.String(),
+	}
+}
+
 // extractWordAt extracts the word at the given position in the content
 func extractWordAt(content string, line, char int) string {
+	word, _, _ := wordRangeAt(content, line, char)
+	return word
+}
+
+// wordRangeAt is like extractWordAt but also returns the word's start and
+// end columns on the line, including any namespace qualifiers
+func wordRangeAt(content string, line, char int) (string, int, int) {
 	lines := strings.Split(content, "\n")
 	if line < 0 || line >= len(lines) {
-		return ""
+		return "", 0, 0
 	}
 
 	lineText := lines[line]
@@ -166,7 +326,7 @@ func extractWordAt(content string, line, char int) string {
 		if char >= len(lineText) && len(lineText) > 0 {
 			char = len(lineText) - 1
 		} else {
-			return ""
+			return "", 0, 0
 		}
 	}
 
@@ -201,7 +361,7 @@ func extractWordAt(content string, line, char int) string {
 	}
 
 	if start == end {
-		return ""
+		return "", 0, 0
 	}
 
 	word := lineText[start:end]
@@ -219,12 +379,13 @@ func extractWordAt(content string, line, char int) string {
 		if idStart == idEnd {
 			// No identifier before ::, this is a leading :: (absolute scope)
 			word = "::" + word
+			pos -= 2
 			break
 		}
 		word = lineText[idStart:idEnd] + "::" + word
 		pos = idStart
 	}
-	return word
+	return word, pos, end
 }
 
 // isWordChar returns true if c is a valid Ruby identifier character
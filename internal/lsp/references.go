@@ -0,0 +1,105 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+)
+
+// referenceOwners returns the classes or modules whose method name refers
+// to at line (1-based) of path, given the text before it on the line: the
+// receiver's class when there is one (User.find, user.name,
+// described_class.call, or a let helper's invoice.total), else the
+// enclosing class when it defines the method, else every class defining it
+func (s *Server) referenceOwners(name, path string, line int, before string) []string {
+	if receiver := index.ReceiverBefore(before); receiver != "" && receiver != "self" {
+		if receiver[0] >= 'A' && receiver[0] <= 'Z' || strings.HasPrefix(receiver, "::") {
+			return s.constantOwners(receiver, path, line)
+		}
+		if local := s.index.FindLocalVariable(receiver, path, line); local != nil && local.TypeName != "" {
+			return s.constantOwners(local.TypeName, path, line)
+		}
+		if receiver == "described_class" {
+			if class := s.index.DescribedClass(path, line); class != "" {
+				return s.constantOwners(class, path, line)
+			}
+		}
+		if let := s.index.FindLet(receiver, path, line); let != nil {
+			if class := s.index.LetClass(let); class != "" {
+				return s.constantOwners(class, path, let.Line)
+			}
+		}
+		return nil
+	}
+
+	defs, _ := s.index.ResolveDefinitions(name, path, line)
+	enclosing := ""
+	if scope := s.index.ScopeAt(path, line); scope != nil {
+		enclosing = strings.Join(scope.Scope, "::")
+		if scope.Kind == index.KindClass || scope.Kind == index.KindModule {
+			enclosing = strings.TrimPrefix(enclosing+"::"+scope.Name, "::")
+		}
+	}
+
+	var owners []string
+	seen := make(map[string]bool)
+	for _, sym := range defs {
+		owner := strings.Join(sym.Scope, "::")
+		if owner == "" || seen[owner] {
+			continue
+		}
+		if owner == enclosing {
+			return []string{owner}
+		}
+		seen[owner] = true
+		owners = append(owners, owner)
+	}
+	return owners
+}
+
+// constantOwners resolves a class or module name as written at line of path
+func (s *Server) constantOwners(name, path string, line int) []string {
+	syms, _ := s.index.ResolveDefinitions(name, path, line)
+	var owners []string
+	seen := make(map[string]bool)
+	for _, sym := range syms {
+		if (sym.Kind == index.KindClass || sym.Kind == index.KindModule) && !seen[sym.FullName] {
+			seen[sym.FullName] = true
+			owners = append(owners, sym.FullName)
+		}
+	}
+	return owners
+}
+
+// commonNameWarning explains why references to a common name were filtered
+func commonNameWarning(name string, owners []string, kept, total int) string {
+	if len(owners) == 0 {
+		return fmt.Sprintf("%s is too common to search unqualified: call it on a receiver (e.g. User.%s) to find its references", name, name)
+	}
+	return fmt.Sprintf("%s is a common name: showing %d of %d matches qualified by %s", name, kept, total, strings.Join(owners, ", "))
+}
+
+// isLiteralWord reports whether the word at text[start:end] is a string
+// ("admin", 'admin') or a symbol (:admin) rather than code
+func isLiteralWord(text string, start, end int) bool {
+	if start == 0 || end > len(text) {
+		return false
+	}
+	switch before := text[start-1]; before {
+	case '"', '\'':
+		return end < len(text) && text[end] == before
+	case ':':
+		return start < 2 || text[start-2] != ':'
+	}
+	return false
+}
+
+// lineAt returns line (0-based) of content, or "" past the end
+func lineAt(content string, line int) string {
+	lines := strings.Split(content, "\n")
+	if line < 0 || line >= len(lines) {
+		return ""
+	}
+	return lines[line]
+}
@@ -0,0 +1,449 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// RenameParams for textDocument/rename and goruby/checkRename
+type RenameParams struct {
+	TextDocumentPositionParams
+	WorkDoneProgressParams
+	NewName string `json:"newName"`
+}
+
+// RenameCheck is the result of goruby/checkRename
+type RenameCheck struct {
+	FullName    string        `json:"fullName,omitempty"`
+	NewFullName string        `json:"newFullName,omitempty"`
+	Conflicts   []RenameIssue `json:"conflicts"`
+}
+
+// RenameIssue is a conflict that renaming would create
+type RenameIssue struct {
+	Kind     string    `json:"kind"`
+	Message  string    `json:"message"`
+	Location *Location `json:"location,omitempty"`
+	Path     string    `json:"path,omitempty"`
+}
+
+// handleCheckRename reports conflicts renaming the constant under the
+// cursor would create (an existing definition of the new name, or a file
+// already at its autoload path), so they can be shown before renaming
+func (s *Server) handleCheckRename(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params RenameParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	content := s.getDocumentContent(uri)
+	s.syncDocument(uri)
+
+	check := RenameCheck{Conflicts: []RenameIssue{}}
+	sym := s.constantAt(content, uri, int(params.Position.Line), int(params.Position.Character))
+	if sym == nil {
+		return reply(ctx, check, nil)
+	}
+
+	check.FullName = sym.FullName
+	check.NewFullName = index.RenamedFullName(sym.FullName, params.NewName)
+	check.Conflicts = renameIssues(s.index.RenameConflicts(sym.FullName, params.NewName))
+	return reply(ctx, check, nil)
+}
+
+// constantAt resolves the class, module, or constant under the cursor
+func (s *Server) constantAt(content, uri string, line, char int) *index.Symbol {
+	word := extractWordAt(content, line, char)
+	if word == "" {
+		return nil
+	}
+	for _, sym := range s.index.FindDefinitionsInContext(strings.TrimPrefix(word, "::"), uriToPath(uri), line+1) {
+		switch sym.Kind {
+		case types.KindClass, types.KindModule, types.KindConstant:
+			return sym
+		}
+	}
+	return nil
+}
+
+// renameIssues converts index conflicts to their response form
+func renameIssues(conflicts []index.RenameConflict) []RenameIssue {
+	issues := make([]RenameIssue, 0, len(conflicts))
+	for _, c := range conflicts {
+		issue := RenameIssue{Kind: c.Kind, Message: c.Message, Path: c.Path}
+		if c.Symbol != nil {
+			loc := symbolToLocation(c.Symbol)
+			issue.Location = &loc
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// codeRequestFailed is the LSP error code for a valid request that failed
+const codeRequestFailed jsonrpc2.Code = -32803
+
+// codeRequestCancelled is the LSP error code for a request the client
+// cancelled
+const codeRequestCancelled jsonrpc2.Code = -32800
+
+// errRenameCancelled is returned when a rename is cancelled while its edit
+// is computed; no partial edit is returned
+var errRenameCancelled = errors.New("rename cancelled; no changes were made")
+
+// renameCheckInterval is how many references are checked between
+// cancellation checks and progress reports
+const renameCheckInterval = 64
+
+// rubyKeywords can't be renamed, or used as new names
+var rubyKeywords = map[string]bool{
+	"alias": true, "and": true, "begin": true, "BEGIN": true, "break": true, "case": true,
+	"class": true, "def": true, "defined?": true, "do": true, "else": true, "elsif": true,
+	"end": true, "END": true, "ensure": true, "false": true, "for": true, "if": true,
+	"in": true, "module": true, "next": true, "nil": true, "not": true, "or": true,
+	"redo": true, "rescue": true, "retry": true, "return": true, "self": true, "super": true,
+	"then": true, "true": true, "undef": true, "unless": true, "until": true, "when": true,
+	"while": true, "yield": true, "__FILE__": true, "__LINE__": true, "__ENCODING__": true,
+}
+
+var (
+	constantNamePattern   = regexp.MustCompile(`^[A-Z]\w*$`)
+	identifierNamePattern = regexp.MustCompile(`^[a-z_]\w*$`)
+
+	// An assignment after a name (name = x, name=(v)), not a comparison
+	assignmentAfterPattern = regexp.MustCompile(`^\s*=(?:[^=~>]|$)`)
+
+	// The namespace written before a constant (Billing:: in Billing::Invoice)
+	qualifierBeforePattern = regexp.MustCompile(`(?:::)?(?:[A-Z]\w*::)*$`)
+)
+
+// handleRename renames the local variable, class, module, constant, or
+// method under the cursor across the project
+func (s *Server) handleRename(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params RenameParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	content := s.getDocumentContent(uri)
+	s.syncDocument(uri)
+
+	progress := s.beginProgress(ctx, params.WorkDoneToken, "Renaming to "+params.NewName, true)
+	edit, err := s.renameEdit(ctx, progress, content, uri, int(params.Position.Line), int(params.Position.Character), params.NewName)
+	switch {
+	case errors.Is(err, errRenameCancelled):
+		progress.end(ctx, "Cancelled")
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    codeRequestCancelled,
+			Message: err.Error(),
+		})
+	case err != nil:
+		progress.end(ctx, "Failed")
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    codeRequestFailed,
+			Message: err.Error(),
+		})
+	}
+	progress.end(ctx, fmt.Sprintf("%d files", len(edit.Changes)))
+	return reply(ctx, edit, nil)
+}
+
+// renameEdit builds the edit renaming the symbol at line/char (0-based),
+// reporting progress through the references it checks. It returns
+// errRenameCancelled once ctx is cancelled.
+func (s *Server) renameEdit(ctx context.Context, progress *progressReporter, content, uri string, line, char int, newName string) (WorkspaceEdit, error) {
+	word, _, end := wordRangeAt(content, line, char)
+	name := word
+	if i := strings.LastIndex(word, "::"); i >= 0 {
+		name = word[i+2:]
+	}
+	if name == "" {
+		return WorkspaceEdit{}, fmt.Errorf("no symbol to rename here")
+	}
+	if rubyKeywords[name] {
+		return WorkspaceEdit{}, fmt.Errorf("%s is a Ruby keyword and can't be renamed", name)
+	}
+	if rubyKeywords[newName] {
+		return WorkspaceEdit{}, fmt.Errorf("%s is a Ruby keyword", newName)
+	}
+
+	path := uriToPath(uri)
+	if local := s.index.FindLocalVariable(name, path, line+1); local != nil {
+		return s.renameLocal(local, newName)
+	}
+	if sym := s.constantAt(content, uri, line, char); sym != nil {
+		return s.renameConstant(ctx, progress, sym, newName)
+	}
+	before := lineAt(content, line)[:end-len(name)]
+	return s.renameMethod(ctx, progress, name, path, line+1, before, newName)
+}
+
+// checkRename reports progress through the i-th of n references every
+// renameCheckInterval references, returning errRenameCancelled once ctx is
+// cancelled
+func checkRename(ctx context.Context, progress *progressReporter, i, n int) error {
+	if i%renameCheckInterval != 0 {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return errRenameCancelled
+	}
+	progress.report(ctx, fmt.Sprintf("%d/%d references", i, n), i, n)
+	return nil
+}
+
+// renameLocal renames a local variable within its method
+func (s *Server) renameLocal(local *index.Symbol, newName string) (WorkspaceEdit, error) {
+	if !identifierNamePattern.MatchString(newName) {
+		return WorkspaceEdit{}, fmt.Errorf("%s is not a valid local variable name", newName)
+	}
+
+	var refs []*index.Reference
+	for _, ref := range s.index.FindReferences(local.Name) {
+		if ref.FilePath == local.FilePath {
+			refs = append(refs, ref)
+		}
+	}
+	return renameReferences(s.localOccurrences(local, refs), len(local.Name), newName), nil
+}
+
+// renameConstant renames a class, module, or constant wherever a reference
+// resolves to it, refusing when the new name would clash
+func (s *Server) renameConstant(ctx context.Context, progress *progressReporter, sym *index.Symbol, newName string) (WorkspaceEdit, error) {
+	if !constantNamePattern.MatchString(newName) {
+		return WorkspaceEdit{}, fmt.Errorf("%s is not a valid constant name", newName)
+	}
+	if conflicts := s.index.RenameConflicts(sym.FullName, newName); len(conflicts) > 0 {
+		messages := make([]string, len(conflicts))
+		for i, c := range conflicts {
+			messages[i] = c.Message
+		}
+		return WorkspaceEdit{}, fmt.Errorf("can't rename %s: %s", sym.FullName, strings.Join(messages, "; "))
+	}
+
+	var refs []*index.Reference
+	found := s.index.FindReferences(sym.Name)
+	for i, ref := range found {
+		if err := checkRename(ctx, progress, i, len(found)); err != nil {
+			return WorkspaceEdit{}, err
+		}
+		if prev := charBefore(ref); prev == '.' || prev == '@' || prev == '$' {
+			continue
+		}
+		// A definition is renamed only if it's of the constant itself
+		if def := s.definitionAt(ref); def != nil {
+			if def.FullName == sym.FullName {
+				refs = append(refs, ref)
+			}
+			continue
+		}
+		written := qualifierBeforePattern.FindString(ref.LineText[:ref.Column]) + sym.Name
+		defs, _ := s.index.ResolveDefinitions(written, ref.FilePath, ref.Line)
+		for _, def := range defs {
+			if def.FullName == sym.FullName {
+				refs = append(refs, ref)
+				break
+			}
+		}
+	}
+	return renameReferences(refs, len(sym.Name), newName), nil
+}
+
+// definitionAt returns the symbol defined at a reference's position, if any
+func (s *Server) definitionAt(ref *index.Reference) *index.Symbol {
+	for _, sym := range s.index.SymbolsInFile(ref.FilePath) {
+		if sym.Line == ref.Line && sym.Column == ref.Column {
+			return sym
+		}
+	}
+	return nil
+}
+
+// renameMethod renames a method, its calls, and symbol references to it.
+// The Ruby suffix (? ! =) is part of the method's identity: the new name
+// keeps it, and only references with the same suffix are renamed. before
+// is the text preceding the name at the cursor, for common names that
+// need their receiver to tell which method is meant.
+func (s *Server) renameMethod(ctx context.Context, progress *progressReporter, name, path string, line int, before, newName string) (WorkspaceEdit, error) {
+	base, suffix := splitMethodSuffix(name)
+	newBase, newSuffix := splitMethodSuffix(newName)
+	if newSuffix != "" && newSuffix != suffix {
+		return WorkspaceEdit{}, fmt.Errorf("can't change %s to %s: the %q suffix must stay the same", name, newName, suffix)
+	}
+	if !identifierNamePattern.MatchString(newBase) || rubyKeywords[newBase] {
+		return WorkspaceEdit{}, fmt.Errorf("%s is not a valid method name", newName)
+	}
+
+	// The owners of the method being renamed; another class's method of
+	// the same name is left alone
+	owners := s.referenceOwners(name, path, line, before)
+	owned := make(map[string]bool, len(owners))
+	for _, owner := range owners {
+		owned[owner] = true
+	}
+
+	defs, _ := s.index.ResolveDefinitions(name, path, line)
+	accessor := false
+	found := false
+	for _, def := range defs {
+		if len(owned) > 0 && !owned[strings.Join(def.Scope, "::")] {
+			continue
+		}
+		switch def.Kind {
+		case index.KindAttrAccessor:
+			accessor = true
+			found = true
+		case index.KindMethod, index.KindSingletonMethod, index.KindAttrReader, index.KindAttrWriter:
+			found = true
+		}
+	}
+	if !found {
+		return WorkspaceEdit{}, fmt.Errorf("no method, class, or local variable named %s to rename", name)
+	}
+
+	refs := s.index.FindReferences(base)
+	if index.IsCommonName(name) || s.definedElsewhere(name, owned) {
+		refs = s.index.QualifiedReferences(refs, owners)
+	}
+
+	var kept []*index.Reference
+	for i, ref := range refs {
+		if err := checkRename(ctx, progress, i, len(refs)); err != nil {
+			return WorkspaceEdit{}, err
+		}
+		if prev := charBefore(ref); prev == '@' || prev == '$' {
+			continue
+		}
+		if !s.isMethodReference(ref, base, owned) {
+			continue
+		}
+		refSuffix, ok := methodSuffixAt(ref, len(base))
+		if !ok {
+			continue
+		}
+		// Symbols like attr_accessor :name name both the reader and writer
+		if refSuffix == suffix || (accessor && refSuffix != "?" && refSuffix != "!") ||
+			(charBefore(ref) == ':' && refSuffix == "" && suffix == "=") {
+			kept = append(kept, ref)
+		}
+	}
+	return renameReferences(kept, len(base), newBase), nil
+}
+
+// definedElsewhere reports whether a method named name is defined outside
+// the owner classes, so its calls need their receivers checked
+func (s *Server) definedElsewhere(name string, owned map[string]bool) bool {
+	for _, def := range s.index.FindDefinitions(name) {
+		switch def.Kind {
+		case index.KindMethod, index.KindSingletonMethod, index.KindAttrReader, index.KindAttrWriter, index.KindAttrAccessor:
+			if !owned[strings.Join(def.Scope, "::")] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isMethodReference reports whether a reference to a method's base name
+// can be a use of the method owned by one of owned: not another class's
+// definition of the same name, a hash key or keyword argument (settle: 2),
+// or a local variable read
+func (s *Server) isMethodReference(ref *index.Reference, base string, owned map[string]bool) bool {
+	if def := s.definitionAt(ref); def != nil {
+		return len(owned) == 0 || owned[strings.Join(def.Scope, "::")]
+	}
+	if charAfter(ref, len(base)) == ':' && charAfter(ref, len(base)+1) != ':' && charBefore(ref) != ':' {
+		return false
+	}
+	if charBefore(ref) != '.' && charBefore(ref) != ':' && s.index.FindLocalVariable(base, ref.FilePath, ref.Line) != nil {
+		return false
+	}
+	return true
+}
+
+// splitMethodSuffix splits a method name into its base and ? ! = suffix
+func splitMethodSuffix(name string) (string, string) {
+	if n := len(name); n > 0 && strings.ContainsRune("?!=", rune(name[n-1])) {
+		return name[:n-1], name[n-1:]
+	}
+	return name, ""
+}
+
+// methodSuffixAt classifies the reference to a method base name of length
+// n by what follows it: "?" or "!", "=" for a setter (def name=, x.name =),
+// or "". A bare name = value is a local assignment, not a call, and is
+// reported as not ok.
+func methodSuffixAt(ref *index.Reference, n int) (string, bool) {
+	switch next := charAfter(ref, n); next {
+	case '?', '!':
+		return string(next), true
+	}
+	if ref.Column+n > len(ref.LineText) || !assignmentAfterPattern.MatchString(ref.LineText[ref.Column+n:]) {
+		return "", true
+	}
+	before := strings.TrimRight(ref.LineText[:ref.Column], " ")
+	if strings.HasSuffix(before, ".") || strings.HasSuffix(before, "def") {
+		return "=", true
+	}
+	return "", false
+}
+
+// charBefore returns the character before a reference, or 0
+func charBefore(ref *index.Reference) byte {
+	if ref.Column == 0 || ref.Column > len(ref.LineText) {
+		return 0
+	}
+	return ref.LineText[ref.Column-1]
+}
+
+// charAfter returns the character n bytes after a reference starts, or 0
+func charAfter(ref *index.Reference, n int) byte {
+	if i := ref.Column + n; i < len(ref.LineText) {
+		return ref.LineText[i]
+	}
+	return 0
+}
+
+// renameReferences replaces the first n bytes of each reference with
+// newText, skipping duplicates
+func renameReferences(refs []*index.Reference, n int, newText string) WorkspaceEdit {
+	edit := WorkspaceEdit{Changes: map[string][]TextEdit{}}
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		key := fmt.Sprintf("%s:%d:%d", ref.FilePath, ref.Line, ref.Column)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		uri := pathToURI(ref.FilePath)
+		line := uint32(ref.Line - 1)
+		edit.Changes[uri] = append(edit.Changes[uri], TextEdit{
+			Range: Range{
+				Start: Position{Line: line, Character: uint32(ref.Column)},
+				End:   Position{Line: line, Character: uint32(ref.Column + n)},
+			},
+			NewText: newText,
+		})
+	}
+	for uri, edits := range edit.Changes {
+		edit.Changes[uri] = sortedEdits(edits)
+	}
+	return edit
+}
@@ -0,0 +1,312 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+func TestCheckRenameReportsConflicts(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"invoice.rb": "class Invoice\nend\n",
+		"receipt.rb": "class Receipt\nend\n",
+	}
+	s := newTestServer(tmpDir)
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.index.AddFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(newName string) RenameCheck {
+		t.Helper()
+		raw, err := callHandler(t, s, "goruby/checkRename", RenameParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: pathToURI(filepath.Join(tmpDir, "invoice.rb"))},
+				Position:     Position{Line: 0, Character: 8},
+			},
+			NewName: newName,
+		})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var result RenameCheck
+		if err := json.Unmarshal(raw, &result); err != nil {
+			t.Fatalf("failed to decode result: %v", err)
+		}
+		return result
+	}
+
+	result := check("Receipt")
+	if result.FullName != "Invoice" || result.NewFullName != "Receipt" {
+		t.Errorf("unexpected names %+v", result)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Kind != "existing_definition" ||
+		result.Conflicts[0].Location == nil || result.Conflicts[0].Location.URI != pathToURI(filepath.Join(tmpDir, "receipt.rb")) {
+		t.Errorf("expected a conflict with receipt.rb, got %+v", result.Conflicts)
+	}
+
+	if result := check("Bill"); len(result.Conflicts) != 0 {
+		t.Errorf("expected no conflicts for Bill, got %+v", result.Conflicts)
+	}
+}
+
+func TestRename(t *testing.T) {
+	s := newTestServer("/app")
+	files := map[string]string{
+		"/app/billing/invoice.rb": `module Billing
+  class Invoice
+    def state=(value)
+    end
+
+    def paid?
+      state == "paid"
+    end
+
+    def paid
+      total = 0
+      total += 1
+      totals = total
+    end
+  end
+end`,
+		"/app/shipping/invoice.rb": "module Shipping\n  class Invoice\n  end\nend\n",
+		"/app/report.rb": `class Report
+  def rows(invoice)
+    invoice.paid? && Billing::Invoice.new
+    Shipping::Invoice.new.paid
+    invoice.state = "void"
+  end
+end`,
+	}
+	for path, content := range files {
+		s.index.UpdateContent(path, []byte(content), 0)
+		s.documents.Open(pathToURI(path), 0, content)
+	}
+
+	rename := func(path string, line, char int, newName string) (map[string][]string, error) {
+		t.Helper()
+		raw, err := callHandler(t, s, "textDocument/rename", RenameParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: pathToURI(path)},
+				Position:     Position{Line: uint32(line), Character: uint32(char)},
+			},
+			NewName: newName,
+		})
+		if err != nil {
+			return nil, err
+		}
+		var edit WorkspaceEdit
+		if err := json.Unmarshal(raw, &edit); err != nil {
+			t.Fatalf("failed to decode edit: %v", err)
+		}
+		// Render each edited line for comparison
+		result := make(map[string][]string)
+		preview := previewWorkspaceEdit(edit, s.getDocumentContent)
+		for _, file := range preview.Files {
+			for _, hunk := range file.Hunks {
+				result[file.Path] = append(result[file.Path], hunk.After...)
+			}
+		}
+		return result, nil
+	}
+
+	// A predicate keeps its suffix and leaves the bang-less method alone
+	got, err := rename("/app/billing/invoice.rb", 5, 9, "settled")
+	if err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	if fmt.Sprint(got["/app/billing/invoice.rb"]) != "[    def settled?]" ||
+		fmt.Sprint(got["/app/report.rb"]) != `[    invoice.settled? && Billing::Invoice.new]` {
+		t.Errorf("unexpected predicate rename %v", got)
+	}
+
+	// A class is renamed where references resolve to it, not to a
+	// same-named class in another namespace
+	got, err = rename("/app/billing/invoice.rb", 1, 9, "Bill")
+	if err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	if fmt.Sprint(got["/app/billing/invoice.rb"]) != "[  class Bill]" ||
+		fmt.Sprint(got["/app/report.rb"]) != "[    invoice.paid? && Billing::Bill.new]" || len(got) != 2 {
+		t.Errorf("unexpected class rename %v", got)
+	}
+
+	// Locals are renamed within their method only
+	got, err = rename("/app/billing/invoice.rb", 10, 7, "sum")
+	if err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	if fmt.Sprint(got["/app/billing/invoice.rb"]) != "[      sum = 0       sum += 1       totals = sum]" {
+		t.Errorf("unexpected local rename %v", got)
+	}
+
+	// Setters rename their definition and assignments, not reads
+	got, err = rename("/app/billing/invoice.rb", 2, 9, "status")
+	if err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	if fmt.Sprint(got["/app/billing/invoice.rb"]) != "[    def status=(value)]" ||
+		fmt.Sprint(got["/app/report.rb"]) != `[    invoice.status = "void"]` {
+		t.Errorf("unexpected setter rename %v", got)
+	}
+
+	// Unsafe renames are refused
+	for _, tt := range []struct {
+		line, char int
+		newName    string
+		want       string
+	}{
+		{5, 4, "defn", "keyword"},          // Cursor on def
+		{5, 9, "end", "keyword"},           // New name is a keyword
+		{5, 9, "settled!", "suffix"},       // Predicate can't become a bang method
+		{1, 9, "invoice", "constant name"}, // Classes stay capitalized
+		{1, 9, "Invoice2", ""},             // Valid, checked below
+	} {
+		_, err := rename("/app/billing/invoice.rb", tt.line, tt.char, tt.newName)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("rename to %s: unexpected error %v", tt.newName, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("rename to %s: expected %q error, got %v", tt.newName, tt.want, err)
+		}
+	}
+}
+
+func TestRenameMethodKeepsOtherOwners(t *testing.T) {
+	s := newTestServer("/app")
+	files := map[string]string{
+		"/app/order.rb": `class Order
+  def settle
+    refund if settle
+  end
+
+  def close
+    settle
+    retry_in(settle: 2)
+  end
+end`,
+		"/app/payment.rb": `class Payment
+  def settle
+  end
+
+  def process
+    settle = 1
+    settle + 1
+  end
+end`,
+		"/app/checkout.rb": `class Checkout
+  def run
+    order = Order.new
+    order.settle
+    Payment.new.settle
+  end
+end`,
+	}
+	for path, content := range files {
+		s.index.UpdateContent(path, []byte(content), 0)
+		s.documents.Open(pathToURI(path), 0, content)
+	}
+
+	raw, err := callHandler(t, s, "textDocument/rename", RenameParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: pathToURI("/app/order.rb")},
+			Position:     Position{Line: 1, Character: 7},
+		},
+		NewName: "finalize",
+	})
+	if err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	var edit WorkspaceEdit
+	if err := json.Unmarshal(raw, &edit); err != nil {
+		t.Fatalf("failed to decode edit: %v", err)
+	}
+	got := make(map[string][]string)
+	for _, file := range previewWorkspaceEdit(edit, s.getDocumentContent).Files {
+		for _, hunk := range file.Hunks {
+			got[file.Path] = append(got[file.Path], hunk.After...)
+		}
+	}
+
+	if want := "[  def finalize     refund if finalize     finalize]"; fmt.Sprint(got["/app/order.rb"]) != want {
+		t.Errorf("expected Order's definition and calls renamed, not the hash key, got %v", got["/app/order.rb"])
+	}
+	if want := "[    order.finalize]"; fmt.Sprint(got["/app/checkout.rb"]) != want {
+		t.Errorf("expected only the call on an Order renamed, got %v", got["/app/checkout.rb"])
+	}
+	if lines, ok := got["/app/payment.rb"]; ok {
+		t.Errorf("expected Payment's method and local left alone, got %v", lines)
+	}
+}
+
+func TestRenameProgressAndCancellation(t *testing.T) {
+	tmpDir := t.TempDir()
+	s := newTestServer(tmpDir)
+	for i := 0; i < 100; i++ {
+		path := filepath.Join(tmpDir, fmt.Sprintf("use_%d.rb", i))
+		if err := os.WriteFile(path, []byte("Invoice.new\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.index.AddFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	invoice := filepath.Join(tmpDir, "invoice.rb")
+	if err := os.WriteFile(invoice, []byte("class Invoice\nend\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.index.AddFile(invoice); err != nil {
+		t.Fatal(err)
+	}
+	client := &fakeClient{}
+	s.client = client
+
+	params := RenameParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: pathToURI(invoice)},
+			Position:     Position{Line: 0, Character: 8},
+		},
+		WorkDoneProgressParams: WorkDoneProgressParams{WorkDoneToken: "rename-1"},
+		NewName:                "Bill",
+	}
+	raw, err := callHandler(t, s, "textDocument/rename", params)
+	if err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	var edit WorkspaceEdit
+	if err := json.Unmarshal(raw, &edit); err != nil || len(edit.Changes) != 101 {
+		t.Fatalf("expected edits in 101 files, got %d", len(edit.Changes))
+	}
+	if n := len(client.notifications); n < 3 || client.notifications[0] != "$/progress" || client.notifications[n-1] != "$/progress" {
+		t.Errorf("expected begin, report, and end progress, got %v", client.notifications)
+	}
+
+	// A cancelled rename returns an error, not part of the edit
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := jsonrpc2.NewCall(jsonrpc2.NewNumberID(2), "textDocument/rename", params)
+	var replyErr error
+	var result interface{}
+	s.handler(ctx, func(ctx context.Context, res interface{}, err error) error {
+		result, replyErr = res, err
+		return nil
+	}, req)
+	if rpcErr, ok := replyErr.(*jsonrpc2.Error); !ok || rpcErr.Code != codeRequestCancelled || result != nil {
+		t.Errorf("expected a cancelled error and no edit, got %v, %v", result, replyErr)
+	}
+}
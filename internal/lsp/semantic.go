@@ -0,0 +1,285 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// Semantic token types and modifiers, in legend order
+var (
+	semanticTokenTypes     = []string{"namespace", "class", "method", "property", "variable", "macro"}
+	semanticTokenModifiers = []string{"declaration", "readonly", "static"}
+)
+
+// Indexes into semanticTokenTypes
+const (
+	tokenNamespace = iota
+	tokenClass
+	tokenMethod
+	tokenProperty
+	tokenVariable
+	tokenMacro
+)
+
+// Bits for semanticTokenModifiers
+const (
+	modDeclaration = 1 << iota
+	modReadonly
+	modStatic
+)
+
+// SemanticTokensLegend names the token types and modifiers used in data
+type SemanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+// SemanticTokensOptions advertises semantic token support
+type SemanticTokensOptions struct {
+	Legend SemanticTokensLegend `json:"legend"`
+	Full   bool                 `json:"full"`
+}
+
+// SemanticTokensParams for textDocument/semanticTokens/full
+type SemanticTokensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// SemanticTokens holds tokens encoded as relative position quintuples:
+// line delta, start delta, length, type, modifier bits
+type SemanticTokens struct {
+	Data []uint32 `json:"data"`
+}
+
+// semanticToken is one highlighted range on a 0-based line
+type semanticToken struct {
+	line, start, length int
+	typ, mods           int
+}
+
+// relationMacros declare Rails associations
+var relationMacros = []string{"has_and_belongs_to_many", "belongs_to", "has_many", "has_one"}
+
+func (s *Server) handleSemanticTokensFull(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params SemanticTokensParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	content := s.getDocumentContent(uri)
+	if strings.TrimSpace(content) == "" {
+		return reply(ctx, SemanticTokens{Data: []uint32{}}, nil)
+	}
+
+	// Parse the live document so tokens match unsaved edits
+	symbols := s.index.ParseContent(uriToPath(uri), content)
+	tokens := semanticTokens(content, symbols, s.index.ConstantKind)
+	return reply(ctx, SemanticTokens{Data: encodeSemanticTokens(tokens)}, nil)
+}
+
+// semanticTokens classifies a document: definitions from its symbols,
+// references to its local variables within their methods, constants the
+// index knows (by constantKind), and relation macros. Strings and
+// comments are skipped.
+func semanticTokens(content string, symbols []*index.Symbol, constantKind func(string) (index.SymbolKind, bool)) []semanticToken {
+	lines := strings.Split(content, "\n")
+	byPos := make(map[[2]int]semanticToken)
+	add := func(t semanticToken) {
+		key := [2]int{t.line, t.start}
+		if _, ok := byPos[key]; !ok {
+			byPos[key] = t
+		}
+	}
+
+	// Definitions first so they win over usage classification
+	locals := make(map[string][]*index.Symbol) // Method or lambda full name -> locals
+	scopes := make(map[string]*index.Symbol)   // Methods and lambdas locals are scoped to
+	for _, sym := range symbols {
+		typ, mods, ok := definitionToken(sym)
+		if !ok || sym.Line < 1 || sym.Line > len(lines) {
+			continue
+		}
+		add(semanticToken{line: sym.Line - 1, start: sym.Column, length: len(sym.Name), typ: typ, mods: mods | modDeclaration})
+
+		switch sym.Kind {
+		case index.KindLocalVariable:
+			locals[sym.MethodFullName] = append(locals[sym.MethodFullName], sym)
+			if sym.EndLine != 0 {
+				scopes[sym.FullName] = sym // A lambda, with its body's range
+			}
+		case index.KindMethod, index.KindSingletonMethod:
+			scopes[sym.FullName] = sym
+		case index.KindRelation:
+			line := lines[sym.Line-1]
+			for _, macro := range relationMacros {
+				if i := strings.Index(line, macro); i >= 0 && i < sym.Column {
+					add(semanticToken{line: sym.Line - 1, start: i, length: len(macro), typ: tokenMacro})
+					break
+				}
+			}
+		}
+	}
+
+	// Usages: locals inside their method, and known constants anywhere
+	kinds := make(map[string]int)
+	for i, line := range lines {
+		for _, w := range codeWords(line) {
+			if w.word[0] >= 'A' && w.word[0] <= 'Z' {
+				typ, ok := kinds[w.word]
+				if !ok {
+					typ = -1
+					if kind, found := constantKind(w.word); found {
+						typ = map[index.SymbolKind]int{index.KindClass: tokenClass, index.KindModule: tokenNamespace, index.KindConstant: tokenVariable}[kind]
+					}
+					kinds[w.word] = typ
+				}
+				if typ >= 0 {
+					mods := 0
+					if typ == tokenVariable {
+						mods = modReadonly
+					}
+					add(semanticToken{line: i, start: w.start, length: len(w.word), typ: typ, mods: mods})
+				}
+				continue
+			}
+			if w.afterDot {
+				continue
+			}
+			if local := localInScope(w.word, i+1, locals, scopes); local != nil {
+				add(semanticToken{line: i, start: w.start, length: len(w.word), typ: tokenVariable})
+			}
+		}
+	}
+
+	tokens := make([]semanticToken, 0, len(byPos))
+	for _, t := range byPos {
+		tokens = append(tokens, t)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].line != tokens[j].line {
+			return tokens[i].line < tokens[j].line
+		}
+		return tokens[i].start < tokens[j].start
+	})
+	return tokens
+}
+
+// definitionToken maps a symbol kind to its token type and modifiers
+func definitionToken(sym *index.Symbol) (typ, mods int, ok bool) {
+	switch sym.Kind {
+	case index.KindClass:
+		return tokenClass, 0, true
+	case index.KindModule:
+		return tokenNamespace, 0, true
+	case index.KindMethod:
+		return tokenMethod, 0, true
+	case index.KindSingletonMethod:
+		return tokenMethod, modStatic, true
+	case index.KindConstant:
+		return tokenVariable, modReadonly, true
+	case index.KindRelation, index.KindAttrReader, index.KindAttrWriter, index.KindAttrAccessor:
+		return tokenProperty, 0, true
+	case index.KindLocalVariable:
+		return tokenVariable, 0, true
+	}
+	return 0, 0, false
+}
+
+// localInScope finds the local variable name assigned at or before line
+// (1-based) in a method or lambda whose range covers line
+func localInScope(name string, line int, locals map[string][]*index.Symbol, scopes map[string]*index.Symbol) *index.Symbol {
+	for scopeName, syms := range locals {
+		scope := scopes[scopeName]
+		if scope == nil || line < scope.Line || (scope.EndLine != 0 && line > scope.EndLine) {
+			continue
+		}
+		for _, sym := range syms {
+			if sym.Name == name && sym.Line <= line {
+				return sym
+			}
+		}
+	}
+	return nil
+}
+
+// codeWord is an identifier outside strings and comments
+type codeWord struct {
+	word     string
+	start    int
+	afterDot bool // A method call on a receiver, not a local
+}
+
+// codeWords returns the identifiers on a line, skipping string literals,
+// symbols, instance/global variables, and comments
+func codeWords(line string) []codeWord {
+	var words []codeWord
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == '#':
+			return words
+		case c == '"' || c == '\'' || c == '`':
+			i++
+			for i < len(line) && line[i] != c {
+				if line[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+		case c == ':' && i+1 < len(line) && line[i+1] == ':':
+			i += 2
+		case c == ':' || c == '@' || c == '$':
+			// Skip the sigil and the name after it
+			i++
+			for i < len(line) && (isWordChar(line[i]) || line[i] == '@') {
+				i++
+			}
+		case isWordChar(c) && (c < '0' || c > '9'):
+			start := i
+			for i < len(line) && isWordChar(line[i]) {
+				i++
+			}
+			// A trailing colon makes a keyword argument or hash key label
+			if i < len(line) && line[i] == ':' && (i+1 >= len(line) || line[i+1] != ':') {
+				continue
+			}
+			words = append(words, codeWord{
+				word:     line[start:i],
+				start:    start,
+				afterDot: start > 0 && line[start-1] == '.',
+			})
+		case c >= '0' && c <= '9':
+			for i < len(line) && isWordChar(line[i]) {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return words
+}
+
+// encodeSemanticTokens converts sorted tokens to the LSP relative encoding
+func encodeSemanticTokens(tokens []semanticToken) []uint32 {
+	data := make([]uint32, 0, len(tokens)*5)
+	prevLine, prevStart := 0, 0
+	for _, t := range tokens {
+		deltaStart := t.start
+		if t.line == prevLine {
+			deltaStart = t.start - prevStart
+		}
+		data = append(data, uint32(t.line-prevLine), uint32(deltaStart), uint32(t.length), uint32(t.typ), uint32(t.mods))
+		prevLine, prevStart = t.line, t.start
+	}
+	return data
+}
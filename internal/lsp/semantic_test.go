@@ -0,0 +1,79 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSemanticTokens(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/line_item.rb", []byte("class LineItem\nend\n"), 0)
+
+	uri := "file:///app/order.rb"
+	content := `module Shop
+  class Order
+    has_many :line_items
+    LIMIT = 10
+
+    def self.build
+    end
+
+    def total(rate)
+      sum = LineItem.count # LineItem
+      puts "LineItem #{sum}"
+      sum * rate.sum
+    end
+  end
+end
+`
+	s.documents.Open(uri, 1, content)
+
+	raw, err := callHandler(t, s, "textDocument/semanticTokens/full", SemanticTokensParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var result SemanticTokens
+	if err := json.Unmarshal(raw, &result); err != nil {
+		t.Fatalf("failed to decode tokens: %v", err)
+	}
+
+	// Decode back to absolute positions, rendered as text:type[+mods]
+	lines := strings.Split(content, "\n")
+	var got []string
+	line, start := 0, 0
+	for i := 0; i+4 < len(result.Data); i += 5 {
+		d := result.Data[i : i+5]
+		if d[0] > 0 {
+			start = 0
+		}
+		line += int(d[0])
+		start += int(d[1])
+		token := lines[line][start:start+int(d[2])] + ":" + semanticTokenTypes[d[3]]
+		for bit, mod := range semanticTokenModifiers {
+			if d[4]&(1<<bit) != 0 {
+				token += "+" + mod
+			}
+		}
+		got = append(got, fmt.Sprintf("%d:%s", line, token))
+	}
+
+	want := []string{
+		"0:Shop:namespace+declaration",
+		"1:Order:class+declaration",
+		"2:has_many:macro",
+		"2:line_items:property+declaration",
+		"3:LIMIT:variable+declaration+readonly",
+		"5:build:method+declaration+static",
+		"8:total:method+declaration",
+		"9:sum:variable+declaration",
+		"9:LineItem:class", // Not the one in the comment
+		"11:sum:variable",  // Not the string interpolation, or rate.sum
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected tokens:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
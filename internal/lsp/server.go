@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 
 	"github.com/jarredhawkins/goruby-lsp/internal/index"
 	"go.lsp.dev/jsonrpc2"
@@ -14,29 +17,110 @@ import (
 // Server implements the LSP server
 type Server struct {
 	index     *index.Index
-	documents map[string]string // URI -> content cache for open documents
+	documents *DocumentStore         // Open documents, by URI
+	config    atomic.Pointer[Config] // Client settings, replaced whole (see settings)
+
+	client       clientCaller       // Connection for server-to-client requests
+	capabilities ClientCapabilities // Sent by the client in initialize
+
+	readOnly bool // Set by the -read-only flag; the readOnly option can't clear it
+
+	trace atomic.Int32 // Trace level set by the client (see traced)
+
+	clientWatching bool   // Register file watchers with the client on initialized
+	watchFallback  func() // Starts server-side watching when the client can't
+
+	indexReady func(error) // Set to build the index on initialized; runs when the build finishes
+
+	lifecycle *lifecycle
+	serving   atomic.Bool // Set by Serve, which may only be called once
 }
 
 // NewServer creates a new LSP server
 func NewServer(idx *index.Index) *Server {
-	return &Server{
+	s := &Server{
 		index:     idx,
-		documents: make(map[string]string),
+		documents: NewDocumentStore(),
+		lifecycle: newLifecycle(),
+	}
+	s.config.Store(&Config{})
+	idx.OnRepeatedFailure(s.reportFileFailure)
+	return s
+}
+
+// settings returns the client's current settings. Handlers and the index
+// build run concurrently with didChangeConfiguration, which swaps in a new
+// Config rather than changing this one, so read it once per use and never
+// modify it.
+func (s *Server) settings() *Config {
+	return s.config.Load()
+}
+
+// SetReadOnly stops the server from changing the workspace or running
+// project code, for untrusted repositories
+func (s *Server) SetReadOnly(readOnly bool) {
+	s.readOnly = readOnly
+}
+
+// BuildIndexOnInitialized defers building the index until the client has
+// initialized, so the build can report its progress. ready runs with the
+// build's error once it finishes; until then requests see a partial index.
+func (s *Server) BuildIndexOnInitialized(ready func(error)) {
+	s.indexReady = ready
+}
+
+// buildIndex builds the index, reporting progress when the client can
+// show it
+func (s *Server) buildIndex(ctx context.Context) {
+	progress := s.createProgress(ctx, indexProgressToken, "Indexing Ruby project")
+	total := 0
+	err := s.index.BuildWithProgress(ctx, func(done, n int) {
+		total = n
+		progress.report(ctx, countProgress(done, n, "files"), done, n)
+	})
+	if err != nil {
+		progress.end(ctx, "Indexing failed")
+		s.showMessage(context.WithoutCancel(ctx), MessageTypeError, fmt.Sprintf("Indexing failed: %v", err))
+	} else {
+		progress.end(ctx, fmt.Sprintf("Indexed %s files", groupDigits(total)))
+		if s.settings().IndexGems {
+			result, gemErr := s.indexGems(ctx, lockfilePath(filepath.Join(s.index.RootPath(), "Gemfile")))
+			log.Printf("indexed %d files of gems (err=%v)", result.Files, gemErr)
+		}
 	}
+	s.indexReady(err)
+}
+
+// isReadOnly reports whether read-only mode is on, by flag or config
+func (s *Server) isReadOnly() bool {
+	return s.readOnly || s.settings().ReadOnly
 }
 
-// Serve starts the LSP server on the given reader/writer
+// Serve starts the LSP server on the given reader/writer. It returns nil
+// once the client sends exit after shutdown. A server serves one
+// connection; calling Serve again returns an error.
 func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	if !s.serving.CompareAndSwap(false, true) {
+		return errAlreadyServing
+	}
+
 	stream := jsonrpc2.NewStream(&readWriteCloser{in, out})
 	conn := jsonrpc2.NewConn(stream)
+	s.client = conn
 
-	conn.Go(ctx, s.handler)
+	// Requests are handled one at a time off the read loop, so the server
+	// can wait on client replies and see cancellations while handling one
+	handler, cancel := jsonrpc2.CancelHandler(jsonrpc2.AsyncHandler(s.lifecycle.guard(s.traced(s.handler))))
+	conn.Go(ctx, cancelRequests(handler, cancel))
 
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	case <-conn.Done():
 		return conn.Err()
+	case <-s.lifecycle.exited:
+		conn.Close()
+		return s.lifecycle.exitErr()
 	}
 }
 
@@ -47,21 +131,83 @@ func (s *Server) handler(ctx context.Context, reply jsonrpc2.Replier, req jsonrp
 	case "initialize":
 		return s.handleInitialize(ctx, reply, req)
 	case "initialized":
-		return reply(ctx, nil, nil)
+		return s.handleInitialized(ctx, reply, req)
 	case "shutdown":
 		return reply(ctx, nil, nil)
 	case "exit":
-		return nil
+		return reply(ctx, nil, nil)
 	case "textDocument/definition":
 		return s.handleDefinition(ctx, reply, req)
+	case "textDocument/declaration":
+		return s.handleDeclaration(ctx, reply, req)
+	case "textDocument/typeDefinition":
+		return s.handleTypeDefinition(ctx, reply, req)
 	case "textDocument/references":
 		return s.handleReferences(ctx, reply, req)
+	case "textDocument/implementation":
+		return s.handleImplementation(ctx, reply, req)
+	case "textDocument/completion":
+		return s.handleCompletion(ctx, reply, req)
+	case "textDocument/prepareTypeHierarchy":
+		return s.handlePrepareTypeHierarchy(ctx, reply, req)
+	case "typeHierarchy/supertypes":
+		return s.handleTypeHierarchySupertypes(ctx, reply, req)
+	case "typeHierarchy/subtypes":
+		return s.handleTypeHierarchySubtypes(ctx, reply, req)
+	case "textDocument/codeAction":
+		return s.handleCodeAction(ctx, reply, req)
+	case "textDocument/linkedEditingRange":
+		return s.handleLinkedEditingRange(ctx, reply, req)
+	case "textDocument/documentHighlight":
+		return s.handleDocumentHighlight(ctx, reply, req)
+	case "textDocument/codeLens":
+		return s.handleCodeLens(ctx, reply, req)
+	case "textDocument/inlayHint":
+		return s.handleInlayHint(ctx, reply, req)
+	case "textDocument/formatting":
+		return s.handleFormatting(ctx, reply, req)
+	case "textDocument/documentLink":
+		return s.handleDocumentLink(ctx, reply, req)
+	case "textDocument/foldingRange":
+		return s.handleFoldingRange(ctx, reply, req)
+	case "textDocument/semanticTokens/full":
+		return s.handleSemanticTokensFull(ctx, reply, req)
+	case "textDocument/documentSymbol":
+		return s.handleDocumentSymbol(ctx, reply, req)
 	case "textDocument/didOpen":
 		return s.handleDidOpen(ctx, reply, req)
 	case "textDocument/didChange":
 		return s.handleDidChange(ctx, reply, req)
+	case "textDocument/didSave":
+		return s.handleDidSave(ctx, reply, req)
 	case "textDocument/didClose":
 		return s.handleDidClose(ctx, reply, req)
+	case "$/setTrace":
+		return s.handleSetTrace(ctx, reply, req)
+	case "workspace/symbol":
+		return s.handleWorkspaceSymbol(ctx, reply, req)
+	case "workspaceSymbol/resolve":
+		return s.handleWorkspaceSymbolResolve(ctx, reply, req)
+	case "workspace/didChangeConfiguration":
+		return s.handleDidChangeConfiguration(ctx, reply, req)
+	case "workspace/didChangeWatchedFiles":
+		return s.handleDidChangeWatchedFiles(ctx, reply, req)
+	case "workspace/executeCommand":
+		return s.handleExecuteCommand(ctx, reply, req)
+	case "goruby/associationReferences":
+		return s.handleAssociationReferences(ctx, reply, req)
+	case "goruby/associationGraph":
+		return s.handleAssociationGraph(ctx, reply, req)
+	case "goruby/listSymbols":
+		return s.handleListSymbols(ctx, reply, req)
+	case "goruby/indexStatus":
+		return s.handleIndexStatus(ctx, reply, req)
+	case "goruby/previewWorkspaceEdit":
+		return s.handlePreviewWorkspaceEdit(ctx, reply, req)
+	case "textDocument/rename":
+		return s.handleRename(ctx, reply, req)
+	case "goruby/checkRename":
+		return s.handleCheckRename(ctx, reply, req)
 	default:
 		// Method not found
 		return reply(ctx, nil, &jsonrpc2.Error{
@@ -71,15 +217,86 @@ func (s *Server) handler(ctx context.Context, reply jsonrpc2.Replier, req jsonrp
 	}
 }
 
+// CancelParams for the $/cancelRequest notification
+type CancelParams struct {
+	ID jsonrpc2.ID `json:"id"`
+}
+
+// cancelRequests handles $/cancelRequest as soon as it arrives, ahead of
+// the requests queued behind the one it cancels
+func cancelRequests(handler jsonrpc2.Handler, cancel func(jsonrpc2.ID)) jsonrpc2.Handler {
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		if req.Method() != "$/cancelRequest" {
+			return handler(ctx, reply, req)
+		}
+		var params CancelParams
+		if err := json.Unmarshal(req.Params(), &params); err == nil {
+			cancel(params.ID)
+		}
+		return reply(ctx, nil, nil)
+	}
+}
+
 func (s *Server) handleInitialize(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params InitializeParams
+	if len(req.Params()) > 0 {
+		if err := json.Unmarshal(req.Params(), &params); err != nil {
+			return reply(ctx, nil, &jsonrpc2.Error{
+				Code:    jsonrpc2.InvalidParams,
+				Message: err.Error(),
+			})
+		}
+	}
+
+	s.capabilities = params.Capabilities
+	s.trace.Store(parseTrace(params.Trace))
+
+	if len(params.InitializationOptions) > 0 {
+		var config Config
+		if err := json.Unmarshal(params.InitializationOptions, &config); err != nil {
+			log.Printf("ignoring invalid initializationOptions: %v", err)
+		}
+		s.config.Store(&config)
+		s.applyMatcherConfig(&config)
+	}
+
 	result := InitializeResult{
 		Capabilities: ServerCapabilities{
 			TextDocumentSync: &TextDocumentSyncOptions{
 				OpenClose: true,
 				Change:    TextDocumentSyncKindFull,
+				Save:      &SaveOptions{},
+			},
+			DefinitionProvider:         true,
+			ReferencesProvider:         true,
+			DocumentSymbolProvider:     true,
+			WorkspaceSymbolProvider:    &WorkspaceSymbolOptions{ResolveProvider: true},
+			RenameProvider:             true,
+			FoldingRangeProvider:       true,
+			TypeHierarchyProvider:      true,
+			ImplementationProvider:     true,
+			DeclarationProvider:        true,
+			TypeDefinitionProvider:     true,
+			InlayHintProvider:          true,
+			DocumentHighlightProvider:  true,
+			LinkedEditingRangeProvider: true,
+			CodeActionProvider:         true,
+			DocumentFormattingProvider: true,
+			SemanticTokensProvider: &SemanticTokensOptions{
+				Legend: SemanticTokensLegend{
+					TokenTypes:     semanticTokenTypes,
+					TokenModifiers: semanticTokenModifiers,
+				},
+				Full: true,
+			},
+			CodeLensProvider:     &CodeLensOptions{},
+			DocumentLinkProvider: &DocumentLinkOptions{},
+			ExecuteCommandProvider: &ExecuteCommandOptions{
+				Commands: commandNames(),
+			},
+			CompletionProvider: &CompletionOptions{
+				TriggerCharacters: []string{".", ":"},
 			},
-			DefinitionProvider: true,
-			ReferencesProvider: true,
 		},
 		ServerInfo: &ServerInfo{
 			Name:    "ruby-lsp",
@@ -109,38 +326,142 @@ func (s *Server) handleDefinition(ctx context.Context, reply jsonrpc2.Replier, r
 		return reply(ctx, nil, nil)
 	}
 
+	// Plain-text Gherkin steps resolve to the step definition matching them
+	if isFeatureFile(filePath) {
+		if step := gherkinStepAt(content, line); step != "" {
+			if symbols := s.index.FindStepDefinitions(step); len(symbols) > 0 {
+				return reply(ctx, s.definitionResult(symbols, nil, index.ConfidenceExact), nil)
+			}
+		}
+		return reply(ctx, nil, nil)
+	}
+
+	s.syncDocument(uri)
+
+	// Dependencies in a Gemfile or gemspec resolve to their lockfile entry
+	// and the installed gem
+	if isBundlerFile(filePath) {
+		if name := gemDependencyAt(s.index.SymbolsInFile(filePath), line, char); name != "" {
+			if locations := gemLocations(filePath, name); len(locations) > 0 {
+				return reply(ctx, locations, nil)
+			}
+			return reply(ctx, nil, nil)
+		}
+	}
+
+	// Shared examples are referenced by their string name
+	if name := sharedExampleAt(content, line, char); name != "" {
+		if symbols := s.index.FindSharedExamples(name); len(symbols) > 0 {
+			return reply(ctx, s.definitionResult(symbols, nil, index.ConfidenceExact), nil)
+		}
+		return reply(ctx, nil, nil)
+	}
+
+	// Request paths in specs resolve to the Sinatra or Grape endpoints
+	// serving them
+	if verb, path, ok := requestPathAt(content, line, char); ok {
+		if symbols := s.index.FindEndpoints(verb, path); len(symbols) > 0 {
+			return reply(ctx, s.definitionResult(symbols, nil, index.ConfidenceContextual), nil)
+		}
+		return reply(ctx, nil, nil)
+	}
+
+	// Stubbed messages resolve against the stubbed receiver's class
+	if target := stubTargetAt(content, line, char); target != nil {
+		if symbols, confidence := s.resolveStub(target); len(symbols) > 0 {
+			return reply(ctx, s.definitionResult(symbols, nil, confidence), nil)
+		}
+		return reply(ctx, nil, nil)
+	}
+
 	// Extract word at position
-	word := extractWordAt(content, line, char)
+	word, start, end := wordRangeAt(content, line, char)
 	if word == "" {
 		return reply(ctx, nil, nil)
 	}
+	origin := &Range{
+		Start: Position{Line: uint32(line), Character: uint32(start)},
+		End:   Position{Line: uint32(line), Character: uint32(end)},
+	}
 
 	log.Printf("definition request for word: %s at %s:%d:%d", word, filePath, line, char)
 
 	// Try local variable lookup first (lowercase names only)
-	if len(word) > 0 && ((word[0] >= 'a' && word[0] <= 'z') || word[0] == '_') {
-		// line is 0-indexed from LSP, FindLocalVariable expects 1-indexed
-		if sym := s.index.FindLocalVariable(word, filePath, line+1); sym != nil {
-			return reply(ctx, symbolToLocation(sym), nil)
-		}
+	if sym, confidence := s.localDefinition(word, filePath, line+1); sym != nil {
+		return reply(ctx, s.definitionResult([]*index.Symbol{sym}, origin, confidence), nil)
+	}
+
+	// described_class is the constant its example group describes, and
+	// methods called on it or on let helpers resolve against their class
+	if symbols, confidence := s.specDefinitions(word, lineAt(content, line)[:start], filePath, line+1); len(symbols) > 0 {
+		return reply(ctx, s.definitionResult(symbols, origin, confidence), nil)
+	}
+
+	// Association option values (foreign_key:, counter_cache:) name columns
+	if symbols := s.index.FindAssociationColumn(word, filePath, line+1); len(symbols) > 0 {
+		return reply(ctx, s.definitionResult(symbols, origin, index.ConfidenceContextual), nil)
 	}
 
 	// Look up definitions in global index (namespace-aware)
-	symbols := s.index.FindDefinitionsInContext(word, filePath, line+1)
+	symbols, confidence := s.index.ResolveDefinitions(word, filePath, line+1)
 	if len(symbols) == 0 {
 		return reply(ctx, nil, nil)
 	}
 
-	// Convert to LSP locations
-	if len(symbols) == 1 {
-		return reply(ctx, symbolToLocation(symbols[0]), nil)
+	return reply(ctx, s.definitionResult(symbols, origin, confidence), nil)
+}
+
+// localDefinition finds a lowercase name's definition as a local variable
+// in the method around line (1-based), then as an RSpec let/subject
+// helper, innermost example group first. Returns nil if it's neither.
+func (s *Server) localDefinition(word, filePath string, line int) (*index.Symbol, index.Confidence) {
+	if word == "" || !((word[0] >= 'a' && word[0] <= 'z') || word[0] == '_') {
+		return nil, index.ConfidenceExact
+	}
+	if sym := s.index.FindLocalVariable(word, filePath, line); sym != nil {
+		return sym, index.ConfidenceExact
+	}
+	if sym := s.index.FindLet(word, filePath, line); sym != nil {
+		return sym, index.ConfidenceContextual
+	}
+	return nil, index.ConfidenceExact
+}
+
+// definitionResult converts definitions resolved with confidence to a
+// response, dropping results below the configured minimum confidence. When
+// the client supports links, each becomes a LocationLink from origin (the
+// qualified name under the cursor, e.g. all of A::B::C) carrying its
+// container and kind, so several matches make a useful pick list.
+func (s *Server) definitionResult(symbols []*index.Symbol, origin *Range, confidence index.Confidence) interface{} {
+	minimum := s.settings().minConfidence()
+
+	var links []LocationLink
+	var locations []Location
+	for _, sym := range symbols {
+		c := index.SymbolConfidence(sym, confidence)
+		if c > minimum {
+			continue
+		}
+		if s.capabilities.TextDocument.Definition.LinkSupport {
+			link := symbolToLocationLink(sym, origin)
+			link.Confidence = c.String()
+			links = append(links, link)
+		} else {
+			loc := symbolToLocation(sym)
+			loc.Confidence = c.String()
+			locations = append(locations, loc)
+		}
 	}
 
-	locations := make([]Location, len(symbols))
-	for i, sym := range symbols {
-		locations[i] = symbolToLocation(sym)
+	switch {
+	case links != nil:
+		return links
+	case len(locations) == 1:
+		return locations[0]
+	case locations != nil:
+		return locations
 	}
-	return reply(ctx, locations, nil)
+	return nil
 }
 
 func (s *Server) handleReferences(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
@@ -160,8 +481,9 @@ func (s *Server) handleReferences(ctx context.Context, reply jsonrpc2.Replier, r
 	if content == "" {
 		return reply(ctx, nil, nil)
 	}
+	s.syncDocument(uri)
 
-	word := extractWordAt(content, line, char)
+	word, start, end := wordRangeAt(content, line, char)
 	if word == "" {
 		return reply(ctx, nil, nil)
 	}
@@ -175,6 +497,25 @@ func (s *Server) handleReferences(ctx context.Context, reply jsonrpc2.Replier, r
 	// Find all references using trigram search
 	refs := s.index.FindReferences(word)
 	log.Printf("trigram search returned %d refs", len(refs))
+
+	// Common names like new or name match everywhere; keep only the
+	// references qualified by the class the cursor's method belongs to
+	var owned map[string]bool
+	config := s.settings()
+	if index.IsCommonName(word) && !config.UnqualifiedReferences {
+		owners := s.referenceOwners(word, uriToPath(uri), line+1, lineAt(content, line)[:start])
+		total := len(refs)
+		refs = s.index.QualifiedReferences(refs, owners)
+		log.Printf("common name %s: kept %d of %d refs qualified by %v", word, len(refs), total, owners)
+		if len(refs) < total {
+			s.showMessage(ctx, MessageTypeWarning, commonNameWarning(word, owners, len(refs), total))
+		}
+
+		owned = make(map[string]bool)
+		for _, owner := range owners {
+			owned[owner] = true
+		}
+	}
 	for _, ref := range refs {
 		log.Printf("  ref: %s:%d:%d", ref.FilePath, ref.Line, ref.Column)
 		key := fmt.Sprintf("%s:%d:%d", ref.FilePath, ref.Line, ref.Column)
@@ -210,11 +551,27 @@ func (s *Server) handleReferences(ctx context.Context, reply jsonrpc2.Replier, r
 		locations = append(locations, symbolToLocation(sym))
 	}
 
+	// A string or symbol may be one of a constant's literal values
+	// (ROLES = %w[admin member]); the constants declaring it refer to it too
+	if isLiteralWord(lineAt(content, line), start, end) {
+		for _, sym := range s.index.FindConstantsWithValue(word) {
+			key := fmt.Sprintf("%s:%d:%d", sym.FilePath, sym.Line, sym.Column)
+			if _, exists := seen[key]; exists {
+				continue
+			}
+			seen[key] = struct{}{}
+			locations = append(locations, symbolToLocation(sym))
+		}
+	}
+
 	// Include declarations if requested - deduplication prevents double-adding
 	if params.Context.IncludeDeclaration {
 		symbols := s.index.FindDefinitions(word)
 		log.Printf("definitions returned %d symbols (includeDeclaration=%v)", len(symbols), params.Context.IncludeDeclaration)
 		for _, sym := range symbols {
+			if owned != nil && !owned[strings.Join(sym.Scope, "::")] {
+				continue
+			}
 			log.Printf("  def: %s:%d:%d", sym.FilePath, sym.Line, sym.Column)
 			key := fmt.Sprintf("%s:%d:%d", sym.FilePath, sym.Line, sym.Column)
 			if _, exists := seen[key]; exists {
@@ -225,6 +582,11 @@ func (s *Server) handleReferences(ctx context.Context, reply jsonrpc2.Replier, r
 		}
 	}
 
+	if limit := config.referenceLimit(); len(locations) > limit {
+		s.showMessage(ctx, MessageTypeWarning, fmt.Sprintf("Showing the first %d of %d references to %s", limit, len(locations), word))
+		locations = locations[:limit]
+	}
+
 	log.Printf("returning %d total locations", len(locations))
 	return reply(ctx, locations, nil)
 }
@@ -235,7 +597,8 @@ func (s *Server) handleDidOpen(ctx context.Context, reply jsonrpc2.Replier, req
 		return reply(ctx, nil, err)
 	}
 
-	s.documents[params.TextDocument.URI] = params.TextDocument.Text
+	s.documents.Open(params.TextDocument.URI, params.TextDocument.Version, params.TextDocument.Text)
+	s.publishDiagnostics(ctx, params.TextDocument.URI)
 	return reply(ctx, nil, nil)
 }
 
@@ -246,8 +609,11 @@ func (s *Server) handleDidChange(ctx context.Context, reply jsonrpc2.Replier, re
 	}
 
 	if len(params.ContentChanges) > 0 {
-		// Full sync mode - just take the last content
-		s.documents[params.TextDocument.URI] = params.ContentChanges[len(params.ContentChanges)-1].Text
+		// Full sync mode - just take the last content. The index catches up
+		// lazily, the next time a request needs this document.
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		s.documents.Update(params.TextDocument.URI, params.TextDocument.Version, text)
+		s.publishDiagnostics(ctx, params.TextDocument.URI)
 	}
 	return reply(ctx, nil, nil)
 }
@@ -258,13 +624,80 @@ func (s *Server) handleDidClose(ctx context.Context, reply jsonrpc2.Replier, req
 		return reply(ctx, nil, err)
 	}
 
-	delete(s.documents, params.TextDocument.URI)
+	uri := params.TextDocument.URI
+	s.documents.Close(uri)
+	s.publishDiagnostics(ctx, uri)
+
+	// Drop any unsaved edits from the index by going back to the file on disk
+	path := uriToPath(uri)
+	if s.index.FileVersion(path) != 0 {
+		if err := s.index.UpdateFile(path); err != nil {
+			s.index.RemoveFile(path)
+		}
+	}
 	return reply(ctx, nil, nil)
 }
 
+// syncDocument reindexes an open document when the index was built from a
+// different version, so results never point at lines that no longer exist
+// after rapid edits. It is a no-op for documents that aren't open.
+func (s *Server) syncDocument(uri string) {
+	doc, ok := s.documents.Snapshot(uri)
+	if !ok {
+		return
+	}
+	path := uriToPath(uri)
+	if s.index.FileVersion(path) == doc.Version {
+		return
+	}
+	s.index.UpdateContent(path, []byte(doc.Content), doc.Version)
+}
+
+// showMessage notifies the client of something the user should see
+func (s *Server) showMessage(ctx context.Context, typ MessageType, message string) {
+	if s.client == nil {
+		return
+	}
+	if err := s.client.Notify(ctx, "window/showMessage", ShowMessageParams{Type: typ, Message: message}); err != nil {
+		log.Printf("failed to send message: %v", err)
+	}
+}
+
+// logMessage sends the client something for its log, such as a problem
+// worth keeping a record of but not interrupting the user for
+func (s *Server) logMessage(ctx context.Context, typ MessageType, message string) {
+	if s.client == nil {
+		return
+	}
+	if err := s.client.Notify(ctx, "window/logMessage", ShowMessageParams{Type: typ, Message: message}); err != nil {
+		log.Printf("failed to send log message: %v", err)
+	}
+}
+
+// reportFileFailure tells the user about a file that repeatedly failed to
+// index, so its symbols are missing
+func (s *Server) reportFileFailure(path string, err error) {
+	if rel, relErr := filepath.Rel(s.index.RootPath(), path); relErr == nil {
+		path = rel
+	}
+	s.showMessage(context.Background(), MessageTypeWarning, fmt.Sprintf("Could not index %s: %v", path, err))
+}
+
+// WatcherError reports trouble watching files for changes: the user is
+// shown a watcher that stopped (or never started), since the index goes
+// stale without it, while passing errors only go to the client's log
+func (s *Server) WatcherError(err error, stopped bool) {
+	ctx := context.Background()
+	if stopped {
+		s.showMessage(ctx, MessageTypeError, fmt.Sprintf("File watching stopped, so changes on disk won't be indexed until restart: %v", err))
+		return
+	}
+	s.logMessage(ctx, MessageTypeWarning, fmt.Sprintf("File watcher: %v", err))
+}
+
 func (s *Server) getDocumentContent(uri string) string {
 	// Check open documents first
-	if content, ok := s.documents[uri]; ok {
+	if content, ok := s.documents.Get(uri); ok {
 		return content
 	}
 
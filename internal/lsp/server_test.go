@@ -1,8 +1,10 @@
 package lsp
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/jarredhawkins/goruby-lsp/internal/index"
@@ -256,3 +258,289 @@ func itoa(i int) string {
 	}
 	return s
 }
+
+func TestDefinitionFollowsDocumentVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "order.rb")
+	onDisk := "class Order\n  def total\n  end\n\n  def recalc\n    total\n  end\nend\n"
+	if err := os.WriteFile(path, []byte(onDisk), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestServer(tmpDir)
+	if err := s.index.AddFile(path); err != nil {
+		t.Fatal(err)
+	}
+	uri := pathToURI(path)
+
+	definitionLine := func(line, char int) int {
+		t.Helper()
+		raw, err := callHandler(t, s, "textDocument/definition", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: uint32(line), Character: uint32(char)},
+		})
+		if err != nil {
+			t.Fatalf("definition failed: %v", err)
+		}
+		var loc Location
+		if err := json.Unmarshal(raw, &loc); err != nil || loc.URI != uri {
+			t.Fatalf("expected a location in %s, got %s", uri, raw)
+		}
+		return int(loc.Range.Start.Line)
+	}
+
+	if got := definitionLine(5, 5); got != 1 {
+		t.Fatalf("expected total on line 1 from disk, got %d", got)
+	}
+
+	// Unsaved edits push the method down three lines
+	edited := "class Order\n  # Sums\n  # the\n  # lines\n  def total\n  end\n\n  def recalc\n    total\n  end\nend\n"
+	callHandler(t, s, "textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "ruby", Version: 1, Text: onDisk},
+	})
+	callHandler(t, s, "textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{TextDocumentIdentifier: TextDocumentIdentifier{URI: uri}, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: edited}},
+	})
+
+	if got := definitionLine(8, 5); got != 4 {
+		t.Errorf("expected total on line 4 from the edited buffer, got %d", got)
+	}
+	if v := s.index.FileVersion(path); v != 2 {
+		t.Errorf("expected index at version 2, got %d", v)
+	}
+
+	// Closing without saving reverts to the file on disk
+	callHandler(t, s, "textDocument/didClose", DidCloseTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+	if v := s.index.FileVersion(path); v != 0 {
+		t.Errorf("expected disk version after close, got %d", v)
+	}
+	if syms := s.index.FindDefinitions("total"); len(syms) != 1 || syms[0].Line != 2 {
+		t.Errorf("expected total back on line 2, got %+v", syms)
+	}
+}
+
+func TestDefinitionLinksForAmbiguousMatches(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"billing.rb":  "module Billing\n  class Invoice\n  end\nend\n",
+		"shipping.rb": "module Shipping\n  class Invoice\n    def ship\n    end\n  end\nend\n",
+		"report.rb":   "puts Invoice.count\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := newTestServer(tmpDir)
+	for name := range files {
+		if err := s.index.AddFile(filepath.Join(tmpDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	callHandler(t, s, "initialize", json.RawMessage(`{"capabilities":{"textDocument":{"definition":{"linkSupport":true}}}}`))
+
+	raw, err := callHandler(t, s, "textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: pathToURI(filepath.Join(tmpDir, "report.rb"))},
+		Position:     Position{Line: 0, Character: 7},
+	})
+	if err != nil {
+		t.Fatalf("definition failed: %v", err)
+	}
+
+	var links []LocationLink
+	if err := json.Unmarshal(raw, &links); err != nil || len(links) != 2 {
+		t.Fatalf("expected two location links, got %s", raw)
+	}
+	containers := map[string]LocationLink{}
+	for _, link := range links {
+		if link.Kind != "class" {
+			t.Errorf("expected class kind, got %q", link.Kind)
+		}
+		if link.OriginSelectionRange == nil || link.OriginSelectionRange.Start.Character != 5 ||
+			link.OriginSelectionRange.End.Character != 12 {
+			t.Errorf("expected origin on Invoice, got %+v", link.OriginSelectionRange)
+		}
+		containers[link.ContainerName] = link
+	}
+	shipping, ok := containers["Shipping"]
+	if !ok || containers["Billing"].TargetURI == "" {
+		t.Fatalf("expected Billing and Shipping containers, got %s", raw)
+	}
+	if shipping.TargetRange.Start.Line != 1 || shipping.TargetRange.End.Line != 4 ||
+		shipping.TargetSelectionRange.Start.Character != 8 {
+		t.Errorf("unexpected Shipping::Invoice ranges %+v", shipping)
+	}
+}
+
+func TestDefinitionLinkOriginCoversQualifiedName(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"invoice.rb": "module Billing\n  class Invoice\n  end\nend\n",
+		"report.rb":  "x = Billing::Invoice.new\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := newTestServer(tmpDir)
+	for name := range files {
+		if err := s.index.AddFile(filepath.Join(tmpDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	callHandler(t, s, "initialize", json.RawMessage(`{"capabilities":{"textDocument":{"definition":{"linkSupport":true}}}}`))
+
+	raw, err := callHandler(t, s, "textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: pathToURI(filepath.Join(tmpDir, "report.rb"))},
+		Position:     Position{Line: 0, Character: 15},
+	})
+	if err != nil {
+		t.Fatalf("definition failed: %v", err)
+	}
+
+	var links []LocationLink
+	if err := json.Unmarshal(raw, &links); err != nil || len(links) != 1 {
+		t.Fatalf("expected a single location link, got %s", raw)
+	}
+	origin := links[0].OriginSelectionRange
+	if origin == nil || origin.Start.Character != 4 || origin.End.Character != 20 {
+		t.Errorf("expected origin over Billing::Invoice, got %+v", origin)
+	}
+	if sel := links[0].TargetSelectionRange; sel.Start.Character != 8 || sel.End.Character != 15 {
+		t.Errorf("expected target selection on the class name, got %+v", sel)
+	}
+}
+
+func TestDefinitionConfidence(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "report.rb")
+	content := "class Invoice\n  def total\n  end\nend\n\nclass Report\n  def render\n    total\n  end\nend\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestServer(tmpDir)
+	if err := s.index.AddFile(path); err != nil {
+		t.Fatal(err)
+	}
+	definition := func() json.RawMessage {
+		t.Helper()
+		raw, err := callHandler(t, s, "textDocument/definition", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: pathToURI(path)},
+			Position:     Position{Line: 7, Character: 5},
+		})
+		if err != nil {
+			t.Fatalf("definition failed: %v", err)
+		}
+		return raw
+	}
+
+	// Report#render calling total only matches Invoice#total by name
+	var loc Location
+	if err := json.Unmarshal(definition(), &loc); err != nil || loc.Confidence != "fuzzy" {
+		t.Fatalf("expected a fuzzy location, got %+v (%v)", loc, err)
+	}
+
+	callHandler(t, s, "initialize", json.RawMessage(`{"initializationOptions":{"minConfidence":"contextual"}}`))
+	if raw := definition(); len(raw) != 0 && string(raw) != "null" {
+		t.Errorf("expected fuzzy result to be hidden, got %s", raw)
+	}
+}
+
+func TestReferencesQualifyCommonNames(t *testing.T) {
+	s := newTestServer("/app")
+	client := &fakeClient{}
+	s.client = client
+
+	files := map[string]string{
+		"/app/user.rb":   "class User\n  def name\n  end\nend\n",
+		"/app/team.rb":   "class Team\n  def name\n  end\nend\n",
+		"/app/report.rb": "class Report\n  def rows\n    User.first.name\n    user.name\n    team.name\n  end\nend\n",
+		"/app/cart.rb":   "class Cart\n  def size\n  end\n\n  def empty?\n    size.zero?\n  end\nend\n",
+	}
+	for path, content := range files {
+		s.index.UpdateContent(path, []byte(content), 0)
+		s.documents.Open(pathToURI(path), 0, content)
+	}
+
+	references := func(uri string, line, char int) []Location {
+		t.Helper()
+		raw, err := callHandler(t, s, "textDocument/references", ReferenceParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: uri},
+				Position:     Position{Line: uint32(line), Character: uint32(char)},
+			},
+			Context: ReferenceContext{IncludeDeclaration: true},
+		})
+		if err != nil {
+			t.Fatalf("references failed: %v", err)
+		}
+		var locs []Location
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &locs); err != nil {
+				t.Fatalf("failed to decode locations: %v", err)
+			}
+		}
+		return locs
+	}
+
+	// From User#name: its definition and user.name, not Team's
+	locs := references("file:///app/user.rb", 1, 7)
+	if len(locs) != 2 {
+		t.Fatalf("expected 2 qualified references, got %+v", locs)
+	}
+	for _, loc := range locs {
+		if loc.URI == "file:///app/team.rb" || (loc.URI == "file:///app/report.rb" && loc.Range.Start.Line != 3) {
+			t.Errorf("unexpected reference %+v", loc)
+		}
+	}
+	if len(client.notifications) != 1 || !strings.Contains(client.notifications[0], "qualified by User") {
+		t.Errorf("expected a warning naming User, got %v", client.notifications)
+	}
+
+	// Nothing is dropped when every match is qualified, so no warning
+	client.notifications = nil
+	if locs := references("file:///app/cart.rb", 1, 7); len(locs) != 2 {
+		t.Errorf("expected Cart#size and its call, got %+v", locs)
+	}
+	if len(client.notifications) != 0 {
+		t.Errorf("expected no warning when nothing was filtered, got %v", client.notifications)
+	}
+
+	// Unqualified mode lists every match, capped by the reference limit
+	s.config.Store(&Config{UnqualifiedReferences: true, ReferenceLimit: 3})
+	client.notifications = nil
+	if locs := references("file:///app/user.rb", 1, 7); len(locs) != 3 {
+		t.Errorf("expected references capped at 3, got %d", len(locs))
+	}
+	if len(client.notifications) != 1 || !strings.Contains(client.notifications[0], "first 3 of") {
+		t.Errorf("expected a truncation warning, got %v", client.notifications)
+	}
+}
+
+func TestErrorsReachTheClient(t *testing.T) {
+	s := newTestServer("/app")
+	client := &fakeClient{}
+	s.client = client
+
+	s.reportFileFailure("/app/lib/broken.rb", os.ErrPermission)
+	s.WatcherError(os.ErrInvalid, false)
+	s.WatcherError(os.ErrClosed, true)
+
+	want := []string{
+		"window/showMessage: Could not index lib/broken.rb: permission denied",
+		"window/logMessage: File watcher: invalid argument",
+		"window/showMessage: File watching stopped, so changes on disk won't be indexed until restart: file already closed",
+	}
+	if strings.Join(client.notifications, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected %q, got %q", want, client.notifications)
+	}
+}
@@ -0,0 +1,72 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+)
+
+// it_behaves_like "an auditable model" / include_context 'with a user'
+var sharedExampleRefPattern = regexp.MustCompile(`\b(?:it_behaves_like|it_should_behave_like|include_examples|include_context)\b\s*\(?\s*(?:"([^"]+)"|'([^']+)')`)
+
+// sharedExampleAt returns the shared example name when the position is on
+// the string argument of an it_behaves_like/include_examples call
+func sharedExampleAt(content string, line, char int) string {
+	lines := strings.Split(content, "\n")
+	if line < 0 || line >= len(lines) {
+		return ""
+	}
+
+	for _, m := range sharedExampleRefPattern.FindAllStringSubmatchIndex(lines[line], -1) {
+		group := 2
+		if m[group] < 0 {
+			group = 4
+		}
+		// Include the surrounding quotes
+		if char >= m[group]-1 && char <= m[group+1] {
+			return lines[line][m[group]:m[group+1]]
+		}
+	}
+	return ""
+}
+
+// specDefinitions resolves described_class to the class its
+// example group describes, and a method called on described_class or on a
+// let helper of known class (see index.LetClass) to that class's method.
+// before is the text preceding word on its line (1-based). Returns nil
+// when the receiver's class is unknown or doesn't define the method.
+func (s *Server) specDefinitions(word, before, path string, line int) ([]*index.Symbol, index.Confidence) {
+	receiver := index.ReceiverBefore(before)
+	if word == "described_class" && receiver == "" {
+		class := s.index.DescribedClass(path, line)
+		if class == "" {
+			return nil, index.ConfidenceExact
+		}
+		symbols, confidence := s.index.ResolveDefinitions(class, path, line)
+		var classes []*index.Symbol
+		for _, sym := range symbols {
+			if sym.Kind == index.KindClass || sym.Kind == index.KindModule {
+				classes = append(classes, sym)
+			}
+		}
+		return classes, confidence
+	}
+
+	if receiver != "described_class" && s.index.FindLet(receiver, path, line) == nil {
+		return nil, index.ConfidenceExact
+	}
+	owners, singleton := s.receiverClasses(receiver, path, line)
+	if len(owners) == 0 {
+		return nil, index.ConfidenceExact
+	}
+	symbols, confidence := s.index.ResolveDefinitions(word, path, line)
+	var methods []*index.Symbol
+	for _, sym := range symbols {
+		if isMethodKind(sym.Kind) && (sym.Kind == index.KindSingletonMethod) == singleton &&
+			owners[strings.Join(sym.Scope, "::")] {
+			methods = append(methods, sym)
+		}
+	}
+	return methods, confidence
+}
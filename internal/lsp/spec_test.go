@@ -0,0 +1,157 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSharedExampleAt(t *testing.T) {
+	content := `describe Invoice do
+  it_behaves_like "an auditable model"
+  include_context('with a user')
+  it "works" do
+end`
+
+	tests := []struct {
+		line, char int
+		want       string
+	}{
+		{1, 20, "an auditable model"},
+		{1, 18, "an auditable model"}, // opening quote
+		{1, 5, ""},                    // on it_behaves_like itself
+		{2, 22, "with a user"},
+		{3, 8, ""},
+	}
+	for _, tt := range tests {
+		if got := sharedExampleAt(content, tt.line, tt.char); got != tt.want {
+			t.Errorf("sharedExampleAt(%d, %d) = %q, want %q", tt.line, tt.char, got, tt.want)
+		}
+	}
+}
+
+func TestDescribedClassNavigation(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/app/models/billing/invoice.rb", []byte(`module Billing
+  class Invoice
+    def self.issue(order)
+    end
+
+    def total
+    end
+  end
+end
+`), 0)
+	s.index.UpdateContent("/app/app/models/refund.rb", []byte("class Refund\n  def self.issue\n  end\nend\n"), 0)
+
+	uri := "file:///app/spec/models/billing/invoice_spec.rb"
+	s.documents.Open(uri, 1, `RSpec.describe Billing::Invoice do
+  context "when issued" do
+    it "totals" do
+      described_class.issue(order)
+      described_class.is
+    end
+  end
+end
+`)
+
+	definition := func(line, char uint32) Location {
+		t.Helper()
+		raw, err := callHandler(t, s, "textDocument/definition", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line, Character: char},
+		})
+		if err != nil {
+			t.Fatalf("definition failed: %v", err)
+		}
+		var location Location
+		if err := json.Unmarshal(raw, &location); err != nil {
+			t.Fatalf("expected one location, got %s", raw)
+		}
+		return location
+	}
+
+	if loc := definition(3, 8); loc.URI != "file:///app/app/models/billing/invoice.rb" || loc.Range.Start.Line != 1 {
+		t.Errorf("expected described_class to resolve to Billing::Invoice, got %+v", loc)
+	}
+	if loc := definition(3, 23); loc.URI != "file:///app/app/models/billing/invoice.rb" || loc.Range.Start.Line != 2 {
+		t.Errorf("expected issue to resolve to Billing::Invoice.issue, got %+v", loc)
+	}
+
+	raw, err := callHandler(t, s, "textDocument/completion", CompletionParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: 4, Character: 24},
+		},
+	})
+	if err != nil {
+		t.Fatalf("completion failed: %v", err)
+	}
+	var list CompletionList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		t.Fatalf("failed to decode completions: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Detail != "Billing::Invoice.issue" {
+		t.Errorf("expected only Billing::Invoice.issue, got %+v", list.Items)
+	}
+}
+
+func TestLetHelperReceivers(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/app/models/user.rb", []byte("class User\n  def activate!\n  end\nend\n"), 0)
+	s.index.UpdateContent("/app/app/models/account.rb", []byte("class Account\n  def activate!\n  end\n\n  def archive\n  end\nend\n"), 0)
+	s.index.UpdateContent("/app/app/services/signup.rb", []byte("class Signup\n  def activate!\n  end\nend\n"), 0)
+	s.index.UpdateContent("/app/spec/factories/users.rb", []byte("FactoryBot.define do\n  factory :user do\n  end\nend\n"), 0)
+
+	uri := "file:///app/spec/services/signup_spec.rb"
+	s.documents.Open(uri, 1, `RSpec.describe Signup do
+  subject { described_class.new(user) }
+  let(:user) { create(:user) }
+
+  it "activates" do
+    user.activate!
+    subject.activate!
+    subject.act
+  end
+end
+`)
+
+	definition := func(line, char uint32) string {
+		t.Helper()
+		raw, err := callHandler(t, s, "textDocument/definition", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line, Character: char},
+		})
+		if err != nil {
+			t.Fatalf("definition failed: %v", err)
+		}
+		var location Location
+		if err := json.Unmarshal(raw, &location); err != nil {
+			t.Fatalf("expected one location, got %s", raw)
+		}
+		return location.URI
+	}
+
+	if got := definition(5, 10); got != "file:///app/app/models/user.rb" {
+		t.Errorf("expected the factory's User#activate!, got %s", got)
+	}
+	if got := definition(6, 13); got != "file:///app/app/services/signup.rb" {
+		t.Errorf("expected the described Signup#activate!, got %s", got)
+	}
+
+	raw, err := callHandler(t, s, "textDocument/completion", CompletionParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: 7, Character: 15},
+		},
+	})
+	if err != nil {
+		t.Fatalf("completion failed: %v", err)
+	}
+	var list CompletionList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		t.Fatalf("failed to decode completions: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Detail != "Signup#activate!" {
+		t.Errorf("expected only Signup#activate!, got %+v", list.Items)
+	}
+}
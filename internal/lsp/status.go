@@ -0,0 +1,13 @@
+package lsp
+
+import (
+	"context"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+// handleIndexStatus replies with the index size and the timing of its
+// last build, for finding files or matchers that slow indexing down
+func (s *Server) handleIndexStatus(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	return reply(ctx, s.index.Status(), nil)
+}
@@ -0,0 +1,90 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"github.com/jarredhawkins/goruby-lsp/internal/parser"
+)
+
+// allow(user).to receive(:refresh!)
+// expect_any_instance_of(Order).to receive(:total)
+// expect(Order).to have_received(:import)
+var stubPattern = regexp.MustCompile(`\b(allow|expect)(_any_instance_of)?\(\s*((?:[^()]|\([^()]*\))+?)\s*\)\s*\.\s*(?:to|not_to|to_not)\s+(?:receive|have_received)\(?\s*:(\w+[?!=]?)`)
+
+// instance_double(User) / instance_double("Billing::Invoice")
+var instanceDoublePattern = regexp.MustCompile(`^instance_double\(\s*["']?((?:::)?[A-Z][\w:]*)`)
+
+var constantPattern = regexp.MustCompile(`^(?:::)?[A-Z]\w*(?:::[A-Z]\w*)*$`)
+
+// stubTarget is a stubbed message and the class it was stubbed on
+type stubTarget struct {
+	Method    string
+	ClassName string // Empty when the receiver's class can't be inferred
+	Singleton bool   // Stubbed on the class itself rather than an instance
+}
+
+// stubTargetAt returns the stubbed message when the position is on the
+// symbol argument of receive/have_received
+func stubTargetAt(content string, line, char int) *stubTarget {
+	lines := strings.Split(content, "\n")
+	if line < 0 || line >= len(lines) {
+		return nil
+	}
+
+	for _, m := range stubPattern.FindAllStringSubmatchIndex(lines[line], -1) {
+		// Include the leading colon of the symbol
+		if char < m[8]-1 || char > m[9] {
+			continue
+		}
+		text := lines[line]
+		target := &stubTarget{Method: text[m[8]:m[9]]}
+		receiver := text[m[6]:m[7]]
+
+		switch {
+		case m[4] >= 0: // _any_instance_of(Const)
+			target.ClassName = receiver
+		case constantPattern.MatchString(receiver):
+			target.ClassName = receiver
+			target.Singleton = true
+		default:
+			target.ClassName = inferReceiverClass(receiver)
+		}
+		return target
+	}
+	return nil
+}
+
+// inferReceiverClass guesses the class of a stub receiver from an inline
+// double or, for variables and lets, from the name (user → User)
+func inferReceiverClass(receiver string) string {
+	if m := instanceDoublePattern.FindStringSubmatch(receiver); m != nil {
+		return m[1]
+	}
+	name := strings.TrimLeft(receiver, "@")
+	if name == "" || !isIdentifier(name) {
+		return ""
+	}
+	return parser.CamelCase(name)
+}
+
+func isIdentifier(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isWordChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveStub finds the real method behind a stubbed message, falling
+// back to any method with that name when the receiver's class is unknown
+func (s *Server) resolveStub(target *stubTarget) ([]*index.Symbol, index.Confidence) {
+	if target.ClassName != "" {
+		if symbols := s.index.FindMember(target.ClassName, target.Method, target.Singleton); len(symbols) > 0 {
+			return symbols, index.ConfidenceContextual
+		}
+	}
+	return s.index.FindDefinitions(target.Method), index.ConfidenceFuzzy
+}
@@ -0,0 +1,89 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStubTargetAt(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		char int
+		want *stubTarget
+	}{
+		{"allow on variable", "    allow(user).to receive(:refresh!)", 29, &stubTarget{Method: "refresh!", ClassName: "User"}},
+		{"any instance", "    expect_any_instance_of(Order).to receive(:total)", 46, &stubTarget{Method: "total", ClassName: "Order"}},
+		{"class stub", "    allow(Billing::Invoice).to receive(:import).and_call_original", 40, &stubTarget{Method: "import", ClassName: "Billing::Invoice", Singleton: true}},
+		{"have_received", "    expect(account).to have_received(:close)", 40, &stubTarget{Method: "close", ClassName: "Account"}},
+		{"instance double", `    allow(instance_double("Order")).to receive(:total)`, 50, &stubTarget{Method: "total", ClassName: "Order"}},
+		{"complex receiver", "    allow(order.items.first).to receive(:price)", 41, &stubTarget{Method: "price"}},
+		{"cursor on receiver", "    allow(user).to receive(:refresh!)", 11, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := stubTargetAt(tt.line, 0, tt.char)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("expected nil, got %+v", got)
+				}
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Errorf("stubTargetAt = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefinitionOnStubbedMessage(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"user.rb": `class User
+  def refresh!
+  end
+end
+`,
+		"account.rb": `class Account
+  def refresh!
+  end
+end
+`,
+		"user_spec.rb": `describe User do
+  it "refreshes" do
+    allow(user).to receive(:refresh!)
+  end
+end
+`,
+	}
+	s := newTestServer(tmpDir)
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		if err := s.index.AddFile(path); err != nil {
+			t.Fatalf("failed to index %s: %v", name, err)
+		}
+	}
+
+	raw, err := callHandler(t, s, "textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: pathToURI(filepath.Join(tmpDir, "user_spec.rb"))},
+		Position:     Position{Line: 2, Character: 30},
+	})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	var loc Location
+	if err := json.Unmarshal(raw, &loc); err != nil {
+		t.Fatalf("expected a single location, got %s", raw)
+	}
+	if loc.URI != pathToURI(filepath.Join(tmpDir, "user.rb")) || loc.Range.Start.Line != 1 {
+		t.Errorf("expected User#refresh!, got %+v", loc)
+	}
+}
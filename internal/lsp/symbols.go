@@ -0,0 +1,373 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// SymbolKind is the LSP symbol kind
+type SymbolKind int
+
+const (
+	SymbolKindModule      SymbolKind = 2
+	SymbolKindNamespace   SymbolKind = 3
+	SymbolKindClass       SymbolKind = 5
+	SymbolKindMethod      SymbolKind = 6
+	SymbolKindProperty    SymbolKind = 7
+	SymbolKindField       SymbolKind = 8
+	SymbolKindConstructor SymbolKind = 9
+	SymbolKindFunction    SymbolKind = 12
+	SymbolKindVariable    SymbolKind = 13
+	SymbolKindConstant    SymbolKind = 14
+	SymbolKindObject      SymbolKind = 19
+	SymbolKindEnumMember  SymbolKind = 22
+	SymbolKindEvent       SymbolKind = 24
+)
+
+// DocumentSymbolParams for textDocument/documentSymbol
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// DocumentSymbol is a node in a document's symbol hierarchy
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           SymbolKind       `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+// lspSymbolKind maps an index symbol kind to the closest LSP kind
+func lspSymbolKind(kind index.SymbolKind) SymbolKind {
+	switch kind {
+	case index.KindClass:
+		return SymbolKindClass
+	case index.KindModule:
+		return SymbolKindModule
+	case index.KindMethod, index.KindSingletonMethod:
+		return SymbolKindMethod
+	case index.KindConstant:
+		return SymbolKindConstant
+	case index.KindAttrReader, index.KindAttrWriter, index.KindAttrAccessor:
+		return SymbolKindProperty
+	case index.KindRelation, index.KindColumn, index.KindInstanceVariable:
+		return SymbolKindField
+	case index.KindLocalVariable, index.KindLet:
+		return SymbolKindVariable
+	case index.KindExampleGroup, index.KindSharedExample, index.KindNamespace:
+		return SymbolKindNamespace
+	case index.KindStep, index.KindTask:
+		return SymbolKindFunction
+	case index.KindEnumValue:
+		return SymbolKindEnumMember
+	case index.KindRoute:
+		return SymbolKindEvent
+	case index.KindFactory:
+		return SymbolKindConstructor
+	case index.KindGem:
+		return SymbolKindModule
+	case index.KindGemAttribute:
+		return SymbolKindProperty
+	default:
+		return SymbolKindObject
+	}
+}
+
+// symbolDisplayName is the name shown in outlines (self.build for class methods)
+func symbolDisplayName(sym *index.Symbol) string {
+	if sym.Kind == index.KindSingletonMethod {
+		return "self." + sym.Name
+	}
+	return sym.Name
+}
+
+// symbolDetail is the secondary text shown next to a symbol
+func symbolDetail(sym *index.Symbol) string {
+	switch {
+	case sym.Superclass != "":
+		return "< " + sym.Superclass
+	case sym.Refines != "":
+		return "refine " + sym.Refines
+	case sym.RelationType != "":
+		return sym.RelationType + " " + sym.TargetName
+	case sym.Options["to"] != "":
+		return sym.Macro + " " + sym.Options["to"]
+	case sym.Options["class"] != "":
+		return sym.Macro + " " + sym.Options["class"]
+	case sym.Options["path"] != "" && sym.Kind == index.KindNamespace:
+		return sym.Macro + " " + sym.Options["path"]
+	case sym.Options["version"] != "":
+		return sym.Macro + " " + sym.Options["version"]
+	case len(sym.Values) > 0:
+		return "= [" + strings.Join(sym.Values, ", ") + "]"
+	case sym.Options["value"] != "":
+		return "= " + sym.Options["value"]
+	case sym.Macro != "":
+		return sym.Macro
+	case sym.Visibility != index.VisibilityPublic:
+		return strings.TrimSpace(sym.Visibility.String() + " " + sym.Options["params"])
+	}
+	return sym.Options["params"]
+}
+
+// symbolRanges returns the full range of a symbol (through its end keyword
+// for containers) and the range of its name
+func symbolRanges(sym *index.Symbol) (full, selection Range) {
+	line := uint32(sym.Line - 1)
+	selection = Range{
+		Start: Position{Line: line, Character: uint32(sym.Column)},
+		End:   Position{Line: line, Character: uint32(sym.Column + len(sym.Name))},
+	}
+
+	full = Range{Start: Position{Line: line}, End: selection.End}
+	if sym.EndLine > sym.Line {
+		full.End = Position{Line: uint32(sym.EndLine - 1), Character: uint32(sym.EndColumn)}
+	}
+	return full, selection
+}
+
+// documentSymbols arranges a file's symbols into a hierarchy by range, so
+// methods nest under their class and classes under their modules
+func documentSymbols(symbols []*index.Symbol) []DocumentSymbol {
+	var outline []*index.Symbol
+	for _, sym := range symbols {
+		// Locals and instance variables are too noisy for an outline
+		if sym.Kind != index.KindLocalVariable && sym.Kind != index.KindInstanceVariable {
+			outline = append(outline, sym)
+		}
+	}
+	sort.SliceStable(outline, func(i, j int) bool {
+		return outline[i].Line < outline[j].Line
+	})
+
+	type node struct {
+		sym      *index.Symbol
+		children []*node
+	}
+	var roots []*node
+	var stack []*node
+
+	for _, sym := range outline {
+		for len(stack) > 0 && !encloses(stack[len(stack)-1].sym, sym) {
+			stack = stack[:len(stack)-1]
+		}
+
+		n := &node{sym: sym}
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, n)
+		} else {
+			roots = append(roots, n)
+		}
+		if sym.EndLine > sym.Line {
+			stack = append(stack, n)
+		}
+	}
+
+	var convert func(nodes []*node) []DocumentSymbol
+	convert = func(nodes []*node) []DocumentSymbol {
+		result := make([]DocumentSymbol, 0, len(nodes))
+		for _, n := range nodes {
+			full, selection := symbolRanges(n.sym)
+			result = append(result, DocumentSymbol{
+				Name:           symbolDisplayName(n.sym),
+				Detail:         symbolDetail(n.sym),
+				Kind:           lspSymbolKind(n.sym.Kind),
+				Range:          full,
+				SelectionRange: selection,
+				Children:       convert(n.children),
+			})
+		}
+		return result
+	}
+	return convert(roots)
+}
+
+// encloses reports whether child starts inside parent's range
+func encloses(parent, child *index.Symbol) bool {
+	return child.Line > parent.Line && child.Line < parent.EndLine
+}
+
+func (s *Server) handleDocumentSymbol(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params DocumentSymbolParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	content := s.getDocumentContent(uri)
+	if strings.TrimSpace(content) == "" {
+		return reply(ctx, []DocumentSymbol{}, nil)
+	}
+
+	// Parse the live document so the outline matches unsaved edits
+	symbols := s.index.ParseContent(uriToPath(uri), content)
+	return reply(ctx, documentSymbols(symbols), nil)
+}
+
+// workspaceSymbolLimit caps workspace/symbol results; clients search again
+// as the query grows
+const workspaceSymbolLimit = 200
+
+// WorkspaceSymbolParams for workspace/symbol
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+// SymbolInformation is a flat symbol result for workspace/symbol
+type SymbolInformation struct {
+	Name          string     `json:"name"`
+	Kind          SymbolKind `json:"kind"`
+	Location      Location   `json:"location"`
+	ContainerName string     `json:"containerName,omitempty"`
+}
+
+// WorkspaceSymbolOptions advertises workspace symbol support
+type WorkspaceSymbolOptions struct {
+	ResolveProvider bool `json:"resolveProvider"`
+}
+
+// WorkspaceSymbol is a workspace/symbol result whose location may leave
+// out the range, for the client to fill in with workspaceSymbol/resolve
+type WorkspaceSymbol struct {
+	Name          string                  `json:"name"`
+	Kind          SymbolKind              `json:"kind"`
+	Location      WorkspaceSymbolLocation `json:"location"`
+	ContainerName string                  `json:"containerName,omitempty"`
+	Data          *workspaceSymbolData    `json:"data,omitempty"`
+}
+
+// WorkspaceSymbolLocation is a Location whose range may be left out
+type WorkspaceSymbolLocation struct {
+	URI   string `json:"uri"`
+	Range *Range `json:"range,omitempty"`
+}
+
+// workspaceSymbolData identifies the symbol a lazy result stands for
+// within its file. The line is where it was when searched; the file may
+// have changed since.
+type workspaceSymbolData struct {
+	FullName string `json:"fullName"`
+	Kind     string `json:"kind"`
+	Line     int    `json:"line"`
+}
+
+// lazySymbolLocations reports whether the client resolves workspace symbol
+// ranges itself, so search results can leave them out
+func (s *Server) lazySymbolLocations() bool {
+	return slices.Contains(s.capabilities.Workspace.Symbol.ResolveSupport.Properties, "location.range")
+}
+
+func (s *Server) handleWorkspaceSymbol(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params WorkspaceSymbolParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	symbols := s.index.SearchSymbols(s.settings().symbolQuery(params.Query), workspaceSymbolLimit)
+	if s.lazySymbolLocations() {
+		// Only the file is sent; ranges are resolved for the results the
+		// user looks at
+		result := make([]WorkspaceSymbol, 0, len(symbols))
+		for _, sym := range symbols {
+			result = append(result, WorkspaceSymbol{
+				Name:          symbolDisplayName(sym),
+				Kind:          lspSymbolKind(sym.Kind),
+				Location:      WorkspaceSymbolLocation{URI: pathToURI(sym.FilePath)},
+				ContainerName: symbolContainer(sym),
+				Data:          &workspaceSymbolData{FullName: sym.FullName, Kind: sym.Kind.String(), Line: sym.Line},
+			})
+		}
+		return reply(ctx, result, nil)
+	}
+
+	result := make([]SymbolInformation, 0, len(symbols))
+	for _, sym := range symbols {
+		result = append(result, SymbolInformation{
+			Name:          symbolDisplayName(sym),
+			Kind:          lspSymbolKind(sym.Kind),
+			Location:      symbolToLocation(sym),
+			ContainerName: symbolContainer(sym),
+		})
+	}
+	return reply(ctx, result, nil)
+}
+
+// handleWorkspaceSymbolResolve fills in the range of a lazy workspace
+// symbol from the index as it is now: the symbol with the same full name
+// and kind in its file, nearest the line it was found on
+func (s *Server) handleWorkspaceSymbolResolve(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var symbol WorkspaceSymbol
+	if err := json.Unmarshal(req.Params(), &symbol); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+	if symbol.Data == nil || symbol.Location.Range != nil {
+		return reply(ctx, symbol, nil)
+	}
+
+	var found *index.Symbol
+	for _, sym := range s.index.SymbolsInFile(uriToPath(symbol.Location.URI)) {
+		if sym.FullName != symbol.Data.FullName || sym.Kind.String() != symbol.Data.Kind {
+			continue
+		}
+		if found == nil || lineDistance(sym.Line, symbol.Data.Line) < lineDistance(found.Line, symbol.Data.Line) {
+			found = sym
+		}
+	}
+
+	// A symbol gone since the search points at the top of its file
+	rng := Range{}
+	if found != nil {
+		rng = symbolToLocation(found).Range
+	}
+	symbol.Location.Range = &rng
+	return reply(ctx, symbol, nil)
+}
+
+// lineDistance is how many lines apart a and b are
+func lineDistance(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// symbolContainer is the containerName of a workspace symbol: its
+// namespace, followed by the DSL call that declared it so scopes, enum
+// values, and routes can be told apart from plain methods and constants
+// ("Shop::Order (scope)", "admin (resources)", "factory")
+func symbolContainer(sym *index.Symbol) string {
+	if sym.Kind == index.KindLocalVariable {
+		return sym.MethodFullName
+	}
+
+	container := strings.Join(sym.Scope, "::")
+	macro := sym.Macro
+	if sym.RelationType != "" {
+		macro = sym.RelationType
+	}
+	switch {
+	case macro == "":
+		return container
+	case container == "":
+		return macro
+	}
+	return container + " (" + macro + ")"
+}
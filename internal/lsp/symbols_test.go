@@ -0,0 +1,238 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDocumentSymbolHierarchy(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "invoice.rb")
+	content := `module Billing
+  class Invoice < Record
+    has_many :lines
+
+    def total
+      sums = lines.map do |l|
+        l.amount
+      end
+      sums.sum
+    end
+
+    def self.build(lines = [], **attrs)
+    end
+  end
+end
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestServer(tmpDir)
+	raw, err := callHandler(t, s, "textDocument/documentSymbol", DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{URI: pathToURI(path)},
+	})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	var symbols []DocumentSymbol
+	if err := json.Unmarshal(raw, &symbols); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(symbols) != 1 || symbols[0].Name != "Billing" || symbols[0].Kind != SymbolKindModule {
+		t.Fatalf("expected Billing module at the root, got %s", raw)
+	}
+
+	billing := symbols[0]
+	if billing.Range.End.Line != 14 {
+		t.Errorf("expected Billing to end on line 14, got %+v", billing.Range)
+	}
+	if len(billing.Children) != 1 || billing.Children[0].Name != "Invoice" {
+		t.Fatalf("expected Invoice under Billing, got %+v", billing.Children)
+	}
+
+	invoice := billing.Children[0]
+	if invoice.Detail != "< Record" {
+		t.Errorf("expected superclass detail, got %q", invoice.Detail)
+	}
+	var names []string
+	for _, child := range invoice.Children {
+		names = append(names, child.Name)
+	}
+	want := []string{"lines", "total", "self.build"}
+	if len(names) != len(want) {
+		t.Fatalf("expected Invoice children %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("expected Invoice children %v, got %v", want, names)
+		}
+	}
+
+	total := invoice.Children[1]
+	if total.Range.Start.Line != 4 || total.Range.End.Line != 9 {
+		t.Errorf("expected total to span lines 4-9, got %+v", total.Range)
+	}
+	if total.SelectionRange.Start.Character != 8 || total.SelectionRange.End.Character != 13 {
+		t.Errorf("expected selection on the method name, got %+v", total.SelectionRange)
+	}
+	if build := invoice.Children[2]; build.Detail != "(lines = [], **attrs)" {
+		t.Errorf("expected build's parameters as its detail, got %q", build.Detail)
+	}
+}
+
+func TestDocumentSymbolUsesOpenDocument(t *testing.T) {
+	s := newTestServer("/test")
+	uri := "file:///test/unsaved.rb"
+	s.documents.Open(uri, 1, "class Draft\n  def edit\n  end\nend\n")
+
+	raw, err := callHandler(t, s, "textDocument/documentSymbol", DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	var symbols []DocumentSymbol
+	if err := json.Unmarshal(raw, &symbols); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(symbols) != 1 || len(symbols[0].Children) != 1 || symbols[0].Children[0].Name != "edit" {
+		t.Errorf("expected Draft > edit from the open document, got %s", raw)
+	}
+}
+
+func TestWorkspaceSymbol(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/billing.rb", []byte(`module Billing
+  class Invoice
+    def self.build
+    end
+  end
+end`), 0)
+
+	search := func(query string) []SymbolInformation {
+		t.Helper()
+		raw, err := callHandler(t, s, "workspace/symbol", WorkspaceSymbolParams{Query: query})
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var result []SymbolInformation
+		if err := json.Unmarshal(raw, &result); err != nil {
+			t.Fatalf("failed to decode symbols: %v", err)
+		}
+		return result
+	}
+
+	// Billing::Invoice.build matches by full name, after the class itself
+	result := search("INVOICE")
+	if len(result) != 2 || result[0].Name != "Invoice" || result[0].Kind != SymbolKindClass || result[0].ContainerName != "Billing" {
+		t.Fatalf("expected Billing::Invoice, got %+v", result)
+	}
+	if result[0].Location.URI != "file:///app/billing.rb" || result[0].Location.Range.Start.Line != 1 {
+		t.Errorf("unexpected location %+v", result[0].Location)
+	}
+
+	// The configured kind filter applies unless the query has its own
+	s.config.Store(&Config{SymbolKinds: []string{"class"}})
+	if result := search("build"); len(result) != 0 {
+		t.Errorf("expected methods filtered out, got %+v", result)
+	}
+	if result := search("kind:singleton_method build"); len(result) != 1 || result[0].Name != "self.build" {
+		t.Errorf("expected self.build, got %+v", result)
+	}
+}
+
+func TestWorkspaceSymbolResolve(t *testing.T) {
+	s := newTestServer("/app")
+	s.capabilities.Workspace.Symbol.ResolveSupport.Properties = []string{"location.range"}
+	s.index.UpdateContent("/app/billing.rb", []byte(`module Billing
+  class Invoice
+  end
+end`), 0)
+
+	raw, err := callHandler(t, s, "workspace/symbol", WorkspaceSymbolParams{Query: "Invoice"})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var result []WorkspaceSymbol
+	if err := json.Unmarshal(raw, &result); err != nil {
+		t.Fatalf("failed to decode symbols: %v", err)
+	}
+	if len(result) != 1 || result[0].Location.URI != "file:///app/billing.rb" || result[0].Location.Range != nil || result[0].Data == nil {
+		t.Fatalf("expected Invoice without a range, got %s", raw)
+	}
+
+	// The range comes from the index at resolve time
+	s.index.UpdateContent("/app/billing.rb", []byte(`module Billing
+  # Sent to customers
+  class Invoice
+  end
+end`), 1)
+	raw, err = callHandler(t, s, "workspaceSymbol/resolve", result[0])
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	var resolved WorkspaceSymbol
+	if err := json.Unmarshal(raw, &resolved); err != nil {
+		t.Fatalf("failed to decode symbol: %v", err)
+	}
+	want := Range{Start: Position{Line: 2, Character: 8}, End: Position{Line: 2, Character: 15}}
+	if resolved.Name != "Invoice" || resolved.Location.Range == nil || *resolved.Location.Range != want {
+		t.Errorf("expected Invoice resolved to %+v, got %s", want, raw)
+	}
+}
+
+func TestWorkspaceSymbolDSLEntities(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/app/models/order.rb", []byte(`class Order < ApplicationRecord
+  belongs_to :customer
+  scope :recent, -> { order(:created_at) }
+  enum status: { draft: 0, placed: 1 }
+end`), 0)
+	s.index.UpdateContent("/app/config/routes.rb", []byte(`Rails.application.routes.draw do
+  namespace :admin do
+    resources :orders
+  end
+  get "checkout", to: "orders#checkout"
+end`), 0)
+	s.index.UpdateContent("/app/spec/factories/orders.rb", []byte(`FactoryBot.define do
+  factory :draft_order, class: "Order" do
+  end
+end`), 0)
+
+	raw, err := callHandler(t, s, "workspace/symbol", WorkspaceSymbolParams{Query: ""})
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var result []SymbolInformation
+	if err := json.Unmarshal(raw, &result); err != nil {
+		t.Fatalf("failed to decode symbols: %v", err)
+	}
+
+	type entry struct {
+		kind      SymbolKind
+		container string
+	}
+	got := map[string]entry{}
+	for _, info := range result {
+		got[info.Name] = entry{info.Kind, info.ContainerName}
+	}
+
+	want := map[string]entry{
+		"customer":    {SymbolKindField, "Order (belongs_to)"},
+		"self.recent": {SymbolKindMethod, "Order (scope)"},
+		"draft":       {SymbolKindEnumMember, "Order (enum status)"},
+		"orders":      {SymbolKindEvent, "admin (resources)"},
+		"checkout":    {SymbolKindEvent, "get"},
+		"draft_order": {SymbolKindConstructor, "factory"},
+	}
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("%s: expected %+v, got %+v", name, w, got[name])
+		}
+	}
+}
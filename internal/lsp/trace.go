@@ -0,0 +1,102 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+// Trace levels a client can set with $/setTrace or in initialize
+const (
+	traceOff      int32 = iota
+	traceMessages       // A $/logTrace per message handled
+	traceVerbose        // Also a summary of each message's params
+)
+
+// traceSummaryLimit caps the params summary sent with verbose tracing
+const traceSummaryLimit = 200
+
+// SetTraceParams for the $/setTrace notification
+type SetTraceParams struct {
+	Value string `json:"value"` // "off", "messages", or "verbose"
+}
+
+// LogTraceParams for the $/logTrace notification
+type LogTraceParams struct {
+	Message string `json:"message"`
+	Verbose string `json:"verbose,omitempty"`
+}
+
+// parseTrace converts a trace value to its level; unknown values are off
+func parseTrace(value string) int32 {
+	switch value {
+	case "messages":
+		return traceMessages
+	case "verbose":
+		return traceVerbose
+	}
+	return traceOff
+}
+
+func (s *Server) handleSetTrace(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params SetTraceParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, err)
+	}
+	s.trace.Store(parseTrace(params.Value))
+	return reply(ctx, nil, nil)
+}
+
+// traced wraps a handler to send the client a $/logTrace for each message
+// once it's answered, with how long it took, while tracing is on
+func (s *Server) traced(next jsonrpc2.Handler) jsonrpc2.Handler {
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		level := s.trace.Load()
+		if level == traceOff {
+			return next(ctx, reply, req)
+		}
+		start := time.Now()
+		return next(ctx, func(ctx context.Context, result interface{}, err error) error {
+			s.logTrace(ctx, level, req, time.Since(start), err)
+			return reply(ctx, result, err)
+		}, req)
+	}
+}
+
+// logTrace sends the $/logTrace for a handled message
+func (s *Server) logTrace(ctx context.Context, level int32, req jsonrpc2.Request, elapsed time.Duration, err error) {
+	if s.client == nil {
+		return
+	}
+	kind := "notification"
+	if _, ok := req.(*jsonrpc2.Call); ok {
+		kind = "request"
+	}
+	params := LogTraceParams{
+		Message: fmt.Sprintf("Handled %s '%s' in %s", kind, req.Method(), elapsed.Round(time.Microsecond)),
+	}
+	if err != nil {
+		params.Message += fmt.Sprintf(" (failed: %v)", err)
+	}
+	if level == traceVerbose {
+		params.Verbose = "Params: " + traceSummary(req.Params())
+	}
+	if err := s.client.Notify(context.WithoutCancel(ctx), "$/logTrace", params); err != nil {
+		log.Printf("failed to send trace: %v", err)
+	}
+}
+
+// traceSummary shortens a message's params to traceSummaryLimit bytes
+func traceSummary(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return "none"
+	}
+	if len(raw) > traceSummaryLimit {
+		return fmt.Sprintf("%s... (%d bytes)", raw[:traceSummaryLimit], len(raw))
+	}
+	return string(raw)
+}
@@ -0,0 +1,68 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+func TestTrace(t *testing.T) {
+	s := newTestServer("/app")
+	client := &fakeClient{}
+	s.client = client
+	s.index.UpdateContent("/app/user.rb", []byte("class User\nend\n"), 0)
+	handler := s.traced(s.handler)
+
+	send := func(method string, params interface{}) {
+		t.Helper()
+		var req jsonrpc2.Request
+		var err error
+		if strings.HasPrefix(method, "$/") {
+			req, err = jsonrpc2.NewNotification(method, params)
+		} else {
+			req, err = jsonrpc2.NewCall(jsonrpc2.NewNumberID(1), method, params)
+		}
+		if err != nil {
+			t.Fatalf("failed to build %s: %v", method, err)
+		}
+		reply := func(ctx context.Context, result interface{}, err error) error { return nil }
+		if err := handler(context.Background(), reply, req); err != nil {
+			t.Fatalf("%s failed: %v", method, err)
+		}
+	}
+	search := WorkspaceSymbolParams{Query: "User"}
+
+	// Off until the client asks
+	send("workspace/symbol", search)
+	if len(client.traces) != 0 {
+		t.Fatalf("expected no traces, got %+v", client.traces)
+	}
+
+	send("$/setTrace", SetTraceParams{Value: "messages"})
+	send("workspace/symbol", search)
+	if len(client.traces) != 1 {
+		t.Fatalf("expected one trace, got %+v", client.traces)
+	}
+	if got := client.traces[0]; !strings.HasPrefix(got.Message, "Handled request 'workspace/symbol' in ") || got.Verbose != "" {
+		t.Errorf("unexpected trace %+v", got)
+	}
+
+	send("$/setTrace", SetTraceParams{Value: "verbose"})
+	send("workspace/symbol", search)
+	if got := client.traces[len(client.traces)-1]; got.Verbose != `Params: {"query":"User"}` {
+		t.Errorf("expected the params summarized, got %+v", got)
+	}
+
+	send("$/setTrace", SetTraceParams{Value: "off"})
+	n := len(client.traces)
+	send("workspace/symbol", search)
+	if len(client.traces) != n {
+		t.Errorf("expected tracing off again, got %+v", client.traces[n:])
+	}
+
+	if got := traceSummary([]byte(`"` + strings.Repeat("x", 300) + `"`)); !strings.HasSuffix(got, "... (302 bytes)") || len(got) > 220 {
+		t.Errorf("expected a long payload cut short, got %s", got)
+	}
+}
@@ -0,0 +1,94 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// handleTypeDefinition resolves a variable under the cursor to the class
+// of the value it was assigned: a local assigned User.new, or an instance
+// variable assigned Order.find(id) anywhere in its class
+func (s *Server) handleTypeDefinition(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	filePath := uriToPath(uri)
+	line := int(params.Position.Line)
+	content := s.getDocumentContent(uri)
+	if content == "" {
+		return reply(ctx, nil, nil)
+	}
+	s.syncDocument(uri)
+
+	word, start, end := wordRangeAt(content, line, skipSigil(content, line, int(params.Position.Character)))
+	if word == "" {
+		return reply(ctx, nil, nil)
+	}
+	origin := &Range{
+		Start: Position{Line: uint32(line), Character: uint32(start)},
+		End:   Position{Line: uint32(line), Character: uint32(end)},
+	}
+
+	var variable *index.Symbol
+	if sigil := variableSigil(content, line, start); sigil == "@" {
+		variable = s.index.FindInstanceVariable("@"+word, filePath, line+1)
+		origin.Start.Character--
+	} else if sigil == "" {
+		variable = s.index.FindLocalVariable(word, filePath, line+1)
+	}
+	if variable == nil || variable.TypeName == "" {
+		return reply(ctx, nil, nil)
+	}
+
+	// The class is resolved from where the variable was assigned
+	symbols, confidence := s.index.ResolveDefinitions(variable.TypeName, variable.FilePath, variable.Line)
+	var classes []*index.Symbol
+	for _, sym := range symbols {
+		if sym.Kind == index.KindClass || sym.Kind == index.KindModule {
+			classes = append(classes, sym)
+		}
+	}
+	if len(classes) == 0 {
+		return reply(ctx, nil, nil)
+	}
+	return reply(ctx, s.definitionResult(classes, origin, confidence), nil)
+}
+
+// skipSigil moves a cursor on a variable's @ or @@ onto its name
+func skipSigil(content string, line, char int) int {
+	lines := strings.Split(content, "\n")
+	if line < 0 || line >= len(lines) {
+		return char
+	}
+	for char >= 0 && char < len(lines[line]) && lines[line][char] == '@' {
+		char++
+	}
+	return char
+}
+
+// variableSigil returns the @ or @@ before the word starting at column
+// start of a line, or "" for a bare word
+func variableSigil(content string, line, start int) string {
+	lines := strings.Split(content, "\n")
+	if line < 0 || line >= len(lines) || start > len(lines[line]) {
+		return ""
+	}
+	before := lines[line][:start]
+	switch {
+	case strings.HasSuffix(before, "@@"):
+		return "@@"
+	case strings.HasSuffix(before, "@"):
+		return "@"
+	}
+	return ""
+}
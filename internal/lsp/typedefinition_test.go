@@ -0,0 +1,66 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTypeDefinition(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/app/models/order.rb", []byte("class Order\nend\n"), 0)
+	s.index.UpdateContent("/app/app/models/billing/invoice.rb", []byte("module Billing\n  class Invoice\n  end\nend\n"), 0)
+
+	uri := "file:///app/app/controllers/orders_controller.rb"
+	s.documents.Open(uri, 1, `module Billing
+  class OrdersController
+    def show
+      @order = Order.find(params[:id])
+      invoice = Invoice.new
+      invoice.total
+    end
+
+    def update
+      @order.save
+      @count.to_s
+    end
+  end
+end
+`)
+
+	tests := []struct {
+		name string
+		line uint32
+		char uint32
+		want string
+	}{
+		{"local", 5, 8, "file:///app/app/models/billing/invoice.rb"},
+		{"instance variable in another method", 9, 8, "file:///app/app/models/order.rb"},
+		{"instance variable sigil", 9, 6, "file:///app/app/models/order.rb"},
+		{"untyped instance variable", 10, 8, ""},
+		{"method", 5, 16, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := callHandler(t, s, "textDocument/typeDefinition", TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: uri},
+				Position:     Position{Line: tt.line, Character: tt.char},
+			})
+			if err != nil {
+				t.Fatalf("typeDefinition failed: %v", err)
+			}
+			if tt.want == "" {
+				if len(raw) != 0 && string(raw) != "null" {
+					t.Errorf("expected no type, got %s", raw)
+				}
+				return
+			}
+			var location Location
+			if err := json.Unmarshal(raw, &location); err != nil {
+				t.Fatalf("failed to decode location: %v", err)
+			}
+			if location.URI != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/index"
+	"go.lsp.dev/jsonrpc2"
+)
+
+// TypeHierarchyItem is a class or module in the type hierarchy
+type TypeHierarchyItem struct {
+	Name           string            `json:"name"`
+	Kind           SymbolKind        `json:"kind"`
+	Detail         string            `json:"detail,omitempty"`
+	URI            string            `json:"uri"`
+	Range          Range             `json:"range"`
+	SelectionRange Range             `json:"selectionRange"`
+	Data           typeHierarchyData `json:"data"`
+}
+
+// typeHierarchyData is carried by items between requests, so supertypes
+// and subtypes are looked up by the item's full name
+type typeHierarchyData struct {
+	FullName string `json:"fullName"`
+}
+
+// TypeHierarchyItemParams for typeHierarchy/supertypes and typeHierarchy/subtypes
+type TypeHierarchyItemParams struct {
+	Item TypeHierarchyItem `json:"item"`
+}
+
+// typeHierarchyItem describes a class or module definition
+func typeHierarchyItem(sym *index.Symbol) TypeHierarchyItem {
+	full, selection := symbolRanges(sym)
+	return TypeHierarchyItem{
+		Name:           sym.Name,
+		Kind:           lspSymbolKind(sym.Kind),
+		Detail:         strings.Join(sym.Scope, "::"),
+		URI:            pathToURI(sym.FilePath),
+		Range:          full,
+		SelectionRange: selection,
+		Data:           typeHierarchyData{FullName: sym.FullName},
+	}
+}
+
+func typeHierarchyItems(syms []*index.Symbol) []TypeHierarchyItem {
+	items := make([]TypeHierarchyItem, 0, len(syms))
+	for _, sym := range syms {
+		items = append(items, typeHierarchyItem(sym))
+	}
+	return items
+}
+
+// handlePrepareTypeHierarchy resolves the class or module under the cursor
+func (s *Server) handlePrepareTypeHierarchy(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	uri := params.TextDocument.URI
+	line := int(params.Position.Line)
+	content := s.getDocumentContent(uri)
+	s.syncDocument(uri)
+
+	word := extractWordAt(content, line, int(params.Position.Character))
+	if word == "" {
+		return reply(ctx, nil, nil)
+	}
+
+	// One item per class or module, even when it's reopened in several files
+	var items []TypeHierarchyItem
+	seen := make(map[string]bool)
+	for _, sym := range s.index.FindDefinitionsInContext(word, uriToPath(uri), line+1) {
+		if (sym.Kind == index.KindClass || sym.Kind == index.KindModule) && !seen[sym.FullName] {
+			seen[sym.FullName] = true
+			items = append(items, typeHierarchyItem(sym))
+		}
+	}
+	if len(items) == 0 {
+		return reply(ctx, nil, nil)
+	}
+	return reply(ctx, items, nil)
+}
+
+// handleTypeHierarchySupertypes lists the superclass and mixins of an item
+func (s *Server) handleTypeHierarchySupertypes(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params TypeHierarchyItemParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+	return reply(ctx, typeHierarchyItems(s.index.Supertypes(params.Item.Data.FullName)), nil)
+}
+
+// handleTypeHierarchySubtypes lists the classes and modules inheriting
+// from or mixing in an item
+func (s *Server) handleTypeHierarchySubtypes(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params TypeHierarchyItemParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+	return reply(ctx, typeHierarchyItems(s.index.Subtypes(params.Item.Data.FullName)), nil)
+}
@@ -0,0 +1,55 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTypeHierarchy(t *testing.T) {
+	s := newTestServer("/app")
+	s.index.UpdateContent("/app/record.rb", []byte("class Record\nend\n"), 0)
+	s.index.UpdateContent("/app/auditable.rb", []byte("module Auditable\nend\n"), 0)
+	s.index.UpdateContent("/app/credit_note.rb", []byte("class CreditNote < Invoice\nend\n"), 0)
+
+	uri := "file:///app/invoice.rb"
+	content := "class Invoice < Record\n  include Auditable\nend\n"
+	s.documents.Open(uri, 1, content)
+
+	raw, err := callHandler(t, s, "textDocument/prepareTypeHierarchy", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: 0, Character: 7},
+	})
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	var items []TypeHierarchyItem
+	if err := json.Unmarshal(raw, &items); err != nil {
+		t.Fatalf("failed to decode items: %v", err)
+	}
+	if len(items) != 1 || items[0].Name != "Invoice" || items[0].Kind != SymbolKindClass || items[0].Range.End.Line != 2 {
+		t.Fatalf("expected the Invoice class, got %+v", items)
+	}
+
+	names := func(method string) []string {
+		raw, err := callHandler(t, s, method, TypeHierarchyItemParams{Item: items[0]})
+		if err != nil {
+			t.Fatalf("%s failed: %v", method, err)
+		}
+		var related []TypeHierarchyItem
+		if err := json.Unmarshal(raw, &related); err != nil {
+			t.Fatalf("failed to decode %s: %v", method, err)
+		}
+		var names []string
+		for _, item := range related {
+			names = append(names, item.Data.FullName)
+		}
+		return names
+	}
+
+	if got := names("typeHierarchy/supertypes"); len(got) != 2 || got[0] != "Record" || got[1] != "Auditable" {
+		t.Errorf("expected supertypes Record and Auditable, got %v", got)
+	}
+	if got := names("typeHierarchy/subtypes"); len(got) != 1 || got[0] != "CreditNote" {
+		t.Errorf("expected subtype CreditNote, got %v", got)
+	}
+}
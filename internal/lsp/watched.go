@@ -0,0 +1,162 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+// FileChangeType is the kind of a watched file event
+type FileChangeType int
+
+const (
+	FileChangeTypeCreated FileChangeType = 1
+	FileChangeTypeChanged FileChangeType = 2
+	FileChangeTypeDeleted FileChangeType = 3
+)
+
+// FileEvent is a change to a file the client watches
+type FileEvent struct {
+	URI  string         `json:"uri"`
+	Type FileChangeType `json:"type"`
+}
+
+// DidChangeWatchedFilesParams for workspace/didChangeWatchedFiles
+type DidChangeWatchedFilesParams struct {
+	Changes []FileEvent `json:"changes"`
+}
+
+// WatchKind limits a file system watcher to some events (bit flags)
+type WatchKind int
+
+const (
+	WatchKindCreate WatchKind = 1
+	WatchKindChange WatchKind = 2
+	WatchKindDelete WatchKind = 4
+)
+
+// FileSystemWatcher asks the client to watch paths matching a glob
+type FileSystemWatcher struct {
+	GlobPattern string    `json:"globPattern"`
+	Kind        WatchKind `json:"kind,omitempty"`
+}
+
+// DidChangeWatchedFilesRegistrationOptions lists the watchers to register
+type DidChangeWatchedFilesRegistrationOptions struct {
+	Watchers []FileSystemWatcher `json:"watchers"`
+}
+
+// Registration is one capability registered with the client
+type Registration struct {
+	ID              string      `json:"id"`
+	Method          string      `json:"method"`
+	RegisterOptions interface{} `json:"registerOptions,omitempty"`
+}
+
+// RegistrationParams for the client/registerCapability request
+type RegistrationParams struct {
+	Registrations []Registration `json:"registrations"`
+}
+
+// errNoDynamicWatchers is why file watchers weren't registered with a
+// client that doesn't support it
+var errNoDynamicWatchers = errors.New("client doesn't support registering file watchers")
+
+// rubyFileWatchers match the files the index reads, plus deletes of
+// anything, so removing a directory drops the files indexed under it
+var rubyFileWatchers = []FileSystemWatcher{
+	{GlobPattern: "**/*.{rb,rake,gemspec,cap}"},
+	{GlobPattern: "**/{Gemfile,Rakefile,Guardfile,Vagrantfile,Capfile}"},
+	{GlobPattern: "**/*", Kind: WatchKindDelete},
+}
+
+// SetClientWatching makes the server ask the client to watch the
+// workspace's files, for remote and container setups where fsnotify misses
+// events. Clients that can't register watchers get fallback, which should
+// start the server-side watcher, instead.
+func (s *Server) SetClientWatching(fallback func()) {
+	s.clientWatching = true
+	s.watchFallback = fallback
+}
+
+func (s *Server) handleInitialized(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	if s.clientWatching {
+		// Don't hold up the requests queued behind this one waiting for
+		// the client to answer
+		go s.registerFileWatchers(ctx)
+	}
+	if s.indexReady != nil {
+		go s.buildIndex(ctx)
+	}
+	return reply(ctx, nil, nil)
+}
+
+// registerFileWatchers registers rubyFileWatchers with the client, falling
+// back to server-side watching when it can't
+func (s *Server) registerFileWatchers(ctx context.Context) {
+	err := errNoDynamicWatchers
+	if s.client != nil && s.capabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration {
+		_, err = s.client.Call(ctx, "client/registerCapability", RegistrationParams{
+			Registrations: []Registration{{
+				ID:              "goruby-watched-files",
+				Method:          "workspace/didChangeWatchedFiles",
+				RegisterOptions: DidChangeWatchedFilesRegistrationOptions{Watchers: rubyFileWatchers},
+			}},
+		}, nil)
+	}
+	if err == nil {
+		log.Printf("registered file watchers with the client")
+		return
+	}
+
+	log.Printf("client-side file watching unavailable (%v), watching files on the server", err)
+	if s.watchFallback != nil {
+		s.watchFallback()
+	}
+}
+
+// watchedFileBatch splits client file events into the changed and removed
+// paths of an index batch. A path reported more than once takes its last
+// event.
+func watchedFileBatch(events []FileEvent) (changed, removed []string) {
+	last := make(map[string]FileChangeType, len(events))
+	var order []string
+	for _, event := range events {
+		path := uriToPath(event.URI)
+		if _, seen := last[path]; !seen {
+			order = append(order, path)
+		}
+		last[path] = event.Type
+	}
+	for _, path := range order {
+		if last[path] == FileChangeTypeDeleted {
+			removed = append(removed, path)
+		} else {
+			changed = append(changed, path)
+		}
+	}
+	return changed, removed
+}
+
+// handleDidChangeWatchedFiles applies file events from clients that watch
+// the workspace themselves. They go through the same batch update as the
+// fsnotify watcher, which skips files already indexed at their current
+// content, so a change both report is only applied once.
+func (s *Server) handleDidChangeWatchedFiles(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+	var params DidChangeWatchedFilesParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, &jsonrpc2.Error{
+			Code:    jsonrpc2.InvalidParams,
+			Message: err.Error(),
+		})
+	}
+
+	changed, removed := watchedFileBatch(params.Changes)
+	if len(changed) > 0 || len(removed) > 0 {
+		s.index.ApplyBatch(changed, removed)
+	}
+	return reply(ctx, nil, nil)
+}
@@ -0,0 +1,79 @@
+package lsp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDidChangeWatchedFiles(t *testing.T) {
+	root := t.TempDir()
+	s := newTestServer(root)
+	order := filepath.Join(root, "order.rb")
+	notes := filepath.Join(root, "notes.txt")
+	for path, content := range map[string]string{order: "class Order\nend\n", notes: "class Note\nend\n"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notify := func(events ...FileEvent) {
+		t.Helper()
+		if _, err := callHandler(t, s, "workspace/didChangeWatchedFiles", DidChangeWatchedFilesParams{Changes: events}); err != nil {
+			t.Fatalf("didChangeWatchedFiles failed: %v", err)
+		}
+	}
+
+	notify(FileEvent{URI: pathToURI(order), Type: FileChangeTypeCreated}, FileEvent{URI: pathToURI(notes), Type: FileChangeTypeCreated})
+	defs := s.index.FindDefinitions("Order")
+	if len(defs) != 1 {
+		t.Fatalf("expected the created file indexed, got %+v", defs)
+	}
+	if len(s.index.FindDefinitions("Note")) != 0 {
+		t.Error("expected non-Ruby files to be ignored")
+	}
+
+	// A change the index already has (reported by the server's own watcher
+	// too) is not reapplied
+	notify(FileEvent{URI: pathToURI(order), Type: FileChangeTypeChanged})
+	if again := s.index.FindDefinitions("Order"); len(again) != 1 || again[0] != defs[0] {
+		t.Error("expected an unchanged file to keep its symbols")
+	}
+
+	// The last event for a path wins
+	if err := os.Remove(order); err != nil {
+		t.Fatal(err)
+	}
+	notify(FileEvent{URI: pathToURI(order), Type: FileChangeTypeChanged}, FileEvent{URI: pathToURI(order), Type: FileChangeTypeDeleted})
+	if len(s.index.FindDefinitions("Order")) != 0 {
+		t.Error("expected the deleted file to be dropped")
+	}
+}
+
+func TestRegisterFileWatchers(t *testing.T) {
+	s := newTestServer("/test")
+	client := &fakeClient{}
+	s.client = client
+	fellBack := 0
+	s.SetClientWatching(func() { fellBack++ })
+
+	// Without dynamic registration the server watches files itself
+	s.registerFileWatchers(context.Background())
+	if len(client.registrations) != 0 || fellBack != 1 {
+		t.Fatalf("expected a fallback and no registration, got %d fallbacks and %+v", fellBack, client.registrations)
+	}
+
+	s.capabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration = true
+	s.registerFileWatchers(context.Background())
+	if fellBack != 1 {
+		t.Error("expected no fallback when the client registers watchers")
+	}
+	if len(client.registrations) != 1 || client.registrations[0].Method != "workspace/didChangeWatchedFiles" {
+		t.Fatalf("expected a didChangeWatchedFiles registration, got %+v", client.registrations)
+	}
+	opts := client.registrations[0].RegisterOptions.(DidChangeWatchedFilesRegistrationOptions)
+	if len(opts.Watchers) == 0 || opts.Watchers[0].GlobPattern != "**/*.{rb,rake,gemspec,cap}" {
+		t.Errorf("expected Ruby file watchers, got %+v", opts.Watchers)
+	}
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// class_name: 'Order', :class_name => 'Order', "class_name": 'Order'
+var optionPattern = regexp.MustCompile(`^(?:(\w+):|:(\w+)\s*=>|["'](\w+)["']:)\s*(.+)$`)
+
+// :sym, "str", 'str', true, false, 42
+var literalValuePattern = regexp.MustCompile(`^(?::(\w+[?!]?)|"([^"#]*)"|'([^']*)'|(true|false|\d+))(?:\.freeze)?$`)
+
+// A trailing block opener: do / do |map|
+var trailingDoPattern = regexp.MustCompile(`\s*\bdo\s*(?:\|[^|]*\|)?\s*$`)
+
+// splitArguments splits a Ruby argument list on top-level commas, keeping
+// nested (), [], {} and string literals intact. A closing bracket that
+// balances nothing (the call's own closing paren) ends the list.
+func splitArguments(s string) []string {
+	var args []string
+	var quote byte
+	depth := 0
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '\'', '"':
+			quote = c
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth == 0 {
+				return appendArgument(args, s[start:i])
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				args = appendArgument(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return appendArgument(args, s[start:])
+}
+
+func appendArgument(args []string, arg string) []string {
+	if arg = strings.TrimSpace(arg); arg != "" {
+		args = append(args, arg)
+	}
+	return args
+}
+
+// parseOption splits a `key: value` or `:key => value` argument
+func parseOption(arg string) (key, value string, ok bool) {
+	match := optionPattern.FindStringSubmatch(arg)
+	if match == nil {
+		return "", "", false
+	}
+	return match[1] + match[2] + match[3], strings.TrimSpace(match[4]), true
+}
+
+// literalValue returns the plain value of a symbol, string, boolean, or
+// integer literal. Returns false for any other expression.
+func literalValue(expr string) (string, bool) {
+	match := literalValuePattern.FindStringSubmatch(strings.TrimSpace(expr))
+	if match == nil {
+		return "", false
+	}
+	return match[1] + match[2] + match[3] + match[4], true
+}
+
+// submatch returns group n of a FindStringSubmatchIndex result, or "" when
+// the group didn't participate in the match
+func submatch(line string, loc []int, n int) string {
+	if loc[2*n] < 0 {
+		return ""
+	}
+	return line[loc[2*n]:loc[2*n+1]]
+}
+
+// callArguments splits the arguments of a DSL call starting at column start
+// of line, leaving out a trailing comment and block opener
+func callArguments(line string, start int) []string {
+	code := line[:len(strings.TrimRight(MaskStrings(line), " \t"))]
+	if start > len(code) {
+		return nil
+	}
+	return splitArguments(trailingDoPattern.ReplaceAllString(code[start:], ""))
+}
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArguments(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{":items, class_name: 'Item'", []string{":items", "class_name: 'Item'"}},
+		{":a, -> { where(x: 1, y: 2) }, b: 'c'", []string{":a", "-> { where(x: 1, y: 2) }", "b: 'c'"}},
+		{":a, b: 'x, y')", []string{":a", "b: 'x, y'"}},
+		{"[1, 2], {a: 1}", []string{"[1, 2]", "{a: 1}"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := splitArguments(tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitArguments(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseOption(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantKey   string
+		wantValue string
+		wantOK    bool
+	}{
+		{"class_name: 'Item'", "class_name", "'Item'", true},
+		{":class_name => \"Item\"", "class_name", "\"Item\"", true},
+		{"-> { where(a: 1) }", "", "", false},
+		{":items", "", "", false},
+	}
+
+	for _, tt := range tests {
+		key, value, ok := parseOption(tt.input)
+		if key != tt.wantKey || value != tt.wantValue || ok != tt.wantOK {
+			t.Errorf("parseOption(%q) = %q, %q, %v", tt.input, key, value, ok)
+		}
+	}
+}
+
+func TestLiteralValue(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{":destroy", "destroy", true},
+		{"'Billing::Invoice'", "Billing::Invoice", true},
+		{`"Order".freeze`, "Order", true},
+		{"true", "true", true},
+		{`"#{prefix}_id"`, "", false},
+		{"compute_name", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := literalValue(tt.input)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("literalValue(%q) = %q, %v", tt.input, got, ok)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// attr_reader :name, :email
+// attr_accessor(:status)
+// private attr_writer :token
+var attrPattern = regexp.MustCompile(`^\s*(?:(private|protected|public)\s*\(?\s*)?attr_(reader|writer|accessor)\b\s*\(?`)
+
+// AttrMatcher extracts attribute accessors, one per name listed
+type AttrMatcher struct{}
+
+func (m *AttrMatcher) Name() string  { return "attr" }
+func (m *AttrMatcher) Priority() int { return 85 }
+
+// StartsMultiline implements MultilineDetector for names continued on the
+// following lines:
+//
+//	attr_reader :name,
+//	            :email
+func (m *AttrMatcher) StartsMultiline(line string) (bool, string, string) {
+	if !attrPattern.MatchString(line) {
+		return false, "", ""
+	}
+	code := strings.TrimRight(MaskStrings(line), " \t")
+	if strings.Count(code, "(") > strings.Count(code, ")") || strings.HasSuffix(code, ",") {
+		return true, "(", ")"
+	}
+	return false, "", ""
+}
+
+func (m *AttrMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	if len(ctx.CurrentScope) == 0 {
+		return nil
+	}
+	loc := attrPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return nil
+	}
+
+	kind := types.KindAttrAccessor
+	switch line[loc[4]:loc[5]] {
+	case "reader":
+		kind = types.KindAttrReader
+	case "writer":
+		kind = types.KindAttrWriter
+	}
+
+	var symbols []*types.Symbol
+	col := loc[1]
+	for _, arg := range callArguments(line, loc[1]) {
+		nameMatch := relationNamePattern.FindStringSubmatch(arg)
+		if nameMatch == nil {
+			return nil // Not a plain list of names
+		}
+		col = strings.Index(line[col:], arg) + col
+		sym := &types.Symbol{
+			Name:     nameMatch[1],
+			Kind:     kind,
+			FilePath: ctx.FilePath,
+			Line:     ctx.LineNum,
+			Column:   col + 1,
+			Scope:    append([]string{}, ctx.CurrentScope...),
+		}
+		sym.FullName = sym.ComputeFullName()
+		symbols = append(symbols, sym)
+		col += len(arg)
+	}
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	// private attr_reader :token makes each accessor it defines private
+	visibility := wrapperVisibility(submatch(line, loc, 1), symbols[0], false)
+	if visibility != nil {
+		visibility.Names = nil
+		for _, sym := range symbols {
+			visibility.Names = append(visibility.Names, sym.Name)
+		}
+	}
+	return &MatchResult{Symbols: symbols, SetVisibility: visibility}
+}
@@ -0,0 +1,140 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestAttrMatcher(t *testing.T) {
+	matcher := &AttrMatcher{}
+
+	tests := []struct {
+		name      string
+		line      string
+		wantKind  types.SymbolKind
+		wantNames []string
+		wantCols  []int
+		wrapped   bool
+		wantVis   types.Visibility
+	}{
+		{
+			name:      "attr_reader with several names",
+			line:      "  attr_reader :name, :email",
+			wantKind:  types.KindAttrReader,
+			wantNames: []string{"name", "email"},
+			wantCols:  []int{15, 22},
+		},
+		{
+			name:      "attr_accessor with parens and a string",
+			line:      `  attr_accessor("status")`,
+			wantKind:  types.KindAttrAccessor,
+			wantNames: []string{"status"},
+			wantCols:  []int{17},
+		},
+		{
+			name:      "attr_writer with trailing comment",
+			line:      "  attr_writer :token # set by the job",
+			wantKind:  types.KindAttrWriter,
+			wantNames: []string{"token"},
+			wantCols:  []int{15},
+		},
+		{
+			name:      "private wrapper",
+			line:      "  private attr_reader :z",
+			wantKind:  types.KindAttrReader,
+			wantNames: []string{"z"},
+			wantCols:  []int{23},
+			wrapped:   true,
+			wantVis:   types.VisibilityPrivate,
+		},
+		{
+			name:      "protected wrapper in parens",
+			line:      "  protected(attr_accessor :a, :b)",
+			wantKind:  types.KindAttrAccessor,
+			wantNames: []string{"a", "b"},
+			wantCols:  []int{27, 31},
+			wrapped:   true,
+			wantVis:   types.VisibilityProtected,
+		},
+		{
+			name: "splatted names",
+			line: "  attr_reader *FIELDS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &ParseContext{FilePath: "/test/user.rb", LineNum: 3, CurrentScope: []string{"User"}}
+			result := matcher.Match(tt.line, ctx)
+			if tt.wantNames == nil {
+				if result != nil {
+					t.Fatalf("expected no match, got %+v", result)
+				}
+				return
+			}
+			if result == nil {
+				t.Fatal("expected a match")
+			}
+
+			var names []string
+			var cols []int
+			for _, sym := range result.Symbols {
+				if sym.Kind != tt.wantKind {
+					t.Errorf("%s: expected kind %v, got %v", sym.Name, tt.wantKind, sym.Kind)
+				}
+				names = append(names, sym.Name)
+				cols = append(cols, sym.Column)
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) || !reflect.DeepEqual(cols, tt.wantCols) {
+				t.Errorf("expected %v at %v, got %v at %v", tt.wantNames, tt.wantCols, names, cols)
+			}
+
+			switch change := result.SetVisibility; {
+			case !tt.wrapped && change != nil:
+				t.Errorf("expected no visibility change, got %+v", change)
+			case tt.wrapped && change == nil:
+				t.Errorf("expected %v", tt.wantVis)
+			case tt.wrapped && (change.Visibility != tt.wantVis || !reflect.DeepEqual(change.Names, tt.wantNames)):
+				t.Errorf("expected %v for %v, got %+v", tt.wantVis, tt.wantNames, change)
+			}
+		})
+	}
+
+	if result := matcher.Match("attr_reader :name", &ParseContext{}); result != nil {
+		t.Errorf("expected no match outside a class, got %+v", result)
+	}
+}
+
+func TestAttrVisibility(t *testing.T) {
+	content := `class User
+  attr_reader :name
+  private attr_reader :z
+
+  private
+
+  attr_accessor :token
+  public attr_writer :nickname
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/user.rb", []byte(content))
+
+	want := map[string]types.Visibility{
+		"User#name":     types.VisibilityPublic,
+		"User#z":        types.VisibilityPrivate,
+		"User#token":    types.VisibilityPrivate,
+		"User#nickname": types.VisibilityPublic,
+	}
+	got := map[string]types.Visibility{}
+	for _, sym := range symbols {
+		got[sym.FullName] = sym.Visibility
+	}
+	for fullName, vis := range want {
+		if v, ok := got[fullName]; !ok || v != vis {
+			t.Errorf("%s: expected %v, got %v (indexed: %v)", fullName, vis, v, ok)
+		}
+	}
+}
@@ -8,9 +8,12 @@ import (
 // These constructs require an `end` but don't create a named scope.
 // We need to track them so their `end` doesn't over-decrement nesting depth.
 //
-// Matches: if, unless, case, while, until, for, begin
+// Matches: if, unless, case, while, until, for, begin, including after
+// `else` on the same line ("else if x" nests a new if that needs its own end)
 // Does NOT match postfix if/unless (e.g., "return if x" — these don't start the line)
-var blockPattern = regexp.MustCompile(`^\s*(if|unless|case|while|until|for|begin)\b`)
+// elsif, when, in, rescue, and ensure continue the enclosing block, so they
+// neither open nor close one
+var blockPattern = regexp.MustCompile(`^\s*(?:else\s+)?(if|unless|case|while|until|for|begin)\b`)
 
 // BlockMatcher tracks block-opening keywords that require `end`
 type BlockMatcher struct{}
@@ -19,10 +22,28 @@ func (m *BlockMatcher) Name() string  { return "block" }
 func (m *BlockMatcher) Priority() int { return 55 } // Above end (50), below do (60)
 
 func (m *BlockMatcher) Match(line string, ctx *ParseContext) *MatchResult {
-	if !blockPattern.MatchString(line) {
+	if !blockPattern.MatchString(line) && !assignedBlockPattern.MatchString(MaskStrings(line)) {
 		return nil
 	}
 	return &MatchResult{
 		OpensBlock: true,
 	}
 }
+
+// Right-hand side of an assignment (with any operator) or a boolean
+// operator that starts a block: x = if cond / KIND = case type /
+// @rows ||= begin / total += if taxed? / self.state = case / load || begin
+var assignedBlockPattern = regexp.MustCompile(`(?:=|\|\||&&)\s*(if|unless|case|while|until|begin)\b`)
+
+// assignmentOpensBlock reports whether an assignment opens a block
+// (x = items.map do |i|, y = if cond), so matchers claiming the line still
+// count it toward nesting
+func assignmentOpensBlock(line string) bool {
+	return opensDoBlock(line) || assignedBlockPattern.MatchString(MaskStrings(line))
+}
+
+// opensBlock reports whether a line starts a block that needs an end, so
+// matchers claiming a line don't hide it from nesting tracking
+func opensBlock(line string) bool {
+	return blockPattern.MatchString(line) || assignmentOpensBlock(line)
+}
@@ -9,7 +9,11 @@ import (
 
 // class MyClass < BaseClass
 // class MyModule::MyClass
-var classPattern = regexp.MustCompile(`^\s*class\s+([A-Z]\w*(?:::[A-Z]\w*)*)(?:\s*<\s*\S+)?`)
+var classPattern = regexp.MustCompile(`^\s*class\s+([A-Z]\w*(?:::[A-Z]\w*)*)(?:\s*<\s*((?:::)?[A-Z]\w*(?:::[A-Z]\w*)*))?`)
+
+// class << self (a singleton class block; other receivers open a block
+// without defining anything indexable)
+var singletonClassPattern = regexp.MustCompile(`^\s*class\s*<<\s*(\S+)`)
 
 // ClassMatcher extracts class definitions
 type ClassMatcher struct{}
@@ -18,37 +22,53 @@ func (m *ClassMatcher) Name() string  { return "class" }
 func (m *ClassMatcher) Priority() int { return 100 }
 
 func (m *ClassMatcher) Match(line string, ctx *ParseContext) *MatchResult {
-	match := classPattern.FindStringSubmatch(line)
-	if match == nil {
+	if match := singletonClassPattern.FindStringSubmatch(line); match != nil {
+		return &MatchResult{OpensBlock: true, EnterSingletonClass: match[1] == "self"}
+	}
+
+	loc := classPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
 		return nil
 	}
 
-	className := match[1]
-	col := strings.Index(line, className)
+	className := line[loc[2]:loc[3]]
+	var superclass string
+	if loc[4] >= 0 {
+		superclass = line[loc[4]:loc[5]]
+	}
 
 	// Handle nested class names like MyModule::MyClass
 	parts := strings.Split(className, "::")
 	shortName := parts[len(parts)-1]
+	col := loc[3] - len(shortName)
 
-	// Build scope: current scope + any inline modules
-	scope := append([]string{}, ctx.CurrentScope...)
-	if len(parts) > 1 {
-		scope = append(scope, parts[:len(parts)-1]...)
-	}
+	scope := compactScope(ctx.CurrentScope, parts[:len(parts)-1])
 
 	sym := &types.Symbol{
-		Name:     shortName,
-		Kind:     types.KindClass,
-		FilePath: ctx.FilePath,
-		Line:     ctx.LineNum,
-		Column:   col,
-		Scope:    scope,
+		Name:       shortName,
+		Kind:       types.KindClass,
+		FilePath:   ctx.FilePath,
+		Line:       ctx.LineNum,
+		Column:     col,
+		Scope:      scope,
+		Superclass: superclass,
 	}
 	sym.FullName = sym.ComputeFullName()
 
-	return &MatchResult{
+	result := &MatchResult{
 		Symbols:    []*types.Symbol{sym},
 		PushScope:  shortName,
 		OpensBlock: true,
 	}
+	// class Point < Struct.new(:x, :y) inherits the struct's accessors
+	switch rest := line[loc[1]:]; {
+	case superclass == "Struct" && strings.HasPrefix(rest, ".new"):
+		result.Symbols = append(result.Symbols, structMembers(line, loc[1]+len(".new"), "Struct.new", sym, ctx)...)
+	case superclass == "Data" && strings.HasPrefix(rest, ".define"):
+		result.Symbols = append(result.Symbols, structMembers(line, loc[1]+len(".define"), "Data.define", sym, ctx)...)
+	}
+	if len(parts) > 1 {
+		result.ScopeParent = scope
+	}
+	return result
 }
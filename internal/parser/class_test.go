@@ -119,3 +119,71 @@ func TestClassMatcherWithExistingScope(t *testing.T) {
 		t.Errorf("expected FullName 'OuterModule::InnerClass', got %q", sym.FullName)
 	}
 }
+
+func TestClassMatcherCompactPathInScope(t *testing.T) {
+	content := `module Admin
+  class Admin::Users::BulkImporter < BaseImporter
+    BATCH = 100
+
+    def run
+    end
+  end
+
+  class Reports::Export
+  end
+
+  def self.enabled?
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/admin.rb", []byte(content))
+
+	var got []string
+	for _, sym := range symbols {
+		got = append(got, sym.FullName)
+	}
+	want := []string{
+		"Admin",
+		"Admin::Users::BulkImporter",
+		"Admin::Users::BulkImporter::BATCH",
+		"Admin::Users::BulkImporter#run",
+		"Admin::Reports::Export",
+		"Admin.enabled?",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("symbol %d: expected %s, got %s", i, want[i], got[i])
+		}
+	}
+}
+
+func TestClassMatcherSuperclass(t *testing.T) {
+	matcher := &ClassMatcher{}
+	ctx := &ParseContext{
+		FilePath: "/test/test.rb",
+		LineNum:  1,
+	}
+
+	tests := map[string]string{
+		"class Admin < User":               "User",
+		"class Job < ::ApplicationJob":     "::ApplicationJob",
+		"class Report < Reports::Base":     "Reports::Base",
+		"class Point < Struct.new(:x, :y)": "Struct",
+		"class Standalone":                 "",
+	}
+
+	for line, want := range tests {
+		result := matcher.Match(line, ctx)
+		if result == nil {
+			t.Fatalf("expected result for %q, got nil", line)
+		}
+		if got := result.Symbols[0].Superclass; got != want {
+			t.Errorf("%q: expected superclass %q, got %q", line, want, got)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"regexp"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// concerning :Validation do
+// concerning "Billing" do
+//
+// ActiveSupport::Concerning defines a module named after the argument inside
+// the current class and includes it, so methods in the block belong to it.
+var concerningPattern = regexp.MustCompile(`^\s*concerning\s*\(?\s*(?::|['"])([A-Z]\w*)['"]?\s*\)?\s*do\b`)
+
+// ConcerningMatcher extracts ActiveSupport `concerning` blocks as modules
+type ConcerningMatcher struct{}
+
+func (m *ConcerningMatcher) Name() string  { return "concerning" }
+func (m *ConcerningMatcher) Priority() int { return 95 }
+
+func (m *ConcerningMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	// Only meaningful inside a class
+	if len(ctx.CurrentScope) == 0 {
+		return nil
+	}
+
+	loc := concerningPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return nil
+	}
+
+	moduleName := line[loc[2]:loc[3]]
+	col := loc[2]
+
+	sym := &types.Symbol{
+		Name:     moduleName,
+		Kind:     types.KindModule,
+		FilePath: ctx.FilePath,
+		Line:     ctx.LineNum,
+		Column:   col,
+		Scope:    append([]string{}, ctx.CurrentScope...),
+	}
+	sym.FullName = sym.ComputeFullName()
+
+	return &MatchResult{
+		Symbols:    []*types.Symbol{sym},
+		PushScope:  moduleName,
+		OpensBlock: true,
+	}
+}
@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestConcerningMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		scope    []string
+		wantName string
+		wantNil  bool
+	}{
+		{
+			name:     "symbol argument",
+			line:     "  concerning :Validation do",
+			scope:    []string{"User"},
+			wantName: "Validation",
+		},
+		{
+			name:     "string argument with parens",
+			line:     `  concerning("Billing") do`,
+			scope:    []string{"User"},
+			wantName: "Billing",
+		},
+		{
+			name:    "outside a class",
+			line:    "concerning :Validation do",
+			wantNil: true,
+		},
+		{
+			name:    "lowercase name",
+			line:    "  concerning :validation do",
+			scope:   []string{"User"},
+			wantNil: true,
+		},
+		{
+			name:    "no block",
+			line:    "  concerning :Validation",
+			scope:   []string{"User"},
+			wantNil: true,
+		},
+	}
+
+	matcher := &ConcerningMatcher{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &ParseContext{
+				FilePath:     "/test/test.rb",
+				LineNum:      1,
+				CurrentScope: tt.scope,
+			}
+			result := matcher.Match(tt.line, ctx)
+			if tt.wantNil {
+				if result != nil {
+					t.Errorf("expected nil, got %+v", result)
+				}
+				return
+			}
+			if result == nil {
+				t.Fatal("expected result, got nil")
+			}
+			sym := result.Symbols[0]
+			if sym.Name != tt.wantName || sym.Kind != types.KindModule {
+				t.Errorf("expected module %q, got %s %q", tt.wantName, sym.Kind, sym.Name)
+			}
+			if result.PushScope != tt.wantName || !result.OpensBlock {
+				t.Errorf("expected PushScope %q with OpensBlock, got %q/%v", tt.wantName, result.PushScope, result.OpensBlock)
+			}
+		})
+	}
+}
+
+func TestConcerningParsing(t *testing.T) {
+	content := `class User
+  concerning :Validation do
+    included do
+      validates :name
+    end
+
+    def validate_name
+    end
+  end
+
+  concerning :Billing do
+    def validate_name
+    end
+  end
+
+  def full_name
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/user.rb", []byte(content))
+
+	want := map[string]bool{
+		"User::Validation":               false,
+		"User::Validation#validate_name": false,
+		"User::Billing":                  false,
+		"User::Billing#validate_name":    false,
+		"User#full_name":                 false,
+	}
+	for _, sym := range symbols {
+		if _, ok := want[sym.FullName]; ok {
+			want[sym.FullName] = true
+		}
+	}
+	for name, found := range want {
+		if !found {
+			t.Errorf("expected symbol %s", name)
+		}
+	}
+}
@@ -9,30 +9,78 @@ import (
 
 // MY_CONSTANT = value
 // MyConstant = value
-var constantPattern = regexp.MustCompile(`^\s*([A-Z][A-Z0-9_]*)\s*=`)
+// TIMEOUT ||= 5 (defined unless already defined)
+var constantPattern = regexp.MustCompile(`^\s*([A-Z][A-Z0-9_]*)\s*(?:\|\|)?=`)
+
+// MyError = Class.new(StandardError)
+// Helpers = Module.new
+// Point = Struct.new(:x, :y)
+// Coordinates = Data.define(:lat, :lng)
+// Timeout ||= Class.new(StandardError)
+var classNewPattern = regexp.MustCompile(`^\s*([A-Z]\w*)\s*(?:\|\|)?=\s*(Class\.new|Module\.new|Struct\.new|Data\.define)\b(?:\s*\(\s*((?:::)?[A-Z]\w*(?:::[A-Z]\w*)*))?`)
 
 // Pattern to detect comparison operators (==, ===)
 var constantComparisonPattern = regexp.MustCompile(`^\s*[A-Z][A-Z0-9_]*\s*={2,3}`)
 
-// ConstantMatcher extracts constant definitions
+// ROLES = %w[ / STATES = %i( / KINDS = [ opening an array literal
+var constantArrayStartPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*\s*=\s*(?:%[wWiI]([\[\(\{<])|\[)`)
+
+// ConstantMatcher extracts constant definitions. A constant assigned a
+// literal records it: the elements of an array of words, symbols, or
+// strings in Values, and a scalar (ADMIN = "admin".freeze) as its value
+// option.
 type ConstantMatcher struct{}
 
 func (m *ConstantMatcher) Name() string  { return "constant" }
 func (m *ConstantMatcher) Priority() int { return 80 }
 
+// StartsMultiline implements MultilineDetector for array literals whose
+// elements continue on the following lines, and for Struct.new and
+// Data.define calls listing their members one per line
+func (m *ConstantMatcher) StartsMultiline(line string) (bool, string, string) {
+	if classNewPattern.MatchString(line) {
+		masked := MaskStrings(line)
+		if strings.Count(masked, "(") > strings.Count(masked, ")") {
+			return true, "(", ")"
+		}
+		return false, "", ""
+	}
+	match := constantArrayStartPattern.FindStringSubmatch(line)
+	if match == nil {
+		return false, "", ""
+	}
+	opener, closer := "[", "]"
+	switch match[1] {
+	case "(":
+		opener, closer = "(", ")"
+	case "{":
+		opener, closer = "{", "}"
+	case "<":
+		opener, closer = "<", ">"
+	}
+	if strings.Count(line, opener) > strings.Count(line, closer) {
+		return true, opener, closer
+	}
+	return false, "", ""
+}
+
 func (m *ConstantMatcher) Match(line string, ctx *ParseContext) *MatchResult {
 	// Skip comparison operators (==, ===)
 	if constantComparisonPattern.MatchString(line) {
 		return nil
 	}
 
-	match := constantPattern.FindStringSubmatch(line)
-	if match == nil {
+	if loc := classNewPattern.FindStringSubmatchIndex(line); loc != nil {
+		return m.matchClassNew(loc, line, ctx)
+	}
+
+	loc := constantPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
 		return nil
 	}
 
-	constName := match[1]
-	col := strings.Index(line, constName)
+	constName := line[loc[2]:loc[3]]
+	col := loc[2]
 
 	sym := &types.Symbol{
 		Name:     constName,
@@ -41,10 +89,102 @@ func (m *ConstantMatcher) Match(line string, ctx *ParseContext) *MatchResult {
 		Line:     ctx.LineNum,
 		Column:   col,
 		Scope:    append([]string{}, ctx.CurrentScope...),
+		Values:   parseArrayLiteral(line[loc[1]:]),
 	}
 	sym.FullName = sym.ComputeFullName()
+	if literal, ok := literalValue(line[loc[1]:]); ok {
+		sym.Options = map[string]string{"value": literal}
+	}
 
 	return &MatchResult{
-		Symbols: []*types.Symbol{sym},
+		Symbols:    []*types.Symbol{sym},
+		OpensBlock: assignmentOpensBlock(line),
+	}
+}
+
+// matchClassNew indexes constants assigned from Class.new, Module.new,
+// Struct.new, or Data.define as the class or module they define, along
+// with the accessors of a struct's members
+func (m *ConstantMatcher) matchClassNew(loc []int, line string, ctx *ParseContext) *MatchResult {
+	name := line[loc[2]:loc[3]]
+
+	kind := types.KindClass
+	var superclass string
+	if loc[6] >= 0 {
+		superclass = line[loc[6]:loc[7]]
+	}
+	call := line[loc[4]:loc[5]]
+	switch call {
+	case "Module.new":
+		kind = types.KindModule
+		superclass = ""
+	case "Struct.new", "Data.define":
+		superclass = strings.TrimSuffix(strings.TrimSuffix(call, ".new"), ".define")
+	}
+
+	sym := &types.Symbol{
+		Name:       name,
+		Kind:       kind,
+		FilePath:   ctx.FilePath,
+		Line:       ctx.LineNum,
+		Column:     loc[2],
+		Scope:      append([]string{}, ctx.CurrentScope...),
+		Superclass: superclass,
+	}
+	sym.FullName = sym.ComputeFullName()
+
+	result := &MatchResult{Symbols: []*types.Symbol{sym}}
+	if superclass == "Struct" || superclass == "Data" {
+		result.Symbols = append(result.Symbols, structMembers(line, loc[5], call, sym, ctx)...)
+	}
+
+	// Class.new(Base) do ... end defines methods on the new class; without
+	// a block the class spans just its statement
+	if opensDoBlock(line) {
+		result.PushScope = name
+		result.OpensBlock = true
+	} else {
+		sym.EndLine, sym.EndColumn = ctx.LineNum, len(strings.TrimRight(MaskStrings(line), " \t"))
+	}
+	return result
+}
+
+// structMembers returns the accessors a Struct.new or Data.define call
+// defines on class, one per member it lists after column start:
+// Point = Struct.new(:x, :y, keyword_init: true). Struct members can be
+// assigned; Data members are read-only.
+func structMembers(line string, start int, call string, class *types.Symbol, ctx *ParseContext) []*types.Symbol {
+	kind := types.KindAttrAccessor
+	if call == "Data.define" {
+		kind = types.KindAttrReader
+	}
+	if rest := strings.TrimLeft(line[start:], " \t"); strings.HasPrefix(rest, "(") {
+		start = len(line) - len(rest) + 1
+	}
+
+	var members []*types.Symbol
+	col := start
+	for _, arg := range callArguments(line, start) {
+		if _, _, ok := parseOption(arg); ok {
+			break // Options follow the members
+		}
+		name, ok := literalValue(arg)
+		if !ok || !strings.HasPrefix(arg, ":") {
+			continue // Struct.new("Point", ...) names a constant under Struct
+		}
+		col = strings.Index(line[col:], arg) + col
+		member := &types.Symbol{
+			Name:     name,
+			Kind:     kind,
+			FilePath: ctx.FilePath,
+			Line:     ctx.LineNum,
+			Column:   col + 1,
+			Scope:    append(append([]string{}, class.Scope...), class.Name),
+			Macro:    call,
+		}
+		member.FullName = member.ComputeFullName()
+		members = append(members, member)
+		col += len(arg)
 	}
+	return members
 }
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/jarredhawkins/goruby-lsp/internal/types"
@@ -40,7 +41,7 @@ func TestConstantMatcher(t *testing.T) {
 		},
 		{
 			name:    "mixed case not matched by this pattern",
-			line:    "MyClass = Class.new",
+			line:    "MyValue = compute",
 			wantNil: true,
 		},
 		{
@@ -104,3 +105,261 @@ func TestConstantMatcherWithScope(t *testing.T) {
 		t.Errorf("expected FullName 'MyModule::MyClass::MY_CONSTANT', got %q", sym.FullName)
 	}
 }
+
+func TestConstantMatcherClassNew(t *testing.T) {
+	tests := []struct {
+		name           string
+		line           string
+		wantName       string
+		wantKind       types.SymbolKind
+		wantSuperclass string
+		wantPushScope  string
+	}{
+		{
+			name:           "error class",
+			line:           "  Error = Class.new(StandardError)",
+			wantName:       "Error",
+			wantKind:       types.KindClass,
+			wantSuperclass: "StandardError",
+		},
+		{
+			name:           "namespaced superclass",
+			line:           "NotFound = Class.new(::Api::Error)",
+			wantName:       "NotFound",
+			wantKind:       types.KindClass,
+			wantSuperclass: "::Api::Error",
+		},
+		{
+			name:     "class without superclass",
+			line:     "MyClass = Class.new",
+			wantName: "MyClass",
+			wantKind: types.KindClass,
+		},
+		{
+			name:     "module",
+			line:     "Helpers = Module.new",
+			wantName: "Helpers",
+			wantKind: types.KindModule,
+		},
+		{
+			name:           "struct",
+			line:           "Config = Struct.new(:host, :port)",
+			wantName:       "Config",
+			wantKind:       types.KindClass,
+			wantSuperclass: "Struct",
+		},
+		{
+			name:           "class with block body",
+			line:           "Handler = Class.new(Base) do",
+			wantName:       "Handler",
+			wantKind:       types.KindClass,
+			wantSuperclass: "Base",
+			wantPushScope:  "Handler",
+		},
+	}
+
+	matcher := &ConstantMatcher{}
+	ctx := &ParseContext{
+		FilePath:     "/test/test.rb",
+		LineNum:      1,
+		CurrentScope: []string{"Api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := matcher.Match(tt.line, ctx)
+			if result == nil {
+				t.Fatal("expected result, got nil")
+			}
+			sym := result.Symbols[0]
+			if sym.Name != tt.wantName {
+				t.Errorf("expected name %q, got %q", tt.wantName, sym.Name)
+			}
+			if sym.Kind != tt.wantKind {
+				t.Errorf("expected kind %v, got %v", tt.wantKind, sym.Kind)
+			}
+			if sym.Superclass != tt.wantSuperclass {
+				t.Errorf("expected superclass %q, got %q", tt.wantSuperclass, sym.Superclass)
+			}
+			if sym.FullName != "Api::"+tt.wantName {
+				t.Errorf("expected FullName %q, got %q", "Api::"+tt.wantName, sym.FullName)
+			}
+			if result.PushScope != tt.wantPushScope {
+				t.Errorf("expected PushScope %q, got %q", tt.wantPushScope, result.PushScope)
+			}
+			if result.OpensBlock != (tt.wantPushScope != "") {
+				t.Errorf("expected OpensBlock %v, got %v", tt.wantPushScope != "", result.OpensBlock)
+			}
+		})
+	}
+}
+
+func TestClassNewBlockScope(t *testing.T) {
+	content := `module Api
+  Handler = Class.new(Base) do
+    def call
+    end
+  end
+
+  def self.configure
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/api.rb", []byte(content))
+
+	want := map[string]bool{
+		"Api::Handler":      false,
+		"Api::Handler#call": false,
+		"Api.configure":     false,
+	}
+	for _, sym := range symbols {
+		if _, ok := want[sym.FullName]; ok {
+			want[sym.FullName] = true
+		}
+	}
+	for name, found := range want {
+		if !found {
+			t.Errorf("expected symbol %s", name)
+		}
+	}
+}
+
+func TestConstantLiteralValues(t *testing.T) {
+	content := `class User
+  ROLES = %w[admin member guest].freeze
+  STATES = %i(
+    draft
+    published
+  ).freeze
+  KINDS = [
+    "personal", # one user
+    "business", # "#" "a company"
+  ].freeze
+  HANDLERS = [
+    method(:notify),
+  ]
+  ADMIN = "admin".freeze
+  LIMIT = 10
+
+  def role
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/app/models/user.rb", []byte(content))
+
+	byName := make(map[string]*types.Symbol)
+	for _, sym := range symbols {
+		byName[sym.FullName] = sym
+	}
+
+	tests := []struct {
+		name       string
+		wantLine   int
+		wantValues string
+		wantValue  string
+	}{
+		{"User::ROLES", 2, "admin member guest", ""},
+		{"User::STATES", 3, "draft published", ""},
+		{"User::KINDS", 7, "personal business", ""},
+		{"User::HANDLERS", 11, "", ""},
+		{"User::ADMIN", 14, "", "admin"},
+		{"User::LIMIT", 15, "", "10"},
+	}
+	for _, tt := range tests {
+		sym := byName[tt.name]
+		if sym == nil {
+			t.Errorf("expected constant %s", tt.name)
+			continue
+		}
+		if sym.Line != tt.wantLine {
+			t.Errorf("%s: expected line %d, got %d", tt.name, tt.wantLine, sym.Line)
+		}
+		if values := strings.Join(sym.Values, " "); values != tt.wantValues {
+			t.Errorf("%s: expected values %q, got %q", tt.name, tt.wantValues, values)
+		}
+		if sym.Options["value"] != tt.wantValue {
+			t.Errorf("%s: expected value %q, got %q", tt.name, tt.wantValue, sym.Options["value"])
+		}
+	}
+	if byName["User#role"] == nil {
+		t.Error("expected the method after the arrays to stay in User")
+	}
+}
+
+func TestStructMembers(t *testing.T) {
+	content := `module Geo
+  Point = Struct.new(:x, :y, keyword_init: true)
+  Coordinates = Data.define(:lat, :lng) do
+    def to_s
+    end
+  end
+  Address = Struct.new(
+    :street, # number and name
+    :city,
+  )
+  Empty = Data.define
+  class Segment < Struct.new(:from, :to)
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/app/lib/geo.rb", []byte(content))
+
+	type member struct {
+		kind      types.SymbolKind
+		line, col int
+	}
+	want := map[string]member{
+		"Geo::Point#x":            {types.KindAttrAccessor, 2, 22},
+		"Geo::Point#y":            {types.KindAttrAccessor, 2, 26},
+		"Geo::Coordinates#lat":    {types.KindAttrReader, 3, 29},
+		"Geo::Coordinates#lng":    {types.KindAttrReader, 3, 35},
+		"Geo::Address#street":     {types.KindAttrAccessor, 8, 5},
+		"Geo::Address#city":       {types.KindAttrAccessor, 9, 5},
+		"Geo::Segment#from":       {types.KindAttrAccessor, 12, 30},
+		"Geo::Segment#to":         {types.KindAttrAccessor, 12, 37},
+		"Geo::Coordinates#to_s":   {types.KindMethod, 4, 8},
+		"Geo::Point#keyword_init": {},
+	}
+	got := make(map[string]*types.Symbol)
+	for _, sym := range symbols {
+		got[sym.FullName] = sym
+	}
+	for fullName, w := range want {
+		sym := got[fullName]
+		if w == (member{}) {
+			if sym != nil {
+				t.Errorf("expected no %s, got %+v", fullName, sym)
+			}
+			continue
+		}
+		if sym == nil {
+			t.Errorf("expected %s", fullName)
+			continue
+		}
+		if sym.Kind != w.kind || sym.Line != w.line || sym.Column != w.col {
+			t.Errorf("%s: expected %v at %d:%d, got %v at %d:%d", fullName, w.kind, w.line, w.col, sym.Kind, sym.Line, sym.Column)
+		}
+	}
+	if empty := got["Geo::Empty"]; empty == nil || empty.Superclass != "Data" {
+		t.Errorf("expected Empty to be a Data class, got %+v", empty)
+	}
+
+	// A class without a block spans its statement
+	ranges := map[string][2]int{ // FullName -> EndLine, EndColumn
+		"Geo::Point":       {2, 48},
+		"Geo::Coordinates": {6, 5},
+		"Geo::Address":     {10, 3},
+		"Geo::Empty":       {11, 21},
+	}
+	for fullName, end := range ranges {
+		if sym := got[fullName]; sym == nil || [2]int{sym.EndLine, sym.EndColumn} != end {
+			t.Errorf("%s: expected end %v, got %+v", fullName, end, sym)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// namespace :deploy do
+var namespaceDSLPattern = regexp.MustCompile(`^\s*namespace\s*\(?\s*(?::(\w+)|["'](\w+)["'])`)
+
+// task :restart do / task "assets:precompile" / task default: :spec / task :seed => :environment
+var taskDSLPattern = regexp.MustCompile(`^\s*task\s*\(?\s*(?::(\w+)|["']([\w:-]+)["']|(\w+):\s)`)
+
+// config.vm.define "web" do |web| / config.vm.define :db
+var vagrantDefinePattern = regexp.MustCompile(`^\s*\w+\.vm\.define\s*\(?\s*(?::(\w+)|["']([\w.-]+)["'])`)
+
+// define :nginx_site do (Chef definition) / action :create do (custom resource)
+var chefDSLPattern = regexp.MustCompile(`^\s*(?:define|action)\s*\(?\s*:(\w+)`)
+
+// DeployMatcher extracts namespaces and tasks from deploy-oriented DSLs:
+// Rake and Capistrano tasks, Vagrant machines, and Chef cookbooks
+type DeployMatcher struct{}
+
+func (m *DeployMatcher) Name() string  { return "deploy" }
+func (m *DeployMatcher) Priority() int { return 64 } // Above do (60), DSL blocks end with do
+
+func (m *DeployMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	path := filepath.ToSlash(ctx.FilePath)
+	base := filepath.Base(path)
+
+	switch {
+	case base == "Vagrantfile":
+		return m.match(line, ctx, vagrantDefinePattern, types.KindNamespace)
+	case strings.Contains(path, "/cookbooks/"):
+		return m.match(line, ctx, chefDSLPattern, types.KindTask)
+	case isTaskFile(path):
+		if result := m.match(line, ctx, namespaceDSLPattern, types.KindNamespace); result != nil {
+			return result
+		}
+		return m.match(line, ctx, taskDSLPattern, types.KindTask)
+	}
+	return nil
+}
+
+// match builds a symbol from the first non-empty capture group. Namespace
+// blocks push a scope so nested tasks get qualified names (deploy::restart).
+func (m *DeployMatcher) match(line string, ctx *ParseContext, pattern *regexp.Regexp, kind types.SymbolKind) *MatchResult {
+	loc := pattern.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return nil
+	}
+
+	var name string
+	col := -1
+	for n := 1; n < len(loc)/2; n++ {
+		if group := submatch(line, loc, n); group != "" {
+			name, col = group, loc[2*n]
+			break
+		}
+	}
+
+	sym := &types.Symbol{
+		Name:     name,
+		Kind:     kind,
+		FilePath: ctx.FilePath,
+		Line:     ctx.LineNum,
+		Column:   col,
+		Scope:    append([]string{}, ctx.CurrentScope...),
+	}
+	sym.FullName = sym.ComputeFullName()
+
+	result := &MatchResult{
+		Symbols:    []*types.Symbol{sym},
+		OpensBlock: opensDoBlock(line),
+	}
+	if kind == types.KindNamespace && result.OpensBlock {
+		result.PushScope = name
+	}
+	return result
+}
+
+// isTaskFile reports whether a file holds Rake or Capistrano task definitions
+func isTaskFile(path string) bool {
+	switch filepath.Base(path) {
+	case "Rakefile", "Capfile":
+		return true
+	}
+	switch filepath.Ext(path) {
+	case ".rake", ".cap":
+		return true
+	}
+	return strings.Contains(path, "/config/deploy")
+}
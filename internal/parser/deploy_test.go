@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestDeployMatcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    map[string]types.SymbolKind
+	}{
+		{
+			name: "capistrano namespaces and tasks",
+			path: "/app/config/deploy.rb",
+			content: `set :application, "shop"
+
+namespace :deploy do
+  desc "Restart the app"
+  task :restart do
+    on roles(:app) do
+      execute :touch, release_path.join("tmp/restart.txt")
+    end
+  end
+
+  after :publishing, :restart
+end
+
+task :cleanup_assets`,
+			want: map[string]types.SymbolKind{
+				"deploy":          types.KindNamespace,
+				"deploy::restart": types.KindTask,
+				"cleanup_assets":  types.KindTask,
+			},
+		},
+		{
+			name: "rake tasks",
+			path: "/app/lib/tasks/db.rake",
+			content: `namespace "db" do
+  task seed_demo: :environment do
+  end
+
+  task "reset:all" => :environment
+end
+
+task default: :spec`,
+			want: map[string]types.SymbolKind{
+				"db":            types.KindNamespace,
+				"db::seed_demo": types.KindTask,
+				"db::reset:all": types.KindTask,
+				"default":       types.KindTask,
+			},
+		},
+		{
+			name: "vagrant machines",
+			path: "/app/Vagrantfile",
+			content: `Vagrant.configure("2") do |config|
+  config.vm.define "web" do |web|
+    web.vm.box = "ubuntu/jammy64"
+  end
+  config.vm.define :db
+end`,
+			want: map[string]types.SymbolKind{
+				"web": types.KindNamespace,
+				"db":  types.KindNamespace,
+			},
+		},
+		{
+			name: "chef definitions and actions",
+			path: "/app/cookbooks/nginx/resources/site.rb",
+			content: `property :port, Integer
+
+action :create do
+  template "/etc/nginx/sites/#{new_resource.name}"
+end
+
+define :nginx_site do
+end`,
+			want: map[string]types.SymbolKind{
+				"create":     types.KindTask,
+				"nginx_site": types.KindTask,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+			RegisterDefaults(registry)
+			symbols := NewScanner(registry).Parse(tt.path, []byte(tt.content))
+
+			got := map[string]types.SymbolKind{}
+			for _, sym := range symbols {
+				if sym.Kind == types.KindNamespace || sym.Kind == types.KindTask {
+					got[sym.FullName] = sym.Kind
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			for fullName, kind := range tt.want {
+				if got[fullName] != kind {
+					t.Errorf("expected %s to be %v, got %v (all: %v)", fullName, kind, got[fullName], got)
+				}
+			}
+		})
+	}
+}
+
+func TestDeployMatcherIgnoresAppCode(t *testing.T) {
+	ctx := &ParseContext{FilePath: "/app/app/models/job.rb", LineNum: 1}
+	if result := (&DeployMatcher{}).Match("  task :run do", ctx); result != nil {
+		t.Errorf("expected no match outside deploy files, got %+v", result.Symbols)
+	}
+}
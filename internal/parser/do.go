@@ -8,14 +8,36 @@ import (
 // Matches: foo.each do |x|, loop do, etc.
 var doPattern = regexp.MustCompile(`\bdo\s*(\|[^|]*\|)?\s*$`)
 
-// DoMatcher tracks do...end block nesting
+// { |x| or ->(x) { at end of line: a brace block whose closing brace is on
+// a later line. Hash literals ({ or { key: value) aren't blocks and don't match
+var braceBlockPattern = regexp.MustCompile(`(?:\{\s*\|[^|]*\||->\s*(?:\([^)]*\))?\s*\{)\s*$`)
+
+// closingBracePattern matches a brace block's closing line: }, }.compact, })
+var closingBracePattern = regexp.MustCompile(`^\s*\}`)
+
+// opensDoBlock reports whether a line ends by opening a do block or a
+// multi-line brace block, ignoring do inside strings and comments
+func opensDoBlock(line string) bool {
+	masked := MaskStrings(line)
+	return doPattern.MatchString(masked) || braceBlockPattern.MatchString(masked)
+}
+
+// opensBraceBlock reports whether a line ends by opening a brace block,
+// which closes at a line starting with } rather than at end
+func opensBraceBlock(line string) bool {
+	return braceBlockPattern.MatchString(MaskStrings(line))
+}
+
+// DoMatcher tracks do...end and multi-line { |x| ... } block nesting
 type DoMatcher struct{}
 
 func (m *DoMatcher) Name() string  { return "do" }
 func (m *DoMatcher) Priority() int { return 60 } // Below local vars (70), above end (50)
 
 func (m *DoMatcher) Match(line string, ctx *ParseContext) *MatchResult {
-	if !doPattern.MatchString(line) {
+	// end.each do |x| closes a block before opening one; the end matcher
+	// claims it and the scanner opens the chained block
+	if !opensDoBlock(line) || closingLoc(line, ctx) != nil {
 		return nil
 	}
 	// Opens a block but doesn't create a named scope
@@ -20,6 +20,15 @@ func TestDoMatcher(t *testing.T) {
 		{"not do - method with args", "do_something(1, 2)", false},
 		{"not do - redo keyword", "redo if condition", false},
 		{"not do - middle of line", "items.each do |x| puts x end", false}, // single-line block
+		{"not do - word starting with do", "queue.push x do_not_match", false},
+		{"not do - inside a string", `label = "things to do"`, false},
+		{"not do - string ending the line", `notes.add "what to do"`, false},
+		{"not do - trailing comment", "retry_later # nothing left to do", false},
+		{"do after a string", `Given(/^I log in as "([^"]*)"$/) do |name|`, true},
+		{"brace block", "items.map { |item|", true},
+		{"brace block with params", "hash.each_with_object({}) { |(key, value), memo|", true},
+		{"not brace - hash literal", "options = {", false},
+		{"not brace - single-line block", "items.map { |item| item.id }", false},
 	}
 
 	matcher := &DoMatcher{}
@@ -45,3 +54,43 @@ func TestDoMatcher(t *testing.T) {
 		})
 	}
 }
+
+func TestBraceBlocksKeepNesting(t *testing.T) {
+	content := `class Report
+  def rows
+    totals = items.map { |item|
+      {
+        id: item.id,
+      }
+    }
+    totals.each_slice(2) { |pair|
+      pair.sum
+    }.compact
+    options = {
+      title: "do",
+    }
+  end
+
+  def footer
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/report.rb", []byte(content))
+
+	want := map[string]int{ // FullName -> EndLine
+		"Report":        18,
+		"Report#rows":   14,
+		"Report#footer": 17,
+	}
+	got := map[string]int{}
+	for _, sym := range symbols {
+		got[sym.FullName] = sym.EndLine
+	}
+	for fullName, end := range want {
+		if got[fullName] != end {
+			t.Errorf("%s: expected end line %d, got %d", fullName, end, got[fullName])
+		}
+	}
+}
@@ -4,21 +4,52 @@ import (
 	"regexp"
 )
 
-// end keyword (for scope tracking)
+// end keyword (for scope tracking), including calls chained off it:
+// end.compact / end.each do |x| / end.tap { |x| ... }
 var endPattern = regexp.MustCompile(`^\s*end\b`)
 
+// end closing a block on the line that opened it: def noop; end /
+// class Stub; end / if paid? then total else 0 end, including under a
+// modifier: class Error < StandardError; end unless defined?(Error)
+var inlineEndPattern = regexp.MustCompile(`[;\s](end)(?:\s+(?:if|unless)\s.*|\s*(?:#.*)?)$`)
+
+// inlineEndColumn returns the column just past a trailing inline end, or
+// -1 when the line doesn't end with one. Strings are masked, so a modifier
+// can't be mistaken for one inside them ("the end if").
+func inlineEndColumn(line string) int {
+	loc := inlineEndPattern.FindStringSubmatchIndex(MaskStrings(line))
+	if loc == nil {
+		return -1
+	}
+	return loc[3]
+}
+
 // EndMatcher tracks scope closing
 type EndMatcher struct{}
 
 func (m *EndMatcher) Name() string  { return "end" }
 func (m *EndMatcher) Priority() int { return 50 }
 
+// closingLoc returns the location of the end (or, in a brace block, the
+// closing brace) starting the line, or nil
+func closingLoc(line string, ctx *ParseContext) []int {
+	if loc := endPattern.FindStringIndex(line); loc != nil {
+		return loc
+	}
+	if ctx.InBraceBlock {
+		return closingBracePattern.FindStringIndex(line)
+	}
+	return nil
+}
+
 func (m *EndMatcher) Match(line string, ctx *ParseContext) *MatchResult {
-	if !endPattern.MatchString(line) {
+	loc := closingLoc(line, ctx)
+	if loc == nil {
 		return nil
 	}
 	return &MatchResult{
 		ClosesBlock: true,
 		PopScope:    true,
+		CloseColumn: loc[1],
 	}
 }
@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// get "/orders/:id" do (Sinatra) / get ":id" do / post do (Grape)
+var endpointVerbPattern = regexp.MustCompile(`^\s*(get|post|put|patch|delete|head|options)\b\s*\(?\s*(?:(?:"([^"#]*)"|'([^']*)'|:(\w+))\s*\)?\s*,?)?`)
+
+// resource :orders do / namespace "admin" do / route_param :id do (Grape),
+// namespace "/admin" do (sinatra-contrib)
+var endpointPathPattern = regexp.MustCompile(`^\s*(resources?|namespace|group|segment|route_param)\s*\(?\s*(?::(\w+)|"([^"#]+)"|'([^']+)')`)
+
+// params do (Grape's parameter declarations for the next endpoint)
+var endpointParamsPattern = regexp.MustCompile(`^\s*(params)\s+do\b`)
+
+// EndpointMatcher extracts HTTP endpoints declared by Sinatra and Grape
+// route blocks. Path blocks (Grape resources, namespaces, route params)
+// push their segment as a scope; an endpoint is named by its full path,
+// joined from the path scopes it's nested in (/orders/:id/refund), with a
+// full name of its verb and path (POST /orders/:id/refund).
+type EndpointMatcher struct{}
+
+func (m *EndpointMatcher) Name() string  { return "endpoint" }
+func (m *EndpointMatcher) Priority() int { return 63 } // Above do (60), endpoints are do blocks
+
+func (m *EndpointMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	if !isEndpointPath(ctx.FilePath) || ctx.CurrentMethod != nil || !opensDoBlock(line) {
+		return nil
+	}
+
+	if loc := endpointVerbPattern.FindStringSubmatchIndex(line); loc != nil {
+		verb := line[loc[2]:loc[3]]
+		segment, col := "", loc[2]
+		for n := 2; n <= 4; n++ {
+			if loc[2*n] >= 0 {
+				segment, col = submatch(line, loc, n), loc[2*n]
+			}
+		}
+		path := joinEndpointPath(endpointPrefix(ctx.CurrentScope), segment)
+		sym := m.symbol(path, verb, col, ctx)
+		sym.Kind = types.KindRoute
+		sym.FullName = strings.ToUpper(verb) + " " + path
+		return &MatchResult{Symbols: []*types.Symbol{sym}, OpensBlock: true}
+	}
+
+	if loc := endpointPathPattern.FindStringSubmatchIndex(line); loc != nil {
+		macro := line[loc[2]:loc[3]]
+		var segment string
+		col := -1
+		for n := 2; n <= 4; n++ {
+			if loc[2*n] >= 0 {
+				segment, col = strings.Trim(submatch(line, loc, n), "/"), loc[2*n]
+			}
+		}
+		if macro == "route_param" {
+			segment = ":" + segment
+		}
+		sym := m.symbol(segment, macro, col, ctx)
+		sym.Kind = types.KindNamespace
+		sym.FullName = sym.ComputeFullName()
+		sym.Options["path"] = joinEndpointPath(endpointPrefix(ctx.CurrentScope), segment)
+		return &MatchResult{Symbols: []*types.Symbol{sym}, OpensBlock: true, PushScope: segment}
+	}
+
+	if loc := endpointParamsPattern.FindStringSubmatchIndex(line); loc != nil {
+		sym := m.symbol("params", "params", loc[2], ctx)
+		sym.Kind = types.KindNamespace
+		sym.FullName = sym.ComputeFullName()
+		sym.Options["path"] = joinEndpointPath(endpointPrefix(ctx.CurrentScope), "")
+		return &MatchResult{Symbols: []*types.Symbol{sym}, OpensBlock: true}
+	}
+	return nil
+}
+
+func (m *EndpointMatcher) symbol(name, macro string, col int, ctx *ParseContext) *types.Symbol {
+	return &types.Symbol{
+		Name:     name,
+		FilePath: ctx.FilePath,
+		Line:     ctx.LineNum,
+		Column:   col,
+		Scope:    append([]string{}, ctx.CurrentScope...),
+		Macro:    macro,
+		Options:  map[string]string{"path": name},
+	}
+}
+
+// endpointPrefix returns the path segments pushed by the path blocks an
+// endpoint is nested in: the scopes after the innermost class or module
+// (API::V1::orders:::id → orders/:id)
+func endpointPrefix(scope []string) string {
+	start := 0
+	for i, name := range scope {
+		if name != "" && name[0] >= 'A' && name[0] <= 'Z' {
+			start = i + 1
+		}
+	}
+	return strings.Join(scope[start:], "/")
+}
+
+// joinEndpointPath joins a prefix and a segment into an absolute path
+func joinEndpointPath(prefix, segment string) string {
+	path := strings.Trim(prefix+"/"+strings.Trim(segment, "/"), "/")
+	return "/" + path
+}
+
+// isEndpointPath reports whether path may declare Sinatra or Grape
+// endpoints: Ruby files other than Rails routes, DSL task files, Gemfiles,
+// and tests, whose get "/path" calls are requests rather than routes
+func isEndpointPath(path string) bool {
+	if filepath.Ext(path) != ".rb" || isRoutesPath(path) || isGemfilePath(path) {
+		return false
+	}
+	base := filepath.Base(path)
+	slashed := filepath.ToSlash(path)
+	return !strings.HasSuffix(base, "_spec.rb") && !strings.HasSuffix(base, "_test.rb") &&
+		!strings.Contains(slashed, "/spec/") && !strings.Contains(slashed, "/test/")
+}
@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestEndpointMatcher(t *testing.T) {
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	scanner := NewScanner(registry)
+
+	grape := `module API
+  class Orders < Grape::API
+    resource :orders do
+      get do
+      end
+
+      params do
+        requires :id, type: Integer
+      end
+      route_param :id do
+        get do
+        end
+
+        post "refund" do
+        end
+      end
+    end
+
+    helpers do
+      def current_user
+      end
+    end
+  end
+end`
+
+	sinatra := `class Storefront < Sinatra::Base
+  get '/' do
+  end
+
+  namespace "/admin" do
+    delete "/orders/:id" do
+    end
+  end
+
+  def render_page
+  end
+end`
+
+	type endpoint struct {
+		kind             types.SymbolKind
+		name, full, path string
+		line             int
+	}
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    []endpoint
+	}{
+		{"grape", "/app/app/api/orders.rb", grape, []endpoint{
+			{types.KindNamespace, "orders", "API::Orders::orders", "/orders", 3},
+			{types.KindRoute, "/orders", "GET /orders", "/orders", 4},
+			{types.KindNamespace, "params", "API::Orders::orders::params", "/orders", 7},
+			{types.KindNamespace, ":id", "API::Orders::orders:::id", "/orders/:id", 10},
+			{types.KindRoute, "/orders/:id", "GET /orders/:id", "/orders/:id", 11},
+			{types.KindRoute, "/orders/:id/refund", "POST /orders/:id/refund", "/orders/:id/refund", 14},
+		}},
+		{"sinatra", "/app/storefront.rb", sinatra, []endpoint{
+			{types.KindRoute, "/", "GET /", "/", 2},
+			{types.KindNamespace, "admin", "Storefront::admin", "/admin", 5},
+			{types.KindRoute, "/admin/orders/:id", "DELETE /admin/orders/:id", "/admin/orders/:id", 6},
+		}},
+		{"request spec", "/app/spec/requests/orders_spec.rb", "it do\n  get \"/orders\" do\n  end\nend", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []endpoint
+			for _, sym := range scanner.Parse(tt.path, []byte(tt.content)) {
+				if sym.Options["path"] != "" {
+					got = append(got, endpoint{sym.Kind, sym.Name, sym.FullName, sym.Options["path"], sym.Line})
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d endpoints, got %+v", len(tt.want), got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected %+v, got %+v", tt.want[i], got[i])
+				}
+			}
+		})
+	}
+
+	// The params block is located at its keyword
+	for _, sym := range scanner.Parse("/app/app/api/orders.rb", []byte(grape)) {
+		if sym.Name == "params" && sym.Column != 6 {
+			t.Errorf("expected params at column 6, got %d", sym.Column)
+		}
+	}
+
+	// Helpers keep their class scope; methods after a path block aren't in it
+	for _, sym := range scanner.Parse("/app/storefront.rb", []byte(sinatra)) {
+		if sym.Name == "render_page" && sym.FullName != "Storefront#render_page" {
+			t.Errorf("expected Storefront#render_page, got %s", sym.FullName)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// enum status: { draft: 0, placed: 1 } / enum status: [:draft, :placed]
+// enum :status, { draft: 0 }, prefix: true / enum(:status, %i[draft placed])
+var enumPattern = regexp.MustCompile(`^\s*enum\s*\(?\s*(?::(\w+)\s*,|(\w+):)\s*(\{|\[|%[iw]\[)`)
+
+// EnumMatcher extracts the values of ActiveRecord enums declared on one
+// line. Each value is a symbol in the model, since Rails defines a scope
+// and predicate named after it (Order.draft, order.draft?).
+type EnumMatcher struct{}
+
+func (m *EnumMatcher) Name() string  { return "enum" }
+func (m *EnumMatcher) Priority() int { return 85 }
+
+func (m *EnumMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	if len(ctx.CurrentScope) == 0 || ctx.CurrentMethod != nil {
+		return nil
+	}
+
+	loc := enumPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return nil
+	}
+
+	attribute := submatch(line, loc, 1) + submatch(line, loc, 2)
+	open := line[loc[6]:loc[7]]
+	body := line[loc[7]:]
+	if strings.HasPrefix(open, "%") {
+		// %i[draft placed] holds bare words rather than comma-separated literals
+		if end := strings.IndexByte(body, ']'); end >= 0 {
+			body = body[:end]
+		}
+		body = strings.Join(strings.Fields(body), ", ")
+	}
+
+	var symbols []*types.Symbol
+	offset := loc[7]
+	for _, arg := range splitArguments(body) {
+		value, ok := literalValue(arg)
+		if open == "{" {
+			value, _, ok = parseOption(arg)
+		} else if strings.HasPrefix(open, "%") {
+			value, ok = arg, true
+		}
+		if !ok {
+			continue
+		}
+
+		col := strings.Index(line[offset:], value)
+		if col < 0 {
+			continue
+		}
+		col += offset
+		offset = col + len(value)
+
+		sym := &types.Symbol{
+			Name:     value,
+			Kind:     types.KindEnumValue,
+			FilePath: ctx.FilePath,
+			Line:     ctx.LineNum,
+			Column:   col,
+			Scope:    append([]string{}, ctx.CurrentScope...),
+			Macro:    "enum " + attribute,
+		}
+		sym.FullName = sym.ComputeFullName()
+		symbols = append(symbols, sym)
+	}
+
+	if len(symbols) == 0 {
+		return nil
+	}
+	return &MatchResult{Symbols: symbols}
+}
@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestEnumMatcher(t *testing.T) {
+	matcher := &EnumMatcher{}
+
+	tests := []struct {
+		name      string
+		line      string
+		scope     []string
+		wantNames []string
+		wantCols  []int
+		wantMacro string
+	}{
+		{"hash", "  enum status: { draft: 0, placed: 1 }", []string{"Order"}, []string{"draft", "placed"}, []int{17, 27}, "enum status"},
+		{"array", "  enum status: [:draft, :placed]", []string{"Order"}, []string{"draft", "placed"}, []int{17, 25}, "enum status"},
+		{"positional with options", "  enum :priority, { standard: 0, express: 1 }, prefix: true", []string{"Order"}, []string{"standard", "express"}, []int{20, 33}, "enum priority"},
+		{"percent literal", "  enum(:state, %i[open closed])", []string{"Ticket"}, []string{"open", "closed"}, []int{18, 23}, "enum state"},
+		{"string values", `  enum kind: { "credit" => "credit" }`, []string{"Card"}, nil, nil, ""},
+		{"outside class", "enum status: { draft: 0 }", nil, nil, nil, ""},
+		{"multi-line", "  enum status: {", []string{"Order"}, nil, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &ParseContext{FilePath: "/app/models/order.rb", CurrentScope: tt.scope, LineNum: 2}
+			result := matcher.Match(tt.line, ctx)
+
+			if tt.wantNames == nil {
+				if result != nil {
+					t.Errorf("expected no match, got %+v", result.Symbols)
+				}
+				return
+			}
+			if result == nil {
+				t.Fatal("expected a match")
+			}
+			var names []string
+			var cols []int
+			for _, sym := range result.Symbols {
+				names = append(names, sym.Name)
+				cols = append(cols, sym.Column)
+				if sym.Kind != types.KindEnumValue || sym.Macro != tt.wantMacro {
+					t.Errorf("%s: expected enum value from %q, got %v from %q", sym.Name, tt.wantMacro, sym.Kind, sym.Macro)
+				}
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) || !reflect.DeepEqual(cols, tt.wantCols) {
+				t.Errorf("expected %v at %v, got %v at %v", tt.wantNames, tt.wantCols, names, cols)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// factory :user do / factory :admin, class: "User" do / factory(:order, parent: :base)
+var factoryPattern = regexp.MustCompile(`^\s*factory\s*\(?\s*(?::(\w+)|["'](\w+)["'])\s*,?`)
+
+// FactoryMatcher extracts FactoryBot factories. Factory names are global,
+// so nested factories aren't qualified by the factory they're nested in.
+type FactoryMatcher struct{}
+
+func (m *FactoryMatcher) Name() string  { return "factory" }
+func (m *FactoryMatcher) Priority() int { return 61 } // Above do (60), factories are do blocks
+
+func (m *FactoryMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	if !isFactoryPath(ctx.FilePath) {
+		return nil
+	}
+
+	loc := factoryPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return nil
+	}
+
+	col := loc[2]
+	if col < 0 {
+		col = loc[4]
+	}
+	name := submatch(line, loc, 1) + submatch(line, loc, 2)
+	sym := &types.Symbol{
+		Name:     name,
+		Kind:     types.KindFactory,
+		FilePath: ctx.FilePath,
+		Line:     ctx.LineNum,
+		Column:   col,
+		FullName: name, // Factories are looked up globally by name
+		Macro:    "factory",
+	}
+
+	// The class: option names the model when it isn't the factory's name
+	for _, arg := range callArguments(line, loc[1]) {
+		if key, value, ok := parseOption(arg); ok && key == "class" {
+			if literal, isLiteral := literalValue(value); isLiteral {
+				value = literal
+			}
+			sym.Options = map[string]string{"class": value}
+		}
+	}
+
+	return &MatchResult{
+		Symbols:    []*types.Symbol{sym},
+		OpensBlock: opensDoBlock(line),
+	}
+}
+
+// isFactoryPath reports whether path holds FactoryBot definitions
+// (spec/factories/, test/factories/, or a factories.rb)
+func isFactoryPath(path string) bool {
+	path = filepath.ToSlash(path)
+	return strings.Contains(path, "/factories/") || filepath.Base(path) == "factories.rb"
+}
@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestFactoryMatcher(t *testing.T) {
+	matcher := &FactoryMatcher{}
+
+	tests := []struct {
+		name      string
+		path      string
+		line      string
+		wantName  string
+		wantCol   int
+		wantClass string
+	}{
+		{"symbol", "/app/spec/factories/users.rb", "  factory :user do", "user", 11, ""},
+		{"string class", "/app/spec/factories/users.rb", `  factory :admin, class: "User" do`, "admin", 11, "User"},
+		{"constant class", "/app/test/factories/orders.rb", "  factory(:order, class: Shop::Order) do", "order", 11, "Shop::Order"},
+		{"factories file", "/app/spec/factories.rb", "  factory 'account' do", "account", 11, ""},
+		{"outside factories", "/app/app/models/user.rb", "  factory :user do", "", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &ParseContext{FilePath: tt.path, CurrentScope: []string{"Outer"}, LineNum: 2}
+			result := matcher.Match(tt.line, ctx)
+
+			if tt.wantName == "" {
+				if result != nil {
+					t.Errorf("expected no match, got %+v", result.Symbols)
+				}
+				return
+			}
+			if result == nil || len(result.Symbols) != 1 {
+				t.Fatalf("expected 1 symbol, got %+v", result)
+			}
+			sym := result.Symbols[0]
+			if sym.Name != tt.wantName || sym.FullName != tt.wantName || sym.Column != tt.wantCol {
+				t.Errorf("expected %s at %d, got %s (%s) at %d", tt.wantName, tt.wantCol, sym.Name, sym.FullName, sym.Column)
+			}
+			if sym.Kind != types.KindFactory || sym.Options["class"] != tt.wantClass {
+				t.Errorf("expected factory of %q, got %v of %q", tt.wantClass, sym.Kind, sym.Options["class"])
+			}
+			if !result.OpensBlock {
+				t.Error("expected the factory to open a block")
+			}
+		})
+	}
+}
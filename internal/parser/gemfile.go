@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// gem "rails", "~> 7.1" / gem 'pg', require: false
+var gemPattern = regexp.MustCompile(`^\s*gem\s*\(?\s*["']([\w.-]+)["']`)
+
+// group :development, :test do / platforms :jruby do
+var gemGroupPattern = regexp.MustCompile(`^\s*(group|platforms?)\s*\(?\s*(:\w+.*)`)
+
+// spec.add_dependency "rack", ">= 2" / s.add_development_dependency("rspec")
+var gemDependencyPattern = regexp.MustCompile(`^\s*\w+\.(add_(?:runtime_|development_)?dependency)\s*\(?\s*["']([\w.-]+)["']`)
+
+// spec.name = "billing" / spec.required_ruby_version = ">= 3.1"
+var gemAttributePattern = regexp.MustCompile(`^\s*\w+\.(\w+)\s*=\s*([^=~\s].*)`)
+
+// GemfileMatcher extracts the dependencies a Gemfile or gemspec declares
+// (kind gem, looked up globally by name), Gemfile groups, and gemspec
+// attribute assignments. Groups don't push a scope: a gem is the same gem
+// in any group.
+type GemfileMatcher struct{}
+
+func (m *GemfileMatcher) Name() string  { return "gemfile" }
+func (m *GemfileMatcher) Priority() int { return 64 } // Above do (60), groups open do blocks
+
+func (m *GemfileMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	switch {
+	case isGemfilePath(ctx.FilePath):
+		if loc := gemPattern.FindStringSubmatchIndex(line); loc != nil {
+			return m.gem(line, loc[2], loc[3], "gem", ctx)
+		}
+		if loc := gemGroupPattern.FindStringSubmatchIndex(line); loc != nil {
+			return m.group(line, loc, ctx)
+		}
+	case isGemspecPath(ctx.FilePath):
+		if loc := gemDependencyPattern.FindStringSubmatchIndex(line); loc != nil {
+			return m.gem(line, loc[4], loc[5], line[loc[2]:loc[3]], ctx)
+		}
+		if loc := gemAttributePattern.FindStringSubmatchIndex(line); loc != nil {
+			return m.attribute(line, loc, ctx)
+		}
+	}
+	return nil
+}
+
+// gem builds a dependency named by the string at line[start:end], with
+// the version requirements that follow the name
+func (m *GemfileMatcher) gem(line string, start, end int, macro string, ctx *ParseContext) *MatchResult {
+	name := line[start:end]
+	sym := &types.Symbol{
+		Name:     name,
+		Kind:     types.KindGem,
+		FilePath: ctx.FilePath,
+		Line:     ctx.LineNum,
+		Column:   start,
+		FullName: name, // Gems are looked up globally by name
+		Macro:    macro,
+	}
+
+	var versions []string
+	args := callArguments(line, start-1)
+	for _, arg := range args[min(1, len(args)):] {
+		if _, _, ok := parseOption(arg); ok {
+			break // Options follow the requirements
+		}
+		if literal, ok := literalValue(arg); ok && literal != "" {
+			versions = append(versions, literal)
+		}
+	}
+	if len(versions) > 0 {
+		sym.Options = map[string]string{"version": strings.Join(versions, ", ")}
+	}
+
+	return &MatchResult{
+		Symbols:    []*types.Symbol{sym},
+		OpensBlock: opensDoBlock(line),
+	}
+}
+
+// group builds a namespace named by the groups or platforms it lists
+// (development, test)
+func (m *GemfileMatcher) group(line string, loc []int, ctx *ParseContext) *MatchResult {
+	var names []string
+	for _, arg := range callArguments(line, loc[4]) {
+		if !strings.HasPrefix(arg, ":") {
+			break // Options follow the group names
+		}
+		names = append(names, strings.TrimPrefix(arg, ":"))
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	name := strings.Join(names, ", ")
+	sym := &types.Symbol{
+		Name:     name,
+		Kind:     types.KindNamespace,
+		FilePath: ctx.FilePath,
+		Line:     ctx.LineNum,
+		Column:   loc[4] + 1,
+		FullName: name,
+		Macro:    line[loc[2]:loc[3]],
+	}
+	return &MatchResult{
+		Symbols:    []*types.Symbol{sym},
+		OpensBlock: opensDoBlock(line),
+	}
+}
+
+// attribute builds a gemspec attribute (name, version, summary) with its
+// value when that's a literal
+func (m *GemfileMatcher) attribute(line string, loc []int, ctx *ParseContext) *MatchResult {
+	name := line[loc[2]:loc[3]]
+	sym := &types.Symbol{
+		Name:     name,
+		Kind:     types.KindGemAttribute,
+		FilePath: ctx.FilePath,
+		Line:     ctx.LineNum,
+		Column:   loc[2],
+		FullName: "Gem::Specification#" + name, // Kept apart from methods called name
+		Macro:    "spec",
+	}
+
+	if args := callArguments(line, loc[4]); len(args) == 1 {
+		if literal, ok := literalValue(args[0]); ok {
+			sym.Options = map[string]string{"value": literal}
+		}
+	}
+
+	return &MatchResult{
+		Symbols:    []*types.Symbol{sym},
+		OpensBlock: opensDoBlock(line),
+	}
+}
+
+// isGemfilePath reports whether path is a Bundler Gemfile (Gemfile or gems.rb)
+func isGemfilePath(path string) bool {
+	base := filepath.Base(path)
+	return base == "Gemfile" || base == "gems.rb"
+}
+
+// isGemspecPath reports whether path is a gem specification
+func isGemspecPath(path string) bool {
+	return filepath.Ext(path) == ".gemspec"
+}
@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestGemfileMatcher(t *testing.T) {
+	matcher := &GemfileMatcher{}
+
+	tests := []struct {
+		name        string
+		path        string
+		line        string
+		wantName    string
+		wantKind    types.SymbolKind
+		wantCol     int
+		wantMacro   string
+		wantOption  string
+		wantOpening bool
+	}{
+		{"gem", "/app/Gemfile", `gem "rails", "~> 7.1", ">= 7.1.2"`, "rails", types.KindGem, 5, "gem", "~> 7.1, >= 7.1.2", false},
+		{"gem with options", "/app/Gemfile", `  gem 'pg', require: false`, "pg", types.KindGem, 7, "gem", "", false},
+		{"gems.rb", "/app/gems.rb", `gem("puma")`, "puma", types.KindGem, 5, "gem", "", false},
+		{"group", "/app/Gemfile", `group :development, :test do`, "development, test", types.KindNamespace, 7, "group", "", true},
+		{"platforms", "/app/Gemfile", `platforms :jruby do`, "jruby", types.KindNamespace, 11, "platforms", "", true},
+		{"dependency", "/gem/billing.gemspec", `  spec.add_dependency "rack", ">= 2"`, "rack", types.KindGem, 23, "add_dependency", ">= 2", false},
+		{"development dependency", "/gem/billing.gemspec", `  s.add_development_dependency('rspec')`, "rspec", types.KindGem, 32, "add_development_dependency", "", false},
+		{"attribute", "/gem/billing.gemspec", `  spec.version = "1.2.0"`, "version", types.KindGemAttribute, 7, "spec", "1.2.0", false},
+		{"computed attribute", "/gem/billing.gemspec", `  spec.files = Dir["lib/**/*.rb"]`, "files", types.KindGemAttribute, 7, "spec", "", false},
+		{"gem outside a Gemfile", "/app/lib/tasks.rb", `gem "rails"`, "", 0, 0, "", "", false},
+		{"comparison", "/gem/billing.gemspec", `  spec.name == "x"`, "", 0, 0, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &ParseContext{FilePath: tt.path, LineNum: 3}
+			result := matcher.Match(tt.line, ctx)
+
+			if tt.wantName == "" {
+				if result != nil {
+					t.Errorf("expected no match, got %+v", result.Symbols)
+				}
+				return
+			}
+			if result == nil || len(result.Symbols) != 1 {
+				t.Fatalf("expected 1 symbol, got %+v", result)
+			}
+			sym := result.Symbols[0]
+			if sym.Name != tt.wantName || sym.Kind != tt.wantKind || sym.Column != tt.wantCol {
+				t.Errorf("expected %v %s at %d, got %v %s at %d", tt.wantKind, tt.wantName, tt.wantCol, sym.Kind, sym.Name, sym.Column)
+			}
+			option := sym.Options["version"] + sym.Options["value"]
+			if sym.Macro != tt.wantMacro || option != tt.wantOption {
+				t.Errorf("expected %s %q, got %s %q", tt.wantMacro, tt.wantOption, sym.Macro, option)
+			}
+			if result.OpensBlock != tt.wantOpening {
+				t.Errorf("expected OpensBlock %v", tt.wantOpening)
+			}
+		})
+	}
+}
+
+func TestGemfileGroupRange(t *testing.T) {
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/app/Gemfile", []byte(`source "https://rubygems.org"
+
+gem "rails"
+
+group :test do
+  gem "rspec-rails"
+end
+`))
+
+	var group *types.Symbol
+	var gems []*types.Symbol
+	for _, sym := range symbols {
+		switch sym.Kind {
+		case types.KindNamespace:
+			group = sym
+		case types.KindGem:
+			gems = append(gems, sym)
+		}
+	}
+	if group == nil || group.Line != 5 || group.EndLine != 7 {
+		t.Fatalf("expected the test group on lines 5-7, got %+v", group)
+	}
+	if len(gems) != 2 || gems[1].FullName != "rspec-rails" {
+		t.Errorf("expected rails and rspec-rails unscoped, got %+v", gems)
+	}
+}
@@ -0,0 +1,140 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// %w[draft published].each do |state|
+// STATES.each do |state|
+var literalIterationPattern = regexp.MustCompile(`^\s*(.+?)\.each\s+do\s*\|\s*([a-z_]\w*)\s*\|\s*$`)
+
+// define_method(:name), define_method("#{state}?"), define_method :"#{kind}_url"
+var defineMethodPattern = regexp.MustCompile(`^\s*define_method\s*\(?\s*(?::(\w+[?!=]?)|:?"([^"]*)"|:?'([^']*)'|([a-z_]\w*))`)
+
+// Prefix checks inside method_missing / respond_to_missing?:
+// name.to_s.start_with?("find_by_"), method_name =~ /^find_by_/
+var ghostPrefixPattern = regexp.MustCompile(`(?:start_with\?\s*\(?\s*['"]([a-z_]\w*)['"]|=~\s*/\^([a-z_]\w*))`)
+
+// Interpolation of a block variable: #{state}
+var interpolationPattern = regexp.MustCompile(`#\{\s*([a-z_]\w*)\s*\}`)
+
+// GhostMethodMatcher infers methods created through metaprogramming:
+// define_method calls (including loops over literal arrays) and
+// prefix-dispatching method_missing implementations. Inferred symbols are
+// flagged Synthetic since they are best-effort guesses.
+type GhostMethodMatcher struct{}
+
+func (m *GhostMethodMatcher) Name() string  { return "ghost" }
+func (m *GhostMethodMatcher) Priority() int { return 65 } // Below local vars (70), above do (60)
+
+func (m *GhostMethodMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	if len(ctx.CurrentScope) == 0 {
+		return nil
+	}
+
+	if loc := defineMethodPattern.FindStringSubmatchIndex(line); loc != nil {
+		return m.matchDefineMethod(loc, line, ctx)
+	}
+
+	if match := literalIterationPattern.FindStringSubmatch(line); match != nil {
+		return m.matchIteration(match, ctx)
+	}
+
+	if ctx.CurrentMethod != nil && isMissingHook(ctx.CurrentMethod.FullName) {
+		if loc := ghostPrefixPattern.FindStringSubmatchIndex(line); loc != nil {
+			start, end := loc[2], loc[3]
+			if start < 0 {
+				start, end = loc[4], loc[5]
+			}
+			// The line is claimed for the prefix; a block it opens
+			// (if name.to_s.start_with?("find_by_")) is still counted by
+			// the scanner
+			sym := m.newSymbol(line[start:end]+"*", start, ctx)
+			sym.Synthetic = true
+			return &MatchResult{Symbols: []*types.Symbol{sym}}
+		}
+	}
+
+	return nil
+}
+
+// matchDefineMethod handles a define_method call, expanding interpolated
+// names against the enclosing literal iteration if there is one
+func (m *GhostMethodMatcher) matchDefineMethod(loc []int, line string, ctx *ParseContext) *MatchResult {
+	result := &MatchResult{OpensBlock: opensDoBlock(line)}
+
+	// define_method(:name) is an exact definition
+	if loc[2] >= 0 {
+		sym := m.newSymbol(line[loc[2]:loc[3]], loc[2], ctx)
+		result.Symbols = append(result.Symbols, sym)
+		return result
+	}
+
+	iter := ctx.CurrentIteration
+	if iter == nil {
+		return result
+	}
+
+	template := submatch(line, loc, 2) + submatch(line, loc, 3)
+	if bare := submatch(line, loc, 4); bare != "" {
+		template = "#{" + bare + "}"
+	}
+
+	varMatch := interpolationPattern.FindStringSubmatch(template)
+	if varMatch == nil || varMatch[1] != iter.Var {
+		return result
+	}
+
+	// Synthesized names don't appear in the source; point at the call
+	col := len(line) - len(strings.TrimLeft(line, " \t"))
+	for _, value := range iter.Values {
+		name := interpolationPattern.ReplaceAllString(template, value)
+		sym := m.newSymbol(name, col, ctx)
+		sym.Synthetic = true
+		result.Symbols = append(result.Symbols, sym)
+	}
+	return result
+}
+
+// matchIteration starts tracking a `.each do |x|` block over literal values
+func (m *GhostMethodMatcher) matchIteration(match []string, ctx *ParseContext) *MatchResult {
+	receiver := strings.TrimSuffix(strings.TrimSpace(match[1]), ".freeze")
+
+	values := parseArrayLiteral(receiver)
+	if values == nil {
+		values = ctx.LiteralConstants[receiver]
+	}
+	if values == nil {
+		return nil // Unknown values; let DoMatcher track the block
+	}
+
+	return &MatchResult{
+		OpensBlock: true,
+		EnterIteration: &IterationContext{
+			Var:    match[2],
+			Values: values,
+		},
+	}
+}
+
+func (m *GhostMethodMatcher) newSymbol(name string, col int, ctx *ParseContext) *types.Symbol {
+	sym := &types.Symbol{
+		Name:     name,
+		Kind:     types.KindMethod,
+		FilePath: ctx.FilePath,
+		Line:     ctx.LineNum,
+		Column:   col,
+		Scope:    append([]string{}, ctx.CurrentScope...),
+	}
+	sym.FullName = sym.ComputeFullName()
+	return sym
+}
+
+// isMissingHook checks if a method full name is a method_missing-style hook
+func isMissingHook(fullName string) bool {
+	return strings.HasSuffix(fullName, "#method_missing") ||
+		strings.HasSuffix(fullName, "#respond_to_missing?")
+}
@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestParseArrayLiteral(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"%w[draft published]", []string{"draft", "published"}},
+		{"%i(low high).freeze", []string{"low", "high"}},
+		{"[:admin, :member]", []string{"admin", "member"}},
+		{`["a", 'b']`, []string{"a", "b"}},
+		{"[compute, :b]", nil},
+		{`["#{x}"]`, nil},
+		{"Set.new", nil},
+	}
+
+	for _, tt := range tests {
+		got := parseArrayLiteral(tt.expr)
+		if len(got) != len(tt.want) {
+			t.Errorf("parseArrayLiteral(%q) = %v, want %v", tt.expr, got, tt.want)
+			continue
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("parseArrayLiteral(%q) = %v, want %v", tt.expr, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestGhostMethodParsing(t *testing.T) {
+	content := `class Post
+  STATES = %w[draft published].freeze
+
+  STATES.each do |state|
+    define_method("#{state}?") do
+      self.state == state
+    end
+  end
+
+  [:created, :updated].each do |event|
+    define_method(:"#{event}_at") { timestamps[event] }
+  end
+
+  define_method(:summary) do
+    body.first(100)
+  end
+
+  def method_missing(name, *args)
+    if name.to_s.start_with?("find_by_")
+      find_by(name.to_s.delete_prefix("find_by_") => args.first)
+    else
+      super
+    end
+  end
+
+  def title
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/post.rb", []byte(content))
+
+	want := map[string]bool{ // FullName -> Synthetic
+		"Post#draft?":     true,
+		"Post#published?": true,
+		"Post#created_at": true,
+		"Post#updated_at": true,
+		"Post#summary":    false,
+		"Post#find_by_*":  true,
+		"Post#title":      false,
+	}
+
+	found := make(map[string]bool)
+	for _, sym := range symbols {
+		synthetic, ok := want[sym.FullName]
+		if !ok {
+			continue
+		}
+		found[sym.FullName] = true
+		if sym.Synthetic != synthetic {
+			t.Errorf("%s: expected Synthetic=%v", sym.FullName, synthetic)
+		}
+		if sym.Kind != types.KindMethod {
+			t.Errorf("%s: expected KindMethod, got %v", sym.FullName, sym.Kind)
+		}
+	}
+	for name := range want {
+		if !found[name] {
+			t.Errorf("expected symbol %s", name)
+		}
+	}
+}
+
+func TestGhostMethodIgnoresUnknownIteration(t *testing.T) {
+	content := `class Post
+  fields.each do |field|
+    define_method("#{field}_label") do
+    end
+  end
+
+  def title
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/post.rb", []byte(content))
+
+	for _, sym := range symbols {
+		if sym.Synthetic {
+			t.Errorf("unexpected synthetic symbol %s", sym.FullName)
+		}
+		if sym.Name == "title" && sym.FullName != "Post#title" {
+			t.Errorf("nesting broken: title has FullName %q", sym.FullName)
+		}
+	}
+}
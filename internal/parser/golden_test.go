@@ -0,0 +1,274 @@
+package parser
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// The golden corpus is a set of real-world shaped Ruby files under
+// testdata/corpus, laid out like a Rails app so path-gated matchers (specs,
+// steps, schema, deploy tasks, routes, factories) fire. Each file has a .yaml file beside it
+// listing the symbols the default matchers produce. Regenerate them after an
+// intended parser change with:
+//
+//	go test ./internal/parser -run TestGoldenCorpus -update
+var updateGolden = flag.Bool("update", false, "rewrite the golden corpus .yaml files")
+
+const corpusDir = "testdata/corpus"
+
+// corpusFile is a corpus source file and its content
+type corpusFile struct {
+	path    string // Relative to corpusDir, with forward slashes
+	content []byte
+}
+
+// loadCorpus reads every Ruby source file in the corpus
+func loadCorpus(t *testing.T) []corpusFile {
+	t.Helper()
+	var files []corpusFile
+	err := filepath.WalkDir(corpusDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) == ".yaml" {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(corpusDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, corpusFile{path: filepath.ToSlash(rel), content: content})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read corpus: %v", err)
+	}
+	if len(files) == 0 {
+		t.Fatal("corpus is empty")
+	}
+	return files
+}
+
+// parseCorpusFile parses a corpus file with the given registry. Files are
+// parsed as if they lived under /corpus so path checks see the app layout.
+func parseCorpusFile(registry *Registry, f corpusFile) []*types.Symbol {
+	return NewScanner(registry).Parse("/corpus/"+f.path, f.content)
+}
+
+// defaultMatcherNames returns the names of the default matchers in
+// priority order
+func defaultMatcherNames() []string {
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	var names []string
+	for _, m := range registry.Matchers() {
+		names = append(names, m.Name())
+	}
+	return names
+}
+
+// registryWithout returns the default registry with the named matchers disabled
+func registryWithout(names ...string) *Registry {
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	registry.SetDisabled(names)
+	return registry
+}
+
+// goldenYAML renders symbols as the YAML stored in the golden files. Only
+// fields that carry information are written, so the files stay readable.
+func goldenYAML(symbols []*types.Symbol) string {
+	var b strings.Builder
+	if len(symbols) == 0 {
+		return "[]\n"
+	}
+	for _, sym := range symbols {
+		fmt.Fprintf(&b, "- name: %s\n", strconv.Quote(sym.Name))
+		fmt.Fprintf(&b, "  kind: %s\n", sym.Kind)
+		fmt.Fprintf(&b, "  full_name: %s\n", strconv.Quote(sym.FullName))
+		fmt.Fprintf(&b, "  line: %d\n", sym.Line)
+		fmt.Fprintf(&b, "  column: %d\n", sym.Column)
+		if sym.EndLine != 0 {
+			fmt.Fprintf(&b, "  end_line: %d\n", sym.EndLine)
+		}
+		if sym.MethodFullName != "" {
+			fmt.Fprintf(&b, "  method: %s\n", strconv.Quote(sym.MethodFullName))
+		}
+		if sym.TargetName != "" {
+			fmt.Fprintf(&b, "  target: %s\n", strconv.Quote(sym.TargetName))
+		}
+		if sym.TypeName != "" {
+			fmt.Fprintf(&b, "  type: %s\n", strconv.Quote(sym.TypeName))
+		}
+		if sym.RelationType != "" {
+			fmt.Fprintf(&b, "  relation: %s\n", sym.RelationType)
+		}
+		if sym.Macro != "" {
+			fmt.Fprintf(&b, "  macro: %s\n", strconv.Quote(sym.Macro))
+		}
+		if sym.Superclass != "" {
+			fmt.Fprintf(&b, "  superclass: %s\n", strconv.Quote(sym.Superclass))
+		}
+		if sym.Refines != "" {
+			fmt.Fprintf(&b, "  refines: %s\n", strconv.Quote(sym.Refines))
+		}
+		if len(sym.Includes) > 0 {
+			quoted := make([]string, len(sym.Includes))
+			for i, name := range sym.Includes {
+				quoted[i] = strconv.Quote(name)
+			}
+			fmt.Fprintf(&b, "  includes: [%s]\n", strings.Join(quoted, ", "))
+		}
+		if sym.Visibility != types.VisibilityPublic {
+			fmt.Fprintf(&b, "  visibility: %s\n", sym.Visibility)
+		}
+		if sym.Synthetic {
+			b.WriteString("  synthetic: true\n")
+		}
+	}
+	return b.String()
+}
+
+func TestGoldenCorpus(t *testing.T) {
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+
+	for _, f := range loadCorpus(t) {
+		t.Run(f.path, func(t *testing.T) {
+			got := goldenYAML(parseCorpusFile(registry, f))
+			golden := filepath.Join(corpusDir, filepath.FromSlash(f.path)) + ".yaml"
+
+			if *updateGolden {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatalf("failed to write %s: %v", golden, err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing golden file (run with -update): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("symbols differ from %s (run with -update if intended)\n%s", golden, firstDiff(string(want), got))
+			}
+		})
+	}
+}
+
+// TestGoldenCorpusMatcherCombinations parses the corpus with each default
+// matcher disabled in turn, and with each enabled on its own. Every
+// combination must produce symbols with sane positions, and disabling a
+// matcher that doesn't track scope must only ever remove symbols.
+func TestGoldenCorpusMatcherCombinations(t *testing.T) {
+	files := loadCorpus(t)
+	names := defaultMatcherNames()
+
+	full := make(map[string]map[string]bool, len(files))
+	for _, f := range files {
+		full[f.path] = symbolKeys(parseCorpusFile(registryWithout(), f))
+	}
+
+	for _, name := range names {
+		t.Run("without_"+name, func(t *testing.T) {
+			registry := registryWithout(name)
+			for _, f := range files {
+				symbols := parseCorpusFile(registry, f)
+				checkPositions(t, f, symbols)
+				if scopeMatchers[name] {
+					continue
+				}
+				for key := range symbolKeys(symbols) {
+					if !full[f.path][key] {
+						t.Errorf("%s: disabling %s produced new symbol %s", f.path, name, key)
+					}
+				}
+			}
+		})
+
+		t.Run("only_"+name, func(t *testing.T) {
+			var others []string
+			for _, other := range names {
+				if other != name {
+					others = append(others, other)
+				}
+			}
+			registry := registryWithout(others...)
+			for _, f := range files {
+				checkPositions(t, f, parseCorpusFile(registry, f))
+			}
+		})
+	}
+}
+
+// scopeMatchers open or close scopes, so disabling one legitimately changes
+// the full names of the symbols around it
+var scopeMatchers = map[string]bool{
+	"class":      true,
+	"module":     true,
+	"concerning": true,
+	"refine":     true,
+	"route":      true,
+	"block":      true,
+	"do":         true,
+	"end":        true,
+}
+
+// symbolKeys identifies symbols by position, kind, and full name
+func symbolKeys(symbols []*types.Symbol) map[string]bool {
+	keys := make(map[string]bool, len(symbols))
+	for _, sym := range symbols {
+		keys[fmt.Sprintf("%d:%d:%s:%s", sym.Line, sym.Column, sym.Kind, sym.FullName)] = true
+	}
+	return keys
+}
+
+// checkPositions reports symbols whose name, line, or column don't fit the file
+func checkPositions(t *testing.T, f corpusFile, symbols []*types.Symbol) {
+	t.Helper()
+	lines := strings.Split(string(f.content), "\n")
+	for _, sym := range symbols {
+		if sym.Name == "" {
+			t.Errorf("%s:%d: symbol with empty name", f.path, sym.Line)
+			continue
+		}
+		if sym.Line < 1 || sym.Line > len(lines) {
+			t.Errorf("%s: %s on line %d outside the file", f.path, sym.FullName, sym.Line)
+			continue
+		}
+		if sym.Column < 0 || sym.Column > len(lines[sym.Line-1]) {
+			t.Errorf("%s:%d: %s at column %d outside the line", f.path, sym.Line, sym.FullName, sym.Column)
+		}
+		if sym.EndLine != 0 && sym.EndLine < sym.Line {
+			t.Errorf("%s:%d: %s ends on line %d before it starts", f.path, sym.Line, sym.FullName, sym.EndLine)
+		}
+	}
+}
+
+// firstDiff describes the first line where got departs from want
+func firstDiff(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want: %s\n   got: %s", i+1, w, g)
+		}
+	}
+	return ""
+}
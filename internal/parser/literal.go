@@ -0,0 +1,208 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// %w[admin member], %i(draft published)
+var percentArrayPattern = regexp.MustCompile(`^%[wWiI]([\[\(\{<])(.*?)[\]\)\}>]`)
+
+// [:admin, :member] or ["admin", 'member']
+var bracketArrayPattern = regexp.MustCompile(`^\[([^\[\]]*)\]`)
+
+// :admin, "admin", 'admin'
+var literalElementPattern = regexp.MustCompile(`^(?::(\w+[?!]?)|"([^"#]*)"|'([^']*)')$`)
+
+// parseArrayLiteral extracts the elements of a simple array literal of words,
+// symbols, or strings. Returns nil if expr does not start with such a literal.
+func parseArrayLiteral(expr string) []string {
+	expr = strings.TrimSpace(expr)
+
+	if match := percentArrayPattern.FindStringSubmatch(expr); match != nil {
+		values := strings.Fields(match[2])
+		if len(values) == 0 {
+			return nil
+		}
+		return values
+	}
+
+	match := bracketArrayPattern.FindStringSubmatch(expr)
+	if match == nil {
+		return nil
+	}
+
+	var values []string
+	for _, elem := range strings.Split(match[1], ",") {
+		elem = strings.TrimSpace(elem)
+		if elem == "" {
+			continue
+		}
+		em := literalElementPattern.FindStringSubmatch(elem)
+		if em == nil {
+			// Not a plain literal (e.g., a method call or interpolation)
+			return nil
+		}
+		values = append(values, em[1]+em[2]+em[3])
+	}
+	return values
+}
+
+// stripComment drops a trailing comment from a line, along with the
+// whitespace before it. A # inside a quoted string ("#{id}", 'a#b') isn't
+// a comment.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return line
+}
+
+// MaskStrings blanks out the contents of quoted strings and a trailing
+// comment, keeping the line's length so columns still line up. Keywords
+// inside them ("things to do", # do this later) then don't look like code.
+// A quote left open at the end of the line is more likely part of a regexp
+// (/"([^"]*)"$/) than a string, so it's left as is.
+func MaskStrings(line string) string {
+	masked := []byte(line)
+	var quote byte
+	opened := 0
+	for i := 0; i < len(masked); i++ {
+		c := masked[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && i+1 < len(masked) {
+				masked[i], masked[i+1] = ' ', ' '
+				i++
+			} else if c == quote {
+				quote = 0
+			} else {
+				masked[i] = ' '
+			}
+		case c == '"' || c == '\'':
+			quote, opened = c, i
+		case c == '#':
+			for j := i; j < len(masked); j++ {
+				masked[j] = ' '
+			}
+			return string(masked)
+		}
+	}
+	if quote != 0 {
+		copy(masked[opened:], line[opened:])
+	}
+	return string(masked)
+}
+
+// <<~SQL, <<-EOS, <<HTML, <<~'TEXT': a heredoc, whose body starts on the
+// next line. Identifiers must be uppercase, so class << self and
+// items <<value aren't taken for one.
+var heredocPattern = regexp.MustCompile("<<[~-]?(?:([A-Z_][A-Z0-9_]*)|(['\"`])([A-Za-z_]\\w*)['\"`])")
+
+// heredocTerminators returns the identifiers closing the heredocs a line
+// opens, in the order their bodies follow it. One never closed in next,
+// the lines after it, is left out: more likely it's still being typed.
+func heredocTerminators(line string, next []string) []string {
+	code := stripComment(line)
+	masked := MaskStrings(code)
+	var terminators []string
+	for _, loc := range heredocPattern.FindAllStringSubmatchIndex(code, -1) {
+		if masked[loc[0]:loc[0]+2] != "<<" {
+			continue // Inside a string
+		}
+		terminator := submatch(code, loc, 1)
+		if terminator == "" {
+			terminator = submatch(code, loc, 3)
+		}
+		for _, l := range next {
+			if strings.TrimSpace(l) == terminator {
+				terminators = append(terminators, terminator)
+				break
+			}
+		}
+	}
+	return terminators
+}
+
+// openString returns the quote of a string literal left open at the end of
+// line, which continues on the following lines, or 0. A quote right after
+// a name, a closing bracket, or regexp punctuation doesn't start a string
+// (/"([^"]*)"$/, ?", $"), so it isn't taken to open one.
+func openString(line string) byte {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || !isStringNeighbor(line[i-1]) {
+				quote = c
+			}
+		case c == '#':
+			return 0
+		}
+	}
+	return quote
+}
+
+// isStringNeighbor reports whether c, just before a quote, shows the quote
+// isn't the start of a string literal
+func isStringNeighbor(c byte) bool {
+	return isWordByte(c) || strings.IndexByte(`)]}/^\$?|*`, c) >= 0
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// maxStringLines bounds how far ahead continuedString looks for the end of
+// a string
+const maxStringLines = 100
+
+// continuedString reports whether a string opened with quote at the end of
+// a line goes on in the lines after it, ending at a quote that leaves the
+// rest of its line balanced (text".freeze, text", class: "note")). A quote
+// that never closes that way is more likely a string still being typed
+// (gem "ra) than the start of a multi-line one.
+func continuedString(next []string, quote byte) bool {
+	for i, line := range next {
+		if i == maxStringLines {
+			return false
+		}
+		if end := closingQuote(line, quote); end >= 0 {
+			return strings.Count(line[end+1:], string(quote))%2 == 0
+		}
+	}
+	return false
+}
+
+// closingQuote returns the column of the quote ending a string continued
+// from an earlier line, or -1 when it continues past this one too
+func closingQuote(line string, quote byte) int {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+		} else if line[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
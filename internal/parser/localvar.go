@@ -16,8 +16,27 @@ var (
 	// Multiple assignment: x, y = 1, 2
 	multiAssignPattern = regexp.MustCompile(`^\s*([a-z_][a-z0-9_]*(?:\s*,\s*[a-z_][a-z0-9_]*)+)\s*=`)
 
-	// Pattern to detect comparison operators (==, ===, =~)
-	comparisonPattern = regexp.MustCompile(`^\s*[a-z_][a-z0-9_]*\s*(?:={2,3}|=~)`)
+	// Instance variable assignment: @user = User.new / @user ||= User.find(id)
+	ivarAssignPattern = regexp.MustCompile(`^\s*(@[a-z_]\w*)\s*(?:\|\|)?=\s*([^=~>].*)`)
+
+	// A single name in a multiple assignment
+	localNamePattern = regexp.MustCompile(`[a-z_][a-z0-9_]*`)
+
+	// Pattern to detect comparison operators (==, ===, =~) and hash rockets
+	// or rightward assignment (rescue => e, value => Integer)
+	comparisonPattern = regexp.MustCompile(`^\s*[a-z_][a-z0-9_]*\s*(?:={2,3}|=~|=>)`)
+
+	// A lambda or proc literal and its parameters: ->(event) {, -> x do,
+	// lambda { |event|, proc do |a, b|, Proc.new { |x|
+	stabbyLambdaPattern = regexp.MustCompile(`^->\s*(?:\(([^)]*)\)|([^{(]*?))\s*(?:\{|do\b)`)
+	blockLambdaPattern  = regexp.MustCompile(`^(?:lambda|proc|Proc\.new)\s*(?:\{|do\b)\s*(?:\|([^|]*)\|)?`)
+
+	// The name at the start of a parameter: *args, &block, key:, opt = 1
+	paramNamePattern = regexp.MustCompile(`^[*&]*([a-z_]\w*)`)
+
+	// A value whose class is evident from the call: User.new, Order.find(id),
+	// Billing::Invoice.find_by!(number: n)
+	typedValuePattern = regexp.MustCompile(`^((?:::)?[A-Z]\w*(?:::[A-Z]\w*)*)\.(?:new|find|find_by!?|create!?|first!?|last!?|take!?|find_or_create_by!?|find_or_initialize_by)(?:[\s(]|$)`)
 )
 
 // LocalVariableMatcher extracts local variable assignments inside methods
@@ -32,55 +51,158 @@ func (m *LocalVariableMatcher) Match(line string, ctx *ParseContext) *MatchResul
 		return nil
 	}
 
-	// Skip comparison operators (==, ===, =~)
+	// Skip comparison operators (==, ===, =~) and =>
 	if comparisonPattern.MatchString(line) {
 		return nil
 	}
 
 	// Try multiple assignment first (more specific pattern)
-	if match := multiAssignPattern.FindStringSubmatch(line); match != nil {
-		return m.handleMultiAssign(match[1], line, ctx)
+	if loc := multiAssignPattern.FindStringSubmatchIndex(line); loc != nil {
+		return m.handleMultiAssign(loc[2], line[loc[2]:loc[3]], line[loc[1]:], line, ctx)
 	}
 
 	// Try single assignment
-	if match := singleAssignPattern.FindStringSubmatch(line); match != nil {
-		return m.handleSingleAssign(match[1], line, ctx)
+	if loc := singleAssignPattern.FindStringSubmatchIndex(line); loc != nil {
+		return m.handleSingleAssign(loc[2], line[loc[2]:loc[3]], line[loc[1]:], line, ctx)
+	}
+
+	if loc := ivarAssignPattern.FindStringSubmatchIndex(line); loc != nil {
+		return m.handleIvarAssign(loc[2], line[loc[2]:loc[3]], line[loc[4]:loc[5]], line, ctx)
 	}
 
 	return nil
 }
 
-func (m *LocalVariableMatcher) handleSingleAssign(varName, line string, ctx *ParseContext) *MatchResult {
-	col := strings.Index(line, varName)
+// handleIvarAssign indexes an instance variable assigned a value whose class
+// is evident (@user = User.new), so it can lead to that class. Other
+// instance variable assignments aren't indexed.
+func (m *LocalVariableMatcher) handleIvarAssign(col int, varName, value, line string, ctx *ParseContext) *MatchResult {
+	typeName := valueType(value)
+	if typeName == "" {
+		return nil
+	}
 
 	sym := &types.Symbol{
 		Name:           varName,
-		Kind:           types.KindLocalVariable,
+		Kind:           types.KindInstanceVariable,
 		FilePath:       ctx.FilePath,
 		Line:           ctx.LineNum,
 		Column:         col,
 		Scope:          append([]string{}, ctx.CurrentScope...),
 		MethodFullName: ctx.CurrentMethod.FullName,
+		TypeName:       typeName,
 	}
 	sym.FullName = sym.ComputeFullName()
 
 	return &MatchResult{
-		Symbols: []*types.Symbol{sym},
+		Symbols:    []*types.Symbol{sym},
+		OpensBlock: assignmentOpensBlock(line),
 	}
 }
 
-func (m *LocalVariableMatcher) handleMultiAssign(varList, line string, ctx *ParseContext) *MatchResult {
-	// Parse comma-separated variable names
-	vars := strings.Split(varList, ",")
-	var symbols []*types.Symbol
+func (m *LocalVariableMatcher) handleSingleAssign(col int, varName, value, line string, ctx *ParseContext) *MatchResult {
+	sym := &types.Symbol{
+		Name:           varName,
+		Kind:           types.KindLocalVariable,
+		FilePath:       ctx.FilePath,
+		Line:           ctx.LineNum,
+		Column:         col,
+		Scope:          append([]string{}, ctx.CurrentScope...),
+		MethodFullName: ctx.CurrentMethod.FullName,
+		TypeName:       valueType(value),
+	}
+	sym.FullName = sym.ComputeFullName()
+
+	result := &MatchResult{
+		Symbols:    []*types.Symbol{sym},
+		OpensBlock: assignmentOpensBlock(line),
+	}
 
-	for _, v := range vars {
-		varName := strings.TrimSpace(v)
-		if varName == "" {
-			continue
+	// A lambda's parameters, and locals assigned in a multi-line body, are
+	// scoped to the lambda: their MethodFullName is the lambda's FullName
+	if params, ok := lambdaParams(value, len(line)-len(value)); ok {
+		for _, p := range params {
+			param := &types.Symbol{
+				Name:           p.name,
+				Kind:           types.KindLocalVariable,
+				FilePath:       ctx.FilePath,
+				Line:           ctx.LineNum,
+				Column:         p.column,
+				Scope:          append([]string{}, ctx.CurrentScope...),
+				MethodFullName: sym.FullName,
+			}
+			param.FullName = param.ComputeFullName()
+			result.Symbols = append(result.Symbols, param)
 		}
+		if result.OpensBlock {
+			result.EnterMethod = &MethodContext{FullName: sym.FullName, StartLine: ctx.LineNum}
+		} else {
+			sym.EndLine = ctx.LineNum
+		}
+	}
+	return result
+}
+
+// lambdaParam is a lambda parameter and its column in the line
+type lambdaParam struct {
+	name   string
+	column int
+}
+
+// lambdaParams returns the parameters of a lambda or proc literal starting
+// value, which starts at column offset, and whether value is one
+func lambdaParams(value string, offset int) ([]lambdaParam, bool) {
+	trimmed := strings.TrimLeft(value, " \t")
+	offset += len(value) - len(trimmed)
 
-		col := strings.Index(line, varName)
+	var loc []int
+	if loc = stabbyLambdaPattern.FindStringSubmatchIndex(trimmed); loc != nil {
+		if loc[2] < 0 {
+			loc = loc[2:] // -> x { without parentheses
+		}
+	} else if loc = blockLambdaPattern.FindStringSubmatchIndex(trimmed); loc == nil {
+		return nil, false
+	}
+	start, end := loc[2], loc[3]
+	if start < 0 {
+		return nil, true
+	}
+
+	var params []lambdaParam
+	for pos := start; pos < end; {
+		next := strings.IndexByte(trimmed[pos:end], ',')
+		if next < 0 {
+			next = end - pos
+		}
+		part := trimmed[pos : pos+next]
+		lead := len(part) - len(strings.TrimLeft(part, " \t"))
+		if m := paramNamePattern.FindStringSubmatchIndex(part[lead:]); m != nil {
+			params = append(params, lambdaParam{
+				name:   part[lead+m[2] : lead+m[3]],
+				column: offset + pos + lead + m[2],
+			})
+		}
+		pos += next + 1
+	}
+	return params, true
+}
+
+// handleMultiAssign indexes each name in varList, which starts at column
+// start of line. When the right side lists one value per name, each name
+// gets the type of its value.
+func (m *LocalVariableMatcher) handleMultiAssign(start int, varList, value, line string, ctx *ParseContext) *MatchResult {
+	var symbols []*types.Symbol
+
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		value = value[1 : len(value)-1]
+	}
+	values := splitArguments(value)
+
+	names := localNamePattern.FindAllStringIndex(varList, -1)
+	for i, loc := range names {
+		varName := varList[loc[0]:loc[1]]
+		col := start + loc[0]
 
 		sym := &types.Symbol{
 			Name:           varName,
@@ -91,6 +213,9 @@ func (m *LocalVariableMatcher) handleMultiAssign(varList, line string, ctx *Pars
 			Scope:          append([]string{}, ctx.CurrentScope...),
 			MethodFullName: ctx.CurrentMethod.FullName,
 		}
+		if len(values) == len(names) {
+			sym.TypeName = valueType(values[i])
+		}
 		sym.FullName = sym.ComputeFullName()
 
 		symbols = append(symbols, sym)
@@ -101,6 +226,16 @@ func (m *LocalVariableMatcher) handleMultiAssign(varList, line string, ctx *Pars
 	}
 
 	return &MatchResult{
-		Symbols: symbols,
+		Symbols:    symbols,
+		OpensBlock: assignmentOpensBlock(line),
+	}
+}
+
+// valueType returns the class of an assigned value when the expression
+// names it (User for User.find(id)), or "" when it can't be told
+func valueType(value string) string {
+	if m := typedValuePattern.FindStringSubmatch(strings.TrimSpace(value)); m != nil {
+		return m[1]
 	}
+	return ""
 }
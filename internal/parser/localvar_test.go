@@ -243,3 +243,141 @@ end`
 		}
 	}
 }
+
+func TestLocalVariableTypeHints(t *testing.T) {
+	content := `class OrdersController
+  def show
+    user = User.new
+    order = Order.find(params[:id])
+    invoice = Billing::Invoice.find_by!(number: order.number)
+    scope = Order.where(state: "open")
+    total = order.total
+    buyer, seller = User.find(1), ::Account.create(name: "x")
+    first, second = [Order.first, Order.last]
+    left, right = method_returning_pair
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/orders_controller.rb", []byte(content))
+
+	hints := make(map[string]string)
+	for _, sym := range symbols {
+		if sym.Kind == types.KindLocalVariable {
+			hints[sym.Name] = sym.TypeName
+		}
+	}
+
+	want := map[string]string{
+		"user":    "User",
+		"order":   "Order",
+		"invoice": "Billing::Invoice",
+		"scope":   "",
+		"total":   "",
+		"buyer":   "User",
+		"seller":  "::Account",
+		"first":   "Order",
+		"second":  "Order",
+		"left":    "",
+		"right":   "",
+	}
+	for name, typ := range want {
+		got, ok := hints[name]
+		if !ok {
+			t.Errorf("local %s not indexed", name)
+		} else if got != typ {
+			t.Errorf("%s: expected type %q, got %q", name, typ, got)
+		}
+	}
+}
+
+func TestInstanceVariableTypes(t *testing.T) {
+	content := `class OrdersController
+  def show
+    @order = Order.find(params[:id])
+    @user ||= User.new
+    @count = 0
+    @order == other
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/orders_controller.rb", []byte(content))
+
+	var ivars []*types.Symbol
+	for _, sym := range symbols {
+		if sym.Kind == types.KindInstanceVariable {
+			ivars = append(ivars, sym)
+		}
+	}
+	if len(ivars) != 2 {
+		t.Fatalf("expected the typed @order and @user, got %+v", ivars)
+	}
+	if ivars[0].FullName != "OrdersController#@order" || ivars[0].TypeName != "Order" || ivars[0].Column != 4 {
+		t.Errorf("expected OrdersController#@order of type Order, got %+v", ivars[0])
+	}
+	if ivars[1].Name != "@user" || ivars[1].TypeName != "User" {
+		t.Errorf("expected @user of type User, got %+v", ivars[1])
+	}
+}
+
+func TestLambdaLocals(t *testing.T) {
+	content := `class Dispatcher
+  def run(events)
+    handler = ->(event) { process(event) }
+    logger = lambda do |message, level = :info|
+      line = format(message)
+      puts line
+    end
+    filter = -> (e, *rest, key:, &blk) {
+      kept = e.ok?
+    }
+    square = proc { |x| x * x }
+    total = 0
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/dispatcher.rb", []byte(content))
+
+	type local struct {
+		line, column, endLine int
+	}
+	want := map[string]local{
+		"Dispatcher#run@handler":        {3, 4, 3},
+		"Dispatcher#run@handler@event":  {3, 17, 0},
+		"Dispatcher#run@logger":         {4, 4, 7},
+		"Dispatcher#run@logger@message": {4, 24, 0},
+		"Dispatcher#run@logger@level":   {4, 33, 0},
+		"Dispatcher#run@logger@line":    {5, 6, 0},
+		"Dispatcher#run@filter":         {8, 4, 10},
+		"Dispatcher#run@filter@e":       {8, 17, 0},
+		"Dispatcher#run@filter@rest":    {8, 21, 0},
+		"Dispatcher#run@filter@key":     {8, 27, 0},
+		"Dispatcher#run@filter@blk":     {8, 34, 0},
+		"Dispatcher#run@filter@kept":    {9, 6, 0},
+		"Dispatcher#run@square":         {11, 4, 11},
+		"Dispatcher#run@square@x":       {11, 21, 0},
+		"Dispatcher#run@total":          {12, 4, 0},
+	}
+	got := map[string]local{}
+	for _, sym := range symbols {
+		if sym.Kind == types.KindLocalVariable {
+			got[sym.FullName] = local{sym.Line, sym.Column, sym.EndLine}
+		}
+		if sym.FullName == "Dispatcher#run" && sym.EndLine != 13 {
+			t.Errorf("expected run to end at line 13, got %d", sym.EndLine)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %d locals, got %v", len(want), got)
+	}
+	for fullName, w := range want {
+		if got[fullName] != w {
+			t.Errorf("%s: expected %+v, got %+v", fullName, w, got[fullName])
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// # frozen_string_literal: true / # encoding: utf-8 / # typed: strict
+var magicCommentPattern = regexp.MustCompile(`^#\s*([\w-]+)\s*:\s*([\w.-]+)\s*$`)
+
+// # -*- coding: utf-8; frozen_string_literal: true -*- (Emacs style)
+var emacsMagicCommentPattern = regexp.MustCompile(`^#.*?-\*-(.*)-\*-`)
+
+// MagicComments are the directives in a file's prelude: the shebang and
+// the comments before its first line of code
+type MagicComments struct {
+	Shebang             string // Interpreter command (/usr/bin/env ruby)
+	Encoding            string // Source encoding (utf-8), from the first line or the one after a shebang
+	FrozenStringLiteral string // "true" or "false" when set
+	Typed               string // Sorbet strictness sigil (false, true, strict, strong)
+	Lines               int    // Lines the prelude spans, blank lines included
+}
+
+// ReadMagicComments reads the magic comments in content's prelude
+func ReadMagicComments(content []byte) MagicComments {
+	return readPrelude(strings.Split(string(content), "\n"))
+}
+
+// readPrelude consumes the shebang, comments, and blank lines at the top of
+// a file, recording the magic comments among them. None of these lines
+// can define anything, so the scanner starts after them.
+func readPrelude(lines []string) MagicComments {
+	var magic MagicComments
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case i == 0 && strings.HasPrefix(trimmed, "#!"):
+			magic.Shebang = strings.TrimSpace(trimmed[2:])
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			// Ruby reads the encoding from the first line, or the second
+			// after a shebang
+			encodingLine := i == 0 || (i == 1 && magic.Shebang != "")
+			for key, value := range magicDirectives(trimmed) {
+				magic.set(key, value, encodingLine)
+			}
+		default:
+			magic.Lines = i
+			return magic
+		}
+	}
+	magic.Lines = len(lines)
+	return magic
+}
+
+// magicDirectives parses the key: value pairs of a comment, normalizing
+// keys to lowercase with underscores (Frozen-String-Literal →
+// frozen_string_literal)
+func magicDirectives(comment string) map[string]string {
+	var pairs []string
+	if m := emacsMagicCommentPattern.FindStringSubmatch(comment); m != nil {
+		pairs = strings.Split(m[1], ";")
+	} else if magicCommentPattern.MatchString(comment) {
+		pairs = []string{strings.TrimSpace(strings.TrimPrefix(comment, "#"))}
+	}
+
+	directives := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "-", "_")
+		directives[key] = strings.TrimSpace(value)
+	}
+	return directives
+}
+
+// set records a directive the scanner knows; others are ignored
+func (m *MagicComments) set(key, value string, encodingLine bool) {
+	switch key {
+	case "encoding", "coding":
+		if encodingLine {
+			m.Encoding = strings.ToLower(value)
+		}
+	case "frozen_string_literal":
+		m.FrozenStringLiteral = strings.ToLower(value)
+	case "typed":
+		m.Typed = value
+	}
+}
@@ -0,0 +1,75 @@
+package parser
+
+import "testing"
+
+func TestReadMagicComments(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    MagicComments
+	}{
+		{
+			"frozen string literal",
+			"# frozen_string_literal: true\n\nclass User\nend",
+			MagicComments{FrozenStringLiteral: "true", Lines: 2},
+		},
+		{
+			"shebang and encoding",
+			"#!/usr/bin/env ruby\n# encoding: UTF-8\n# frozen_string_literal: false\nputs 1",
+			MagicComments{Shebang: "/usr/bin/env ruby", Encoding: "utf-8", FrozenStringLiteral: "false", Lines: 3},
+		},
+		{
+			"emacs style",
+			"# -*- coding: utf-8; Frozen-String-Literal: true -*-\nmodule Billing\nend",
+			MagicComments{Encoding: "utf-8", FrozenStringLiteral: "true", Lines: 1},
+		},
+		{
+			"sorbet sigil after a comment",
+			"# Billing entry point\n# typed: strict\nmodule Billing\nend",
+			MagicComments{Typed: "strict", Lines: 2},
+		},
+		{
+			"encoding past the second line",
+			"# frozen_string_literal: true\n# typed: true\n# encoding: ascii\nx = 1",
+			MagicComments{FrozenStringLiteral: "true", Typed: "true", Lines: 3},
+		},
+		{
+			"after the first line of code",
+			"require \"json\"\n# frozen_string_literal: true",
+			MagicComments{},
+		},
+		{
+			"comments only",
+			"# typed: false\n",
+			MagicComments{Typed: "false", Lines: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReadMagicComments([]byte(tt.content)); got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPreludeSkipped(t *testing.T) {
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/app/bin/setup.rb", []byte(`#!/usr/bin/env ruby
+# frozen_string_literal: true
+# typed: strict
+
+module Setup
+  def self.run
+  end
+end`))
+
+	if len(symbols) != 2 || symbols[0].FullName != "Setup" || symbols[0].Line != 5 || symbols[0].EndLine != 8 {
+		t.Fatalf("expected Setup on lines 5-8, got %+v", symbols)
+	}
+	if symbols[1].FullName != "Setup.run" || symbols[1].Line != 6 {
+		t.Errorf("expected Setup.run on line 6, got %+v", symbols[1])
+	}
+}
@@ -10,7 +10,15 @@ import (
 // def my_method
 // def my_method(args)
 // def self.my_class_method
-var methodPattern = regexp.MustCompile(`^\s*def\s+(self\.)?(\w+[?!=]?)`)
+// private def helper / memoize def total / sig { returns(String) }; def name
+//
+// Leading wrappers are method calls taking the definition as an argument:
+// a name followed by a space or parenthesis (so undef isn't un + def), or a
+// Sorbet sig block.
+var methodPattern = regexp.MustCompile(`^\s*((?:(?:sig\s*\{[^{}]*\}\s*;?|[a-z_]\w*[?!]?(?:\s+|\s*\())\s*)*)def\s+(self\.)?(\w+[?!=]?)`)
+
+// The wrappers that set a method's visibility: private def helper
+var visibilityWrapperPattern = regexp.MustCompile(`\b(private|protected|public|private_class_method|public_class_method)\b`)
 
 // MethodMatcher extracts method definitions
 type MethodMatcher struct{}
@@ -18,19 +26,38 @@ type MethodMatcher struct{}
 func (m *MethodMatcher) Name() string  { return "method" }
 func (m *MethodMatcher) Priority() int { return 90 }
 
+// StartsMultiline implements MultilineDetector for definitions whose
+// parameter list continues on the following lines:
+//
+//	def create_order(
+//	  customer:,
+//	  coupon: nil
+//	)
+func (m *MethodMatcher) StartsMultiline(line string) (bool, string, string) {
+	loc := methodPattern.FindStringIndex(line)
+	if loc == nil {
+		return false, "", ""
+	}
+	rest := MaskStrings(line[loc[1]:])
+	if strings.HasPrefix(strings.TrimLeft(rest, " \t"), "(") && strings.Count(rest, "(") > strings.Count(rest, ")") {
+		return true, "(", ")"
+	}
+	return false, "", ""
+}
+
 func (m *MethodMatcher) Match(line string, ctx *ParseContext) *MatchResult {
-	match := methodPattern.FindStringSubmatch(line)
-	if match == nil {
+	loc := methodPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
 		return nil
 	}
 
-	isSingleton := match[1] != "" // self.
-	methodName := match[2]
-
-	col := strings.Index(line, methodName)
+	wrappers := line[loc[2]:loc[3]]
+	selfDef := loc[4] >= 0 // self.
+	methodName := line[loc[6]:loc[7]]
+	col := loc[6]
 
 	kind := types.KindMethod
-	if isSingleton {
+	if selfDef || ctx.InSingletonClass {
 		kind = types.KindSingletonMethod
 	}
 
@@ -43,6 +70,9 @@ func (m *MethodMatcher) Match(line string, ctx *ParseContext) *MatchResult {
 		Scope:    append([]string{}, ctx.CurrentScope...),
 	}
 	sym.FullName = sym.ComputeFullName()
+	if params, ok := methodParams(line, loc[1]); ok {
+		sym.Options = map[string]string{"params": params}
+	}
 
 	return &MatchResult{
 		Symbols:    []*types.Symbol{sym},
@@ -52,5 +82,47 @@ func (m *MethodMatcher) Match(line string, ctx *ParseContext) *MatchResult {
 			StartLine: ctx.LineNum,
 			// NestingDepth will be set by scanner after OpensBlock is processed
 		},
+		SetVisibility: wrapperVisibility(wrappers, sym, selfDef),
+	}
+}
+
+// wrapperVisibility returns the visibility a wrapper like private def sets
+// on the method it defines, or nil. selfDef is set for def self.x, which
+// only the _class_method wrappers apply to (inside class << self, private
+// def x makes a class method private). It applies after any visibility
+// section the method is in, as `private :helper` would.
+func wrapperVisibility(wrappers string, sym *types.Symbol, selfDef bool) *VisibilityChange {
+	matches := visibilityWrapperPattern.FindAllString(wrappers, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	change := &VisibilityChange{Kinds: []types.SymbolKind{sym.Kind}, Names: []string{sym.Name}}
+	switch wrapper := matches[len(matches)-1]; {
+	case strings.HasSuffix(wrapper, "_class_method") != selfDef:
+		return nil // private def self.x and private_class_method def x don't apply
+	case strings.HasPrefix(wrapper, "private"):
+		change.Visibility = types.VisibilityPrivate
+	case wrapper == "protected":
+		change.Visibility = types.VisibilityProtected
+	default:
+		change.Visibility = types.VisibilityPublic
+	}
+	return change
+}
+
+// methodParams returns the parenthesized parameter list of a definition
+// whose name ends at column start, with the whitespace of a list spread
+// over several lines collapsed: (customer:, items:, coupon: nil). ok is
+// false when the parameters aren't in parentheses.
+func methodParams(line string, start int) (string, bool) {
+	open := start + len(line[start:]) - len(strings.TrimLeft(line[start:], " \t"))
+	if open >= len(line) || line[open] != '(' {
+		return "", false
+	}
+	args := callArguments(line, open+1)
+	for i, arg := range args {
+		args[i] = strings.Join(strings.Fields(arg), " ")
 	}
+	return "(" + strings.Join(args, ", ") + ")", true
 }
@@ -62,6 +62,40 @@ func TestMethodMatcher(t *testing.T) {
 			wantName: "valid?",
 			wantKind: types.KindSingletonMethod,
 		},
+		{
+			name:     "private def",
+			line:     "  private def helper(arg)",
+			wantName: "helper",
+			wantKind: types.KindMethod,
+		},
+		{
+			name:     "decorated method",
+			line:     "  memoize def total",
+			wantName: "total",
+			wantKind: types.KindMethod,
+		},
+		{
+			name:     "stacked wrappers",
+			line:     "  private_class_method memoize(def self.build",
+			wantName: "build",
+			wantKind: types.KindSingletonMethod,
+		},
+		{
+			name:     "sig block",
+			line:     "  sig { params(x: Integer).returns(String) }; def format(x)",
+			wantName: "format",
+			wantKind: types.KindMethod,
+		},
+		{
+			name:    "undef",
+			line:    "  undef helper",
+			wantNil: true,
+		},
+		{
+			name:    "def in a string",
+			line:    `  puts "def helper"`,
+			wantNil: true,
+		},
 		{
 			name:    "not a method",
 			line:    "class MyClass",
@@ -128,3 +162,51 @@ func TestMethodMatcherWithScope(t *testing.T) {
 		t.Errorf("expected EnterMethod.StartLine 5, got %d", result.EnterMethod.StartLine)
 	}
 }
+
+func TestMultilineMethodDefinition(t *testing.T) {
+	content := `class OrderService
+  def create_order(
+    customer:, # who's buying
+    items:,
+    coupon: nil,
+    note: "(gift)"
+  )
+    total = items.sum(&:price)
+  end
+
+  def cancel(order, reason = "n/a") = order.cancel!(reason)
+
+  def refresh
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/app/services/order_service.rb", []byte(content))
+
+	byName := make(map[string]*types.Symbol)
+	for _, sym := range symbols {
+		byName[sym.FullName] = sym
+	}
+
+	create := byName["OrderService#create_order"]
+	if create == nil {
+		t.Fatalf("expected create_order, got %+v", symbols)
+	}
+	if create.Line != 2 || create.Column != 6 || create.EndLine != 9 {
+		t.Errorf("expected create_order at 2:6 through line 9, got %d:%d through %d", create.Line, create.Column, create.EndLine)
+	}
+	if got, want := create.Options["params"], `(customer:, items:, coupon: nil, note: "(gift)")`; got != want {
+		t.Errorf("expected params %s, got %s", want, got)
+	}
+	if local := byName["OrderService#create_order@total"]; local == nil || local.Line != 8 {
+		t.Errorf("expected the body's local on line 8, got %+v", local)
+	}
+
+	if got := byName["OrderService#cancel"].Options["params"]; got != `(order, reason = "n/a")` {
+		t.Errorf("expected cancel's params, got %q", got)
+	}
+	if refresh := byName["OrderService#refresh"]; refresh == nil || refresh.Options["params"] != "" {
+		t.Errorf("expected refresh without params, got %+v", refresh)
+	}
+}
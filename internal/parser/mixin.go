@@ -0,0 +1,35 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// include Comparable
+// prepend Auditing::Hooks, Tracing
+var mixinPattern = regexp.MustCompile(`^\s*(?:include|prepend)\s*\(?\s*((?:::)?[A-Z]\w*(?:::[A-Z]\w*)*(?:\s*,\s*(?:::)?[A-Z]\w*(?:::[A-Z]\w*)*)*)\s*\)?\s*(?:#.*)?$`)
+
+// MixinMatcher records the modules a class or module mixes in with include
+// or prepend, for the type hierarchy. extend is left out: it adds to the
+// singleton class, not to the ancestors of instances.
+type MixinMatcher struct{}
+
+func (m *MixinMatcher) Name() string  { return "mixin" }
+func (m *MixinMatcher) Priority() int { return 84 }
+
+func (m *MixinMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	if len(ctx.CurrentScope) == 0 {
+		return nil
+	}
+
+	match := mixinPattern.FindStringSubmatch(line)
+	if match == nil {
+		return nil
+	}
+
+	var names []string
+	for _, name := range strings.Split(match[1], ",") {
+		names = append(names, strings.TrimSpace(name))
+	}
+	return &MatchResult{Includes: names}
+}
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMixinMatcher(t *testing.T) {
+	matcher := &MixinMatcher{}
+
+	tests := []struct {
+		name  string
+		line  string
+		scope []string
+		want  []string
+	}{
+		{"include", "  include Comparable", []string{"Money"}, []string{"Comparable"}},
+		{"prepend with several", "  prepend Auditing::Hooks, ::Tracing", []string{"Order"}, []string{"Auditing::Hooks", "::Tracing"}},
+		{"parenthesized", "  include(Enumerable)", []string{"List"}, []string{"Enumerable"}},
+		{"trailing comment", "  include Comparable # for sorting", []string{"Money"}, []string{"Comparable"}},
+		{"extend is not an ancestor", "  extend ActiveSupport::Concern", []string{"Auditable"}, nil},
+		{"top level", "include Helpers", nil, nil},
+		{"dynamic argument", "  include mod", []string{"Money"}, nil},
+		{"method call with include in name", "  includes(:items)", []string{"Order"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := matcher.Match(tt.line, &ParseContext{FilePath: "/test/test.rb", LineNum: 1, CurrentScope: tt.scope})
+			var got []string
+			if result != nil {
+				got = result.Includes
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMixinsAttachToEnclosingClass(t *testing.T) {
+	content := `module Billing
+  class Invoice < Record
+    include Comparable
+    prepend Auditing
+
+    included do
+      include Ignored
+    end
+
+    def compare
+      include Ignored
+    end
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/invoice.rb", []byte(content))
+
+	for _, sym := range symbols {
+		switch sym.FullName {
+		case "Billing::Invoice":
+			if strings.Join(sym.Includes, ",") != "Comparable,Auditing" {
+				t.Errorf("expected Comparable and Auditing, got %v", sym.Includes)
+			}
+		case "Billing":
+			if len(sym.Includes) != 0 {
+				t.Errorf("expected no mixins on Billing, got %v", sym.Includes)
+			}
+		}
+	}
+}
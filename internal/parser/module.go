@@ -18,22 +18,19 @@ func (m *ModuleMatcher) Name() string  { return "module" }
 func (m *ModuleMatcher) Priority() int { return 100 }
 
 func (m *ModuleMatcher) Match(line string, ctx *ParseContext) *MatchResult {
-	match := modulePattern.FindStringSubmatch(line)
-	if match == nil {
+	loc := modulePattern.FindStringSubmatchIndex(line)
+	if loc == nil {
 		return nil
 	}
 
-	moduleName := match[1]
-	col := strings.Index(line, moduleName)
+	moduleName := line[loc[2]:loc[3]]
 
 	// Handle nested module names
 	parts := strings.Split(moduleName, "::")
 	shortName := parts[len(parts)-1]
+	col := loc[3] - len(shortName)
 
-	scope := append([]string{}, ctx.CurrentScope...)
-	if len(parts) > 1 {
-		scope = append(scope, parts[:len(parts)-1]...)
-	}
+	scope := compactScope(ctx.CurrentScope, parts[:len(parts)-1])
 
 	sym := &types.Symbol{
 		Name:     shortName,
@@ -45,9 +42,29 @@ func (m *ModuleMatcher) Match(line string, ctx *ParseContext) *MatchResult {
 	}
 	sym.FullName = sym.ComputeFullName()
 
-	return &MatchResult{
+	result := &MatchResult{
 		Symbols:    []*types.Symbol{sym},
 		PushScope:  shortName,
 		OpensBlock: true,
 	}
+	if len(parts) > 1 {
+		result.ScopeParent = scope
+	}
+	return result
+}
+
+// compactScope returns the namespace a compact path (Admin::Users in
+// `class Admin::Users::Importer`) names from within scope. Ruby resolves the
+// path's first segment lexically, so when it names a module being defined
+// in scope the path continues from that module rather than repeating it:
+// inside `module Admin`, Admin::Users is Admin::Users, not Admin::Admin::Users.
+func compactScope(scope, path []string) []string {
+	if len(path) > 0 {
+		for i := len(scope) - 1; i >= 0; i-- {
+			if scope[i] == path[0] {
+				return append(append([]string{}, scope[:i]...), path...)
+			}
+		}
+	}
+	return append(append([]string{}, scope...), path...)
 }
@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"regexp"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// scope :recent, -> { order(created_at: :desc) }
+// scope(:active, lambda { where(active: true) })
+var namedScopePattern = regexp.MustCompile(`^\s*scope\s*\(?\s*:([a-z_]\w*[?!]?)\s*,`)
+
+// NamedScopeMatcher extracts ActiveRecord named scopes as class methods,
+// since that's how they're called (Order.recent)
+type NamedScopeMatcher struct{}
+
+func (m *NamedScopeMatcher) Name() string  { return "named_scope" }
+func (m *NamedScopeMatcher) Priority() int { return 85 }
+
+func (m *NamedScopeMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	if len(ctx.CurrentScope) == 0 {
+		return nil
+	}
+
+	loc := namedScopePattern.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return nil
+	}
+
+	name := line[loc[2]:loc[3]]
+	sym := &types.Symbol{
+		Name:     name,
+		Kind:     types.KindSingletonMethod,
+		FilePath: ctx.FilePath,
+		Line:     ctx.LineNum,
+		Column:   loc[2],
+		Scope:    append([]string{}, ctx.CurrentScope...),
+		Macro:    "scope",
+	}
+	sym.FullName = sym.ComputeFullName()
+
+	// A multi-line `-> do ... end` body needs its end tracked
+	return &MatchResult{
+		Symbols:    []*types.Symbol{sym},
+		OpensBlock: opensDoBlock(line),
+	}
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestNamedScopeMatcher(t *testing.T) {
+	matcher := &NamedScopeMatcher{}
+
+	tests := []struct {
+		name      string
+		line      string
+		scope     []string
+		wantMatch bool
+		wantName  string
+	}{
+		{"stabby lambda", "  scope :recent, -> { order(created_at: :desc) }", []string{"Order"}, true, "recent"},
+		{"parenthesized lambda", "  scope(:active, lambda { where(active: true) })", []string{"User"}, true, "active"},
+		{"predicate name", "  scope :paid?, -> { where(paid: true) }", []string{"Order"}, true, "paid?"},
+		{"outside class", "scope :recent, -> { all }", nil, false, ""},
+		{"dynamic name", "  scope name, -> { all }", []string{"Order"}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &ParseContext{FilePath: "/test/model.rb", CurrentScope: tt.scope, LineNum: 3}
+			result := matcher.Match(tt.line, ctx)
+
+			if !tt.wantMatch {
+				if result != nil {
+					t.Errorf("expected no match, got %+v", result)
+				}
+				return
+			}
+			if result == nil || len(result.Symbols) != 1 {
+				t.Fatalf("expected 1 symbol, got %+v", result)
+			}
+			sym := result.Symbols[0]
+			if sym.Name != tt.wantName {
+				t.Errorf("expected Name %q, got %q", tt.wantName, sym.Name)
+			}
+			if sym.Kind != types.KindSingletonMethod {
+				t.Errorf("expected KindSingletonMethod, got %v", sym.Kind)
+			}
+		})
+	}
+}
+
+func TestNamedScopeWithDoBlock(t *testing.T) {
+	content := `class Order
+  scope :recent, -> do
+    order(:created_at)
+  end
+
+  def total
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/order.rb", []byte(content))
+
+	found := map[string]bool{}
+	for _, sym := range symbols {
+		found[sym.FullName] = true
+	}
+	for _, want := range []string{"Order.recent", "Order#total"} {
+		if !found[want] {
+			t.Errorf("expected symbol %s, got %v", want, found)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"sort"
+	"sync"
 
 	"github.com/jarredhawkins/goruby-lsp/internal/types"
 )
@@ -11,14 +12,30 @@ type MethodContext struct {
 	FullName     string // "MyClass#method_name"
 	StartLine    int    // Line where method definition starts
 	NestingDepth int    // Nesting depth when method started (set by scanner)
+	// Parent is the method a lambda body is nested in, restored when the
+	// lambda closes (set by scanner, nil for methods)
+	Parent *MethodContext
+}
+
+// IterationContext tracks a block iterating over known literal values,
+// e.g. `%w[draft published].each do |state|`
+type IterationContext struct {
+	Var          string   // Block parameter bound to each value
+	Values       []string // Literal values being iterated
+	NestingDepth int      // Nesting depth when the block started (set by scanner)
 }
 
 // ParseContext provides context for matching
 type ParseContext struct {
-	FilePath      string         // Absolute path of the file being parsed
-	CurrentScope  []string       // Current namespace stack ["MyModule", "MyClass"]
-	LineNum       int            // Current line number (1-indexed)
-	CurrentMethod *MethodContext // Current method being parsed (nil if not in a method)
+	FilePath         string              // Absolute path of the file being parsed
+	CurrentScope     []string            // Current namespace stack ["MyModule", "MyClass"]
+	LineNum          int                 // Current line number (1-indexed)
+	CurrentMethod    *MethodContext      // Current method being parsed (nil if not in a method)
+	CurrentIteration *IterationContext   // Current literal iteration block (nil if none)
+	LiteralConstants map[string][]string // Array literal values of constants seen so far, by name
+	InBraceBlock     bool                // The innermost open block is a { |x| ... } block, closed by }
+	InSingletonClass bool                // Directly inside a class << self block of the current scope
+	Magic            MagicComments       // The file's magic comments, read before its first line of code
 }
 
 // MatchResult contains extracted symbol info from a match
@@ -26,6 +43,9 @@ type MatchResult struct {
 	Symbols []*types.Symbol
 	// PushScope adds a name to the scope stack (for building fully qualified names)
 	PushScope string
+	// ScopeParent, when set, is the namespace PushScope opens in instead of
+	// the current scope (for compact paths like `class Admin::Users::Importer`)
+	ScopeParent []string
 	// PopScope removes a name from the scope stack
 	PopScope bool
 	// OpensBlock increments nesting depth (class, module, method, do blocks)
@@ -34,6 +54,28 @@ type MatchResult struct {
 	ClosesBlock bool
 	// EnterMethod indicates this match starts a method (set by MethodMatcher)
 	EnterMethod *MethodContext
+	// EnterIteration indicates this match starts a block iterating over literal values
+	EnterIteration *IterationContext
+	// EnterSingletonClass indicates this match opens a class << self block,
+	// whose methods are class methods of the current scope
+	EnterSingletonClass bool
+	// CloseColumn is the column just past the closing keyword (set by EndMatcher)
+	CloseColumn int
+	// Includes lists modules mixed into the enclosing class or module (set
+	// by MixinMatcher)
+	Includes []string
+	// SetVisibility applies a visibility modifier in the current scope (set by VisibilityMatcher)
+	SetVisibility *VisibilityChange
+}
+
+// VisibilityChange describes a visibility modifier such as `private :foo`
+type VisibilityChange struct {
+	Visibility types.Visibility
+	// Kinds limits which symbols the modifier applies to
+	Kinds []types.SymbolKind
+	// Names lists explicitly named symbols. When empty, the modifier opens a
+	// section and applies to the methods defined after it in the same scope.
+	Names []string
 }
 
 // Matcher defines how to recognize a Ruby pattern
@@ -56,44 +98,109 @@ type MultilineDetector interface {
 	StartsMultiline(line string) (bool, string, string)
 }
 
-// Registry holds all registered matchers
+// Registry holds all registered matchers. It is safe for concurrent use;
+// matchers can be disabled by name at runtime.
 type Registry struct {
-	matchers []Matcher
-	sorted   bool
+	mu       sync.RWMutex
+	matchers []Matcher // All registered matchers in priority order
+	disabled map[string]bool
+	active   []Matcher // Enabled matchers in priority order, nil when stale
 }
 
 // NewRegistry creates a new empty registry
 func NewRegistry() *Registry {
 	return &Registry{
 		matchers: make([]Matcher, 0),
+		disabled: make(map[string]bool),
 	}
 }
 
 // Register adds a matcher to the registry
 func (r *Registry) Register(m Matcher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	r.matchers = append(r.matchers, m)
-	r.sorted = false
+	sort.SliceStable(r.matchers, func(i, j int) bool {
+		return r.matchers[i].Priority() > r.matchers[j].Priority()
+	})
+	r.active = nil
 }
 
-// Matchers returns all registered matchers in priority order
+// Matchers returns the enabled matchers in priority order. The returned
+// slice is a snapshot and must not be modified.
 func (r *Registry) Matchers() []Matcher {
-	if !r.sorted {
-		sort.Slice(r.matchers, func(i, j int) bool {
-			return r.matchers[i].Priority() > r.matchers[j].Priority()
-		})
-		r.sorted = true
+	r.mu.RLock()
+	active := r.active
+	r.mu.RUnlock()
+	if active != nil {
+		return active
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active == nil {
+		r.active = make([]Matcher, 0, len(r.matchers))
+		for _, m := range r.matchers {
+			if !r.disabled[m.Name()] {
+				r.active = append(r.active, m)
+			}
+		}
+	}
+	return r.active
+}
+
+// SetDisabled replaces the set of disabled matchers, returning the names
+// of registered matchers that were newly disabled or re-enabled
+func (r *Registry) SetDisabled(names []string) (disabled, enabled []string) {
+	want := make(map[string]bool, len(names))
+	for _, name := range names {
+		want[name] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, m := range r.matchers {
+		name := m.Name()
+		switch {
+		case want[name] && !r.disabled[name]:
+			disabled = append(disabled, name)
+		case !want[name] && r.disabled[name]:
+			enabled = append(enabled, name)
+		}
+	}
+	if len(disabled) > 0 || len(enabled) > 0 {
+		r.disabled = want
+		r.active = nil
 	}
-	return r.matchers
+	return disabled, enabled
 }
 
 // RegisterDefaults adds the default Ruby matchers to the registry
 func RegisterDefaults(r *Registry) {
 	r.Register(&ClassMatcher{})
 	r.Register(&ModuleMatcher{})
+	r.Register(&ConcerningMatcher{})
+	r.Register(&RefineMatcher{})
 	r.Register(&MethodMatcher{})
 	r.Register(&ConstantMatcher{})
 	r.Register(&LocalVariableMatcher{})
+	r.Register(&AttrMatcher{})
 	r.Register(&RelationMatcher{})
+	r.Register(&NamedScopeMatcher{})
+	r.Register(&EnumMatcher{})
+	r.Register(&MixinMatcher{})
+	r.Register(&GhostMethodMatcher{})
+	r.Register(&SchemaMatcher{})
+	r.Register(&SpecMatcher{})
+	r.Register(&StepMatcher{})
+	r.Register(&DeployMatcher{})
+	r.Register(&RouteMatcher{})
+	r.Register(&FactoryMatcher{})
+	r.Register(&GemfileMatcher{})
+	r.Register(&EndpointMatcher{})
+	r.Register(&VisibilityMatcher{})
 	r.Register(&BlockMatcher{})
 	r.Register(&DoMatcher{})
 	r.Register(&EndMatcher{})
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRegistrySetDisabled(t *testing.T) {
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	total := len(registry.Matchers())
+
+	disabled, enabled := registry.SetDisabled([]string{"localvar", "no_such_matcher"})
+	if len(disabled) != 1 || disabled[0] != "localvar" || len(enabled) != 0 {
+		t.Fatalf("expected local_variable disabled, got disabled=%v enabled=%v", disabled, enabled)
+	}
+	for _, m := range registry.Matchers() {
+		if m.Name() == "localvar" {
+			t.Fatal("disabled matcher still returned")
+		}
+	}
+	if len(registry.Matchers()) != total-1 {
+		t.Errorf("expected %d matchers, got %d", total-1, len(registry.Matchers()))
+	}
+
+	// Same set again is a no-op
+	if disabled, enabled := registry.SetDisabled([]string{"localvar"}); len(disabled)+len(enabled) != 0 {
+		t.Errorf("expected no changes, got disabled=%v enabled=%v", disabled, enabled)
+	}
+
+	disabled, enabled = registry.SetDisabled(nil)
+	if len(enabled) != 1 || enabled[0] != "localvar" || len(disabled) != 0 {
+		t.Errorf("expected local_variable re-enabled, got disabled=%v enabled=%v", disabled, enabled)
+	}
+	if len(registry.Matchers()) != total {
+		t.Errorf("expected %d matchers, got %d", total, len(registry.Matchers()))
+	}
+}
+
+func TestRegistryConcurrentAccess(t *testing.T) {
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	scanner := NewScanner(registry)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if i%2 == 0 {
+					registry.SetDisabled([]string{"localvar"})
+				} else {
+					registry.SetDisabled(nil)
+				}
+				scanner.Parse("/test/a.rb", []byte("class A\n  def b\n    x = 1\n  end\nend"))
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestParseMatchedReportsMatchers(t *testing.T) {
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+
+	_, matched := NewScanner(registry).ParseMatched("/test/a.rb", []byte("class A\n  def b\n    x = 1\n  end\nend"))
+	want := []string{"class", "end", "localvar", "method"}
+	if len(matched) != len(want) {
+		t.Fatalf("expected %v, got %v", want, matched)
+	}
+	for i := range want {
+		if matched[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, matched)
+		}
+	}
+}
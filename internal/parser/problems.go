@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// Problem is a structural error in a file, found by the same block
+// tracking that gives symbols their ranges
+type Problem struct {
+	Line      int // 1-indexed
+	Column    int // 0-indexed start
+	EndColumn int // 0-indexed end, exclusive
+	Message   string
+}
+
+// Problems returns the structural errors in content parsed as the file at
+// filePath, ordered by position: ends that close no open block, blocks
+// still open at the end of the file, and multi-line parentheses that are
+// never closed. A file whose scan was cut short reports only the stray
+// ends found before it stopped.
+func (s *Scanner) Problems(filePath string, content []byte) []Problem {
+	var problems []Problem
+	_, _, result := s.parse(filePath, content, parseHooks{
+		onStrayEnd: func(line, column int) {
+			problems = append(problems, Problem{
+				Line:      line,
+				Column:    column - len("end"),
+				EndColumn: column,
+				Message:   "unexpected end: no open block to close",
+			})
+		},
+	})
+
+	lines := strings.Split(string(content), "\n")
+	lineProblem := func(line int, message string) Problem {
+		text := lines[line-1]
+		trimmed := strings.TrimSpace(text)
+		start := strings.Index(text, trimmed)
+		return Problem{Line: line, Column: start, EndColumn: start + len(trimmed), Message: message}
+	}
+
+	switch state := result.state; {
+	case state.truncated:
+	case state.unclosed != nil:
+		// Everything after it was swallowed, so open blocks prove nothing
+		acc := state.unclosed
+		message := fmt.Sprintf("unclosed %q: expected a matching %q", acc.opener, acc.closer)
+		if acc.depth <= 0 {
+			message = "argument list continues after a trailing comma but never ends"
+		}
+		problems = append(problems, lineProblem(acc.startLine, message))
+	default:
+		for _, open := range result.open {
+			problems = append(problems, lineProblem(open.line, openBlockName(open.container)+" is missing its end"))
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Column < problems[j].Column
+	})
+	return problems
+}
+
+// openBlockName describes an unclosed block by what opened it
+func openBlockName(container *types.Symbol) string {
+	if container == nil {
+		return "block"
+	}
+	switch container.Kind {
+	case types.KindClass, types.KindModule:
+		return container.Kind.String() + " " + container.FullName
+	case types.KindMethod, types.KindSingletonMethod:
+		return "def " + container.Name
+	}
+	return "block"
+}
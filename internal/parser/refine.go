@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// refine String do
+// refine(ActiveSupport::TimeWithZone) do
+//
+// Module#refine opens an anonymous refinement of the class, so methods in
+// the block are scoped under the refined class's name inside the module.
+var refinePattern = regexp.MustCompile(`^\s*refine\s*\(?\s*((?:::)?[A-Z]\w*(?:::[A-Z]\w*)*)\s*\)?\s*do\b`)
+
+// RefineMatcher extracts refinement blocks as modules named after the class
+// they refine
+type RefineMatcher struct{}
+
+func (m *RefineMatcher) Name() string  { return "refine" }
+func (m *RefineMatcher) Priority() int { return 95 }
+
+func (m *RefineMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	// refine is only defined on Module
+	if len(ctx.CurrentScope) == 0 {
+		return nil
+	}
+
+	loc := refinePattern.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return nil
+	}
+
+	refined := line[loc[2]:loc[3]]
+	parts := strings.Split(strings.TrimPrefix(refined, "::"), "::")
+	shortName := parts[len(parts)-1]
+
+	sym := &types.Symbol{
+		Name:     shortName,
+		Kind:     types.KindModule,
+		FilePath: ctx.FilePath,
+		Line:     ctx.LineNum,
+		Column:   loc[3] - len(shortName),
+		Scope:    append([]string{}, ctx.CurrentScope...),
+		Refines:  refined,
+	}
+	sym.FullName = sym.ComputeFullName()
+
+	return &MatchResult{
+		Symbols:    []*types.Symbol{sym},
+		PushScope:  shortName,
+		OpensBlock: true,
+	}
+}
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestRefineMatcher(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		scope       []string
+		wantName    string
+		wantRefines string
+		wantNil     bool
+	}{
+		{
+			name:        "bare constant",
+			line:        "  refine String do",
+			scope:       []string{"StringExtensions"},
+			wantName:    "String",
+			wantRefines: "String",
+		},
+		{
+			name:        "qualified constant with parens",
+			line:        "  refine(ActiveSupport::TimeWithZone) do",
+			scope:       []string{"TimeExtensions"},
+			wantName:    "TimeWithZone",
+			wantRefines: "ActiveSupport::TimeWithZone",
+		},
+		{
+			name:        "absolute constant",
+			line:        "  refine ::Integer do",
+			scope:       []string{"Money"},
+			wantName:    "Integer",
+			wantRefines: "::Integer",
+		},
+		{
+			name:    "outside a module",
+			line:    "refine String do",
+			wantNil: true,
+		},
+		{
+			name:    "no block",
+			line:    "  refine String",
+			scope:   []string{"StringExtensions"},
+			wantNil: true,
+		},
+		{
+			name:    "method named refine_search",
+			line:    "  refine_search Query do",
+			scope:   []string{"Search"},
+			wantNil: true,
+		},
+	}
+
+	matcher := &RefineMatcher{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &ParseContext{
+				FilePath:     "/test/test.rb",
+				LineNum:      1,
+				CurrentScope: tt.scope,
+			}
+			result := matcher.Match(tt.line, ctx)
+			if tt.wantNil {
+				if result != nil {
+					t.Errorf("expected nil, got %+v", result)
+				}
+				return
+			}
+			if result == nil {
+				t.Fatal("expected result, got nil")
+			}
+			sym := result.Symbols[0]
+			if sym.Name != tt.wantName || sym.Kind != types.KindModule || sym.Refines != tt.wantRefines {
+				t.Errorf("expected module %q refining %q, got %s %q refining %q", tt.wantName, tt.wantRefines, sym.Kind, sym.Name, sym.Refines)
+			}
+			if result.PushScope != tt.wantName || !result.OpensBlock {
+				t.Errorf("expected PushScope %q with OpensBlock, got %q/%v", tt.wantName, result.PushScope, result.OpensBlock)
+			}
+		})
+	}
+}
+
+func TestRefineParsing(t *testing.T) {
+	content := `module StringExtensions
+  refine String do
+    def shout
+      upcase + "!"
+    end
+  end
+
+  def self.version
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/string_extensions.rb", []byte(content))
+
+	want := map[string]int{
+		"StringExtensions":               10,
+		"StringExtensions::String":       6,
+		"StringExtensions::String#shout": 5,
+		"StringExtensions.version":       9,
+	}
+	for _, sym := range symbols {
+		if endLine, ok := want[sym.FullName]; ok {
+			if sym.EndLine != endLine {
+				t.Errorf("%s: expected end line %d, got %d", sym.FullName, endLine, sym.EndLine)
+			}
+			delete(want, sym.FullName)
+		}
+	}
+	for name := range want {
+		t.Errorf("expected symbol %s", name)
+	}
+}
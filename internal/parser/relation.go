@@ -13,25 +13,34 @@ type RelationMatcher struct{}
 func (m *RelationMatcher) Name() string  { return "relation" }
 func (m *RelationMatcher) Priority() int { return 85 }
 
-// Pattern: belongs_to/has_one/has_many :name, optional class_name: 'ClassName'
-// Updated to handle whitespace between elements for multi-line support
-var relationPattern = regexp.MustCompile(
-	`^\s*(belongs_to|has_one|has_many)\s*[\(\s]+:([a-z_][a-z0-9_]*)` +
-		`(?:.*class_name:\s*['"]([A-Za-z][A-Za-z0-9_:]*)['"'])?`,
-)
+// Pattern: belongs_to/has_one/has_many followed by its argument list, e.g.
+//
+//	has_many :recent_orders, -> { where(recent: true) }, class_name: 'Order'
+//	belongs_to(:owner, class_name: "Person", inverse_of: :pets)
+var relationPattern = regexp.MustCompile(`^\s*(belongs_to|has_one|has_many)\b\s*\(?\s*(.*)$`)
+
+// The relation name: :address or "address"
+var relationNamePattern = regexp.MustCompile(`^(?::|["'])([a-z_][a-z0-9_]*)["']?$`)
 
 // multilineStartPattern detects start of multi-line relation definitions
-var multilineStartPattern = regexp.MustCompile(`^\s*(belongs_to|has_one|has_many)\s*\(`)
+var multilineStartPattern = regexp.MustCompile(`^\s*(belongs_to|has_one|has_many)\b`)
 
 // StartsMultiline implements MultilineDetector
 func (m *RelationMatcher) StartsMultiline(line string) (bool, string, string) {
 	if !multilineStartPattern.MatchString(line) {
 		return false, "", ""
 	}
-	// Check if line has unclosed parens
-	openCount := strings.Count(line, "(")
-	closeCount := strings.Count(line, ")")
-	if openCount > closeCount {
+	// Unclosed call parens or an unclosed scope lambda (-> {), ignoring
+	// brackets inside strings (class_name: "Tag(")
+	code := MaskStrings(line)
+	if strings.Count(code, "(") > strings.Count(code, ")") {
+		return true, "(", ")"
+	}
+	if strings.Count(code, "{") > strings.Count(code, "}") {
+		return true, "{", "}"
+	}
+	// Options continued on the next line after a trailing comma
+	if strings.HasSuffix(line, ",") {
 		return true, "(", ")"
 	}
 	return false, "", ""
@@ -43,38 +52,75 @@ func (m *RelationMatcher) Match(line string, ctx *ParseContext) *MatchResult {
 		return nil
 	}
 
-	match := relationPattern.FindStringSubmatch(line)
-	if match == nil {
+	loc := relationPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
 		return nil
 	}
 
-	relationType := match[1] // belongs_to, has_one, has_many
-	relationName := match[2] // :address → address
-	className := match[3]    // optional class_name: 'Person'
+	relationType := line[loc[2]:loc[3]] // belongs_to, has_one, has_many
+
+	// A trailing comment isn't an argument, and a trailing do block
+	// extends the association
+	args := line[loc[4]:loc[5]]
+	args = args[:len(strings.TrimRight(MaskStrings(args), " \t"))]
+	opensBlock := false
+	if doLoc := doPattern.FindStringIndex(MaskStrings(args)); doLoc != nil {
+		args = args[:doLoc[0]]
+		opensBlock = true
+	}
+
+	parsed := splitArguments(args)
+	if len(parsed) == 0 {
+		return nil
+	}
+	nameMatch := relationNamePattern.FindStringSubmatch(parsed[0])
+	if nameMatch == nil {
+		return nil
+	}
+	relationName := nameMatch[1] // :address → address
+
+	// Remaining arguments: scope lambdas (-> { }, lambda { }) and options
+	// in any order. Only literal option values are kept.
+	var options map[string]string
+	for _, arg := range parsed[1:] {
+		key, value, ok := parseOption(arg)
+		if !ok {
+			continue
+		}
+		if literal, ok := literalValue(value); ok {
+			if options == nil {
+				options = make(map[string]string)
+			}
+			options[key] = literal
+		}
+	}
 
 	// Resolve target class name
 	var targetClass string
-	if className != "" {
+	if className := options["class_name"]; className != "" {
 		targetClass = className
 	} else {
 		// Infer from relation name
 		targetClass = toClassName(relationName, relationType == "has_many")
 	}
 
-	col := strings.Index(line, ":"+relationName) + 1 // Position of relation symbol
+	// Position of the name, past its leading colon or quote
+	col := loc[4] + strings.Index(args, parsed[0]) + 1
 
 	sym := &types.Symbol{
-		Name:       relationName,
-		TargetName: targetClass,
-		Kind:       types.KindRelation,
-		FilePath:   ctx.FilePath,
-		Line:       ctx.LineNum,
-		Column:     col,
-		Scope:      append([]string{}, ctx.CurrentScope...),
+		Name:         relationName,
+		TargetName:   targetClass,
+		RelationType: relationType,
+		Options:      options,
+		Kind:         types.KindRelation,
+		FilePath:     ctx.FilePath,
+		Line:         ctx.LineNum,
+		Column:       col,
+		Scope:        append([]string{}, ctx.CurrentScope...),
 	}
 	sym.FullName = sym.ComputeFullName()
 
-	return &MatchResult{Symbols: []*types.Symbol{sym}}
+	return &MatchResult{Symbols: []*types.Symbol{sym}, OpensBlock: opensBlock}
 }
 
 // toClassName converts snake_case to CamelCase, with optional singularization
@@ -95,6 +141,19 @@ func toClassName(name string, singularize bool) string {
 	return strings.Join(parts, "")
 }
 
+// CamelCase converts a snake_case name to CamelCase (tax_calculator → TaxCalculator)
+func CamelCase(name string) string {
+	return toClassName(name, false)
+}
+
+// SingularName singularizes the last word of a snake_case name (line_items → line_item)
+func SingularName(name string) string {
+	if i := strings.LastIndex(name, "_"); i >= 0 {
+		return name[:i+1] + singular(name[i+1:])
+	}
+	return singular(name)
+}
+
 // singular handles common English pluralization rules
 func singular(word string) string {
 	// Handle common irregular plurals
@@ -41,6 +41,30 @@ func TestRelationMatcher(t *testing.T) {
 			wantName:       "owner",
 			wantTargetName: "Person",
 		},
+		{
+			name:           "belongs_to with trailing comment",
+			line:           "  belongs_to :user # the owner",
+			scope:          []string{"Order"},
+			wantMatch:      true,
+			wantName:       "user",
+			wantTargetName: "User",
+		},
+		{
+			name:           "has_many with trailing comment",
+			line:           "  has_many :items # all items",
+			scope:          []string{"Order"},
+			wantMatch:      true,
+			wantName:       "items",
+			wantTargetName: "Item",
+		},
+		{
+			name:           "class_name followed by a comment",
+			line:           `  belongs_to :seller, class_name: "User" # note`,
+			scope:          []string{"Order"},
+			wantMatch:      true,
+			wantName:       "seller",
+			wantTargetName: "User",
+		},
 		{
 			name:           "has_one simple",
 			line:           "  has_one :business_structure",
@@ -117,6 +141,44 @@ func TestRelationMatcher(t *testing.T) {
 			wantName:       "author",
 			wantTargetName: "User",
 		},
+		{
+			name:           "scope lambda before options",
+			line:           "  has_many :recent_orders, -> { where(created_at: 1.week.ago..) }, class_name: 'Order'",
+			scope:          []string{"Customer"},
+			wantMatch:      true,
+			wantName:       "recent_orders",
+			wantTargetName: "Order",
+		},
+		{
+			name:           "options in any order with hash rocket",
+			line:           `  belongs_to :owner, :inverse_of => :pets, :class_name => "Person"`,
+			scope:          []string{"Pet"},
+			wantMatch:      true,
+			wantName:       "owner",
+			wantTargetName: "Person",
+		},
+		{
+			name:           "parenthesized with lambda",
+			line:           "  has_one(:latest_invoice, -> { order(:id) }, class_name: 'Billing::Invoice')",
+			scope:          []string{"Account"},
+			wantMatch:      true,
+			wantName:       "latest_invoice",
+			wantTargetName: "Billing::Invoice",
+		},
+		{
+			name:           "class_name inside lambda is ignored",
+			line:           "  has_many :tags, -> { where(class_name: 'Other') }",
+			scope:          []string{"Post"},
+			wantMatch:      true,
+			wantName:       "tags",
+			wantTargetName: "Tag",
+		},
+		{
+			name:      "dynamic relation name",
+			line:      "  has_many name_for(:things)",
+			scope:     []string{"Post"},
+			wantMatch: false,
+		},
 		{
 			name:           "has_many compound with irregular plural",
 			line:           "  has_many :business_people",
@@ -164,6 +226,27 @@ func TestRelationMatcher(t *testing.T) {
 	}
 }
 
+func TestRelationStartsMultilineIgnoresStrings(t *testing.T) {
+	matcher := &RelationMatcher{}
+	if ok, _, _ := matcher.StartsMultiline(`has_many :tags, class_name: "Tag("`); ok {
+		t.Error("expected a paren inside a string not to start a multi-line relation")
+	}
+	if ok, _, _ := matcher.StartsMultiline(`has_many :tags, -> { where(label: "}") `); !ok {
+		t.Error("expected an unclosed scope lambda to start a multi-line relation")
+	}
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/app/models/post.rb", []byte(`class Post
+  has_many :tags, class_name: "Tag("
+  def publish
+  end
+end`))
+	if len(symbols) != 3 || symbols[2].FullName != "Post#publish" {
+		t.Errorf("expected the rest of the file indexed, got %+v", symbols)
+	}
+}
+
 func TestSingular(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -244,6 +327,48 @@ end`,
 			wantTarget: "Comment",
 			wantName:   "comments",
 		},
+		{
+			name: "options continued after trailing comma",
+			input: `class Order
+  has_many :items,
+           class_name: 'LineItem',
+           dependent: :destroy
+end`,
+			wantTarget: "LineItem",
+			wantName:   "items",
+		},
+		{
+			name: "commented-out option",
+			input: `class Order
+  has_many(
+    :items,
+    # class_name: 'Item',
+    class_name: 'LineItem',
+  )
+end`,
+			wantTarget: "LineItem",
+			wantName:   "items",
+		},
+		{
+			name: "trailing comments after continuing commas",
+			input: `class Order
+  has_many :items, # ordered by position
+           class_name: 'LineItem', # not Item
+           inverse_of: :order # "#" isn't a string here
+end`,
+			wantTarget: "LineItem",
+			wantName:   "items",
+		},
+		{
+			name: "multi-line scope lambda",
+			input: `class Customer
+  has_many :recent_orders, -> {
+    where(created_at: 1.week.ago..)
+  }, class_name: 'Order'
+end`,
+			wantTarget: "Order",
+			wantName:   "recent_orders",
+		},
 	}
 
 	for _, tt := range tests {
@@ -299,3 +424,46 @@ func TestToClassName(t *testing.T) {
 		})
 	}
 }
+
+func TestRelationMatcherRecordsTypeAndOptions(t *testing.T) {
+	matcher := &RelationMatcher{}
+	ctx := &ParseContext{
+		FilePath:     "/test/test.rb",
+		LineNum:      1,
+		CurrentScope: []string{"Order"},
+	}
+
+	result := matcher.Match("  has_many :items, inverse_of: :order, dependent: :destroy", ctx)
+	if result == nil {
+		t.Fatal("expected result, got nil")
+	}
+	sym := result.Symbols[0]
+	if sym.RelationType != "has_many" {
+		t.Errorf("expected RelationType has_many, got %q", sym.RelationType)
+	}
+	if sym.Options["inverse_of"] != "order" {
+		t.Errorf("expected inverse_of order, got %v", sym.Options)
+	}
+}
+
+func TestRelationWithExtensionBlock(t *testing.T) {
+	content := `class Order
+  has_many :items do
+    def total
+    end
+  end
+
+  def paid?
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/order.rb", []byte(content))
+
+	for _, sym := range symbols {
+		if sym.Name == "paid?" && sym.FullName != "Order#paid?" {
+			t.Errorf("extension block broke nesting: paid? has FullName %q", sym.FullName)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package parser
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// resources :orders / resource :profile, only: :show / resources :orders, :invoices do
+var resourcesPattern = regexp.MustCompile(`^\s*(resources|resource)\b\s*\(?\s*(.*)`)
+
+// get "about", to: "pages#about" / post "/checkout" => "orders#create"
+// root "home#index" / match "search", to: "search#show", via: :all
+var verbRoutePattern = regexp.MustCompile(`^\s*(get|post|put|patch|delete|match|root)\b\s*\(?\s*(.*)`)
+
+// "/about" => "pages#about"
+var rocketRoutePattern = regexp.MustCompile(`^(?:"([^"]*)"|'([^']*)')\s*=>\s*(.+)$`)
+
+// "orders#show" (literalValue leaves out strings with #, which may interpolate)
+var routeTargetPattern = regexp.MustCompile(`^["']([\w/]+#\w+)["']$`)
+
+// RouteMatcher extracts Rails routes from config/routes.rb: resources by
+// name, other routes by their as: name or path. Route namespaces push a
+// scope, so admin's resources are admin::orders.
+type RouteMatcher struct{}
+
+func (m *RouteMatcher) Name() string  { return "route" }
+func (m *RouteMatcher) Priority() int { return 64 } // Above do (60), routes open do blocks
+
+func (m *RouteMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	if !isRoutesPath(ctx.FilePath) || ctx.CurrentMethod != nil {
+		return nil
+	}
+
+	if loc := namespaceDSLPattern.FindStringSubmatchIndex(line); loc != nil {
+		name := submatch(line, loc, 1) + submatch(line, loc, 2)
+		sym := m.symbol(name, "", ctx)
+		sym.Kind = types.KindNamespace
+		sym.Column = loc[2]
+		if sym.Column < 0 {
+			sym.Column = loc[4]
+		}
+		sym.FullName = sym.ComputeFullName()
+		result := &MatchResult{Symbols: []*types.Symbol{sym}, OpensBlock: opensDoBlock(line)}
+		if result.OpensBlock {
+			result.PushScope = name
+		}
+		return result
+	}
+
+	var symbols []*types.Symbol
+	if loc := resourcesPattern.FindStringSubmatchIndex(line); loc != nil {
+		macro := line[loc[2]:loc[3]]
+		offset := loc[4]
+		for _, arg := range callArguments(line, loc[4]) {
+			if !strings.HasPrefix(arg, ":") {
+				break // Options follow the resource names
+			}
+			name, ok := literalValue(arg)
+			if !ok {
+				break
+			}
+			sym := m.symbol(name, macro, ctx)
+			sym.Column = offset + strings.Index(line[offset:], arg) + 1
+			offset = sym.Column + len(name)
+			symbols = append(symbols, sym)
+		}
+	} else if loc := verbRoutePattern.FindStringSubmatchIndex(line); loc != nil {
+		if sym := m.verbRoute(line, loc, ctx); sym != nil {
+			symbols = append(symbols, sym)
+		}
+	}
+
+	if len(symbols) == 0 {
+		return nil
+	}
+	for _, sym := range symbols {
+		sym.FullName = sym.ComputeFullName()
+	}
+	return &MatchResult{Symbols: symbols, OpensBlock: opensDoBlock(line)}
+}
+
+// verbRoute builds the symbol for a get/post/.../root route. It's named
+// after its as: option, else its path ("root" for root routes); the
+// controller#action it maps to is kept as its "to" option.
+func (m *RouteMatcher) verbRoute(line string, loc []int, ctx *ParseContext) *types.Symbol {
+	verb := line[loc[2]:loc[3]]
+	args := callArguments(line, loc[4])
+	if len(args) == 0 {
+		return nil
+	}
+
+	var name, to string
+	col := -1
+	options := args
+	if verb == "root" {
+		name, col = "root", loc[2]
+	} else if match := rocketRoutePattern.FindStringSubmatch(args[0]); match != nil {
+		name = match[1] + match[2]
+		to = routeTarget(match[3])
+		options = args[1:]
+	} else if value, ok := literalValue(args[0]); ok {
+		name = value
+		options = args[1:]
+	} else {
+		return nil
+	}
+	if col < 0 {
+		col = loc[4] + strings.Index(line[loc[4]:], name)
+	}
+
+	for _, arg := range options {
+		key, value, ok := parseOption(arg)
+		if !ok {
+			if verb == "root" {
+				to = routeTarget(arg) // root "home#index"
+			}
+			continue
+		}
+		switch literal, isLiteral := literalValue(value); {
+		case key == "to":
+			to = routeTarget(value)
+		case key == "as" && isLiteral:
+			if at := strings.Index(line[loc[4]:], literal); at >= 0 {
+				name, col = literal, loc[4]+at
+			}
+		}
+	}
+
+	sym := m.symbol(name, verb, ctx)
+	sym.Column = col
+	if to != "" {
+		sym.Options = map[string]string{"to": to}
+	}
+	return sym
+}
+
+// routeTarget returns the controller#action of a string literal, or ""
+func routeTarget(expr string) string {
+	if match := routeTargetPattern.FindStringSubmatch(strings.TrimSpace(expr)); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+func (m *RouteMatcher) symbol(name, macro string, ctx *ParseContext) *types.Symbol {
+	return &types.Symbol{
+		Name:     name,
+		Kind:     types.KindRoute,
+		FilePath: ctx.FilePath,
+		Line:     ctx.LineNum,
+		Scope:    append([]string{}, ctx.CurrentScope...),
+		Macro:    macro,
+	}
+}
+
+// isRoutesPath reports whether path is config/routes.rb or a file it draws
+// in from config/routes/
+func isRoutesPath(path string) bool {
+	path = filepath.ToSlash(path)
+	return strings.HasSuffix(path, "/config/routes.rb") || strings.Contains(path, "/config/routes/")
+}
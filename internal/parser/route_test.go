@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestRouteMatcher(t *testing.T) {
+	content := `Rails.application.routes.draw do
+  root to: "home#index"
+
+  resources :orders, :invoices, only: :show do
+    post :ship, on: :member
+  end
+
+  namespace :admin do
+    resource :settings
+    get "reports/:year", to: "reports#show", as: :yearly_report
+  end
+
+  get "/about" => "pages#about" # static
+  match "search", to: "search#show", via: :all
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/app/config/routes.rb", []byte(content))
+
+	type route struct {
+		macro, to string
+	}
+	got := map[string]route{}
+	for _, sym := range symbols {
+		if sym.Kind == types.KindRoute {
+			got[sym.FullName] = route{sym.Macro, sym.Options["to"]}
+		}
+	}
+
+	want := map[string]route{
+		"root":                 {"root", "home#index"},
+		"orders":               {"resources", ""},
+		"invoices":             {"resources", ""},
+		"ship":                 {"post", ""},
+		"admin::settings":      {"resource", ""},
+		"admin::yearly_report": {"get", "reports#show"},
+		"/about":               {"get", "pages#about"},
+		"search":               {"match", "search#show"},
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	for fullName, w := range want {
+		if got[fullName] != w {
+			t.Errorf("%s: expected %+v, got %+v", fullName, w, got[fullName])
+		}
+	}
+}
+
+func TestRouteMatcherIgnoresAppCode(t *testing.T) {
+	ctx := &ParseContext{FilePath: "/app/app/controllers/orders_controller.rb", LineNum: 1}
+	if result := (&RouteMatcher{}).Match(`  get "/orders"`, ctx); result != nil {
+		t.Errorf("expected no match outside routes files, got %+v", result.Symbols)
+	}
+}
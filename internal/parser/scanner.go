@@ -1,7 +1,11 @@
 package parser
 
 import (
+	"log"
+	"sort"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/jarredhawkins/goruby-lsp/internal/types"
 )
@@ -9,17 +13,48 @@ import (
 // Scanner parses Ruby files line by line
 type Scanner struct {
 	registry *Registry
+	stats    atomic.Pointer[ParseStats]
+	limits   atomic.Pointer[Limits]
 }
 
+// Limits guards the scanner against pathological input such as generated
+// files with multi-megabyte lines. Zero values disable a guard.
+type Limits struct {
+	MaxLineLength int           // Longer lines are skipped
+	FileBudget    time.Duration // Parsing stops once a file takes longer
+}
+
+// DefaultLimits are applied to new scanners
+var DefaultLimits = Limits{
+	MaxLineLength: 10000,
+	FileBudget:    2 * time.Second,
+}
+
+// budgetCheckInterval is how many lines are scanned between budget checks
+const budgetCheckInterval = 256
+
 // NewScanner creates a new scanner with the given registry
 func NewScanner(registry *Registry) *Scanner {
-	return &Scanner{
+	s := &Scanner{
 		registry: registry,
 	}
+	s.SetLimits(DefaultLimits)
+	return s
+}
+
+// SetLimits replaces the scanner's line-length and time guards
+func (s *Scanner) SetLimits(limits Limits) {
+	s.limits.Store(&limits)
+}
+
+// SetStats enables timing collection into stats, or disables it when nil
+func (s *Scanner) SetStats(stats *ParseStats) {
+	s.stats.Store(stats)
 }
 
-// tryStartMultiline checks if any matcher wants to start multi-line accumulation
-func (s *Scanner) tryStartMultiline(matchers []Matcher, line string, lineNum int) *accumulator {
+// tryStartMultiline checks if any matcher wants to start multi-line accumulation.
+// line is trimmed; indent is the width of the whitespace trimmed before it.
+func (s *Scanner) tryStartMultiline(matchers []Matcher, line string, lineNum, indent int) *accumulator {
 	for _, matcher := range matchers {
 		if detector, ok := matcher.(MultilineDetector); ok {
 			if isStart, opener, closer := detector.StartsMultiline(line); isStart {
@@ -28,7 +63,7 @@ func (s *Scanner) tryStartMultiline(matchers []Matcher, line string, lineNum int
 					opener:    opener,
 					closer:    closer,
 				}
-				acc.addLine(line)
+				acc.addLine(line, lineNum, indent)
 				return acc
 			}
 		}
@@ -43,28 +78,68 @@ type accumulator struct {
 	opener    string
 	closer    string
 	depth     int
+	continued bool // last line ended with a comma, so arguments continue
+	fragments []fragment
+}
+
+// fragment records where one source line landed in the accumulated buffer
+type fragment struct {
+	line   int // 1-based line number in the file
+	indent int // Column of the fragment's first byte in that line
+	offset int // Offset of the fragment in the buffer
 }
 
-func (a *accumulator) addLine(line string) {
+func (a *accumulator) addLine(line string, lineNum, indent int) {
 	if a.buffer.Len() > 0 {
 		a.buffer.WriteString(" ")
 	}
+	a.fragments = append(a.fragments, fragment{line: lineNum, indent: indent, offset: a.buffer.Len()})
 	a.buffer.WriteString(line)
 	a.depth += strings.Count(line, a.opener) - strings.Count(line, a.closer)
+	a.continued = strings.HasSuffix(line, ",")
 }
 
 func (a *accumulator) isComplete() bool {
-	return a.depth <= 0
+	return a.depth <= 0 && !a.continued
 }
 
 func (a *accumulator) content() string {
 	return a.buffer.String()
 }
 
+// position maps a column in the accumulated buffer back to the line and
+// column it came from in the file
+func (a *accumulator) position(col int) (int, int) {
+	frag := a.fragments[0]
+	for _, f := range a.fragments[1:] {
+		if f.offset > col {
+			break
+		}
+		frag = f
+	}
+	return frag.line, col - frag.offset + frag.indent
+}
+
 // scanState holds the shared scope/nesting state during a scan.
 type scanState struct {
 	ScopeStack   []string
 	NestingDepth int
+	// scopeDepths records the nesting depth each scope was opened at, so a
+	// scope opened inside a plain block (e.g., a do block) closes with it
+	scopeDepths []int
+	// scopeParents records the scope each open scope was opened from, which
+	// is restored when it closes (a compact path may not extend it)
+	scopeParents [][]string
+	// braceDepths records the nesting depth of each open brace block,
+	// innermost last
+	braceDepths []int
+	// matched records the names of matchers that produced a result
+	matched map[string]bool
+	// unclosed is the start of a multi-line construct whose delimiters
+	// were never balanced, which swallowed the rest of the file
+	unclosed *accumulator
+	// truncated is set when the file budget stopped the scan early
+	truncated bool
 }
 
 // scanCallbacks controls the scan loop behavior.
@@ -76,12 +151,15 @@ type scanCallbacks struct {
 	// onResult is called after a matcher produces a result, before scope/nesting
 	// updates are applied. Return false to stop scanning.
 	onResult func(ctx *ParseContext, result *MatchResult, state *scanState) bool
+
+	// stats, when set, collects per-matcher and per-file timing
+	stats *ParseStats
 }
 
 // scanLines runs the core line-by-line parse loop.
 func (s *Scanner) scanLines(content []byte, filePath string, cb scanCallbacks) *scanState {
 	lines := strings.Split(string(content), "\n")
-	state := &scanState{}
+	state := &scanState{matched: make(map[string]bool)}
 
 	ctx := &ParseContext{
 		FilePath:     filePath,
@@ -90,99 +168,427 @@ func (s *Scanner) scanLines(content []byte, filePath string, cb scanCallbacks) *
 
 	matchers := s.registry.Matchers()
 	var acc *accumulator
+	// Terminators of the heredocs whose bodies come next, and the quote of
+	// a string continuing on the next line: their text isn't code
+	var heredocs []string
+	var quote byte
+
+	limits := s.limits.Load()
+	fileStart := time.Now()
+	skippedLines := 0
+	timedOut := false
+	defer func() {
+		if skippedLines > 0 {
+			log.Printf("skipped %d lines longer than %d bytes in %s", skippedLines, limits.MaxLineLength, filePath)
+		}
+		if timedOut {
+			log.Printf("stopped parsing %s after %s at line %d of %d", filePath, limits.FileBudget, ctx.LineNum, len(lines))
+		}
+	}()
+
+	stats := cb.stats
+	var timings map[string]*MatcherTiming
+	if stats != nil {
+		timings = make(map[string]*MatcherTiming, len(matchers))
+		defer func() {
+			stats.record(FileTiming{
+				Path:         filePath,
+				Lines:        len(lines),
+				Duration:     time.Since(fileStart),
+				SkippedLines: skippedLines,
+				TimedOut:     timedOut,
+			}, timings)
+		}()
+	}
 
-	for lineNum, line := range lines {
+	// The prelude (shebang and magic comments) never reaches the matchers
+	ctx.Magic = readPrelude(lines)
+	for lineNum := ctx.Magic.Lines; lineNum < len(lines); lineNum++ {
+		line := lines[lineNum]
 		ctx.LineNum = lineNum + 1
 		ctx.CurrentScope = state.ScopeStack
 
+		if limits.FileBudget > 0 && lineNum%budgetCheckInterval == budgetCheckInterval-1 &&
+			time.Since(fileStart) > limits.FileBudget {
+			timedOut = true
+			state.truncated = true
+			return state
+		}
+		if limits.MaxLineLength > 0 && len(line) > limits.MaxLineLength {
+			skippedLines++
+			continue
+		}
+
+		if len(heredocs) > 0 {
+			if strings.TrimSpace(line) == heredocs[0] {
+				heredocs = heredocs[1:]
+			}
+			continue
+		}
+		if quote != 0 {
+			end := closingQuote(line, quote)
+			if end < 0 {
+				continue
+			}
+			// The code after the string is scanned as usual
+			quote = 0
+			line = strings.Repeat(" ", end+1) + line[end+1:]
+		}
+
 		trimmed := strings.TrimSpace(line)
 		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
 			continue
 		}
+		heredocs = heredocTerminators(trimmed, lines[lineNum+1:])
+		if quote = openString(trimmed); quote != 0 && !continuedString(lines[lineNum+1:], quote) {
+			quote = 0
+		}
 
+		indent := strings.Index(line, trimmed)
+
+		// Last line of a multi-line construct, 0 for single lines
+		spanEnd := 0
+		// The accumulator a multi-line construct was assembled in, whose
+		// buffer columns need mapping back to the file
+		var span *accumulator
+
+		// A trailing comment inside a multi-line construct would hide a
+		// continuing comma and end up among its arguments
 		if acc != nil {
-			acc.addLine(trimmed)
+			acc.addLine(stripComment(trimmed), ctx.LineNum, indent)
+			if limits.MaxLineLength > 0 && acc.buffer.Len() > limits.MaxLineLength {
+				// Never-closing construct; give up rather than buffer the file
+				state.unclosed, acc = acc, nil
+				skippedLines++
+				continue
+			}
 			if !acc.isComplete() {
 				continue
 			}
+			spanEnd = ctx.LineNum
 			ctx.LineNum = acc.startLine
 			line = acc.content()
-			acc = nil
-		} else if acc = s.tryStartMultiline(matchers, trimmed, ctx.LineNum); acc != nil {
+			span, acc = acc, nil
+		} else if acc = s.tryStartMultiline(matchers, stripComment(trimmed), ctx.LineNum, indent); acc != nil {
 			if !acc.isComplete() {
 				continue
 			}
 			line = acc.content()
-			acc = nil
+			span, acc = acc, nil
 		}
 
+		n := len(state.braceDepths)
+		ctx.InBraceBlock = n > 0 && state.braceDepths[n-1] == state.NestingDepth
+
 		if cb.beforeMatch != nil {
 			cb.beforeMatch(ctx, state)
 		}
 
 		for _, matcher := range matchers {
-			result := matcher.Match(line, ctx)
+			var result *MatchResult
+			if timings != nil {
+				result = timeMatch(timings, matcher, line, ctx)
+			} else {
+				result = matcher.Match(line, ctx)
+			}
 			if result == nil {
 				continue
 			}
+			state.matched[matcher.Name()] = true
+
+			if span != nil {
+				for _, sym := range result.Symbols {
+					// An end within the statement is a column of the buffer
+					if sym.EndLine == ctx.LineNum {
+						sym.EndLine, sym.EndColumn = span.position(sym.EndColumn)
+					}
+					if spanEnd > 0 && sym.EndLine == 0 {
+						sym.EndLine = spanEnd
+					}
+					sym.Line, sym.Column = span.position(sym.Column)
+				}
+			}
 
 			if !cb.onResult(ctx, result, state) {
 				return state
 			}
-
-			if result.PushScope != "" {
-				state.ScopeStack = append(state.ScopeStack, result.PushScope)
+			state.apply(result)
+
+			// A line claimed for what it defines can still open a block
+			// through its right-hand side (@rows ||= begin, self.state = if)
+			if !result.OpensBlock && !result.ClosesBlock && opensBlock(line) {
+				opening := &MatchResult{OpensBlock: true}
+				if !cb.onResult(ctx, opening, state) {
+					return state
+				}
+				state.apply(opening)
+				result = opening
 			}
-			if result.OpensBlock {
-				state.NestingDepth++
+			if result.OpensBlock && opensBraceBlock(line) {
+				state.braceDepths = append(state.braceDepths, state.NestingDepth)
 			}
-			if result.ClosesBlock && state.NestingDepth > 0 {
-				state.NestingDepth--
+
+			// A block opened and closed on the same line (def noop; end,
+			// class Stub; end, if a then b end) is net-neutral for nesting
+			if col := inlineEndColumn(line); result.OpensBlock && col >= 0 {
+				if span != nil {
+					ctx.LineNum, col = span.position(col)
+				}
+				closing := &MatchResult{ClosesBlock: true, PopScope: true, CloseColumn: col}
+				if !cb.onResult(ctx, closing, state) {
+					return state
+				}
+				state.apply(closing)
 			}
-			if result.PopScope && state.NestingDepth < len(state.ScopeStack) {
-				state.ScopeStack = state.ScopeStack[:len(state.ScopeStack)-1]
+
+			// A call chained off a closing end can open the next block
+			// (end.each do |x|, }.map { |x|)
+			if result.ClosesBlock && opensDoBlock(line) {
+				opening := &MatchResult{OpensBlock: true}
+				if !cb.onResult(ctx, opening, state) {
+					return state
+				}
+				state.apply(opening)
+				if opensBraceBlock(line) {
+					state.braceDepths = append(state.braceDepths, state.NestingDepth)
+				}
 			}
 			break
 		}
 	}
+	if acc != nil {
+		state.unclosed = acc
+	}
 
 	return state
 }
 
+// apply updates nesting and scope for a matcher's result
+func (state *scanState) apply(result *MatchResult) {
+	if result.OpensBlock {
+		state.NestingDepth++
+	}
+	if result.PushScope != "" {
+		state.scopeParents = append(state.scopeParents, state.ScopeStack)
+		state.ScopeStack = openedScope(state.ScopeStack, result)
+		state.scopeDepths = append(state.scopeDepths, state.NestingDepth)
+	}
+	if result.ClosesBlock && state.NestingDepth > 0 {
+		if n := len(state.braceDepths); n > 0 && state.braceDepths[n-1] == state.NestingDepth {
+			state.braceDepths = state.braceDepths[:n-1]
+		}
+		state.NestingDepth--
+	}
+	if result.PopScope && len(state.ScopeStack) > 0 &&
+		state.scopeDepths[len(state.scopeDepths)-1] > state.NestingDepth {
+		n := len(state.scopeDepths) - 1
+		state.ScopeStack = state.scopeParents[n]
+		state.scopeParents = state.scopeParents[:n]
+		state.scopeDepths = state.scopeDepths[:n]
+	}
+}
+
+// openedScope returns the scope inside the block a result opens with
+// PushScope, starting from current unless the result names another parent
+func openedScope(current []string, result *MatchResult) []string {
+	parent := current
+	if result.ScopeParent != nil {
+		parent = result.ScopeParent
+	}
+	return append(append([]string{}, parent...), result.PushScope)
+}
+
+// containerSymbol returns the symbol whose body is the block a result
+// opens (a class, module, method, example group, ...), or nil. Its range
+// is closed out at the matching end.
+func containerSymbol(result *MatchResult) *types.Symbol {
+	if !result.OpensBlock || len(result.Symbols) == 0 {
+		return nil
+	}
+	return result.Symbols[0]
+}
+
+// timeMatch runs a matcher, adding its duration to timings
+func timeMatch(timings map[string]*MatcherTiming, matcher Matcher, line string, ctx *ParseContext) *MatchResult {
+	start := time.Now()
+	result := matcher.Match(line, ctx)
+	elapsed := time.Since(start)
+
+	t := timings[matcher.Name()]
+	if t == nil {
+		t = &MatcherTiming{Name: matcher.Name()}
+		timings[matcher.Name()] = t
+	}
+	t.Calls++
+	t.Duration += elapsed
+	if result != nil {
+		t.Matches++
+	}
+	return result
+}
+
 // Parse scans the file content and returns all discovered symbols
 func (s *Scanner) Parse(filePath string, content []byte) []*types.Symbol {
+	symbols, _ := s.ParseMatched(filePath, content)
+	return symbols
+}
+
+// ParseMatched is like Parse but also returns the names of the matchers
+// that matched in the file, sorted
+func (s *Scanner) ParseMatched(filePath string, content []byte) ([]*types.Symbol, []string) {
+	symbols, matched, _ := s.parse(filePath, content, parseHooks{})
+	return symbols, matched
+}
+
+// parseHooks observe the block structure of a file as parse scans it.
+// Either may be nil.
+type parseHooks struct {
+	// onBlock is called for each block closed by its end
+	onBlock func(Block)
+	// onStrayEnd is called for an end closing no open block, with its line
+	// and the column just past it
+	onStrayEnd func(line, column int)
+}
+
+// singletonClass is an open class << self block
+type singletonClass struct {
+	depth   int               // Nesting depth of its body
+	scope   string            // The scope it's the singleton class of
+	section *VisibilityChange // The scope's visibility section, restored at its end
+}
+
+// openBlock is a block waiting for its end
+type openBlock struct {
+	line      int
+	container *types.Symbol // The class, method, ... whose body it is, or nil
+}
+
+// parse implements ParseMatched, reporting block structure to hooks. It
+// also returns what was left open when the scan finished.
+func (s *Scanner) parse(filePath string, content []byte, hooks parseHooks) ([]*types.Symbol, []string, *parseResult) {
 	var symbols []*types.Symbol
 	var currentMethod *MethodContext
-	var methodSymbol *types.Symbol
-
-	s.scanLines(content, filePath, scanCallbacks{
+	var currentIteration *IterationContext
+	// Open class << self blocks, innermost last
+	var singletons []singletonClass
+	// Open container symbols, innermost last, with the depth of their block
+	var containers []*types.Symbol
+	var containerDepths []int
+	// The open blocks, innermost last
+	var openBlocks []openBlock
+	literalConstants := make(map[string][]string)
+	// Visibility sections opened by a bare `private`/`protected`, keyed by scope
+	sections := make(map[string]*VisibilityChange)
+
+	state := s.scanLines(content, filePath, scanCallbacks{
+		// Timing is only collected while stats are enabled (during Build)
+		stats: s.stats.Load(),
 		beforeMatch: func(ctx *ParseContext, state *scanState) {
 			ctx.CurrentMethod = currentMethod
+			ctx.CurrentIteration = currentIteration
+			ctx.LiteralConstants = literalConstants
+			n := len(singletons)
+			ctx.InSingletonClass = n > 0 && singletons[n-1].scope == strings.Join(ctx.CurrentScope, "::") &&
+				(currentMethod == nil || currentMethod.NestingDepth < singletons[n-1].depth)
 		},
 		onResult: func(ctx *ParseContext, result *MatchResult, state *scanState) bool {
+			scopeKey := strings.Join(ctx.CurrentScope, "::")
+			if section := sections[scopeKey]; section != nil {
+				for _, sym := range result.Symbols {
+					if section.applies(sym.Kind) && strings.Join(sym.Scope, "::") == scopeKey {
+						sym.Visibility = section.Visibility
+					}
+				}
+			}
 			symbols = append(symbols, result.Symbols...)
+			for _, sym := range result.Symbols {
+				if sym.Kind == types.KindConstant && sym.Values != nil {
+					literalConstants[sym.Name] = sym.Values
+				}
+			}
+
+			if change := result.SetVisibility; change != nil {
+				if len(change.Names) > 0 {
+					applyVisibility(symbols, change, ctx.CurrentScope)
+				} else {
+					sections[scopeKey] = change
+				}
+			}
+			if result.PushScope != "" {
+				// A newly opened class or module body starts out public
+				delete(sections, strings.Join(openedScope(ctx.CurrentScope, result), "::"))
+			}
 
 			if result.EnterMethod != nil {
+				result.EnterMethod.Parent = currentMethod
 				currentMethod = result.EnterMethod
 				// NestingDepth will be incremented after this callback returns,
 				// so add 1 to account for the block this result opens.
 				currentMethod.NestingDepth = state.NestingDepth + 1
-				for _, sym := range result.Symbols {
-					if sym.Kind == types.KindMethod || sym.Kind == types.KindSingletonMethod {
-						methodSymbol = sym
-						break
-					}
+			}
+
+			// A class << self body has its own visibility sections, starting
+			// out public, and leaves the class's as they were
+			if result.EnterSingletonClass {
+				singletons = append(singletons, singletonClass{
+					depth:   state.NestingDepth + 1,
+					scope:   scopeKey,
+					section: sections[scopeKey],
+				})
+				delete(sections, scopeKey)
+			}
+
+			if result.EnterIteration != nil {
+				currentIteration = result.EnterIteration
+				currentIteration.NestingDepth = state.NestingDepth + 1
+			}
+
+			// Mixins belong to the class or module whose body they're in,
+			// not to a method or block inside it
+			if n := len(containers); n > 0 && len(result.Includes) > 0 && containerDepths[n-1] == state.NestingDepth {
+				if owner := containers[n-1]; owner.Kind == types.KindClass || owner.Kind == types.KindModule {
+					owner.Includes = append(owner.Includes, result.Includes...)
 				}
 			}
 
+			if container := containerSymbol(result); container != nil {
+				containers = append(containers, container)
+				containerDepths = append(containerDepths, state.NestingDepth+1)
+			}
+
+			if result.OpensBlock {
+				openBlocks = append(openBlocks, openBlock{line: ctx.LineNum, container: containerSymbol(result)})
+			}
+
+			if result.ClosesBlock && state.NestingDepth == 0 && hooks.onStrayEnd != nil {
+				hooks.onStrayEnd(ctx.LineNum, result.CloseColumn)
+			}
 			if result.ClosesBlock && state.NestingDepth > 0 {
+				if n := len(openBlocks); n > 0 {
+					if hooks.onBlock != nil {
+						hooks.onBlock(Block{StartLine: openBlocks[n-1].line, EndLine: ctx.LineNum})
+					}
+					openBlocks = openBlocks[:n-1]
+				}
+				if n := len(containers); n > 0 && state.NestingDepth == containerDepths[n-1] {
+					containers[n-1].EndLine = ctx.LineNum
+					containers[n-1].EndColumn = result.CloseColumn
+					containers, containerDepths = containers[:n-1], containerDepths[:n-1]
+				}
+				if n := len(singletons); n > 0 && state.NestingDepth == singletons[n-1].depth {
+					if closed := singletons[n-1]; closed.section != nil {
+						sections[closed.scope] = closed.section
+					} else {
+						delete(sections, closed.scope)
+					}
+					singletons = singletons[:n-1]
+				}
+				if currentIteration != nil && state.NestingDepth == currentIteration.NestingDepth {
+					currentIteration = nil
+				}
 				// Check BEFORE scanLines decrements nesting
 				if currentMethod != nil && state.NestingDepth == currentMethod.NestingDepth {
-					if methodSymbol != nil {
-						methodSymbol.EndLine = ctx.LineNum
-						methodSymbol = nil
-					}
-					currentMethod = nil
+					currentMethod = currentMethod.Parent
 				}
 			}
 
@@ -190,20 +596,40 @@ func (s *Scanner) Parse(filePath string, content []byte) []*types.Symbol {
 		},
 	})
 
-	return symbols
+	matched := make([]string, 0, len(state.matched))
+	for name := range state.matched {
+		matched = append(matched, name)
+	}
+	sort.Strings(matched)
+
+	return symbols, matched, &parseResult{state: state, open: openBlocks}
 }
 
-// ScopeAtLine returns the scope stack at the given 1-indexed line.
-func (s *Scanner) ScopeAtLine(content []byte, targetLine int) []string {
-	state := s.scanLines(content, "", scanCallbacks{
-		onResult: func(ctx *ParseContext, result *MatchResult, state *scanState) bool {
-			return ctx.LineNum <= targetLine
-		},
-	})
+// parseResult is what parse leaves behind besides symbols
+type parseResult struct {
+	state *scanState
+	open  []openBlock // Blocks never closed, outermost first
+}
+
+// Block is a construct closed by `end`: a class, module, method, do
+// block, or conditional/loop
+type Block struct {
+	StartLine int // 1-indexed line of the opening keyword
+	EndLine   int // 1-indexed line of the matching end
+}
 
-	scope := make([]string, len(state.ScopeStack))
-	copy(scope, state.ScopeStack)
-	return scope
+// Blocks returns the closed blocks of the file ordered by start line. It
+// follows the same block tracking that gives symbols their EndLine, so
+// unclosed blocks at the end of the file are left out.
+func (s *Scanner) Blocks(filePath string, content []byte) []Block {
+	var blocks []Block
+	s.parse(filePath, content, parseHooks{onBlock: func(b Block) {
+		blocks = append(blocks, b)
+	}})
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].StartLine < blocks[j].StartLine
+	})
+	return blocks
 }
 
 // ParseFile reads and parses a Ruby file
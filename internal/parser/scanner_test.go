@@ -0,0 +1,712 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestScannerSkipsLongLines(t *testing.T) {
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	scanner := NewScanner(registry)
+	scanner.SetLimits(Limits{MaxLineLength: 100})
+
+	content := "class Assets\n  MANIFEST = \"" + strings.Repeat("x", 500) + "\"\n  def path\n  end\nend"
+	stats := NewParseStats()
+	scanner.SetStats(stats)
+	symbols := scanner.Parse("/test/assets.rb", []byte(content))
+
+	names := map[string]bool{}
+	for _, sym := range symbols {
+		names[sym.FullName] = true
+	}
+	if names["Assets::MANIFEST"] {
+		t.Error("expected over-long line to be skipped")
+	}
+	if !names["Assets#path"] {
+		t.Errorf("expected parsing to continue after the skipped line, got %v", names)
+	}
+	if files := stats.SlowestFiles(1); len(files) != 1 || files[0].SkippedLines != 1 {
+		t.Errorf("expected 1 skipped line recorded, got %+v", files)
+	}
+}
+
+func TestScannerAbandonsUnclosedMultiline(t *testing.T) {
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	scanner := NewScanner(registry)
+	scanner.SetLimits(Limits{MaxLineLength: 200})
+
+	var b strings.Builder
+	b.WriteString("class Order\n  has_many(:items,\n")
+	for i := 0; i < 50; i++ {
+		b.WriteString("    :option_that_never_closes,\n")
+	}
+	b.WriteString("  def total\n  end\nend")
+
+	symbols := scanner.Parse("/test/order.rb", []byte(b.String()))
+	for _, sym := range symbols {
+		if sym.FullName == "Order#total" {
+			return
+		}
+	}
+	t.Errorf("expected Order#total after abandoning the unclosed call, got %+v", symbols)
+}
+
+func TestScannerStopsAtFileBudget(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(&slowMatcher{delay: time.Millisecond})
+	scanner := NewScanner(registry)
+	scanner.SetLimits(Limits{FileBudget: 10 * time.Millisecond})
+
+	content := strings.Repeat("slow\n", 2000)
+	stats := NewParseStats()
+	scanner.SetStats(stats)
+
+	start := time.Now()
+	symbols := scanner.Parse("/test/generated.rb", []byte(content))
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected parsing to stop near the budget, took %s", elapsed)
+	}
+	if len(symbols) == 0 || len(symbols) >= 2000 {
+		t.Errorf("expected a partial parse, got %d symbols", len(symbols))
+	}
+	if files := stats.SlowestFiles(1); len(files) != 1 || !files[0].TimedOut {
+		t.Errorf("expected timeout recorded, got %+v", files)
+	}
+}
+
+// slowMatcher emits a constant for every line after sleeping
+type slowMatcher struct {
+	delay time.Duration
+}
+
+func (m *slowMatcher) Name() string  { return "slow" }
+func (m *slowMatcher) Priority() int { return 1 }
+
+func (m *slowMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	time.Sleep(m.delay)
+	return &MatchResult{Symbols: []*types.Symbol{{Name: "SLOW", Kind: types.KindConstant, Line: ctx.LineNum}}}
+}
+
+func TestContainerRanges(t *testing.T) {
+	content := `module Billing
+  class Invoice < Record
+    has_many :lines do
+      def subtotal
+      end
+    end
+
+    def total
+      items.each do |i|
+        i.price
+      end
+    end
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/invoice.rb", []byte(content))
+
+	want := map[string][2]int{ // FullName -> EndLine, EndColumn
+		"Billing":                 {14, 3},
+		"Billing::Invoice":        {13, 5},
+		"Billing::Invoice::lines": {6, 7},
+		"Billing::Invoice#total":  {12, 7},
+	}
+	got := map[string][2]int{}
+	for _, sym := range symbols {
+		got[sym.FullName] = [2]int{sym.EndLine, sym.EndColumn}
+	}
+	for fullName, rng := range want {
+		if got[fullName] != rng {
+			t.Errorf("%s: expected end %v, got %v", fullName, rng, got[fullName])
+		}
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	content := `class Invoice
+  def total
+    sum = if items.any?
+      items.sum(&:price)
+    else
+      0
+    end
+    items.each do |i|
+      i.price
+    end
+  end
+
+  def unfinished
+    [1].each do |n|
+`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	blocks := NewScanner(registry).Blocks("/test/invoice.rb", []byte(content))
+
+	want := []Block{{2, 11}, {3, 7}, {8, 10}}
+	if len(blocks) != len(want) {
+		t.Fatalf("expected blocks %v, got %v", want, blocks)
+	}
+	for i := range want {
+		if blocks[i] != want[i] {
+			t.Errorf("block %d: expected %v, got %v", i, want[i], blocks[i])
+		}
+	}
+}
+
+func TestProblems(t *testing.T) {
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	scanner := NewScanner(registry)
+
+	tests := []struct {
+		name    string
+		content string
+		want    []Problem
+	}{
+		{
+			name:    "balanced",
+			content: "class Invoice\n  def total\n    items.each do |i|\n    end\n  end\nend\n",
+		},
+		{
+			name:    "stray end",
+			content: "class Invoice\n  def total\n  end\n  end\nend\n",
+			want:    []Problem{{Line: 5, Column: 0, EndColumn: 3, Message: "unexpected end: no open block to close"}},
+		},
+		{
+			name:    "unterminated scopes",
+			content: "module Billing\n  class Invoice\n    def total\n      if paid?\n    end\n",
+			want: []Problem{
+				{Line: 1, Column: 0, EndColumn: 14, Message: "module Billing is missing its end"},
+				{Line: 2, Column: 2, EndColumn: 15, Message: "class Billing::Invoice is missing its end"},
+				{Line: 3, Column: 4, EndColumn: 13, Message: "def total is missing its end"},
+			},
+		},
+		{
+			name:    "singleton class",
+			content: "class Order\n  class << self\n    def build\n    end\n  end\n\n  def total\n  end\nend\n",
+		},
+		{
+			name: "begin assigned with any operator",
+			content: `class Order
+  def items
+    @items ||= begin
+      load
+    end
+    @total = begin
+      sum
+    end
+    cached ||= begin
+      fetch
+    end
+  end
+end
+`,
+		},
+		{
+			name: "conditional assigned to an ivar",
+			content: `class Order
+  def label
+    @label ||= if paid?
+      "paid"
+    end
+    @kind ||= case state
+    when :open then 1
+    end
+  end
+end
+`,
+		},
+		{
+			name: "conditional assigned to a writer or with an operator",
+			content: `class Order
+  def apply
+    self.state = if paid?
+      :paid
+    end
+    order.total = begin
+      compute
+    end
+    total += if taxed?
+      tax
+    else
+      0
+    end
+  end
+end
+`,
+		},
+		{
+			name: "begin after a boolean operator",
+			content: `class Order
+  def load
+    cached || begin
+      fetch
+    end
+  end
+end
+`,
+		},
+		{
+			name: "keywords in a heredoc",
+			content: `class Report
+  def sql
+    <<~SQL
+      SELECT * FROM orders
+      WHERE state = 'open'
+      if begin while
+    SQL
+  end
+end
+`,
+		},
+		{
+			name: "keywords in a multi-line string",
+			content: `class Report
+  def note
+    "check if
+    begin happened
+    and class names"
+  end
+end
+`,
+		},
+		{
+			name:    "string still being typed",
+			content: "class Report\n  def note\n    gem \"ra\n  end\nend\n",
+		},
+		{
+			name:    "unclosed parentheses",
+			content: "class Order\n  has_many(:items,\n    dependent: :destroy\n\n  def total\n  end\nend\n",
+			want:    []Problem{{Line: 2, Column: 2, EndColumn: 18, Message: `unclosed "(": expected a matching ")"`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scanner.Problems("/test/invoice.rb", []byte(tt.content))
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("problem %d: expected %+v, got %+v", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAssignedBlockKeepsNesting(t *testing.T) {
+	content := `class Report
+  def rows
+    rows = items.map do |i|
+      i.row
+    end
+    TOTALS = if rows.any?
+      rows.sum
+    end
+  end
+
+  def footer
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/report.rb", []byte(content))
+
+	want := map[string]int{ // FullName -> EndLine
+		"Report":        13,
+		"Report#rows":   9,
+		"Report#footer": 12,
+	}
+	got := map[string]int{}
+	for _, sym := range symbols {
+		got[sym.FullName] = sym.EndLine
+	}
+	for fullName, end := range want {
+		if got[fullName] != end {
+			t.Errorf("%s: expected end line %d, got %d", fullName, end, got[fullName])
+		}
+	}
+}
+
+func TestBranchKeywordsKeepNesting(t *testing.T) {
+	content := `class Importer
+  def run(rows)
+    if rows.empty?
+      status = :empty
+    elsif rows.size > LIMIT
+      status = :too_many
+    else if rows.first.nil?
+      status = :blank
+    end
+    end
+
+    case status
+    when :empty
+      message = "nothing"
+    else
+      message = rows.map do |row|
+        row.to_s
+      end
+    end
+
+    begin
+      result = Parser.new.parse(rows)
+    rescue ParseError => error
+      result = nil
+    rescue => error
+      raise
+    ensure
+      cleanup = true
+    end
+    result
+  end
+
+  def retry_run
+    attempts = 0
+  rescue Timeout::Error
+    attempts += 1
+    retry if attempts < 3
+  else
+    done = true
+  ensure
+    finished = true
+  end
+
+  def summary
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/importer.rb", []byte(content))
+
+	want := map[string]int{ // FullName -> EndLine
+		"Importer":           46,
+		"Importer#run":       31,
+		"Importer#retry_run": 42,
+		"Importer#summary":   45,
+	}
+	got := map[string]int{}
+	locals := map[string]string{} // FullName -> containing method
+	for _, sym := range symbols {
+		got[sym.FullName] = sym.EndLine
+		if sym.Kind == types.KindLocalVariable {
+			locals[sym.FullName] = sym.MethodFullName
+		}
+	}
+	for fullName, end := range want {
+		if got[fullName] != end {
+			t.Errorf("%s: expected end line %d, got %d", fullName, end, got[fullName])
+		}
+	}
+
+	// Assignments under every branch keyword stay in their method, and
+	// "rescue => error" isn't mistaken for one
+	wantLocals := map[string]string{
+		"Importer#run@status":         "Importer#run",
+		"Importer#run@message":        "Importer#run",
+		"Importer#run@result":         "Importer#run",
+		"Importer#run@cleanup":        "Importer#run",
+		"Importer#retry_run@attempts": "Importer#retry_run",
+		"Importer#retry_run@done":     "Importer#retry_run",
+		"Importer#retry_run@finished": "Importer#retry_run",
+	}
+	for fullName, method := range wantLocals {
+		if locals[fullName] != method {
+			t.Errorf("expected local %s in %s, got %q", fullName, method, locals[fullName])
+		}
+	}
+	if len(locals) != len(wantLocals) {
+		t.Errorf("expected %d locals, got %v", len(wantLocals), locals)
+	}
+}
+
+func TestInlineEndKeepsNesting(t *testing.T) {
+	content := `class Stub; end
+class Fake < Base; end
+module Empty; end
+class Order
+  def noop; end
+  def self.build() end
+  def total
+    if paid? then amount else 0 end
+    discount = if coupon then coupon.value end
+    amount - discount
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/order.rb", []byte(content))
+
+	want := map[string][2]int{ // FullName -> EndLine, EndColumn
+		"Stub":                 {1, 15},
+		"Fake":                 {2, 22},
+		"Empty":                {3, 17},
+		"Order":                {12, 3},
+		"Order#noop":           {5, 15},
+		"Order.build":          {6, 22},
+		"Order#total":          {11, 5},
+		"Order#total@discount": {9, 46},
+	}
+	got := map[string][2]int{}
+	for _, sym := range symbols {
+		got[sym.FullName] = [2]int{sym.EndLine, sym.EndColumn}
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %d symbols, got %v", len(want), got)
+	}
+	for fullName, end := range want {
+		if got[fullName] != end {
+			t.Errorf("%s: expected end %v, got %v", fullName, end, got[fullName])
+		}
+	}
+}
+
+func TestSingletonClassBlock(t *testing.T) {
+	content := `class Order
+  class << self
+    def build
+    end
+
+    private
+
+    def cache
+    end
+  end
+
+  def total
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/order.rb", []byte(content))
+
+	type result struct {
+		kind       types.SymbolKind
+		visibility types.Visibility
+		endLine    int
+	}
+	want := map[string]result{
+		"Order":       {types.KindClass, types.VisibilityPublic, 14},
+		"Order.build": {types.KindSingletonMethod, types.VisibilityPublic, 4},
+		"Order.cache": {types.KindSingletonMethod, types.VisibilityPrivate, 9},
+		"Order#total": {types.KindMethod, types.VisibilityPublic, 13},
+	}
+	got := map[string]result{}
+	for _, sym := range symbols {
+		got[sym.FullName] = result{sym.Kind, sym.Visibility, sym.EndLine}
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %d symbols, got %v", len(want), got)
+	}
+	for fullName, r := range want {
+		if got[fullName] != r {
+			t.Errorf("%s: expected %+v, got %+v", fullName, r, got[fullName])
+		}
+	}
+}
+
+func TestDefinedGuardsKeepNesting(t *testing.T) {
+	content := `module Billing
+  class Error < StandardError; end unless defined?(Error)
+  module Helpers; end if defined?(Rails)
+  class Gateway
+    def charge
+    end
+  end unless defined?(Gateway)
+  Timeout ||= Class.new(Error)
+  Client ||= Class.new do
+    def call
+    end
+  end
+  RETRIES ||= 3
+  puts "the end if you like" if defined?(Rails)
+  def settle
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	scanner := NewScanner(registry)
+	symbols := scanner.Parse("/test/billing.rb", []byte(content))
+
+	want := map[string]int{ // FullName -> EndLine
+		"Billing":                 17,
+		"Billing::Error":          2,
+		"Billing::Helpers":        3,
+		"Billing::Gateway":        7,
+		"Billing::Gateway#charge": 6,
+		"Billing::Timeout":        8,
+		"Billing::Client":         12,
+		"Billing::Client#call":    11,
+		"Billing::RETRIES":        0,
+		"Billing#settle":          16,
+	}
+	got := map[string]int{}
+	for _, sym := range symbols {
+		got[sym.FullName] = sym.EndLine
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %d symbols, got %v", len(want), got)
+	}
+	for fullName, end := range want {
+		if line, ok := got[fullName]; !ok || line != end {
+			t.Errorf("%s: expected end line %d, got %d (found %v)", fullName, end, line, ok)
+		}
+	}
+	if problems := scanner.Problems("/test/billing.rb", []byte(content)); len(problems) != 0 {
+		t.Errorf("expected balanced blocks, got %+v", problems)
+	}
+}
+
+func TestChainedEndKeepsNesting(t *testing.T) {
+	content := `class Report
+  ROWS = [1, 2].map do |i|
+    i * 2
+  end.freeze
+
+  def rows
+    items.map do |item|
+      item.id
+    end.compact
+    items.select do |item|
+      item.ok?
+    end.each do |item|
+      item.save
+    end
+    items.map { |item|
+      item.id
+    }.each do |id|
+      puts id
+    end
+    sorted = [1].map do |i|
+      i
+    end.tap { |r| r.sort! }
+  end
+
+  def footer
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	scanner := NewScanner(registry)
+	symbols := scanner.Parse("/test/report.rb", []byte(content))
+
+	// Chained calls close their block without adding symbols
+	want := map[string]int{ // FullName -> EndLine
+		"Report":             27,
+		"Report::ROWS":       4,
+		"Report#rows":        23,
+		"Report#rows@sorted": 22,
+		"Report#footer":      26,
+	}
+	got := map[string]int{}
+	for _, sym := range symbols {
+		got[sym.FullName] = sym.EndLine
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %d symbols, got %v", len(want), got)
+	}
+	for fullName, end := range want {
+		if got[fullName] != end {
+			t.Errorf("%s: expected end line %d, got %d", fullName, end, got[fullName])
+		}
+	}
+
+	// The block chained off end.each runs to its own end
+	blocks := map[Block]bool{}
+	for _, b := range scanner.Blocks("/test/report.rb", []byte(content)) {
+		blocks[b] = true
+	}
+	for _, b := range []Block{{10, 12}, {12, 14}, {15, 17}, {17, 19}} {
+		if !blocks[b] {
+			t.Errorf("expected block %+v, got %v", b, blocks)
+		}
+	}
+}
+
+func TestSymbolColumns(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		content  string
+		fullName string
+		column   int
+	}{
+		{"class name repeated in comment", "/app/foo.rb", "class Foo; end # Foo", "Foo", 6},
+		{"nested class name", "/app/b.rb", "class A::B\nend", "A::B", 9},
+		{"module name", "/app/a.rb", "module Outer::Inner\nend", "Outer::Inner", 14},
+		{"method name inside def", "/app/a.rb", "class A\n  def de\n  end\nend", "A#de", 6},
+		{"singleton method", "/app/a.rb", "class A\n  def self.sel\n  end\nend", "A.sel", 11},
+		{"relation named like its macro", "/app/a.rb", "class A\n  has_many :has\nend", "A::has", 12},
+		{"relation with parens", "/app/a.rb", "class A\n  belongs_to( :owner )\nend", "A::owner", 15},
+		{"second of multiple locals", "/app/a.rb", "class A\n  def m\n    ab, a = 1, 2\n  end\nend", "A#m@a", 8},
+		{"constant", "/app/a.rb", "class A\n  MAX = 3 # MAX\nend", "A::MAX", 2},
+		{"named scope", "/app/a.rb", "class A\n  scope :scope, -> { all }\nend", "A.scope", 9},
+		{"schema column named like its type", "/db/schema.rb", "create_table \"a\", force: :cascade do |t|\n  t.string \"string\"\nend", "A#string", 12},
+		{"rake task named task", "/lib/tasks/a.rake", "task :task do\nend", "task", 6},
+		{"let", "/spec/a_spec.rb", "describe A do\n  let(:let) { 1 }\nend", "let", 7},
+	}
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	scanner := NewScanner(registry)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, sym := range scanner.Parse(tt.path, []byte(tt.content)) {
+				if sym.FullName != tt.fullName {
+					continue
+				}
+				if sym.Column != tt.column {
+					t.Errorf("expected column %d, got %d", tt.column, sym.Column)
+				}
+				return
+			}
+			t.Errorf("symbol %s not found", tt.fullName)
+		})
+	}
+}
+
+func TestMultilineSymbolPositions(t *testing.T) {
+	content := `class Order
+  has_many :items,
+    class_name: "LineItem"
+  belongs_to(
+      :customer,
+      optional: true
+  )
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/app/order.rb", []byte(content))
+
+	want := map[string][2]int{ // FullName -> Line, Column
+		"Order::items":    {2, 12},
+		"Order::customer": {5, 7},
+	}
+	got := map[string][2]int{}
+	for _, sym := range symbols {
+		got[sym.FullName] = [2]int{sym.Line, sym.Column}
+	}
+	for fullName, pos := range want {
+		if got[fullName] != pos {
+			t.Errorf("%s: expected line/column %v, got %v", fullName, pos, got[fullName])
+		}
+	}
+}
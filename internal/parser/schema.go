@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// create_table "line_items", force: :cascade do |t|
+var createTablePattern = regexp.MustCompile(`^\s*create_table\s+(?::(\w+)|["'](\w+)["']).*\bdo\s*\|\s*\w+\s*\|`)
+
+// t.integer "owner_id", null: false
+// t.references :customer
+// t.timestamps
+var columnPattern = regexp.MustCompile(`^\s*\w+\.(\w+)(?:\s+(?::(\w+)|["'](\w+)["']))?`)
+
+// SchemaMatcher extracts database columns from db/schema.rb, scoped under the
+// model class inferred from the table name ("line_items" → LineItem)
+type SchemaMatcher struct{}
+
+func (m *SchemaMatcher) Name() string  { return "schema" }
+func (m *SchemaMatcher) Priority() int { return 68 } // Below local vars (70), above do (60)
+
+func (m *SchemaMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	if !isSchemaPath(ctx.FilePath) {
+		return nil
+	}
+
+	if match := createTablePattern.FindStringSubmatch(line); match != nil {
+		return &MatchResult{
+			PushScope:  toClassName(match[1]+match[2], true),
+			OpensBlock: true,
+		}
+	}
+
+	// Columns only appear inside a create_table block, the only block in a
+	// schema that opens a scope
+	if len(ctx.CurrentScope) == 0 || ctx.CurrentMethod != nil {
+		return nil
+	}
+
+	loc := columnPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return nil
+	}
+
+	columnType := line[loc[2]:loc[3]]
+	name := submatch(line, loc, 2) + submatch(line, loc, 3)
+
+	var columns []string
+	switch columnType {
+	case "index", "check_constraint":
+		return nil
+	case "timestamps":
+		columns = []string{"created_at", "updated_at"}
+	case "references", "belongs_to":
+		if name == "" {
+			return nil
+		}
+		columns = []string{name + "_id"}
+	default:
+		if name == "" {
+			return nil
+		}
+		columns = []string{name}
+	}
+
+	col := loc[2]
+	if loc[4] >= 0 {
+		col = loc[4]
+	} else if loc[6] >= 0 {
+		col = loc[6]
+	}
+
+	var symbols []*types.Symbol
+	for _, column := range columns {
+		sym := &types.Symbol{
+			Name:     column,
+			Kind:     types.KindColumn,
+			FilePath: ctx.FilePath,
+			Line:     ctx.LineNum,
+			Column:   col,
+			Scope:    append([]string{}, ctx.CurrentScope...),
+		}
+		sym.FullName = sym.ComputeFullName()
+		symbols = append(symbols, sym)
+	}
+
+	return &MatchResult{Symbols: symbols}
+}
+
+// isSchemaPath reports whether path is a Rails app's db/schema.rb, rather
+// than another file named schema.rb (app/graphql/schema.rb)
+func isSchemaPath(path string) bool {
+	return strings.HasSuffix(filepath.ToSlash(path), "/db/schema.rb")
+}
@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestSchemaParsing(t *testing.T) {
+	content := `ActiveRecord::Schema[7.1].define(version: 2024_01_01_000000) do
+  create_table "line_items", force: :cascade do |t|
+    t.integer "order_id", null: false
+    t.references :product
+    t.timestamps
+    t.index ["order_id"], name: "index_line_items_on_order_id"
+  end
+
+  create_table :orders do |t|
+    t.string "number"
+    t.integer "items_count", default: 0
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/app/db/schema.rb", []byte(content))
+
+	var got []string
+	for _, sym := range symbols {
+		if sym.Kind != types.KindColumn {
+			t.Errorf("unexpected %s symbol %s", sym.Kind, sym.FullName)
+			continue
+		}
+		got = append(got, sym.FullName)
+	}
+
+	want := []string{
+		"LineItem#order_id",
+		"LineItem#product_id",
+		"LineItem#created_at",
+		"LineItem#updated_at",
+		"Order#number",
+		"Order#items_count",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %s at %d, got %s", want[i], i, got[i])
+		}
+	}
+}
+
+func TestSchemaMatcherOnlyInSchemaFile(t *testing.T) {
+	matcher := &SchemaMatcher{}
+	ctx := &ParseContext{
+		FilePath:     "/app/db/migrate/20240101_create_orders.rb",
+		LineNum:      1,
+		CurrentScope: []string{"CreateOrders"},
+	}
+
+	if result := matcher.Match(`    t.string "number"`, ctx); result != nil {
+		t.Errorf("expected migrations to be ignored, got %+v", result)
+	}
+}
+
+func TestSchemaMatcherOnlyInDbSchema(t *testing.T) {
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/app/app/graphql/schema.rb", []byte(`class AppSchema < GraphQL::Schema
+  field :orders do |t|
+    t.string :name
+  end
+end`))
+	for _, sym := range symbols {
+		if sym.Kind == types.KindColumn {
+			t.Errorf("expected no columns outside db/schema.rb, got %s", sym.FullName)
+		}
+	}
+
+	symbols = NewScanner(registry).Parse("/app/db/schema.rb", []byte(`ActiveRecord::Schema[7.1].define(version: 2024_01_01) do
+  create_table "orders", force: :cascade do |t|
+    t.string "number"
+  end
+
+  add_foreign_key "orders", "customers"
+end`))
+	var columns []string
+	for _, sym := range symbols {
+		if sym.Kind == types.KindColumn {
+			columns = append(columns, sym.FullName)
+		}
+	}
+	if len(columns) != 1 || columns[0] != "Order#number" {
+		t.Errorf("expected only Order#number, got %v", columns)
+	}
+}
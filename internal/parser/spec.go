@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// describe Order do / RSpec.describe "checkout", type: :feature do / context "when paid" do
+var exampleGroupPattern = regexp.MustCompile(`^\s*(?:RSpec\.)?[fx]?(describe|context|feature)\b\s*\(?\s*(.*?)\)?\s*\bdo\s*(?:\|[^|]*\|)?\s*$`)
+
+// shared_examples "an auditable model" do / RSpec.shared_context 'with a user' do
+var sharedExamplePattern = regexp.MustCompile(`^\s*(?:RSpec\.)?(shared_examples_for|shared_examples|shared_context)\b\s*\(?\s*(?:"([^"]+)"|'([^']+)'|:(\w+))`)
+
+// A described constant: Billing::Invoice / ::Order
+var describedClassPattern = regexp.MustCompile(`^(?:::)?[A-Z]\w*(?:::[A-Z]\w*)*$`)
+
+// let(:user) { ... } / let!(:order) do / subject(:invoice) { ... } /
+// subject { ... }
+var letPattern = regexp.MustCompile(`^\s*(?:(?:let!?|subject!?)\s*\(\s*:(\w+[?!]?)\s*\)|(subject)!?\s*(?:\{|do\b))`)
+
+// The one-line body of a let: { create(:user) }
+var letBodyPattern = regexp.MustCompile(`^\s*\{\s*(.*?)\s*\}\s*$`)
+
+// A FactoryBot call building a model: create(:user) / FactoryBot.build_stubbed(:order, paid: true)
+var factoryCallPattern = regexp.MustCompile(`^(?:FactoryBot\.)?(?:create|build|build_stubbed)\s*\(?\s*:(\w+)`)
+
+// A class method on the described class: described_class.new(...) / described_class.create!
+var describedValuePattern = regexp.MustCompile(`^described_class\.(?:new|create!?|find)(?:[\s(]|$)`)
+
+// SpecMatcher extracts RSpec example groups, shared examples, and let
+// helpers so spec navigation works like code navigation
+type SpecMatcher struct{}
+
+func (m *SpecMatcher) Name() string  { return "spec" }
+func (m *SpecMatcher) Priority() int { return 63 } // Above do (60), so groups track their end
+
+func (m *SpecMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	if !isSpecPath(ctx.FilePath) {
+		return nil
+	}
+
+	if loc := sharedExamplePattern.FindStringSubmatchIndex(line); loc != nil {
+		col := loc[4]
+		for n := 3; col < 0 && n <= 4; n++ {
+			col = loc[2*n]
+		}
+		name := submatch(line, loc, 2) + submatch(line, loc, 3) + submatch(line, loc, 4)
+		sym := &types.Symbol{
+			Name:     name,
+			Kind:     types.KindSharedExample,
+			FilePath: ctx.FilePath,
+			Line:     ctx.LineNum,
+			Column:   col,
+			FullName: name, // Shared examples are looked up globally by name
+		}
+		return &MatchResult{Symbols: []*types.Symbol{sym}, OpensBlock: opensDoBlock(line)}
+	}
+
+	if loc := exampleGroupPattern.FindStringSubmatchIndex(line); loc != nil {
+		description, described := submatch(line, loc, 2), ""
+		if args := splitArguments(description); len(args) > 0 {
+			description = args[0]
+			if v, ok := literalValue(description); ok {
+				description = v
+			} else if describedClassPattern.MatchString(description) {
+				described = description
+			}
+		}
+		sym := &types.Symbol{
+			Name:     description,
+			Kind:     types.KindExampleGroup,
+			FilePath: ctx.FilePath,
+			Line:     ctx.LineNum,
+			Column:   loc[2],
+			Scope:    append([]string{}, ctx.CurrentScope...),
+			TypeName: described,
+		}
+		sym.FullName = sym.ComputeFullName()
+		return &MatchResult{Symbols: []*types.Symbol{sym}, OpensBlock: true}
+	}
+
+	if loc := letPattern.FindStringSubmatchIndex(line); loc != nil {
+		n, rest := 1, line[loc[1]:]
+		if loc[2] < 0 {
+			n, rest = 2, strings.TrimPrefix(line[loc[5]:], "!") // Unnamed subject
+		}
+		sym := &types.Symbol{
+			Name:     submatch(line, loc, n),
+			Kind:     types.KindLet,
+			FilePath: ctx.FilePath,
+			Line:     ctx.LineNum,
+			Column:   loc[2*n],
+			Scope:    append([]string{}, ctx.CurrentScope...),
+		}
+		sym.FullName = sym.ComputeFullName()
+		letType(sym, rest)
+		return &MatchResult{Symbols: []*types.Symbol{sym}, OpensBlock: opensDoBlock(line)}
+	}
+
+	return nil
+}
+
+// letType records the class a let's one-line body evidently returns: a
+// constant's constructor (TypeName User for { User.new }), the described
+// class (TypeName described_class for { described_class.new(amount) }), or
+// the model a factory builds (Options factory user for { create(:user) }),
+// which only the index can resolve
+func letType(sym *types.Symbol, rest string) {
+	m := letBodyPattern.FindStringSubmatch(rest)
+	if m == nil {
+		return
+	}
+	body := m[1]
+	switch {
+	case describedValuePattern.MatchString(body):
+		sym.TypeName = "described_class"
+	case factoryCallPattern.MatchString(body):
+		sym.Options = map[string]string{"factory": factoryCallPattern.FindStringSubmatch(body)[1]}
+	default:
+		sym.TypeName = valueType(body)
+	}
+}
+
+// isSpecPath reports whether a file belongs to an RSpec suite
+func isSpecPath(path string) bool {
+	return strings.HasSuffix(path, "_spec.rb") ||
+		strings.Contains(filepath.ToSlash(path), "/spec/")
+}
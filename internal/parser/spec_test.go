@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestSpecMatcher(t *testing.T) {
+	matcher := &SpecMatcher{}
+
+	tests := []struct {
+		name     string
+		line     string
+		path     string
+		wantKind types.SymbolKind
+		wantName string
+		wantNil  bool
+	}{
+		{"shared examples", `shared_examples "an auditable model" do`, "/app/spec/support/auditable.rb", types.KindSharedExample, "an auditable model", false},
+		{"shared context with RSpec prefix", `RSpec.shared_context 'with a user' do`, "/app/spec/support/user.rb", types.KindSharedExample, "with a user", false},
+		{"shared examples for", `shared_examples_for(:billable) do`, "/app/spec/support/billable.rb", types.KindSharedExample, "billable", false},
+		{"describe constant", `RSpec.describe Billing::Invoice, type: :model do`, "/app/spec/models/invoice_spec.rb", types.KindExampleGroup, "Billing::Invoice", false},
+		{"context string", `  context "when paid" do`, "/app/spec/models/invoice_spec.rb", types.KindExampleGroup, "when paid", false},
+		{"let with brace block", `  let(:user) { create(:user) }`, "/app/spec/models/invoice_spec.rb", types.KindLet, "user", false},
+		{"let bang with do block", `  let!(:order) do`, "/app/spec/models/invoice_spec.rb", types.KindLet, "order", false},
+		{"named subject", `  subject(:invoice) { described_class.new }`, "/app/spec/models/invoice_spec.rb", types.KindLet, "invoice", false},
+		{"outside spec files", `  let(:user) { create(:user) }`, "/app/lib/thing.rb", 0, "", true},
+		{"it block", `  it "works" do`, "/app/spec/models/invoice_spec.rb", 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &ParseContext{FilePath: tt.path, LineNum: 1}
+			result := matcher.Match(tt.line, ctx)
+			if tt.wantNil {
+				if result != nil {
+					t.Errorf("expected no match, got %+v", result)
+				}
+				return
+			}
+			if result == nil || len(result.Symbols) != 1 {
+				t.Fatalf("expected 1 symbol, got %+v", result)
+			}
+			sym := result.Symbols[0]
+			if sym.Kind != tt.wantKind || sym.Name != tt.wantName {
+				t.Errorf("got %v %q, want %v %q", sym.Kind, sym.Name, tt.wantKind, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestSpecGroupsRecordEndLine(t *testing.T) {
+	content := `RSpec.describe Invoice do
+  let(:user) { create(:user) }
+
+  context "when paid" do
+    let(:user) do
+      create(:admin)
+    end
+
+    it "is closed" do
+      expect(subject).to be_closed
+    end
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/app/spec/models/invoice_spec.rb", []byte(content))
+
+	ends := map[string]int{}
+	lets := 0
+	for _, sym := range symbols {
+		switch sym.Kind {
+		case types.KindExampleGroup:
+			ends[sym.Name] = sym.EndLine
+		case types.KindLet:
+			lets++
+		}
+	}
+	if ends["Invoice"] != 13 {
+		t.Errorf("expected Invoice group to end on line 13, got %d", ends["Invoice"])
+	}
+	if ends["when paid"] != 12 {
+		t.Errorf("expected 'when paid' group to end on line 12, got %d", ends["when paid"])
+	}
+	if lets != 2 {
+		t.Errorf("expected 2 lets, got %d", lets)
+	}
+}
+
+func TestLetTypes(t *testing.T) {
+	matcher := &SpecMatcher{}
+
+	tests := []struct {
+		line        string
+		wantName    string
+		wantType    string
+		wantFactory string
+	}{
+		{`  let(:user) { create(:user) }`, "user", "", "user"},
+		{`  let!(:order) { FactoryBot.build_stubbed(:order, paid: true) }`, "order", "", "order"},
+		{`  let(:invoice) { Billing::Invoice.new(total: 10) }`, "invoice", "Billing::Invoice", ""},
+		{`  subject { described_class.new(order) }`, "subject", "described_class", ""},
+		{`  subject! { described_class.create! }`, "subject", "described_class", ""},
+		{`  subject(:total) { order.total }`, "total", "", ""},
+		{`  let(:attrs) { attributes_for(:user) }`, "attrs", "", ""},
+		{`  let(:user) do`, "user", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			ctx := &ParseContext{FilePath: "/app/spec/models/invoice_spec.rb", LineNum: 1}
+			result := matcher.Match(tt.line, ctx)
+			if result == nil || len(result.Symbols) != 1 {
+				t.Fatalf("expected 1 symbol, got %+v", result)
+			}
+			sym := result.Symbols[0]
+			if sym.Name != tt.wantName || sym.TypeName != tt.wantType || sym.Options["factory"] != tt.wantFactory {
+				t.Errorf("expected %s of type %q from factory %q, got %s of type %q from %v",
+					tt.wantName, tt.wantType, tt.wantFactory, sym.Name, sym.TypeName, sym.Options)
+			}
+		})
+	}
+}
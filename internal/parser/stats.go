@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MatcherTiming is the time one matcher spent across parsed files
+type MatcherTiming struct {
+	Name     string        `json:"name"`
+	Calls    int           `json:"calls"`
+	Matches  int           `json:"matches"`
+	Duration time.Duration `json:"duration"`
+}
+
+// FileTiming is the time spent parsing one file
+type FileTiming struct {
+	Path         string        `json:"path"`
+	Lines        int           `json:"lines"`
+	Duration     time.Duration `json:"duration"`
+	SkippedLines int           `json:"skippedLines,omitempty"` // Lines over the length limit
+	TimedOut     bool          `json:"timedOut,omitempty"`     // Parsing stopped at the time budget
+}
+
+// ParseStats accumulates per-matcher and per-file timing. It is safe for
+// concurrent use by scanners parsing different files.
+type ParseStats struct {
+	mu       sync.Mutex
+	matchers map[string]*MatcherTiming
+	files    []FileTiming
+}
+
+// NewParseStats creates an empty stats collector
+func NewParseStats() *ParseStats {
+	return &ParseStats{matchers: make(map[string]*MatcherTiming)}
+}
+
+// record merges the timing of one parsed file
+func (p *ParseStats) record(file FileTiming, matchers map[string]*MatcherTiming) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.files = append(p.files, file)
+	for name, t := range matchers {
+		total := p.matchers[name]
+		if total == nil {
+			total = &MatcherTiming{Name: name}
+			p.matchers[name] = total
+		}
+		total.Calls += t.Calls
+		total.Matches += t.Matches
+		total.Duration += t.Duration
+	}
+}
+
+// Matchers returns matcher timings, most expensive first
+func (p *ParseStats) Matchers() []MatcherTiming {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := make([]MatcherTiming, 0, len(p.matchers))
+	for _, t := range p.matchers {
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Duration != result[j].Duration {
+			return result[i].Duration > result[j].Duration
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// SlowestFiles returns up to n file timings, slowest first
+func (p *ParseStats) SlowestFiles(n int) []FileTiming {
+	p.mu.Lock()
+	result := make([]FileTiming, len(p.files))
+	copy(result, p.files)
+	p.mu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Duration != result[j].Duration {
+			return result[i].Duration > result[j].Duration
+		}
+		return result[i].Path < result[j].Path
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
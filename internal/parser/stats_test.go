@@ -0,0 +1,36 @@
+package parser
+
+import "testing"
+
+func TestParseStatsCollectsTiming(t *testing.T) {
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	scanner := NewScanner(registry)
+
+	stats := NewParseStats()
+	scanner.SetStats(stats)
+	scanner.Parse("/test/a.rb", []byte("class A\n  def b\n  end\nend"))
+	scanner.Parse("/test/b.rb", []byte("module B\nend"))
+	scanner.SetStats(nil)
+	scanner.Parse("/test/c.rb", []byte("class C\nend"))
+
+	files := stats.SlowestFiles(10)
+	if len(files) != 2 {
+		t.Fatalf("expected 2 timed files, got %+v", files)
+	}
+	if stats.SlowestFiles(1)[0] != files[0] {
+		t.Errorf("expected SlowestFiles(1) to return the slowest file")
+	}
+
+	byName := map[string]MatcherTiming{}
+	for _, m := range stats.Matchers() {
+		byName[m.Name] = m
+	}
+	if byName["class"].Matches != 1 || byName["module"].Matches != 1 {
+		t.Errorf("expected one class and one module match, got %+v", byName)
+	}
+	// Every line runs the top-priority matcher until one matches
+	if byName["class"].Calls != 6 {
+		t.Errorf("expected class matcher to run on all 6 lines, got %d", byName["class"].Calls)
+	}
+}
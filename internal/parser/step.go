@@ -0,0 +1,46 @@
+package parser
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// Given(/^I log in as "([^"]*)"$/) do |name|
+// When "I have {int} cukes in my belly" do |count|
+var stepPattern = regexp.MustCompile(`^\s*(Given|When|Then|And|But|Step)\b\s*\(?\s*(/(?:\\.|[^/])*/[imx]*|"(?:\\.|[^"])*"|'(?:\\.|[^'])*')`)
+
+// StepMatcher extracts Cucumber step definitions. The symbol name is the
+// step's regexp or Cucumber expression literal, delimiters included.
+type StepMatcher struct{}
+
+func (m *StepMatcher) Name() string  { return "step" }
+func (m *StepMatcher) Priority() int { return 62 } // Above do (60), step bodies are do blocks
+
+func (m *StepMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	if !strings.Contains(filepath.ToSlash(ctx.FilePath), "/features/") {
+		return nil
+	}
+
+	loc := stepPattern.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return nil
+	}
+
+	name := line[loc[4]:loc[5]]
+	sym := &types.Symbol{
+		Name:     name,
+		Kind:     types.KindStep,
+		FilePath: ctx.FilePath,
+		Line:     ctx.LineNum,
+		Column:   loc[4],
+		FullName: name,
+	}
+
+	return &MatchResult{
+		Symbols:    []*types.Symbol{sym},
+		OpensBlock: opensDoBlock(line),
+	}
+}
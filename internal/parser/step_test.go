@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestStepMatcher(t *testing.T) {
+	matcher := &StepMatcher{}
+	path := "/app/features/step_definitions/login_steps.rb"
+
+	tests := []struct {
+		line     string
+		path     string
+		wantName string
+	}{
+		{`Given(/^I log in as "([^"]*)"$/) do |name|`, path, `/^I log in as "([^"]*)"$/`},
+		{`When /^I visit the (\w+) page$/i do |page|`, path, `/^I visit the (\w+) page$/i`},
+		{`Then "I should see {int} orders" do |count|`, path, `"I should see {int} orders"`},
+		{`And('I click {string}') do |label|`, path, `'I click {string}'`},
+		{`Given(/^I log in$/) do`, "/app/lib/given.rb", ""},
+		{`visit root_path`, path, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			result := matcher.Match(tt.line, &ParseContext{FilePath: tt.path, LineNum: 1})
+			if tt.wantName == "" {
+				if result != nil {
+					t.Errorf("expected no match, got %+v", result.Symbols)
+				}
+				return
+			}
+			if result == nil || len(result.Symbols) != 1 {
+				t.Fatalf("expected 1 symbol, got %+v", result)
+			}
+			sym := result.Symbols[0]
+			if sym.Name != tt.wantName || sym.Kind != types.KindStep {
+				t.Errorf("got %v %q, want step %q", sym.Kind, sym.Name, tt.wantName)
+			}
+			if !result.OpensBlock {
+				t.Error("expected step definition to open a block")
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+class OrdersController < ApplicationController
+  before_action :set_order, only: %i[show update]
+
+  def index
+    @orders = Shop::Order.recent.page(params[:page])
+    respond_to do |format|
+      format.html
+      format.json { render json: @orders }
+    end
+  end
+
+  def show
+    order = Shop::Order.find(params[:id])
+    items = order.line_items.includes(:product)
+    render :show, locals: { order: order, items: items }
+  end
+
+  def update
+    if @order.update(order_params)
+      redirect_to @order, notice: "Order updated"
+    else
+      render :edit, status: :unprocessable_entity
+    end
+  end
+
+  private
+
+  def set_order
+    @order = Shop::Order.find(params[:id])
+  end
+
+  def order_params
+    params.require(:order).permit(:state, line_items_attributes: %i[id quantity])
+  end
+end
@@ -0,0 +1,74 @@
+# frozen_string_literal: true
+
+module Shop
+  class Order < ApplicationRecord
+    include Auditable
+
+    STATES = %w[draft placed shipped].freeze
+    TAX_RATE = 0.2
+
+    belongs_to :customer, class_name: "Shop::Customer", inverse_of: :orders
+    has_many :line_items, dependent: :destroy
+    has_one :invoice
+
+    scope :recent, -> { order(created_at: :desc) }
+    scope :placed, lambda { where(state: "placed") }
+
+    enum :priority, { standard: 0, express: 1 }, prefix: true
+
+    STATES.each do |state|
+      define_method("#{state}?") do
+        self.state == state
+      end
+    end
+
+    validates :customer, presence: true
+    before_save :recalculate
+
+    def self.for_customer(customer)
+      where(customer: customer)
+    end
+
+    def total
+      subtotal = line_items.sum(&:price)
+      tax, fees = subtotal * TAX_RATE, shipping_fee
+      subtotal + tax + fees
+    end
+
+    def place!
+      return false unless draft?
+
+      update!(state: "placed", placed_at: Time.current)
+    end
+
+    concerning :Exporting do
+      def to_csv
+        [id, total].join(",")
+      end
+    end
+
+    protected
+
+    def shipping_fee
+      line_items.any?(&:oversized?) ? 15 : 5
+    end
+
+    private
+
+    def recalculate
+      if line_items.empty?
+        self.total_cents = 0
+      else
+        self.total_cents = (total * 100).round
+      end
+    end
+
+    def method_missing(name, *args)
+      if name.to_s.start_with?("find_line_")
+        line_items.find_by(sku: name.to_s.delete_prefix("find_line_"))
+      else
+        super
+      end
+    end
+  end
+end
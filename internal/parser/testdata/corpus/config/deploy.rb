@@ -0,0 +1,14 @@
+lock "~> 3.18"
+
+set :application, "shop"
+set :repo_url, "git@example.com:shop.git"
+
+namespace :deploy do
+  task :restart do
+    on roles(:app) do
+      execute :touch, release_path.join("tmp/restart.txt")
+    end
+  end
+
+  after :publishing, :restart
+end
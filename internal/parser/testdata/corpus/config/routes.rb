@@ -0,0 +1,19 @@
+Rails.application.routes.draw do
+  root "home#index"
+
+  resources :orders, only: %i[index show] do
+    member do
+      post :ship
+    end
+    resources :line_items, shallow: true
+  end
+
+  resource :cart
+
+  namespace :admin do
+    resources :customers, :invoices
+    get "reports/:year", to: "reports#show", as: :yearly_report
+  end
+
+  get "/about" => "pages#about" # static page
+end
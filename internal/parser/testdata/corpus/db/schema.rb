@@ -0,0 +1,15 @@
+ActiveRecord::Schema[7.1].define(version: 2024_05_01_120000) do
+  create_table "orders", force: :cascade do |t|
+    t.bigint "customer_id", null: false
+    t.string "state", default: "draft"
+    t.integer "total_cents"
+    t.datetime "placed_at"
+    t.timestamps
+  end
+
+  create_table "line_items" do |t|
+    t.references :order, null: false
+    t.string "sku"
+    t.decimal "price", precision: 10, scale: 2
+  end
+end
@@ -0,0 +1,13 @@
+Given(/^I have (\d+) items? in my cart$/) do |count|
+  count.to_i.times { add_to_cart(create(:product)) }
+end
+
+When("I check out with {string}") do |method|
+  visit checkout_path
+  choose method
+  click_button "Place order"
+end
+
+Then "I see the order confirmation" do
+  expect(page).to have_content("Thank you")
+end
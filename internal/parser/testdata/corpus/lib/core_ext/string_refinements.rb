@@ -0,0 +1,27 @@
+# frozen_string_literal: true
+
+module Shop
+  module StringRefinements
+    SEPARATOR = "-"
+
+    refine String do
+      def to_sku
+        upcase.tr(" ", SEPARATOR)
+      end
+
+      def sku?
+        match?(/\A[A-Z0-9-]+\z/)
+      end
+    end
+
+    refine(::Integer) do
+      def cents
+        self * 100
+      end
+    end
+
+    def self.refined_classes
+      [String, Integer]
+    end
+  end
+end
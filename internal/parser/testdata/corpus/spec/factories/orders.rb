@@ -0,0 +1,14 @@
+FactoryBot.define do
+  factory :order, class: "Shop::Order" do
+    customer
+    state { "draft" }
+
+    factory :placed_order do
+      state { "placed" }
+    end
+  end
+
+  factory :customer, class: Shop::Customer do
+    sequence(:email) { |n| "customer#{n}@example.com" }
+  end
+end
@@ -0,0 +1,27 @@
+require "rails_helper"
+
+RSpec.describe Shop::Order do
+  let(:customer) { create(:customer) }
+  let!(:order) { create(:order, customer: customer) }
+  subject(:total) { order.total }
+
+  shared_examples "a placeable order" do
+    it "places the order" do
+      expect(order.place!).to be true
+    end
+  end
+
+  describe "#total" do
+    context "with line items" do
+      let(:items) { create_list(:line_item, 2, order: order, price: 10) }
+
+      before { items }
+
+      it "adds tax" do
+        expect(total).to eq(24)
+      end
+    end
+  end
+
+  it_behaves_like "a placeable order"
+end
@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+// private / protected / public (section form)
+// private :helper, :other_helper
+// private_class_method :new
+// private_constant :TIMEOUT
+var visibilityPattern = regexp.MustCompile(`^\s*(private|protected|public)(_class_method|_constant)?\b(?:\s*\(?\s*(.*?)\)?)?\s*(?:#.*)?$`)
+
+// Symbol arguments: :name, 'name', "name"
+var visibilityArgPattern = regexp.MustCompile(`^\s*(?::|['"])([A-Za-z_]\w*[?!=]?)['"]?\s*$`)
+
+// VisibilityMatcher records visibility modifiers for methods and constants
+type VisibilityMatcher struct{}
+
+func (m *VisibilityMatcher) Name() string  { return "visibility" }
+func (m *VisibilityMatcher) Priority() int { return 75 }
+
+func (m *VisibilityMatcher) Match(line string, ctx *ParseContext) *MatchResult {
+	match := visibilityPattern.FindStringSubmatch(line)
+	if match == nil {
+		return nil
+	}
+
+	change := &VisibilityChange{}
+	switch match[1] {
+	case "private":
+		change.Visibility = types.VisibilityPrivate
+	case "protected":
+		change.Visibility = types.VisibilityProtected
+	default:
+		change.Visibility = types.VisibilityPublic
+	}
+
+	switch match[2] {
+	case "_class_method":
+		if change.Visibility == types.VisibilityProtected {
+			return nil // protected_class_method is not a Ruby method
+		}
+		change.Kinds = []types.SymbolKind{types.KindSingletonMethod}
+	case "_constant":
+		if change.Visibility == types.VisibilityProtected {
+			return nil
+		}
+		change.Kinds = []types.SymbolKind{types.KindConstant, types.KindClass, types.KindModule}
+	case "":
+		if ctx.InSingletonClass {
+			// Inside class << self, private applies to class methods
+			change.Kinds = []types.SymbolKind{types.KindSingletonMethod}
+			break
+		}
+		change.Kinds = []types.SymbolKind{
+			types.KindMethod, types.KindAttrReader, types.KindAttrWriter, types.KindAttrAccessor,
+		}
+	}
+
+	args := match[3]
+	if args != "" {
+		for _, arg := range strings.Split(args, ",") {
+			argMatch := visibilityArgPattern.FindStringSubmatch(arg)
+			if argMatch == nil {
+				// Not a plain symbol list (e.g., `private def foo`)
+				return nil
+			}
+			change.Names = append(change.Names, argMatch[1])
+		}
+	} else if match[2] != "" {
+		// private_class_method / private_constant require arguments
+		return nil
+	}
+
+	return &MatchResult{SetVisibility: change}
+}
+
+// applies reports whether the change covers the given symbol kind
+func (c *VisibilityChange) applies(kind types.SymbolKind) bool {
+	for _, k := range c.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// applyVisibility updates previously parsed symbols named by the change that
+// are defined directly in scope. Later definitions win, so it walks backwards
+// and stops at the first match per name.
+func applyVisibility(symbols []*types.Symbol, change *VisibilityChange, scope []string) {
+	key := strings.Join(scope, "::")
+	for _, name := range change.Names {
+		for i := len(symbols) - 1; i >= 0; i-- {
+			sym := symbols[i]
+			if sym.Name == name && change.applies(sym.Kind) && strings.Join(sym.Scope, "::") == key {
+				sym.Visibility = change.Visibility
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/jarredhawkins/goruby-lsp/internal/types"
+)
+
+func TestVisibilityMatcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantNil   bool
+		wantVis   types.Visibility
+		wantNames []string
+	}{
+		{
+			name:    "bare private section",
+			line:    "  private",
+			wantVis: types.VisibilityPrivate,
+		},
+		{
+			name:    "bare protected with comment",
+			line:    "  protected # helpers",
+			wantVis: types.VisibilityProtected,
+		},
+		{
+			name:      "private with symbols",
+			line:      "  private :helper, :other?",
+			wantVis:   types.VisibilityPrivate,
+			wantNames: []string{"helper", "other?"},
+		},
+		{
+			name:      "private_class_method",
+			line:      "  private_class_method :new",
+			wantVis:   types.VisibilityPrivate,
+			wantNames: []string{"new"},
+		},
+		{
+			name:      "private_constant with parens",
+			line:      "  private_constant(:TIMEOUT, :RETRIES)",
+			wantVis:   types.VisibilityPrivate,
+			wantNames: []string{"TIMEOUT", "RETRIES"},
+		},
+		{
+			name:      "public_class_method with string",
+			line:      "  public_class_method 'build'",
+			wantVis:   types.VisibilityPublic,
+			wantNames: []string{"build"},
+		},
+		{
+			name:    "private def is not a symbol list",
+			line:    "  private def helper",
+			wantNil: true,
+		},
+		{
+			name:    "private_constant without args",
+			line:    "  private_constant",
+			wantNil: true,
+		},
+		{
+			name:    "protected_class_method does not exist",
+			line:    "  protected_class_method :new",
+			wantNil: true,
+		},
+		{
+			name:    "identifier starting with private",
+			line:    "  privately_owned = true",
+			wantNil: true,
+		},
+	}
+
+	matcher := &VisibilityMatcher{}
+	ctx := &ParseContext{FilePath: "/test/test.rb", LineNum: 1}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := matcher.Match(tt.line, ctx)
+			if tt.wantNil {
+				if result != nil {
+					t.Errorf("expected nil, got %+v", result.SetVisibility)
+				}
+				return
+			}
+			if result == nil || result.SetVisibility == nil {
+				t.Fatal("expected visibility change, got nil")
+			}
+			change := result.SetVisibility
+			if change.Visibility != tt.wantVis {
+				t.Errorf("expected visibility %v, got %v", tt.wantVis, change.Visibility)
+			}
+			if len(change.Names) != len(tt.wantNames) {
+				t.Fatalf("expected names %v, got %v", tt.wantNames, change.Names)
+			}
+			for i, name := range tt.wantNames {
+				if change.Names[i] != name {
+					t.Errorf("expected name %q at %d, got %q", name, i, change.Names[i])
+				}
+			}
+		})
+	}
+}
+
+func TestVisibilityParsing(t *testing.T) {
+	content := `class Client
+  TIMEOUT = 5
+  VERSION = "1.0"
+  private_constant :TIMEOUT
+
+  def self.new(*args)
+  end
+  private_class_method :new
+
+  def call
+  end
+
+  def compare(other)
+  end
+  protected :compare
+
+  private def sign(body)
+  end
+
+  private_class_method def self.default
+  end
+
+  private
+
+  public def status
+  end
+
+  def request
+  end
+
+  class Inner
+    def open
+    end
+  end
+
+  def retry!
+  end
+end
+
+class Client
+  def reopened
+  end
+end`
+
+	registry := NewRegistry()
+	RegisterDefaults(registry)
+	symbols := NewScanner(registry).Parse("/test/client.rb", []byte(content))
+
+	want := map[string]types.Visibility{
+		"Client::TIMEOUT":    types.VisibilityPrivate,
+		"Client::VERSION":    types.VisibilityPublic,
+		"Client.new":         types.VisibilityPrivate,
+		"Client#call":        types.VisibilityPublic,
+		"Client#compare":     types.VisibilityProtected,
+		"Client#sign":        types.VisibilityPrivate,
+		"Client.default":     types.VisibilityPrivate,
+		"Client#status":      types.VisibilityPublic,
+		"Client#request":     types.VisibilityPrivate,
+		"Client::Inner#open": types.VisibilityPublic,
+		"Client#retry!":      types.VisibilityPrivate,
+		"Client#reopened":    types.VisibilityPublic,
+	}
+
+	found := make(map[string]bool)
+	for _, sym := range symbols {
+		vis, ok := want[sym.FullName]
+		if !ok {
+			continue
+		}
+		found[sym.FullName] = true
+		if sym.Visibility != vis {
+			t.Errorf("%s: expected %v, got %v", sym.FullName, vis, sym.Visibility)
+		}
+	}
+	for name := range want {
+		if !found[name] {
+			t.Errorf("symbol %s not found", name)
+		}
+	}
+}
@@ -14,9 +14,22 @@ const (
 	KindAttrReader
 	KindAttrWriter
 	KindAttrAccessor
-	KindLocalVariable // Local variable inside a method
-	KindCustom        // For plugin-defined symbols
-	KindRelation      // Rails relation (belongs_to, has_one, has_many)
+	KindLocalVariable    // Local variable inside a method
+	KindCustom           // For plugin-defined symbols
+	KindRelation         // Rails relation (belongs_to, has_one, has_many)
+	KindColumn           // Database column from db/schema.rb
+	KindExampleGroup     // RSpec describe/context block
+	KindSharedExample    // RSpec shared_examples/shared_context
+	KindLet              // RSpec let/let!/subject helper
+	KindStep             // Cucumber step definition
+	KindNamespace        // DSL namespace block (Rake/Capistrano namespace, Vagrant machine)
+	KindTask             // DSL task (Rake/Capistrano task, Chef definition or action)
+	KindEnumValue        // ActiveRecord enum value (draft in enum status: { draft: 0 })
+	KindRoute            // Rails route from config/routes.rb
+	KindFactory          // FactoryBot factory
+	KindGem              // Gem dependency from a Gemfile or gemspec
+	KindGemAttribute     // Gemspec attribute assignment (spec.version = "1.0")
+	KindInstanceVariable // Instance variable assigned a value of known class (@user = User.new)
 )
 
 func (k SymbolKind) String() string {
@@ -43,11 +56,73 @@ func (k SymbolKind) String() string {
 		return "custom"
 	case KindRelation:
 		return "relation"
+	case KindColumn:
+		return "column"
+	case KindExampleGroup:
+		return "example_group"
+	case KindSharedExample:
+		return "shared_example"
+	case KindLet:
+		return "let"
+	case KindStep:
+		return "step"
+	case KindNamespace:
+		return "namespace"
+	case KindTask:
+		return "task"
+	case KindEnumValue:
+		return "enum_value"
+	case KindRoute:
+		return "route"
+	case KindFactory:
+		return "factory"
+	case KindGem:
+		return "gem"
+	case KindGemAttribute:
+		return "gem_attribute"
+	case KindInstanceVariable:
+		return "instance_variable"
 	default:
 		return "unknown"
 	}
 }
 
+// IsConstant reports whether symbols of the kind are named like constants:
+// classes, modules, and constants
+func (k SymbolKind) IsConstant() bool {
+	return k == KindClass || k == KindModule || k == KindConstant
+}
+
+// ParseSymbolKind converts a kind name (as returned by String) back to a SymbolKind
+func ParseSymbolKind(name string) (SymbolKind, bool) {
+	for k := KindClass; k.String() != "unknown"; k++ {
+		if k.String() == name {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// Visibility is the Ruby visibility of a method or constant
+type Visibility int
+
+const (
+	VisibilityPublic Visibility = iota
+	VisibilityProtected
+	VisibilityPrivate
+)
+
+func (v Visibility) String() string {
+	switch v {
+	case VisibilityProtected:
+		return "protected"
+	case VisibilityPrivate:
+		return "private"
+	default:
+		return "public"
+	}
+}
+
 // Symbol represents a Ruby definition
 type Symbol struct {
 	Name           string // e.g., "MyClass", "my_method"
@@ -61,6 +136,28 @@ type Symbol struct {
 	FullName       string   // Computed: "MyModule::MyClass#my_method"
 	MethodFullName string   // For local variables: the containing method's FullName
 	TargetName     string   // For relations: the target class name to look up
+
+	// TypeName is the class a symbol stands for: for local variables, the
+	// class of the assigned value (User for user = User.find(id)); for
+	// example groups, the described class; for let helpers, the class
+	// constructed, or described_class
+	TypeName string
+
+	RelationType string            // For relations: belongs_to, has_one, or has_many
+	Options      map[string]string // For relations and routes: literal option values (inverse_of, to, ...)
+	Macro        string            // For symbols declared by a DSL call: the call (scope, enum status, resources, factory)
+	Superclass   string            // For classes: the superclass name as written
+	Includes     []string          // For classes and modules: modules mixed in with include or prepend, as written
+	Refines      string            // For refinement blocks: the refined class, as written
+	Values       []string          // For constants: literal array elements (%w[a b], [:a, :b])
+	Visibility   Visibility
+	Synthetic    bool // Ghost symbol inferred from metaprogramming (low confidence)
+}
+
+// IsGhostPrefix reports whether this synthetic symbol stands for every method
+// starting with a prefix (e.g., "find_by_*" from method_missing)
+func (s *Symbol) IsGhostPrefix() bool {
+	return s.Synthetic && strings.HasSuffix(s.Name, "*")
 }
 
 // ComputeFullName generates the fully qualified name for this symbol
@@ -69,7 +166,7 @@ func (s *Symbol) ComputeFullName() string {
 	parts = append(parts, s.Scope...)
 
 	switch s.Kind {
-	case KindMethod, KindAttrReader, KindAttrWriter, KindAttrAccessor:
+	case KindMethod, KindAttrReader, KindAttrWriter, KindAttrAccessor, KindColumn:
 		// Instance methods use #
 		if len(parts) > 0 {
 			return strings.Join(parts, "::") + "#" + s.Name
@@ -81,6 +178,9 @@ func (s *Symbol) ComputeFullName() string {
 			return strings.Join(parts, "::") + "." + s.Name
 		}
 		return "." + s.Name
+	case KindInstanceVariable:
+		// Instance variables are named with their sigil: "MyClass#@ivar"
+		return strings.Join(parts, "::") + "#" + s.Name
 	case KindLocalVariable:
 		// Local variables use @ after the method name: "MyClass#method@varname"
 		if s.MethodFullName != "" {
@@ -1,6 +1,7 @@
 package watcher
 
 import (
+	"errors"
 	"io/fs"
 	"log"
 	"os"
@@ -13,13 +14,22 @@ import (
 // ChangeHandler is called when files change
 type ChangeHandler func(changed, removed []string)
 
+// ErrorHandler is called when watching runs into trouble. stopped is true
+// when the watcher has died and no more changes will be seen.
+type ErrorHandler func(err error, stopped bool)
+
 // Watcher monitors Ruby files for changes using fsnotify
 type Watcher struct {
 	watcher   *fsnotify.Watcher
 	rootPath  string
 	handler   ChangeHandler
+	onError   ErrorHandler
 	debouncer *Debouncer
 	done      chan struct{}
+
+	// Watched directories, so their removal or renaming can be recognized
+	// after they're gone. Only touched by Start and the event loop.
+	dirs map[string]bool
 }
 
 // New creates a new file watcher for the root path
@@ -35,41 +45,65 @@ func New(rootPath string, handler ChangeHandler) (*Watcher, error) {
 		handler:   handler,
 		debouncer: NewDebouncer(100), // 100ms debounce
 		done:      make(chan struct{}),
+		dirs:      make(map[string]bool),
 	}
 
 	return w, nil
 }
 
+// OnError sets the handler told of watcher errors, such as dropped events
+// or the watcher stopping. Call it before Start.
+func (w *Watcher) OnError(handler ErrorHandler) {
+	w.onError = handler
+}
+
+// reportError logs a watcher error and tells the error handler
+func (w *Watcher) reportError(err error, stopped bool) {
+	log.Printf("watcher error: %v", err)
+	if w.onError != nil {
+		w.onError(err, stopped)
+	}
+}
+
 // Start begins watching for file changes
 func (w *Watcher) Start() error {
 	// Add all directories recursively
-	err := filepath.WalkDir(w.rootPath, func(path string, d fs.DirEntry, err error) error {
+	if _, err := w.addTree(w.rootPath); err != nil {
+		return err
+	}
+
+	// Start the event loop
+	go w.eventLoop()
+
+	log.Printf("file watcher started for %s", w.rootPath)
+	return nil
+}
+
+// addTree watches root and every directory below it, returning the Ruby
+// files already there
+func (w *Watcher) addTree(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil // Skip errors
 		}
 
 		if d.IsDir() {
-			name := d.Name()
-			// Skip hidden and vendor directories
-			if strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules" {
+			if path != w.rootPath && skipDir(d.Name()) {
 				return filepath.SkipDir
 			}
 
 			if err := w.watcher.Add(path); err != nil {
 				log.Printf("failed to watch %s: %v", path, err)
+			} else {
+				w.dirs[path] = true
 			}
+		} else if isRubyFile(path) {
+			files = append(files, path)
 		}
 		return nil
 	})
-	if err != nil {
-		return err
-	}
-
-	// Start the event loop
-	go w.eventLoop()
-
-	log.Printf("file watcher started for %s", w.rootPath)
-	return nil
+	return files, err
 }
 
 func (w *Watcher) eventLoop() {
@@ -80,36 +114,66 @@ func (w *Watcher) eventLoop() {
 
 		case event, ok := <-w.watcher.Events:
 			if !ok {
+				w.stopped()
 				return
 			}
 			w.handleEvent(event)
 
 		case err, ok := <-w.watcher.Errors:
 			if !ok {
+				w.stopped()
 				return
 			}
-			log.Printf("watcher error: %v", err)
+			w.reportError(err, false)
 		}
 	}
 }
 
+// stopped reports the event loop ending without Close, which leaves the
+// index blind to changes
+func (w *Watcher) stopped() {
+	select {
+	case <-w.done:
+	default:
+		w.reportError(errors.New("file watcher stopped unexpectedly"), true)
+	}
+}
+
 func (w *Watcher) handleEvent(event fsnotify.Event) {
 	path := event.Name
 
 	// Check if it's a directory event
 	if event.Has(fsnotify.Create) {
-		// If a new directory was created, watch it
+		// If a new directory was created, watch it and everything below it.
+		// With mkdir -p, nested directories and files can appear before the
+		// watch is in place, so index the Ruby files already there.
 		if info, err := os.Lstat(path); err == nil && info.IsDir() {
-			name := filepath.Base(path)
-			if !strings.HasPrefix(name, ".") && name != "vendor" && name != "node_modules" {
-				if err := w.watcher.Add(path); err != nil {
-					log.Printf("failed to watch new directory %s: %v", path, err)
-				}
+			if skipDir(filepath.Base(path)) {
+				return
+			}
+			files, err := w.addTree(path)
+			if err != nil {
+				log.Printf("failed to watch new directory %s: %v", path, err)
+			}
+			for _, file := range files {
+				w.debouncer.Add(file, fsnotify.Create)
+			}
+			if len(files) > 0 {
+				w.flush()
 			}
 			return
 		}
 	}
 
+	// A directory moved or deleted takes the files under it along. Report
+	// the directory itself; the handler expands it to the files it indexed.
+	if w.dirs[path] && (event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)) {
+		w.forgetTree(path)
+		w.debouncer.Add(path, event.Op)
+		w.flush()
+		return
+	}
+
 	// Only process Ruby files
 	if !isRubyFile(path) {
 		return
@@ -117,6 +181,24 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 
 	// Debounce and dispatch changes
 	w.debouncer.Add(path, event.Op)
+	w.flush()
+}
+
+// forgetTree stops watching root and the directories below it. A renamed
+// directory keeps its watch, which would report events under the old path.
+func (w *Watcher) forgetTree(root string) {
+	prefix := root + string(filepath.Separator)
+	for dir := range w.dirs {
+		if dir == root || strings.HasPrefix(dir, prefix) {
+			delete(w.dirs, dir)
+			_ = w.watcher.Remove(dir) // Already gone when the directory was deleted
+		}
+	}
+}
+
+// flush dispatches the pending changes once the debounce interval passes
+// without more
+func (w *Watcher) flush() {
 	w.debouncer.Flush(func(changed, removed []string) {
 		if len(changed) > 0 || len(removed) > 0 {
 			log.Printf("file changes: %d changed, %d removed", len(changed), len(removed))
@@ -131,18 +213,24 @@ func (w *Watcher) Close() error {
 	return w.watcher.Close()
 }
 
+// skipDir reports whether a directory is left unwatched: hidden, vendored,
+// and node_modules directories
+func skipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules"
+}
+
 // isRubyFile checks if a file is a Ruby file
 func isRubyFile(path string) bool {
 	ext := filepath.Ext(path)
 	base := filepath.Base(path)
 
 	switch ext {
-	case ".rb", ".rake", ".gemspec":
+	case ".rb", ".rake", ".gemspec", ".cap":
 		return true
 	}
 
 	switch base {
-	case "Gemfile", "Rakefile", "Guardfile", "Vagrantfile":
+	case "Gemfile", "Rakefile", "Guardfile", "Vagrantfile", "Capfile":
 		return true
 	}
 
@@ -0,0 +1,84 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// changes collects the paths a watcher reports as changed
+func changes(t *testing.T, root string) (*Watcher, <-chan string) {
+	t.Helper()
+	changed := make(chan string, 16)
+	w, err := New(root, func(paths, _ []string) {
+		for _, path := range paths {
+			changed <- path
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return w, changed
+}
+
+// waitFor fails the test unless want is reported within a few seconds
+func waitFor(t *testing.T, changed <-chan string, want string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case path := <-changed:
+			if path == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("expected %s to be reported", want)
+		}
+	}
+}
+
+func TestNestedDirectoryCreatedAtOnce(t *testing.T) {
+	root := t.TempDir()
+	w, changed := changes(t, root)
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// mkdir -p a/b/c && touch a/b/c/x.rb, faster than watches are added
+	if err := os.MkdirAll(filepath.Join(root, "a", "b", "c"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(root, "a", "b", "c", "x.rb")
+	if err := os.WriteFile(file, []byte("class X\nend\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, changed, file)
+}
+
+func TestCreatedDirectoryReportsFilesAlreadyInIt(t *testing.T) {
+	root := t.TempDir()
+	w, changed := changes(t, root)
+	if _, err := w.addTree(root); err != nil {
+		t.Fatal(err)
+	}
+
+	// By the time the event for a arrives, the whole tree is there and no
+	// event will come for x.rb
+	file := filepath.Join(root, "a", "b", "c", "x.rb")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("class X\nend\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.handleEvent(fsnotify.Event{Name: filepath.Join(root, "a"), Op: fsnotify.Create})
+	waitFor(t, changed, file)
+
+	if !w.dirs[filepath.Join(root, "a", "b", "c")] {
+		t.Error("expected the nested directory to be watched")
+	}
+}